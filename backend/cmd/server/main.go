@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/database"
 	"github.com/namanjain.3009/daily_bazaar/internal/handlers"
 	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
 	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository/memory"
 	"github.com/namanjain.3009/daily_bazaar/internal/router"
 	"github.com/namanjain.3009/daily_bazaar/internal/services"
+	"github.com/namanjain.3009/daily_bazaar/pkg/resilience"
+	"github.com/namanjain.3009/daily_bazaar/pkg/uropaysim"
 )
 
 func main() {
@@ -18,26 +27,132 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// A broken state machine file should stop the server, not the first order
+	orderStateMachine, err := services.LoadOrderStateMachine(cfg.OrderStateMachineFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	shippingCalculator, err := services.LoadShippingRules(cfg.ShippingRulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// One breaker per upstream; their state is reported on /health
+	supabaseBreaker := resilience.NewBreaker("supabase", cfg.BreakerFailureThreshold, cfg.BreakerCooldown)
+	uroPayBreaker := resilience.NewBreaker("uropay", cfg.BreakerFailureThreshold, cfg.BreakerCooldown)
+	breakers := []*resilience.Breaker{uroPayBreaker}
+
 	// Initialize repositories
-	userRepo := repository.NewUserRepository()
-	productRepo := repository.NewProductRepository()
-	categoryRepo := repository.NewCategoryRepository()
-	orderRepo := repository.NewOrderRepository()
-	productImageRepo := repository.NewProductImageRepository()
-	userAddressRepo := repository.NewUserAddressRepository()
+	var (
+		userRepo         repository.UserStore
+		productRepo      repository.ProductStore
+		categoryRepo     repository.CategoryStore
+		orderRepo        repository.OrderStore
+		orderEventRepo   repository.OrderEventStore
+		productImageRepo repository.ProductImageStore
+		userAddressRepo  repository.UserAddressStore
+		cartRepo         repository.CartStore
+		idempotencyRepo  repository.IdempotencyStore
+		lookupRepo       repository.PaymentLookupStore
+		quarantineRepo   repository.WebhookQuarantineStore
+		eventRepo        repository.WebhookEventStore
+		refundRepo       repository.RefundStore
+		reportRepo       repository.ReconciliationReportStore
+		returnRepo       repository.ReturnStore
+		invoiceRepo      repository.InvoiceStore
+		walletRepo       repository.WalletStore
+		subscriptionRepo repository.SubscriptionStore
+	)
+
+	if cfg.Storage == "memory" {
+		db := memory.NewDB()
+		if cfg.MemorySeedFile != "" {
+			if err := db.LoadSeed(cfg.MemorySeedFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("Using in-memory storage; data is lost on restart")
+
+		userRepo = memory.NewUserRepository(db)
+		productRepo = memory.NewProductRepository(db)
+		categoryRepo = memory.NewCategoryRepository(db)
+		orderRepo = memory.NewOrderRepository(db)
+		orderEventRepo = memory.NewOrderEventRepository(db)
+		productImageRepo = memory.NewProductImageRepository(db)
+		userAddressRepo = memory.NewUserAddressRepository(db)
+		cartRepo = memory.NewCartRepository(db)
+		idempotencyRepo = memory.NewIdempotencyRepository(db)
+		lookupRepo = memory.NewPaymentLookupRepository(db)
+		quarantineRepo = memory.NewWebhookQuarantineRepository(db)
+		eventRepo = memory.NewWebhookEventRepository(db)
+		refundRepo = memory.NewRefundRepository(db)
+		reportRepo = memory.NewReconciliationReportRepository(db)
+		returnRepo = memory.NewReturnRepository(db)
+		invoiceRepo = memory.NewInvoiceRepository(db)
+		walletRepo = memory.NewWalletRepository(db)
+		subscriptionRepo = memory.NewSubscriptionRepository(db)
+	} else {
+		db := database.NewSupabaseClient(cfg, supabaseBreaker)
+		breakers = append(breakers, supabaseBreaker)
+
+		userRepo = repository.NewUserRepository(db)
+		productRepo = repository.NewProductRepository(db)
+		categoryRepo = repository.NewCategoryRepository(db)
+		orderRepo = repository.NewOrderRepository(db)
+		orderEventRepo = repository.NewOrderEventRepository(db)
+		productImageRepo = repository.NewProductImageRepository(db)
+		userAddressRepo = repository.NewUserAddressRepository(db)
+		cartRepo = repository.NewCartRepository(db)
+		idempotencyRepo = repository.NewIdempotencyRepository(db)
+		lookupRepo = repository.NewPaymentLookupRepository(db)
+		quarantineRepo = repository.NewWebhookQuarantineRepository(db)
+		eventRepo = repository.NewWebhookEventRepository(db)
+		refundRepo = repository.NewRefundRepository(db)
+		reportRepo = repository.NewReconciliationReportRepository(db)
+		returnRepo = repository.NewReturnRepository(db)
+		invoiceRepo = repository.NewInvoiceRepository(db)
+		walletRepo = repository.NewWalletRepository(db)
+		subscriptionRepo = repository.NewSubscriptionRepository(db)
+	}
 
 	// Initialize services - UPDATED: ProductService now needs categoryRepo
 	emailService := services.NewEmailService()
 	authService := services.NewAuthService(userRepo, emailService)
 	productService := services.NewProductService(productRepo, categoryRepo) // ✅ CHANGED
 	categoryService := services.NewCategoryService(categoryRepo)
-	orderService := services.NewOrderService(orderRepo, productRepo)
+	gateways := paymentGateways(cfg, uroPayBreaker)
+	refundService := services.NewRefundService(refundRepo, orderRepo, walletRepo, gateways...)
+	invoiceService := services.NewInvoiceService(cfg, invoiceRepo, orderRepo)
+	orderWorkflow := services.NewOrderWorkflow(orderStateMachine, orderRepo, orderEventRepo, productRepo, userRepo, emailService, refundService, invoiceService)
+	paymentService := services.NewPaymentService(cfg, orderRepo, orderWorkflow, lookupRepo, quarantineRepo, eventRepo, gateways...)
+	orderService := services.NewOrderService(cfg, orderRepo, productRepo, orderEventRepo, walletRepo, orderWorkflow, shippingCalculator)
 	productImageService := services.NewProductImageService(productImageRepo, productRepo)
 	userAddressService := services.NewUserAddressService(userAddressRepo)
-	paymentService := services.NewPaymentService(cfg, orderRepo)
+	cartService := services.NewCartService(cfg, cartRepo, productRepo, orderService)
+	reconciler := services.NewPaymentReconciler(cfg, paymentService, orderRepo, reportRepo)
+	returnService := services.NewReturnService(cfg, returnRepo, orderRepo, productRepo, categoryRepo, orderService, refundService)
+	walletService := services.NewWalletService(walletRepo, userRepo)
+	subscriptionService := services.NewSubscriptionService(cfg, subscriptionRepo, productRepo, userRepo, orderService, emailService)
+
+	// Background jobs stop when the process is asked to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Release stock held by orders that were never paid
+	go orderService.RunReservationSweeper(ctx, cfg.StockReservationTTL)
+
+	// Check in-flight payments with their gateway and report daily mismatches
+	if cfg.PaymentReconcileInterval > 0 {
+		go reconciler.Run(ctx)
+	}
+
+	// Place subscription orders ahead of their delivery days
+	if cfg.SubscriptionRunInterval > 0 {
+		go subscriptionService.Run(ctx)
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, cartService)
 	userHandler := handlers.NewUserHandler(userRepo)
 	productHandler := handlers.NewProductHandler(productService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
@@ -45,10 +160,22 @@ func main() {
 	productImageHandler := handlers.NewProductImageHandler(productImageService)
 	userAddressHandler := handlers.NewUserAddressHandler(userAddressService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService, userRepo)
+	cartHandler := handlers.NewCartHandler(cartService)
+	refundHandler := handlers.NewRefundHandler(refundService, userRepo)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciler)
+	returnHandler := handlers.NewReturnHandler(returnService, userRepo)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService, userRepo)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, userRepo)
+	healthHandler := handlers.NewHealthHandler(breakers...)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(authService)
 	adminMiddleware := middleware.NewAdminMiddleware(userRepo)
+	timeoutMiddleware := middleware.NewTimeoutMiddleware(cfg.RequestTimeout, cfg.RouteTimeouts)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyRepo, cfg.IdempotencyTTL)
+
+	go idempotencyMiddleware.RunCleanup(ctx, time.Hour)
 
 	// Setup routes
 	mux := router.SetupRoutes(
@@ -60,17 +187,35 @@ func main() {
 		productImageHandler,
 		userAddressHandler,
 		paymentHandler,
+		cartHandler,
+		refundHandler,
+		reconciliationHandler,
+		returnHandler,
+		invoiceHandler,
+		walletHandler,
+		subscriptionHandler,
 		authMiddleware,
 		adminMiddleware,
+		idempotencyMiddleware,
 	)
 
+	// Local UroPay stand-in; payments complete when a reference is submitted
+	if cfg.UroPaySimulator {
+		log.Printf("UroPay simulator enabled at /uropay-sim; do not use in production")
+		sim := uropaysim.New(uropaysim.Options{
+			APIKey:       cfg.UroPayAPIKey,
+			Secret:       cfg.UroPaySecret,
+			VPA:          cfg.UroPayVPA,
+			WebhookURL:   "http://localhost:" + cfg.Port + "/api/payments/webhook",
+			PayOnUpdate:  true,
+			WebhookDelay: 2 * time.Second,
+		})
+		mux.Handle("/uropay-sim/", http.StripPrefix("/uropay-sim", sim))
+	}
+
 	// Add a lightweight public health endpoint that doesn't require auth.
 	// Render and load balancers can use this to quickly check service health.
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"status":"ok"}`))
-	})
+	mux.HandleFunc("/health", healthHandler.Health)
 
 	// CORS
 	cors := middleware.NewCORSMiddleware([]string{
@@ -81,8 +226,40 @@ func main() {
 		"*",
 	})
 
-	handler := cors.Handler(mux)
+	handler := cors.Handler(timeoutMiddleware.Handler(mux))
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
 
 	log.Printf("Server starting on port %s", cfg.Port)
-	_ = http.ListenAndServe(":"+cfg.Port, handler)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+func paymentGateways(cfg *config.Config, uroPayBreaker *resilience.Breaker) []services.PaymentGateway {
+	var gateways []services.PaymentGateway
+	for _, name := range cfg.PaymentGateways {
+		switch name {
+		case "uropay":
+			gateways = append(gateways, services.NewUroPayGateway(cfg, resilience.NewHTTPClient(uroPayBreaker, resilience.Options{
+				MaxRetries:     cfg.UpstreamMaxRetries,
+				AttemptTimeout: cfg.UroPayTimeout,
+			})))
+		case "fake":
+			log.Printf("Fake payment gateway enabled; do not use in production")
+			gateways = append(gateways, services.NewFakeGateway(cfg.FakeGatewaySecret))
+		}
+	}
+	return gateways
 }
@@ -0,0 +1,33 @@
+// Command uropaysim runs the UroPay simulator on its own port. Point the
+// backend at it with UROPAY_BASE_URL=http://localhost:9090 and the same
+// UROPAY_API_KEY / UROPAY_SECRET.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/pkg/uropaysim"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	webhook := flag.String("webhook", "http://localhost:8080/api/payments/webhook", "merchant webhook URL (empty disables webhooks)")
+	delay := flag.Duration("delay", 2*time.Second, "delay between reference submission and payment")
+	flag.Parse()
+
+	sim := uropaysim.New(uropaysim.Options{
+		APIKey:       os.Getenv("UROPAY_API_KEY"),
+		Secret:       os.Getenv("UROPAY_SECRET"),
+		VPA:          os.Getenv("UROPAY_VPA"),
+		WebhookURL:   *webhook,
+		PayOnUpdate:  true,
+		WebhookDelay: *delay,
+	})
+
+	log.Printf("UroPay simulator listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, sim))
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,11 +20,101 @@ type Config struct {
 	SupabaseKey string
 	JWTSecret   string
 
+	// Storage selects the repository backend: "supabase" (default) or
+	// "memory" for local development without a Supabase project
+	Storage        string
+	MemorySeedFile string
+
 	// UroPay payment gateway
 	UroPayAPIKey  string
 	UroPaySecret  string
 	UroPayVPA     string
 	UroPayVPAName string
+	// UROPAY_BASE_URL points the client elsewhere, e.g. at a simulator.
+	// UROPAY_SIMULATOR=true serves pkg/uropaysim from this server under
+	// /uropay-sim and uses it unless UROPAY_BASE_URL says otherwise.
+	UroPayBaseURL   string
+	UroPaySimulator bool
+
+	// Payment gateways to enable (PAYMENT_GATEWAYS, e.g. "uropay,fake") and
+	// the one used when checkout doesn't pick one
+	PaymentGateways       []string
+	DefaultPaymentGateway string
+	FakeGatewaySecret     string
+
+	// Cash on delivery eligibility. A zero max order value or max refusals
+	// means no limit; an empty pincode list allows every pincode.
+	CODEnabled       bool
+	CODMaxOrderCents int64
+	CODPincodes      []string
+	CODMaxRefusals   int
+
+	// Days after delivery that items can be returned, for categories that
+	// don't set their own window
+	ReturnWindowDays int
+
+	// GST. The seller's state decides between CGST+SGST and IGST; products
+	// without a tax class take one from their HSN code's longest matching
+	// prefix in GSTHSNTaxClasses, else GSTDefaultTaxClass if one is set, and
+	// otherwise can't be sold. PricesIncludeTax must be configured; a product
+	// can override it.
+	GSTSellerState     string
+	GSTDefaultTaxClass string
+	GSTHSNTaxClasses   map[string]string
+	PricesIncludeTax   bool
+
+	// Seller details printed on GST invoices, and the prefix of invoice
+	// numbers (PREFIX/26-27/000001)
+	SellerName    string
+	SellerGSTIN   string
+	SellerAddress string
+	InvoicePrefix string
+
+	// Subscription orders are placed this long before their delivery day
+	// begins, by a generator that runs every SubscriptionRunInterval (zero
+	// disables it)
+	SubscriptionLeadTime    time.Duration
+	SubscriptionRunInterval time.Duration
+
+	// How long an unpaid order may hold stock before it is released
+	StockReservationTTL time.Duration
+
+	// How guest cart lines are merged into a user cart on login: "sum" or
+	// "latest", optionally capped at available stock
+	CartMergeStrategy   string
+	CartMergeCapAtStock bool
+
+	// Deadline for each request, overridable per route pattern (e.g.
+	// "POST /api/payments/initiate"). Zero disables the deadline.
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+
+	// Per-attempt timeouts for outbound calls
+	SupabaseTimeout time.Duration
+	UroPayTimeout   time.Duration
+
+	// Webhooks carrying a signed timestamp older than this are rejected
+	WebhookMaxAge time.Duration
+
+	// How often in-flight payments are checked with their gateway (zero
+	// disables the reconciler), and how long an unpaid intent lives
+	PaymentReconcileInterval time.Duration
+	PaymentIntentTTL         time.Duration
+
+	// JSON file describing order states and transitions; empty uses the
+	// built-in flow
+	OrderStateMachineFile string
+
+	// JSON file with delivery zones and fees; empty uses the built-in rules
+	ShippingRulesFile string
+
+	// How long an Idempotency-Key's first response is kept for replay
+	IdempotencyTTL time.Duration
+
+	// Retries for outbound calls and the per-upstream circuit breaker
+	UpstreamMaxRetries      int
+	BreakerFailureThreshold int
+	BreakerCooldown         time.Duration
 }
 
 func Load() (*Config, error) {
@@ -31,28 +126,225 @@ func Load() (*Config, error) {
 		}
 	}
 
+	var err error
 	cfg := &Config{
 		Port:        strings.TrimSpace(os.Getenv("PORT")),
 		SupabaseURL: strings.TrimSpace(os.Getenv("SUPABASE_URL")),
 		SupabaseKey: strings.TrimSpace(os.Getenv("SUPABASE_KEY")),
 		JWTSecret:   strings.TrimSpace(os.Getenv("JWT_SECRET")),
 
+		Storage:        strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE"))),
+		MemorySeedFile: strings.TrimSpace(os.Getenv("MEMORY_SEED_FILE")),
+
 		UroPayAPIKey:  strings.TrimSpace(os.Getenv("UROPAY_API_KEY")),
 		UroPaySecret:  strings.TrimSpace(os.Getenv("UROPAY_SECRET")),
 		UroPayVPA:     strings.TrimSpace(os.Getenv("UROPAY_VPA")),
 		UroPayVPAName: strings.TrimSpace(os.Getenv("UROPAY_VPA_NAME")),
+		UroPayBaseURL: strings.TrimRight(strings.TrimSpace(os.Getenv("UROPAY_BASE_URL")), "/"),
+
+		DefaultPaymentGateway: strings.ToLower(strings.TrimSpace(os.Getenv("PAYMENT_GATEWAY_DEFAULT"))),
+		FakeGatewaySecret:     strings.TrimSpace(os.Getenv("FAKE_GATEWAY_SECRET")),
+
+		OrderStateMachineFile: strings.TrimSpace(os.Getenv("ORDER_STATE_MACHINE_FILE")),
+		ShippingRulesFile:     strings.TrimSpace(os.Getenv("SHIPPING_RULES_FILE")),
+
+		GSTSellerState:     strings.TrimSpace(os.Getenv("GST_SELLER_STATE")),
+		GSTDefaultTaxClass: strings.ToLower(strings.TrimSpace(os.Getenv("GST_DEFAULT_TAX_CLASS"))),
+
+		SellerName:    strings.TrimSpace(os.Getenv("SELLER_NAME")),
+		SellerGSTIN:   strings.ToUpper(strings.TrimSpace(os.Getenv("SELLER_GSTIN"))),
+		SellerAddress: strings.TrimSpace(os.Getenv("SELLER_ADDRESS")),
+		InvoicePrefix: strings.ToUpper(strings.TrimSpace(os.Getenv("INVOICE_PREFIX"))),
 	}
 
 	if cfg.Port == "" {
 		cfg.Port = "8080"
 	}
 
+	if v := strings.TrimSpace(os.Getenv("UROPAY_SIMULATOR")); v != "" {
+		if cfg.UroPaySimulator, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid UROPAY_SIMULATOR: %q", v)
+		}
+	}
+	if cfg.UroPayBaseURL == "" {
+		cfg.UroPayBaseURL = "https://api.uropay.me"
+		if cfg.UroPaySimulator {
+			cfg.UroPayBaseURL = "http://localhost:" + cfg.Port + "/uropay-sim"
+		}
+	}
+
+	cfg.StockReservationTTL = 30 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("STOCK_RESERVATION_TTL_MINUTES")); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes <= 0 {
+			return nil, fmt.Errorf("invalid STOCK_RESERVATION_TTL_MINUTES: %q", v)
+		}
+		cfg.StockReservationTTL = time.Duration(minutes) * time.Minute
+	}
+
+	cfg.CartMergeStrategy = strings.ToLower(strings.TrimSpace(os.Getenv("CART_MERGE_STRATEGY")))
+	switch cfg.CartMergeStrategy {
+	case "":
+		cfg.CartMergeStrategy = "sum"
+	case "sum", "latest":
+	default:
+		return nil, fmt.Errorf("invalid CART_MERGE_STRATEGY: %q (want sum or latest)", cfg.CartMergeStrategy)
+	}
+
+	cfg.CartMergeCapAtStock = true
+	if v := strings.TrimSpace(os.Getenv("CART_MERGE_CAP_AT_STOCK")); v != "" {
+		capAtStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CART_MERGE_CAP_AT_STOCK: %q", v)
+		}
+		cfg.CartMergeCapAtStock = capAtStock
+	}
+
+	if cfg.RequestTimeout, err = secondsFromEnv("REQUEST_TIMEOUT_SECONDS", 15*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.SupabaseTimeout, err = secondsFromEnv("SUPABASE_TIMEOUT_SECONDS", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.UroPayTimeout, err = secondsFromEnv("UROPAY_TIMEOUT_SECONDS", 15*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookMaxAge, err = secondsFromEnv("WEBHOOK_MAX_AGE_SECONDS", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.PaymentReconcileInterval, err = secondsFromEnv("PAYMENT_RECONCILE_INTERVAL_SECONDS", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	intentTTLMinutes, err := intFromEnv("PAYMENT_INTENT_TTL_MINUTES", 60)
+	if err != nil || intentTTLMinutes == 0 {
+		return nil, fmt.Errorf("invalid PAYMENT_INTENT_TTL_MINUTES: %q", os.Getenv("PAYMENT_INTENT_TTL_MINUTES"))
+	}
+	cfg.PaymentIntentTTL = time.Duration(intentTTLMinutes) * time.Minute
+	if cfg.RouteTimeouts, err = parseRouteTimeouts(os.Getenv("ROUTE_TIMEOUTS")); err != nil {
+		return nil, err
+	}
+
+	cfg.IdempotencyTTL = 24 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("IDEMPOTENCY_TTL_HOURS")); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours <= 0 {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL_HOURS: %q", v)
+		}
+		cfg.IdempotencyTTL = time.Duration(hours) * time.Hour
+	}
+
+	if cfg.UpstreamMaxRetries, err = intFromEnv("UPSTREAM_MAX_RETRIES", 2); err != nil {
+		return nil, err
+	}
+	if cfg.BreakerFailureThreshold, err = intFromEnv("BREAKER_FAILURE_THRESHOLD", 5); err != nil {
+		return nil, err
+	}
+	if cfg.BreakerCooldown, err = secondsFromEnv("BREAKER_COOLDOWN_SECONDS", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	for _, name := range strings.Split(os.Getenv("PAYMENT_GATEWAYS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case "uropay", "fake":
+			cfg.PaymentGateways = append(cfg.PaymentGateways, name)
+		default:
+			return nil, fmt.Errorf("invalid PAYMENT_GATEWAYS entry: %q (want uropay or fake)", name)
+		}
+	}
+	if len(cfg.PaymentGateways) == 0 {
+		cfg.PaymentGateways = []string{"uropay"}
+	}
+	if cfg.DefaultPaymentGateway == "" {
+		cfg.DefaultPaymentGateway = cfg.PaymentGateways[0]
+	}
+	if !slices.Contains(cfg.PaymentGateways, cfg.DefaultPaymentGateway) {
+		return nil, fmt.Errorf("invalid PAYMENT_GATEWAY_DEFAULT: %q is not in PAYMENT_GATEWAYS", cfg.DefaultPaymentGateway)
+	}
+	if cfg.FakeGatewaySecret == "" {
+		cfg.FakeGatewaySecret = "fake-gateway-secret"
+	}
+
+	cfg.CODEnabled = true
+	if v := strings.TrimSpace(os.Getenv("COD_ENABLED")); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COD_ENABLED: %q", v)
+		}
+		cfg.CODEnabled = enabled
+	}
+	codMaxRupees, err := intFromEnv("COD_MAX_ORDER_RUPEES", 5000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.CODMaxOrderCents = int64(codMaxRupees) * 100
+	for _, pincode := range strings.Split(os.Getenv("COD_PINCODES"), ",") {
+		if pincode = strings.TrimSpace(pincode); pincode != "" {
+			cfg.CODPincodes = append(cfg.CODPincodes, pincode)
+		}
+	}
+	if cfg.CODMaxRefusals, err = intFromEnv("COD_MAX_REFUSALS", 2); err != nil {
+		return nil, err
+	}
+	if cfg.ReturnWindowDays, err = intFromEnv("RETURN_WINDOW_DAYS", 7); err != nil {
+		return nil, err
+	}
+
+	// No default class or tax treatment is assumed: a wrong guess puts the
+	// wrong tax on every invoice
+	if cfg.GSTDefaultTaxClass != "" && !slices.Contains(TaxClasses, cfg.GSTDefaultTaxClass) {
+		return nil, fmt.Errorf("invalid GST_DEFAULT_TAX_CLASS: %q (want one of %s)", cfg.GSTDefaultTaxClass, strings.Join(TaxClasses, ", "))
+	}
+	if cfg.GSTHSNTaxClasses, err = parseHSNTaxClasses(os.Getenv("GST_HSN_TAX_CLASSES")); err != nil {
+		return nil, err
+	}
+	v := strings.TrimSpace(os.Getenv("PRICES_INCLUDE_TAX"))
+	if v == "" {
+		return nil, errors.New("PRICES_INCLUDE_TAX must be set to true or false")
+	}
+	if cfg.PricesIncludeTax, err = strconv.ParseBool(v); err != nil {
+		return nil, fmt.Errorf("invalid PRICES_INCLUDE_TAX: %q", v)
+	}
+
+	if cfg.SellerName == "" {
+		cfg.SellerName = "Daily Bazaar"
+	}
+	if cfg.SellerGSTIN != "" && !reGSTIN.MatchString(cfg.SellerGSTIN) {
+		return nil, fmt.Errorf("invalid SELLER_GSTIN: %q", cfg.SellerGSTIN)
+	}
+	// Invoice numbers may be at most 16 characters
+	if cfg.InvoicePrefix == "" {
+		cfg.InvoicePrefix = "DB"
+	}
+	if len(cfg.InvoicePrefix) > 3 || strings.Trim(cfg.InvoicePrefix, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+		return nil, fmt.Errorf("invalid INVOICE_PREFIX: %q (want up to 3 letters or digits)", cfg.InvoicePrefix)
+	}
+
+	leadHours, err := intFromEnv("SUBSCRIPTION_LEAD_HOURS", 12)
+	if err != nil || leadHours < 1 || leadHours > 72 {
+		return nil, fmt.Errorf("invalid SUBSCRIPTION_LEAD_HOURS: %q (want 1 to 72)", os.Getenv("SUBSCRIPTION_LEAD_HOURS"))
+	}
+	cfg.SubscriptionLeadTime = time.Duration(leadHours) * time.Hour
+	if cfg.SubscriptionRunInterval, err = secondsFromEnv("SUBSCRIPTION_RUN_INTERVAL_SECONDS", 15*time.Minute); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Storage {
+	case "":
+		cfg.Storage = "supabase"
+	case "supabase", "memory":
+	default:
+		return nil, fmt.Errorf("invalid STORAGE: %q (want supabase or memory)", cfg.Storage)
+	}
+
 	// basic required checks
 	missing := []string{}
-	if cfg.SupabaseURL == "" {
+	if cfg.Storage == "supabase" && cfg.SupabaseURL == "" {
 		missing = append(missing, "SUPABASE_URL")
 	}
-	if cfg.SupabaseKey == "" {
+	if cfg.Storage == "supabase" && cfg.SupabaseKey == "" {
 		missing = append(missing, "SUPABASE_KEY")
 	}
 	if cfg.JWTSecret == "" {
@@ -64,3 +356,81 @@ func Load() (*Config, error) {
 
 	return cfg, nil
 }
+
+// secondsFromEnv reads a whole number of seconds; 0 is allowed and means
+// "no timeout".
+func secondsFromEnv(key string, def time.Duration) (time.Duration, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def, nil
+	}
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, v)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func intFromEnv(key string, def int) (int, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, v)
+	}
+	return n, nil
+}
+
+// parseRouteTimeouts parses ROUTE_TIMEOUTS, a comma-separated list of
+// "<route pattern>=<seconds>" pairs such as
+// "POST /api/payments/initiate=30,GET /api/products/search=5". Patterns must
+// match the ones registered in the router exactly.
+func parseRouteTimeouts(raw string) (map[string]time.Duration, error) {
+	out := map[string]time.Duration{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid ROUTE_TIMEOUTS entry: %q (want \"METHOD /path=seconds\")", entry)
+		}
+		pattern := strings.Join(strings.Fields(entry[:i]), " ")
+		seconds, err := strconv.Atoi(strings.TrimSpace(entry[i+1:]))
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid ROUTE_TIMEOUTS entry: %q", entry)
+		}
+		out[pattern] = time.Duration(seconds) * time.Second
+	}
+	return out, nil
+}
+
+// reGSTIN matches a GST identification number: state code, PAN, entity
+// number, "Z" and a check character
+var reGSTIN = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+
+// TaxClasses are the GST slabs a product can be put in
+var TaxClasses = []string{"exempt", "gst_5", "gst_12", "gst_18"}
+
+// parseHSNTaxClasses parses GST_HSN_TAX_CLASSES, a comma-separated list of
+// "<HSN prefix>=<tax class>" pairs such as "0401=exempt,0402=gst_5,1905=gst_18".
+func parseHSNTaxClasses(raw string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, class, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		class = strings.ToLower(strings.TrimSpace(class))
+		if !ok || prefix == "" || strings.Trim(prefix, "0123456789") != "" || !slices.Contains(TaxClasses, class) {
+			return nil, fmt.Errorf("invalid GST_HSN_TAX_CLASSES entry: %q (want \"HSN prefix=tax class\")", entry)
+		}
+		out[prefix] = class
+	}
+	return out, nil
+}
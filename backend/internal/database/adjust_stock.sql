@@ -0,0 +1,30 @@
+-- adjust_stock adds p_delta to the stock of a variant that keeps its own
+-- stock, or else of its product, in a single statement. It returns the new
+-- stock, or null when the row is missing or the change would take the stock
+-- below zero. ProductRepository.ReserveStock and ReleaseStock call it.
+alter table product_variants add column if not exists stock integer;
+
+create or replace function adjust_stock(p_product_id uuid, p_variant_id uuid, p_delta integer)
+returns integer
+language plpgsql
+as $$
+declare
+  v_stock integer;
+begin
+  if p_variant_id is not null
+     and exists (select 1 from product_variants
+                 where id = p_variant_id and product_id = p_product_id and stock is not null) then
+    update product_variants
+       set stock = stock + p_delta
+     where id = p_variant_id and stock + p_delta >= 0
+    returning stock into v_stock;
+    return v_stock;
+  end if;
+
+  update products
+     set stock = stock + p_delta
+   where id = p_product_id and stock + p_delta >= 0
+  returning stock into v_stock;
+  return v_stock;
+end;
+$$;
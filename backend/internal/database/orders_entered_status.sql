@@ -0,0 +1,30 @@
+-- orders_entered_status returns a page of the orders that moved into
+-- p_to_status within [p_from, p_to), most recent such move first, optionally
+-- only those whose current status is p_status. Null bounds, status or limit
+-- leave that filter off. Edits, recorded as events that stay in the same
+-- status, don't count. OrderRepository.GetOrdersEnteredStatus calls it.
+create or replace function orders_entered_status(
+  p_to_status text,
+  p_status text,
+  p_from timestamptz,
+  p_to timestamptz,
+  p_limit integer,
+  p_offset integer
+)
+returns table (order_id uuid, entered_at timestamptz)
+language sql
+stable
+as $$
+  select e.order_id, max(e.created_at) as entered_at
+    from order_events e
+    join orders o on o.id = e.order_id
+   where e.to_status = p_to_status
+     and e.from_status is distinct from p_to_status
+     and (p_from is null or e.created_at >= p_from)
+     and (p_to is null or e.created_at < p_to)
+     and (p_status is null or o.status = p_status)
+   group by e.order_id
+   order by entered_at desc, e.order_id
+   limit p_limit
+  offset p_offset;
+$$;
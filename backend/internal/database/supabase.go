@@ -1 +1,17 @@
 package database
+
+import (
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/pkg/resilience"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// NewSupabaseClient builds the PostgREST client shared by every repository.
+// Reads are retried with backoff and all calls go through breaker.
+func NewSupabaseClient(cfg *config.Config, breaker *resilience.Breaker) *supabase.Client {
+	httpClient := resilience.NewHTTPClient(breaker, resilience.Options{
+		MaxRetries:     cfg.UpstreamMaxRetries,
+		AttemptTimeout: cfg.SupabaseTimeout,
+	})
+	return supabase.NewClient(cfg.SupabaseURL, cfg.SupabaseKey, httpClient)
+}
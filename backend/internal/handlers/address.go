@@ -25,7 +25,7 @@ func (h *UserAddressHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	out, err := h.service.List(claims.UserID)
+	out, err := h.service.List(r.Context(), claims.UserID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -48,7 +48,7 @@ func (h *UserAddressHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	addr, err := h.service.Create(claims.UserID, &req)
+	addr, err := h.service.Create(r.Context(), claims.UserID, &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -79,7 +79,7 @@ func (h *UserAddressHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	addr, err := h.service.Update(claims.UserID, id, &req)
+	addr, err := h.service.Update(r.Context(), claims.UserID, id, &req)
 	if err != nil {
 		if err.Error() == "address not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -111,7 +111,7 @@ func (h *UserAddressHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Delete(claims.UserID, id); err != nil {
+	if err := h.service.Delete(r.Context(), claims.UserID, id); err != nil {
 		if err.Error() == "address not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
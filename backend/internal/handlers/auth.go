@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
@@ -10,10 +11,14 @@ import (
 
 type AuthHandler struct {
 	authService *services.AuthService
+	cartService *services.CartService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, cartService *services.CartService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		cartService: cartService,
+	}
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -29,12 +34,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Register(&req)
+	response, err := h.authService.Register(r.Context(), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	h.mergeGuestCart(r, response.User.ID)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
@@ -52,12 +59,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Login(&req)
+	response, err := h.authService.Login(r.Context(), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	h.mergeGuestCart(r, response.User.ID)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -74,7 +83,7 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.authService.ForgotPassword(req.Email); err != nil {
+	if err := h.authService.ForgotPassword(r.Context(), req.Email); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -102,7 +111,7 @@ func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.authService.ResetPassword(req.Email, req.OTP, req.NewPassword); err != nil {
+	if err := h.authService.ResetPassword(r.Context(), req.Email, req.OTP, req.NewPassword); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -112,3 +121,15 @@ func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		"message": "Password has been reset successfully",
 	})
 }
+
+// mergeGuestCart folds the caller's guest cart (X-Cart-Token) into their user
+// cart. Failures are logged rather than failing the login itself.
+func (h *AuthHandler) mergeGuestCart(r *http.Request, userID string) {
+	token := r.Header.Get("X-Cart-Token")
+	if token == "" {
+		return
+	}
+	if err := h.cartService.MergeGuestCart(r.Context(), userID, token); err != nil {
+		log.Printf("Failed to merge guest cart for user %s: %v", userID, err)
+	}
+}
@@ -1 +1,196 @@
 package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type CartHandler struct {
+	cartService *services.CartService
+}
+
+func NewCartHandler(cartService *services.CartService) *CartHandler {
+	return &CartHandler{cartService: cartService}
+}
+
+// GetCart handles GET /api/cart
+func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.cartService.GetCart(r.Context(), cartOwner(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
+
+// AddItem handles POST /api/cart/items
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	var req models.AddCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.cartService.AddItem(r.Context(), cartOwner(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
+
+// UpdateItem handles PUT /api/cart/items/{id}
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Cart item ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.cartService.UpdateItem(r.Context(), cartOwner(r), id, &req)
+	if err != nil {
+		if err.Error() == "cart item not found" || errors.Is(err, repository.ErrCartNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
+
+// RemoveItem handles DELETE /api/cart/items/{id}
+func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Cart item ID is required", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(r.Context(), cartOwner(r), id)
+	if err != nil {
+		if err.Error() == "cart item not found" || errors.Is(err, repository.ErrCartNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
+
+// ClearCart handles DELETE /api/cart
+func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
+	if err := h.cartService.ClearCart(r.Context(), cartOwner(r)); err != nil {
+		if errors.Is(err, repository.ErrCartNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Checkout handles POST /api/cart/checkout
+func (h *CartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CheckoutCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.cartService.Checkout(r.Context(), claims.UserID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrCartChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// Reorder handles POST /api/orders/{id}/reorder
+func (h *CartHandler) Reorder(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional; without one the items go into the cart
+	var req models.ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.cartService.Reorder(r.Context(), id, claims.UserID, &req)
+	if err != nil {
+		if err.Error() == "order not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err.Error() == "access denied" {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Order != nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// cartOwner identifies the caller's cart: the logged-in user if the request
+// was authenticated, otherwise the guest cart named by X-Cart-Token.
+func cartOwner(r *http.Request) models.CartOwner {
+	owner := models.CartOwner{GuestToken: r.Header.Get("X-Cart-Token")}
+	if claims := middleware.GetUserFromContext(r.Context()); claims != nil {
+		owner.UserID = claims.UserID
+	}
+	return owner
+}
@@ -20,7 +20,7 @@ func NewCategoryHandler(categoryService *services.CategoryService) *CategoryHand
 
 // GetAllCategories handles GET /api/categories
 func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.categoryService.GetAllCategories()
+	categories, err := h.categoryService.GetAllCategories(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -56,7 +56,7 @@ func (h *CategoryHandler) GetRootCategories(w http.ResponseWriter, r *http.Reque
 		maxPos = &n
 	}
 
-	categories, err := h.categoryService.GetRootCategories(minPos, maxPos)
+	categories, err := h.categoryService.GetRootCategories(r.Context(), minPos, maxPos)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -74,7 +74,7 @@ func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	category, err := h.categoryService.GetCategoryByID(id)
+	category, err := h.categoryService.GetCategoryByID(r.Context(), id)
 	if err != nil {
 		if err.Error() == "category not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -96,7 +96,7 @@ func (h *CategoryHandler) GetCategoryBySlug(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	category, err := h.categoryService.GetCategoryBySlug(slug)
+	category, err := h.categoryService.GetCategoryBySlug(r.Context(), slug)
 	if err != nil {
 		if err.Error() == "category not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -118,7 +118,7 @@ func (h *CategoryHandler) GetSubcategories(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	categories, err := h.categoryService.GetSubcategories(parentID)
+	categories, err := h.categoryService.GetSubcategories(r.Context(), parentID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -142,7 +142,7 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	category, err := h.categoryService.CreateCategory(&req)
+	category, err := h.categoryService.CreateCategory(r.Context(), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -173,7 +173,7 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	category, err := h.categoryService.UpdateCategory(id, &req)
+	category, err := h.categoryService.UpdateCategory(r.Context(), id, &req)
 	if err != nil {
 		if err.Error() == "category not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -201,7 +201,7 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.categoryService.DeleteCategory(id); err != nil {
+	if err := h.categoryService.DeleteCategory(r.Context(), id); err != nil {
 		if err.Error() == "cannot delete category with subcategories" {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
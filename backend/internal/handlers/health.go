@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namanjain.3009/daily_bazaar/pkg/resilience"
+)
+
+type HealthHandler struct {
+	breakers []*resilience.Breaker
+}
+
+func NewHealthHandler(breakers ...*resilience.Breaker) *HealthHandler {
+	return &HealthHandler{breakers: breakers}
+}
+
+// Health handles GET /health. It always answers 200 so load balancers keep
+// the instance in rotation; "degraded" means an upstream breaker is open.
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	upstreams := make(map[string]resilience.BreakerStatus, len(h.breakers))
+	for _, b := range h.breakers {
+		st := b.Status()
+		if st.State != resilience.StateClosed {
+			status = "degraded"
+		}
+		upstreams[b.Name()] = st
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"upstreams": upstreams,
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type InvoiceHandler struct {
+	invoiceService *services.InvoiceService
+	userRepo       repository.UserStore
+}
+
+func NewInvoiceHandler(invoiceService *services.InvoiceService, userRepo repository.UserStore) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+		userRepo:       userRepo,
+	}
+}
+
+// GetInvoice handles GET /api/orders/{id}/invoice
+func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+
+	invoice, pdf, err := h.invoiceService.GetInvoicePDF(r.Context(), id, claims.UserID, isAdmin)
+	if err != nil {
+		switch err.Error() {
+		case "order not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "access denied":
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case "order has not been delivered", "replacement orders are not invoiced":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	filename := strings.ReplaceAll(invoice.Number, "/", "-") + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+}
+
+func (h *InvoiceHandler) isUserAdmin(ctx context.Context, userID string) bool {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
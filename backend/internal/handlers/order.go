@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
@@ -13,10 +16,10 @@ import (
 
 type OrderHandler struct {
 	orderService *services.OrderService
-	userRepo     *repository.UserRepository
+	userRepo     repository.UserStore
 }
 
-func NewOrderHandler(orderService *services.OrderService, userRepo *repository.UserRepository) *OrderHandler {
+func NewOrderHandler(orderService *services.OrderService, userRepo repository.UserStore) *OrderHandler {
 	return &OrderHandler{
 		orderService: orderService,
 		userRepo:     userRepo,
@@ -37,7 +40,7 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, err := h.orderService.CreateOrder(claims.UserID, &req)
+	order, err := h.orderService.CreateOrder(r.Context(), claims.UserID, &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -48,6 +51,24 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(order)
 }
 
+// Quote handles POST /api/checkout/quote, pricing an order without placing it
+func (h *OrderHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.orderService.Quote(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
 // GetMyOrders handles GET /api/orders/my
 func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
@@ -56,7 +77,7 @@ func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orders, err := h.orderService.GetUserOrders(claims.UserID)
+	orders, err := h.orderService.GetUserOrders(r.Context(), claims.UserID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -66,6 +87,30 @@ func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(orders)
 }
 
+// CODEligibility handles GET /api/orders/cod-eligibility?total_cents=&pincode=
+func (h *OrderHandler) CODEligibility(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	totalCents, err := strconv.ParseInt(r.URL.Query().Get("total_cents"), 10, 64)
+	if err != nil || totalCents < 0 {
+		http.Error(w, "total_cents is required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.orderService.CODEligibility(r.Context(), claims.UserID, totalCents, r.URL.Query().Get("pincode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // GetOrderByID handles GET /api/orders/{id}
 func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
@@ -81,9 +126,9 @@ func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user is admin
-	isAdmin := h.isUserAdmin(claims.UserID)
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
 
-	order, err := h.orderService.GetOrderByID(id, claims.UserID, isAdmin)
+	order, err := h.orderService.GetOrderByID(r.Context(), id, claims.UserID, isAdmin)
 	if err != nil {
 		if err.Error() == "order not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -101,7 +146,9 @@ func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(order)
 }
 
-// GetAllOrders handles GET /api/orders (Admin only)
+// GetAllOrders handles GET /api/orders (Admin only). With entered_status it
+// lists orders that moved into that status between entered_from and
+// entered_to (RFC 3339 or YYYY-MM-DD, either end optional).
 func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
 	limit := 0
@@ -118,8 +165,24 @@ func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	orders, err := h.orderService.GetAllOrders(status, limit, offset)
+	var orders []models.Order
+	var err error
+	if entered := r.URL.Query().Get("entered_status"); entered != "" {
+		from, ferr := parseTimeParam(r.URL.Query().Get("entered_from"))
+		to, terr := parseTimeParam(r.URL.Query().Get("entered_to"))
+		if ferr != nil || terr != nil {
+			http.Error(w, "entered_from and entered_to must be RFC 3339 times or YYYY-MM-DD dates", http.StatusBadRequest)
+			return
+		}
+		orders, err = h.orderService.GetOrdersEnteredStatus(r.Context(), entered, status, from, to, limit, offset)
+	} else {
+		orders, err = h.orderService.GetAllOrders(r.Context(), status, limit, offset)
+	}
 	if err != nil {
+		if err.Error() == "invalid order status" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -128,8 +191,20 @@ func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(orders)
 }
 
-// UpdateOrderStatus handles PUT /api/orders/{id}/status (Admin only)
+// UpdateOrderStatus handles PUT /api/orders/{id}/status (Admin or delivery agent)
 func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role := h.staffRole(r.Context(), claims.UserID)
+	if role == "" {
+		http.Error(w, "Admin or delivery agent access required", http.StatusForbidden)
+		return
+	}
+
 	id := r.PathValue("id")
 	if id == "" {
 		http.Error(w, "Order ID is required", http.StatusBadRequest)
@@ -142,12 +217,20 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	order, err := h.orderService.UpdateOrderStatus(id, req.Status)
+	order, err := h.orderService.UpdateOrderStatus(r.Context(), id, req.Status, claims.UserID, role, req.Reason)
 	if err != nil {
 		if err.Error() == "order not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if err.Error() == "not allowed to make this status change" {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -170,9 +253,9 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAdmin := h.isUserAdmin(claims.UserID)
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
 
-	order, err := h.orderService.CancelOrder(id, claims.UserID, isAdmin)
+	order, err := h.orderService.CancelOrder(r.Context(), id, claims.UserID, isAdmin)
 	if err != nil {
 		if err.Error() == "order not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -182,6 +265,10 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
 		}
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -190,10 +277,148 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(order)
 }
 
-func (h *OrderHandler) isUserAdmin(userID string) bool {
-	user, err := h.userRepo.GetUserByID(userID)
+// UpdateOrderItem handles PATCH /api/orders/{id}/items/{itemId}
+func (h *OrderHandler) UpdateOrderItem(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateOrderItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	h.reduceOrderItem(w, r, req.Quantity)
+}
+
+// RemoveOrderItem handles DELETE /api/orders/{id}/items/{itemId}
+func (h *OrderHandler) RemoveOrderItem(w http.ResponseWriter, r *http.Request) {
+	h.reduceOrderItem(w, r, 0)
+}
+
+func (h *OrderHandler) reduceOrderItem(w http.ResponseWriter, r *http.Request, quantity int) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	itemID := r.PathValue("itemId")
+	if id == "" || itemID == "" {
+		http.Error(w, "Order ID and item ID are required", http.StatusBadRequest)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+
+	order, err := h.orderService.ReduceOrderItem(r.Context(), id, itemID, quantity, claims.UserID, isAdmin)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		switch err.Error() {
+		case "order not found", "order item not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "access denied":
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case "order has a payment in progress":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// AssignDeliveryAgent handles PUT /api/orders/{id}/delivery-agent (Admin only)
+func (h *OrderHandler) AssignDeliveryAgent(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AssignDeliveryAgent
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		http.Error(w, "agent_id is required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.AssignDeliveryAgent(r.Context(), id, req.AgentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// MarkCODRefused handles POST /api/orders/{id}/cod-refused (Admin only)
+func (h *OrderHandler) MarkCODRefused(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.MarkCODRefused(r.Context(), id, claims.UserID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// parseTimeParam accepts an RFC 3339 time or a YYYY-MM-DD date (midnight
+// UTC); empty means no bound.
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func (h *OrderHandler) isUserAdmin(ctx context.Context, userID string) bool {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return false
 	}
 	return user.IsAdmin
 }
+
+// staffRole is the role a user changes order statuses as, or "" for
+// customers
+func (h *OrderHandler) staffRole(ctx context.Context, userID string) string {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case user.IsAdmin:
+		return models.OrderRoleAdmin
+	case user.IsDeliveryAgent:
+		return models.OrderRoleDeliveryAgent
+	}
+	return ""
+}
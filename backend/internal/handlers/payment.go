@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
@@ -12,10 +16,10 @@ import (
 
 type PaymentHandler struct {
 	paymentService *services.PaymentService
-	userRepo       *repository.UserRepository
+	userRepo       repository.UserStore
 }
 
-func NewPaymentHandler(paymentService *services.PaymentService, userRepo *repository.UserRepository) *PaymentHandler {
+func NewPaymentHandler(paymentService *services.PaymentService, userRepo repository.UserStore) *PaymentHandler {
 	return &PaymentHandler{
 		paymentService: paymentService,
 		userRepo:       userRepo,
@@ -41,13 +45,13 @@ func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resp, err := h.paymentService.InitiatePayment(req.OrderID, req.CustomerName, req.CustomerEmail, claims.UserID, req.Amount)
+	resp, err := h.paymentService.InitiatePayment(r.Context(), &req, claims.UserID)
 	if err != nil {
 		if err.Error() == "access denied" {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
 		}
-		if err.Error() == "order not found" {
+		if errors.Is(err, repository.ErrOrderNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -78,7 +82,7 @@ func (h *PaymentHandler) SubmitReference(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.paymentService.SubmitUPIReference(req.OrderID, req.ReferenceNumber, claims.UserID)
+	err := h.paymentService.SubmitUPIReference(r.Context(), req.OrderID, req.ReferenceNumber, claims.UserID)
 	if err != nil {
 		if err.Error() == "access denied" {
 			http.Error(w, err.Error(), http.StatusForbidden)
@@ -109,9 +113,9 @@ func (h *PaymentHandler) GetPaymentStatus(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	isAdmin := h.isUserAdmin(claims.UserID)
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
 
-	resp, err := h.paymentService.GetPaymentStatus(orderID, claims.UserID, isAdmin)
+	resp, err := h.paymentService.GetPaymentStatus(r.Context(), orderID, claims.UserID, isAdmin)
 	if err != nil {
 		if err.Error() == "access denied" {
 			http.Error(w, err.Error(), http.StatusForbidden)
@@ -126,17 +130,24 @@ func (h *PaymentHandler) GetPaymentStatus(w http.ResponseWriter, r *http.Request
 }
 
 // Webhook handles POST /api/payments/webhook (public - called by UroPay)
+// and POST /api/payments/webhook/{gateway} for other gateways
 func (h *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
-	var payload models.UroPayWebhookPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	gateway := r.PathValue("gateway")
+	if gateway == "" {
+		gateway = "uropay"
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	signature := r.Header.Get("X-Uropay-Signature")
-	environment := r.Header.Get("X-Uropay-Environment")
-
-	if err := h.paymentService.HandleWebhook(payload, signature, environment); err != nil {
+	if err := h.paymentService.HandleWebhook(r.Context(), gateway, r.Header, body); err != nil {
+		if err.Error() == "unsupported payment gateway" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -146,8 +157,135 @@ func (h *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-func (h *PaymentHandler) isUserAdmin(userID string) bool {
-	user, err := h.userRepo.GetUserByID(userID)
+// ListQuarantine handles GET /api/payments/quarantine (Admin only)
+func (h *PaymentHandler) ListQuarantine(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit := 0
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	entries, err := h.paymentService.ListQuarantinedWebhooks(r.Context(), status, limit, offset)
+	if err != nil {
+		if err.Error() == "invalid quarantine status" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ResolveQuarantine handles POST /api/payments/quarantine/{id}/resolve (Admin only)
+func (h *PaymentHandler) ResolveQuarantine(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Quarantine ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ResolveQuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.paymentService.ResolveQuarantinedWebhook(r.Context(), id, claims.UserID, &req)
+	if err != nil {
+		if err.Error() == "quarantined webhook not found" || errors.Is(err, repository.ErrOrderNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// ListWebhookEvents handles GET /api/payments/webhook-events (Admin only)
+func (h *PaymentHandler) ListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	events, err := h.paymentService.ListWebhookEvents(r.Context(), r.URL.Query().Get("gateway"), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		if err.Error() == "invalid webhook event status" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetWebhookEvent handles GET /api/payments/webhook-events/{id} (Admin only)
+func (h *PaymentHandler) GetWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := h.paymentService.GetWebhookEvent(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if err.Error() == "webhook event not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// ReprocessWebhookEvent handles POST /api/payments/webhook-events/{id}/reprocess (Admin only)
+func (h *PaymentHandler) ReprocessWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := h.paymentService.ReprocessWebhookEvent(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if err.Error() == "webhook event not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+func (h *PaymentHandler) isUserAdmin(ctx context.Context, userID string) bool {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return false
 	}
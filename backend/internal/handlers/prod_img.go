@@ -25,7 +25,7 @@ func (h *ProductImageHandler) GetProductImages(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	images, err := h.imageService.GetImagesByProductID(productID)
+	images, err := h.imageService.GetImagesByProductID(r.Context(), productID)
 	if err != nil {
 		if err.Error() == "product not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -47,7 +47,7 @@ func (h *ProductImageHandler) GetImageByID(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	image, err := h.imageService.GetImageByID(id)
+	image, err := h.imageService.GetImageByID(r.Context(), id)
 	if err != nil {
 		if err.Error() == "image not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -82,7 +82,7 @@ func (h *ProductImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 	}
 	req.ProductID = productID
 
-	image, err := h.imageService.AddImage(&req)
+	image, err := h.imageService.AddImage(r.Context(), &req)
 	if err != nil {
 		if err.Error() == "product not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -119,7 +119,7 @@ func (h *ProductImageHandler) AddMultipleImages(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	images, err := h.imageService.AddMultipleImages(productID, req.URLs)
+	images, err := h.imageService.AddMultipleImages(r.Context(), productID, req.URLs)
 	if err != nil {
 		if err.Error() == "product not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -154,7 +154,7 @@ func (h *ProductImageHandler) UpdateImage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	image, err := h.imageService.UpdateImage(id, &req)
+	image, err := h.imageService.UpdateImage(r.Context(), id, &req)
 	if err != nil {
 		if err.Error() == "image not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -188,7 +188,7 @@ func (h *ProductImageHandler) ReorderImages(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := h.imageService.ReorderImages(productID, req.ImageIDs); err != nil {
+	if err := h.imageService.ReorderImages(r.Context(), productID, req.ImageIDs); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -212,7 +212,7 @@ func (h *ProductImageHandler) SetPrimaryImage(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if err := h.imageService.SetPrimaryImage(productID, imageID); err != nil {
+	if err := h.imageService.SetPrimaryImage(r.Context(), productID, imageID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -235,7 +235,7 @@ func (h *ProductImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.imageService.DeleteImage(id); err != nil {
+	if err := h.imageService.DeleteImage(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -257,7 +257,7 @@ func (h *ProductImageHandler) DeleteAllProductImages(w http.ResponseWriter, r *h
 		return
 	}
 
-	if err := h.imageService.DeleteAllProductImages(productID); err != nil {
+	if err := h.imageService.DeleteAllProductImages(r.Context(), productID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
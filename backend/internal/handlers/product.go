@@ -42,7 +42,7 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 		params.CategoryIDs = strings.Split(categoryIDsStr, ",")
 	}
 
-	products, err := h.productService.GetAllProducts(params)
+	products, err := h.productService.GetAllProducts(r.Context(), params)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -60,7 +60,7 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	product, err := h.productService.GetProductByID(id)
+	product, err := h.productService.GetProductByID(r.Context(), id)
 	if err != nil {
 		if err.Error() == "product not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -100,7 +100,7 @@ func (h *ProductHandler) GetProductsByCategory(w http.ResponseWriter, r *http.Re
 		}
 	}
 
-	products, err := h.productService.GetAllProducts(params)
+	products, err := h.productService.GetAllProducts(r.Context(), params)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -133,7 +133,7 @@ func (h *ProductHandler) GetProductsByCategorySQL(w http.ResponseWriter, r *http
 		}
 	}
 
-	products, err := h.productService.GetProductsByCategorySQL(categoryID, limit, offset)
+	products, err := h.productService.GetProductsByCategorySQL(r.Context(), categoryID, limit, offset)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -165,7 +165,7 @@ func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	products, err := h.productService.SearchProductsWithPagination(query, limit, offset)
+	products, err := h.productService.SearchProductsWithPagination(r.Context(), query, limit, offset)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -192,7 +192,7 @@ func (h *ProductHandler) GetSearchSuggestions(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	suggestions, err := h.productService.GetSearchSuggestions(query, limit)
+	suggestions, err := h.productService.GetSearchSuggestions(r.Context(), query, limit)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -216,7 +216,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.productService.CreateProduct(&req)
+	product, err := h.productService.CreateProduct(r.Context(), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -247,7 +247,7 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.productService.UpdateProduct(id, &req)
+	product, err := h.productService.UpdateProduct(r.Context(), id, &req)
 	if err != nil {
 		if err.Error() == "product not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -275,7 +275,7 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.productService.DeleteProduct(id); err != nil {
+	if err := h.productService.DeleteProduct(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
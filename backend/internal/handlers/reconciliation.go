@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type ReconciliationHandler struct {
+	reconciler *services.PaymentReconciler
+}
+
+func NewReconciliationHandler(reconciler *services.PaymentReconciler) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciler: reconciler}
+}
+
+// Run handles POST /api/payments/reconciliation/run (Admin only)
+func (h *ReconciliationHandler) Run(w http.ResponseWriter, r *http.Request) {
+	result, err := h.reconciler.Reconcile(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListReports handles GET /api/payments/reconciliation/reports (Admin only)
+func (h *ReconciliationHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	reports, err := h.reconciler.ListReports(r.Context(), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// GetReport handles GET /api/payments/reconciliation/reports/{date} (Admin only)
+func (h *ReconciliationHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reconciler.GetReport(r.Context(), r.PathValue("date"))
+	if err != nil {
+		if err.Error() == "reconciliation report not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GenerateReport handles POST /api/payments/reconciliation/reports/{date}
+// (Admin only), rebuilding the report for that day
+func (h *ReconciliationHandler) GenerateReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reconciler.Report(r.Context(), r.PathValue("date"))
+	if err != nil {
+		if err.Error() == "invalid date, want YYYY-MM-DD" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type RefundHandler struct {
+	refundService *services.RefundService
+	userRepo      repository.UserStore
+}
+
+func NewRefundHandler(refundService *services.RefundService, userRepo repository.UserStore) *RefundHandler {
+	return &RefundHandler{
+		refundService: refundService,
+		userRepo:      userRepo,
+	}
+}
+
+// GetOrderRefunds handles GET /api/orders/{id}/refunds
+func (h *RefundHandler) GetOrderRefunds(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+
+	refunds, err := h.refundService.GetRefundsForOrder(r.Context(), id, claims.UserID, isAdmin)
+	if err != nil {
+		if err.Error() == "order not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err.Error() == "access denied" {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
+
+// CreateRefund handles POST /api/orders/{id}/refunds (Admin only)
+func (h *RefundHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.refundService.CreateRefund(r.Context(), id, req.AmountCents, req.Reason, claims.UserID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+// ListRefunds handles GET /api/refunds?status= (Admin only)
+func (h *RefundHandler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit := 0
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	refunds, err := h.refundService.ListRefunds(r.Context(), status, limit, offset)
+	if err != nil {
+		if err.Error() == "invalid refund status" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
+
+// CompleteRefund handles POST /api/refunds/{id}/complete (Admin only)
+func (h *RefundHandler) CompleteRefund(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CompleteRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.refundService.CompleteRefund(r.Context(), r.PathValue("id"), claims.UserID, strings.TrimSpace(req.PayoutReference))
+	h.writeRefund(w, refund, err)
+}
+
+// FailRefund handles POST /api/refunds/{id}/fail (Admin only)
+func (h *RefundHandler) FailRefund(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.FailRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.refundService.FailRefund(r.Context(), r.PathValue("id"), claims.UserID, req.Reason)
+	h.writeRefund(w, refund, err)
+}
+
+// RetryRefund handles POST /api/refunds/{id}/retry (Admin only)
+func (h *RefundHandler) RetryRefund(w http.ResponseWriter, r *http.Request) {
+	refund, err := h.refundService.RetryRefund(r.Context(), r.PathValue("id"))
+	h.writeRefund(w, refund, err)
+}
+
+func (h *RefundHandler) writeRefund(w http.ResponseWriter, refund *models.Refund, err error) {
+	if err != nil {
+		if err.Error() == "refund not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err.Error() == "refund is already being retried" {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *RefundHandler) isUserAdmin(ctx context.Context, userID string) bool {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
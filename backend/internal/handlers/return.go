@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type ReturnHandler struct {
+	returnService *services.ReturnService
+	userRepo      repository.UserStore
+}
+
+func NewReturnHandler(returnService *services.ReturnService, userRepo repository.UserStore) *ReturnHandler {
+	return &ReturnHandler{
+		returnService: returnService,
+		userRepo:      userRepo,
+	}
+}
+
+// CreateReturn handles POST /api/orders/{id}/returns
+func (h *ReturnHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	ret, err := h.returnService.CreateReturn(r.Context(), id, claims.UserID, &req)
+	if err != nil {
+		if err.Error() == "order not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err.Error() == "access denied" {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ret)
+}
+
+// GetOrderReturns handles GET /api/orders/{id}/returns
+func (h *ReturnHandler) GetOrderReturns(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Order ID is required", http.StatusBadRequest)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+
+	returns, err := h.returnService.GetReturnsForOrder(r.Context(), id, claims.UserID, isAdmin)
+	if err != nil {
+		if err.Error() == "order not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err.Error() == "access denied" {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+// GetReturn handles GET /api/returns/{id}
+func (h *ReturnHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+
+	ret, err := h.returnService.GetReturn(r.Context(), r.PathValue("id"), claims.UserID, isAdmin)
+	if err != nil && err.Error() == "access denied" {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	h.writeReturn(w, ret, err)
+}
+
+// ListReturns handles GET /api/returns?status= (Admin only)
+func (h *ReturnHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit := 0
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	returns, err := h.returnService.ListReturns(r.Context(), status, limit, offset)
+	if err != nil {
+		if err.Error() == "invalid return status" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+// ApproveReturn handles POST /api/returns/{id}/approve (Admin only)
+func (h *ReturnHandler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ApproveReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.returnService.ApproveReturn(r.Context(), r.PathValue("id"), claims.UserID, &req)
+	h.writeReturn(w, ret, err)
+}
+
+// RejectReturn handles POST /api/returns/{id}/reject (Admin only)
+func (h *ReturnHandler) RejectReturn(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RejectReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.returnService.RejectReturn(r.Context(), r.PathValue("id"), claims.UserID, strings.TrimSpace(req.Note))
+	h.writeReturn(w, ret, err)
+}
+
+func (h *ReturnHandler) writeReturn(w http.ResponseWriter, ret *models.ReturnRequest, err error) {
+	if err != nil {
+		if err.Error() == "return not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (h *ReturnHandler) isUserAdmin(ctx context.Context, userID string) bool {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type SubscriptionHandler struct {
+	subscriptionService *services.SubscriptionService
+	userRepo            repository.UserStore
+}
+
+func NewSubscriptionHandler(subscriptionService *services.SubscriptionService, userRepo repository.UserStore) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		subscriptionService: subscriptionService,
+		userRepo:            userRepo,
+	}
+}
+
+// Create handles POST /api/subscriptions
+func (h *SubscriptionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.subscriptionService.CreateSubscription(r.Context(), claims.UserID, &req)
+	if err != nil {
+		if err.Error() == "product not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// GetMine handles GET /api/subscriptions/my
+func (h *SubscriptionHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subs, err := h.subscriptionService.GetUserSubscriptions(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// List handles GET /api/subscriptions?status= (Admin only)
+func (h *SubscriptionHandler) List(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit, offset := pageParams(r)
+
+	subs, err := h.subscriptionService.ListSubscriptions(r.Context(), status, limit, offset)
+	if err != nil {
+		if err.Error() == "invalid subscription status" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// Get handles GET /api/subscriptions/{id}
+func (h *SubscriptionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+
+	sub, err := h.subscriptionService.GetSubscription(r.Context(), r.PathValue("id"), claims.UserID, isAdmin)
+	h.writeSubscription(w, sub, err)
+}
+
+// Update handles PATCH /api/subscriptions/{id}
+func (h *SubscriptionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.subscriptionService.UpdateSubscription(r.Context(), r.PathValue("id"), claims.UserID, &req)
+	h.writeSubscription(w, sub, err)
+}
+
+// Pause handles POST /api/subscriptions/{id}/pause; the body is optional
+func (h *SubscriptionHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.PauseSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.subscriptionService.PauseSubscription(r.Context(), r.PathValue("id"), claims.UserID, req.Until)
+	h.writeSubscription(w, sub, err)
+}
+
+// Resume handles POST /api/subscriptions/{id}/resume
+func (h *SubscriptionHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sub, err := h.subscriptionService.ResumeSubscription(r.Context(), r.PathValue("id"), claims.UserID)
+	h.writeSubscription(w, sub, err)
+}
+
+// Cancel handles POST /api/subscriptions/{id}/cancel
+func (h *SubscriptionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sub, err := h.subscriptionService.CancelSubscription(r.Context(), r.PathValue("id"), claims.UserID)
+	h.writeSubscription(w, sub, err)
+}
+
+// Skip handles POST /api/subscriptions/{id}/skip
+func (h *SubscriptionHandler) Skip(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SkipSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.subscriptionService.SkipDate(r.Context(), r.PathValue("id"), claims.UserID, req.Date)
+	if err != nil && err.Error() == "the order for this date has already been placed" {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	h.writeSubscription(w, sub, err)
+}
+
+// Unskip handles DELETE /api/subscriptions/{id}/skip/{date}
+func (h *SubscriptionHandler) Unskip(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sub, err := h.subscriptionService.UnskipDate(r.Context(), r.PathValue("id"), claims.UserID, r.PathValue("date"))
+	h.writeSubscription(w, sub, err)
+}
+
+// GetDeliveries handles GET /api/subscriptions/{id}/deliveries
+func (h *SubscriptionHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	isAdmin := h.isUserAdmin(r.Context(), claims.UserID)
+	limit, offset := pageParams(r)
+
+	deliveries, err := h.subscriptionService.GetDeliveries(r.Context(), r.PathValue("id"), claims.UserID, isAdmin, limit, offset)
+	if err != nil {
+		switch err.Error() {
+		case "subscription not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "access denied":
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// GetVacation handles GET /api/subscriptions/vacation
+func (h *SubscriptionHandler) GetVacation(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vacation, err := h.subscriptionService.GetVacation(r.Context(), claims.UserID)
+	if err != nil {
+		if err.Error() == "vacation not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vacation)
+}
+
+// SetVacation handles PUT /api/subscriptions/vacation
+func (h *SubscriptionHandler) SetVacation(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SubscriptionVacationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	vacation, err := h.subscriptionService.SetVacation(r.Context(), claims.UserID, &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vacation)
+}
+
+// EndVacation handles DELETE /api/subscriptions/vacation
+func (h *SubscriptionHandler) EndVacation(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.subscriptionService.EndVacation(r.Context(), claims.UserID); err != nil {
+		if err.Error() == "vacation not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Generate handles POST /api/subscriptions/generate (Admin only), running
+// one pass of the order generator now
+func (h *SubscriptionHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.subscriptionService.RunOnce(r.Context(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (h *SubscriptionHandler) writeSubscription(w http.ResponseWriter, sub *models.Subscription, err error) {
+	if err != nil {
+		switch err.Error() {
+		case "subscription not found", "product not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "access denied":
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *SubscriptionHandler) isUserAdmin(ctx context.Context, userID string) bool {
+	user, err := h.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
+
+func pageParams(r *http.Request) (limit, offset int) {
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+	return limit, offset
+}
@@ -9,10 +9,10 @@ import (
 )
 
 type UserHandler struct {
-	userRepo *repository.UserRepository
+	userRepo repository.UserStore
 }
 
-func NewUserHandler(userRepo *repository.UserRepository) *UserHandler {
+func NewUserHandler(userRepo repository.UserStore) *UserHandler {
 	return &UserHandler{userRepo: userRepo}
 }
 
@@ -24,7 +24,7 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userRepo.GetUserByID(claims.UserID)
+	user, err := h.userRepo.GetUserByID(r.Context(), claims.UserID)
 	if err != nil {
 		if err.Error() == "user not found" {
 			http.Error(w, err.Error(), http.StatusNotFound)
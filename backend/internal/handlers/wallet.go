@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/middleware"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+type WalletHandler struct {
+	walletService *services.WalletService
+}
+
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{walletService: walletService}
+}
+
+// GetMyWallet handles GET /api/wallet
+func (h *WalletHandler) GetMyWallet(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	wallet, err := h.walletService.GetWallet(r.Context(), claims.UserID)
+	h.writeWallet(w, wallet, err)
+}
+
+// GetWallet handles GET /api/wallets/{userId} (Admin only)
+func (h *WalletHandler) GetWallet(w http.ResponseWriter, r *http.Request) {
+	wallet, err := h.walletService.GetWallet(r.Context(), r.PathValue("userId"))
+	h.writeWallet(w, wallet, err)
+}
+
+// Credit handles POST /api/wallets/{userId}/credit (Admin only)
+func (h *WalletHandler) Credit(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreditWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+
+	wallet, err := h.walletService.Credit(r.Context(), r.PathValue("userId"), &req, claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "amount must be positive":
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wallet)
+}
+
+func (h *WalletHandler) writeWallet(w http.ResponseWriter, wallet *models.Wallet, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wallet)
+}
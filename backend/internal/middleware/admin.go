@@ -7,10 +7,10 @@ import (
 )
 
 type AdminMiddleware struct {
-	userRepo *repository.UserRepository
+	userRepo repository.UserStore
 }
 
-func NewAdminMiddleware(userRepo *repository.UserRepository) *AdminMiddleware {
+func NewAdminMiddleware(userRepo repository.UserStore) *AdminMiddleware {
 	return &AdminMiddleware{userRepo: userRepo}
 }
 
@@ -23,7 +23,7 @@ func (m *AdminMiddleware) RequireAdmin(next http.Handler) http.Handler {
 		}
 
 		// Fetch user from database to check admin status
-		user, err := m.userRepo.GetUserByID(claims.UserID)
+		user, err := m.userRepo.GetUserByID(r.Context(), claims.UserID)
 		if err != nil {
 			http.Error(w, "User not found", http.StatusUnauthorized)
 			return
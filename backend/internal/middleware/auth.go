@@ -46,6 +46,18 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// OptionalAuthenticate attaches user claims when a bearer token is sent but
+// lets anonymous requests through, e.g. for guest carts.
+func (m *AuthMiddleware) OptionalAuthenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m.Authenticate(next).ServeHTTP(w, r)
+	})
+}
+
 // Helper function to get user claims from context
 func GetUserFromContext(ctx context.Context) *services.Claims {
 	claims, ok := ctx.Value(UserContextKey).(*services.Claims)
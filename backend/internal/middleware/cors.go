@@ -33,7 +33,8 @@ func (m *CORSMiddleware) Handler(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 			w.Header().Set("Vary", "Origin")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Accept")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Accept, X-Cart-Token, Idempotency-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed")
 			w.Header().Set("Access-Control-Max-Age", "86400")
 		}
 
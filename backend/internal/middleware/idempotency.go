@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// An in-progress record whose heartbeat is older than this belongs to a
+	// request that died (crash, deploy) and may be taken over. The owner
+	// refreshes the heartbeat for as long as its handler runs, so a slow
+	// route (or one with no timeout) keeps its key however long it takes.
+	idempotencyLockTimeout       = 2 * time.Minute
+	idempotencyHeartbeatInterval = 30 * time.Second
+)
+
+// IdempotencyMiddleware makes POST endpoints safe to retry. The first
+// response for a user's Idempotency-Key is stored for the TTL and replayed
+// for later requests with the same key; reusing a key with a different
+// request body is rejected with 422. Requests without the header are passed
+// through unchanged.
+type IdempotencyMiddleware struct {
+	store repository.IdempotencyStore
+	ttl   time.Duration
+
+	lockTimeout time.Duration
+	heartbeat   time.Duration
+}
+
+func NewIdempotencyMiddleware(store repository.IdempotencyStore, ttl time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		store:       store,
+		ttl:         ttl,
+		lockTimeout: idempotencyLockTimeout,
+		heartbeat:   idempotencyHeartbeatInterval,
+	}
+}
+
+// Handle must run after Authenticate, since keys are scoped per user.
+func (m *IdempotencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		claims := GetUserFromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		now := time.Now()
+		record := &models.IdempotencyRecord{
+			UserID:      claims.UserID,
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: hash,
+			State:       models.IdempotencyInProgress,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(m.ttl),
+			Owner:       uuid.New().String(),
+			HeartbeatAt: now,
+		}
+
+		if err := m.store.CreateIdempotencyRecord(r.Context(), record); err != nil {
+			if !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+				log.Printf("Failed to claim idempotency key for user %s: %v", claims.UserID, err)
+				http.Error(w, "Failed to process Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+			if claimed := m.handleExisting(w, r, record); !claimed {
+				return
+			}
+		}
+
+		stop := m.keepAlive(r.Context(), record)
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		stop()
+
+		// Store the outcome even if the client has gone away
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 10*time.Second)
+		defer cancel()
+
+		if rec.status >= http.StatusInternalServerError {
+			// Server errors are not final; let the client retry with the same key
+			if err := m.store.DeleteIdempotencyRecord(ctx, claims.UserID, key, record.Owner); err != nil {
+				log.Printf("Failed to release idempotency key for user %s: %v", claims.UserID, err)
+			}
+			return
+		}
+		if err := m.store.CompleteIdempotencyRecord(ctx, claims.UserID, key, record.Owner, rec.status, rec.Header().Get("Content-Type"), rec.body.String()); err != nil {
+			log.Printf("Failed to store idempotent response for user %s: %v", claims.UserID, err)
+		}
+	})
+}
+
+// RunCleanup periodically deletes expired records until ctx is cancelled.
+func (m *IdempotencyMiddleware) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.store.DeleteExpiredIdempotencyRecords(ctx, time.Now()); err != nil {
+			log.Printf("Idempotency cleanup failed: %v", err)
+		}
+	}
+}
+
+// keepAlive refreshes record's heartbeat until the returned func is called.
+func (m *IdempotencyMiddleware) keepAlive(ctx context.Context, record *models.IdempotencyRecord) func() {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(m.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			touchCtx, touchCancel := context.WithTimeout(ctx, 10*time.Second)
+			if err := m.store.TouchIdempotencyRecord(touchCtx, record.UserID, record.Key, record.Owner, time.Now()); err != nil {
+				log.Printf("Failed to refresh idempotency key for user %s: %v", record.UserID, err)
+			}
+			touchCancel()
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// handleExisting answers a request whose key is already recorded. It returns
+// true when the record was expired or abandoned and record now holds the key,
+// so the request should run.
+func (m *IdempotencyMiddleware) handleExisting(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord) bool {
+	existing, err := m.store.GetIdempotencyRecord(r.Context(), record.UserID, record.Key)
+	if err != nil {
+		// Completed-and-deleted between our insert and read; treat as free
+		if errors.Is(err, repository.ErrIdempotencyRecordNotFound) {
+			return m.claimFreed(w, r, record)
+		}
+		log.Printf("Failed to read idempotency key for user %s: %v", record.UserID, err)
+		http.Error(w, "Failed to process Idempotency-Key", http.StatusInternalServerError)
+		return false
+	}
+
+	if time.Now().After(existing.ExpiresAt) {
+		if err := m.store.DeleteIdempotencyRecord(r.Context(), record.UserID, record.Key, existing.Owner); err != nil {
+			log.Printf("Failed to delete expired idempotency key for user %s: %v", record.UserID, err)
+			http.Error(w, "Failed to process Idempotency-Key", http.StatusInternalServerError)
+			return false
+		}
+		return m.claimFreed(w, r, record)
+	}
+
+	// Checked before any takeover, so a different request can't inherit the
+	// key of one that died
+	if existing.RequestHash != record.RequestHash {
+		http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+		return false
+	}
+
+	if existing.State == models.IdempotencyInProgress && time.Since(existing.HeartbeatAt) > m.lockTimeout {
+		// Only one request wins, and only if the owner still hasn't
+		// heartbeated since we read the record
+		won, err := m.store.TakeOverIdempotencyRecord(r.Context(), record, time.Now().Add(-m.lockTimeout))
+		if err != nil {
+			log.Printf("Failed to take over idempotency key for user %s: %v", record.UserID, err)
+			http.Error(w, "Failed to process Idempotency-Key", http.StatusInternalServerError)
+			return false
+		}
+		if won {
+			log.Printf("Took over abandoned idempotency key for user %s", record.UserID)
+			return true
+		}
+		http.Error(w, "A request with this Idempotency-Key is already in progress", http.StatusConflict)
+		return false
+	}
+
+	if existing.State != models.IdempotencyCompleted {
+		http.Error(w, "A request with this Idempotency-Key is already in progress", http.StatusConflict)
+		return false
+	}
+
+	if existing.ContentType != "" {
+		w.Header().Set("Content-Type", existing.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(existing.StatusCode)
+	io.WriteString(w, existing.ResponseBody)
+	return false
+}
+
+// claimFreed claims a key whose record has just gone away.
+func (m *IdempotencyMiddleware) claimFreed(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord) bool {
+	if err := m.store.CreateIdempotencyRecord(r.Context(), record); err != nil {
+		http.Error(w, "A request with this Idempotency-Key is already in progress", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// requestHash fingerprints what the key is allowed to mean: the endpoint and
+// the exact body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
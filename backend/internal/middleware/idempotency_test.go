@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository/memory"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+)
+
+func idempotentRequest(key string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(`{"items":[]}`))
+	r.Header.Set("Idempotency-Key", key)
+	return r.WithContext(context.WithValue(r.Context(), UserContextKey, &services.Claims{UserID: "u1"}))
+}
+
+func TestIdempotencyKeepsKeyOfSlowRequest(t *testing.T) {
+	store := memory.NewIdempotencyRepository(memory.NewDB())
+	m := NewIdempotencyMiddleware(store, time.Hour)
+	m.lockTimeout, m.heartbeat = 50*time.Millisecond, 10*time.Millisecond
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if runs.Add(1) == 1 {
+			<-release
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	first := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, idempotentRequest("k1"))
+		first <- w.Code
+	}()
+
+	// Well past the lock timeout, but the first request is still running
+	time.Sleep(200 * time.Millisecond)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1"))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("second request got %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	close(release)
+	if code := <-first; code != http.StatusCreated {
+		t.Fatalf("first request got %d, want %d", code, http.StatusCreated)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1"))
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry got %d replayed=%q, want the stored response", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+}
+
+// abandon records an in-progress key for body whose owner stopped
+// heartbeating an hour ago.
+func abandon(t *testing.T, store *memory.IdempotencyRepository, key, body string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/api/orders", nil), []byte(body))
+	err := store.CreateIdempotencyRecord(context.Background(), &models.IdempotencyRecord{
+		UserID: "u1", Key: key, RequestHash: hash, State: models.IdempotencyInProgress, Owner: "dead",
+		CreatedAt: old, HeartbeatAt: old, ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdempotencyTakesOverAbandonedKey(t *testing.T) {
+	store := memory.NewIdempotencyRepository(memory.NewDB())
+	m := NewIdempotencyMiddleware(store, time.Hour)
+	abandon(t, store, "k1", `{"items":[]}`)
+
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	rec, err := store.GetIdempotencyRecord(context.Background(), "u1", "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.State != models.IdempotencyCompleted || rec.Owner == "dead" {
+		t.Fatalf("record %s owned by %q, want completed by the new request", rec.State, rec.Owner)
+	}
+}
+
+func TestIdempotencyRejectsDifferentRequestOnAbandonedKey(t *testing.T) {
+	store := memory.NewIdempotencyRepository(memory.NewDB())
+	m := NewIdempotencyMiddleware(store, time.Hour)
+	abandon(t, store, "k1", `{"items":[{"product_id":"p1"}]}`)
+
+	var runs atomic.Int32
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("k1"))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("handler ran %d times, want 0", n)
+	}
+
+	rec, err := store.GetIdempotencyRecord(context.Background(), "u1", "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Owner != "dead" {
+		t.Fatalf("record owned by %q, want it left with the original request", rec.Owner)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// TimeoutMiddleware gives every request a deadline, chosen by the route
+// pattern it matches. Handlers pass r.Context() down to services and
+// repositories, so when the deadline passes (or the client disconnects) the
+// outbound Supabase/UroPay calls are cancelled as well.
+type TimeoutMiddleware struct {
+	defaultTimeout time.Duration
+	routeTimeouts  map[string]time.Duration
+}
+
+func NewTimeoutMiddleware(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) *TimeoutMiddleware {
+	return &TimeoutMiddleware{defaultTimeout: defaultTimeout, routeTimeouts: routeTimeouts}
+}
+
+// Handler wraps the mux directly because the matched pattern (e.g.
+// "POST /api/orders") is only known to the mux.
+func (m *TimeoutMiddleware) Handler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := m.defaultTimeout
+		if _, pattern := mux.Handler(r); pattern != "" {
+			if d, ok := m.routeTimeouts[pattern]; ok {
+				timeout = d
+			}
+		}
+
+		if timeout <= 0 {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Replies 503 if the handler hasn't finished by the deadline
+		http.TimeoutHandler(mux, timeout, "Request timed out").ServeHTTP(w, r)
+	})
+}
@@ -16,7 +16,8 @@ type UserAddress struct {
 	District     string    `json:"district,omitempty"`
 	State        string    `json:"state"`
 	Pincode      string    `json:"pincode"`
-	CountryCode  string    `json:"country_code"` // always "IN"
+	CountryCode  string    `json:"country_code"`    // always "IN"
+	GSTIN        string    `json:"gstin,omitempty"` // business buyers, printed on invoices
 	CreatedAt    time.Time `json:"created_at,omitempty"`
 	UpdatedAt    time.Time `json:"updated_at,omitempty"`
 }
@@ -33,6 +34,7 @@ type CreateUserAddressRequest struct {
 	District     string `json:"district,omitempty"`
 	State        string `json:"state"`
 	Pincode      string `json:"pincode"`
+	GSTIN        string `json:"gstin,omitempty"`
 }
 
 type UpdateUserAddressRequest struct {
@@ -47,4 +49,5 @@ type UpdateUserAddressRequest struct {
 	District     *string `json:"district,omitempty"`
 	State        *string `json:"state,omitempty"`
 	Pincode      *string `json:"pincode,omitempty"`
+	GSTIN        *string `json:"gstin,omitempty"` // "" removes it
 }
@@ -1 +1,86 @@
 package models
+
+import "time"
+
+type Cart struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id,omitempty"`
+	GuestToken string     `json:"guest_token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty"`
+	Items      []CartItem `json:"items,omitempty"`
+}
+
+type CartItem struct {
+	ID        string `json:"id"`
+	CartID    string `json:"cart_id"`
+	ProductID string `json:"product_id"`
+	VariantID string `json:"variant_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+	// Price when the item was added, used to flag price changes
+	UnitPriceCents int64     `json:"unit_price_cents"`
+	AddedAt        time.Time `json:"added_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// CartOwner identifies a cart either by logged-in user or, for guests, by the
+// opaque token handed out when the guest cart was created.
+type CartOwner struct {
+	UserID     string
+	GuestToken string
+}
+
+// Strategies for folding a guest cart line into an existing user cart line
+const (
+	CartMergeSum    = "sum"    // add both quantities together
+	CartMergeLatest = "latest" // keep whichever line was updated last
+)
+
+type AddCartItemRequest struct {
+	ProductID string `json:"product_id"`
+	VariantID string `json:"variant_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity  int     `json:"quantity"`
+	VariantID *string `json:"variant_id,omitempty"`
+}
+
+type CheckoutCartRequest struct {
+	ShippingAddress map[string]interface{} `json:"shipping_address"`
+	PaymentMetadata map[string]interface{} `json:"payment_metadata,omitempty"`
+	PaymentMethod   string                 `json:"payment_method,omitempty"`
+	DeliverySlot    string                 `json:"delivery_slot,omitempty"`
+}
+
+// CartResponse is the priced view of a cart returned to clients.
+type CartResponse struct {
+	ID            string        `json:"id"`
+	GuestToken    string        `json:"guest_token,omitempty"`
+	Items         []CartLine    `json:"items"`
+	ItemCount     int           `json:"item_count"`
+	SubtotalCents int64         `json:"subtotal_cents"`
+	ShippingCents int64         `json:"shipping_cents"`
+	TaxCents      int64         `json:"tax_cents"`
+	TotalCents    int64         `json:"total_cents"`
+	ShippingFees  []ShippingFee `json:"shipping_fees,omitempty"`
+	Warnings      []string      `json:"warnings,omitempty"`
+}
+
+type CartLine struct {
+	ID              string `json:"id"`
+	ProductID       string `json:"product_id"`
+	ProductName     string `json:"product_name"`
+	ProductImage    string `json:"product_image,omitempty"`
+	VariantID       string `json:"variant_id,omitempty"`
+	VariantName     string `json:"variant_name,omitempty"`
+	Quantity        int    `json:"quantity"`
+	UnitPriceCents  int64  `json:"unit_price_cents"` // current price
+	AddedPriceCents int64  `json:"added_price_cents"`
+	PriceChanged    bool   `json:"price_changed,omitempty"`
+	LineTotalCents  int64  `json:"line_total_cents"`
+	Available       bool   `json:"available"`
+	AvailableStock  int    `json:"available_stock"`
+	Warning         string `json:"warning,omitempty"`
+}
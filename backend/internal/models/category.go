@@ -7,6 +7,9 @@ type Category struct {
 	ParentID string `json:"parent_id,omitempty"`
 	Position int    `json:"position"`
 	ImageURL string `json:"image_url,omitempty"`
+	// Days after delivery that items can be returned; nil uses
+	// RETURN_WINDOW_DAYS and 0 makes them non-returnable
+	ReturnWindowDays *int `json:"return_window_days,omitempty"`
 }
 
 type AddCategory struct {
@@ -15,6 +18,8 @@ type AddCategory struct {
 	ParentID string `json:"parent_id,omitempty"`
 	Position int    `json:"position"`
 	ImageURL string `json:"image_url,omitempty"`
+
+	ReturnWindowDays *int `json:"return_window_days,omitempty"`
 }
 
 type UpdateCategory struct {
@@ -23,4 +28,6 @@ type UpdateCategory struct {
 	ParentID *string `json:"parent_id,omitempty"`
 	Position *int    `json:"position,omitempty"`
 	ImageURL *string `json:"image_url,omitempty"`
+
+	ReturnWindowDays *int `json:"return_window_days,omitempty"`
 }
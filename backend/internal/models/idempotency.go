@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyRecord remembers the first response to a request made with a
+// given Idempotency-Key so retries can be answered without re-executing it.
+type IdempotencyRecord struct {
+	UserID       string    `json:"user_id"`
+	Key          string    `json:"key"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	RequestHash  string    `json:"request_hash"`
+	State        string    `json:"state"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// Owner identifies the request holding the key; it refreshes
+	// HeartbeatAt while it runs so it is never taken over
+	Owner       string    `json:"owner"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
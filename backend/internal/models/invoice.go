@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Invoice is the GST tax invoice of a delivered order. Numbers run without
+// gaps within each financial year (April to March).
+type Invoice struct {
+	ID            string    `json:"id"`
+	OrderID       string    `json:"order_id"`
+	Number        string    `json:"number"`         // e.g. "DB/26-27/000042"
+	FinancialYear string    `json:"financial_year"` // e.g. "2026-27"
+	Sequence      int       `json:"sequence"`
+	IssuedAt      time.Time `json:"issued_at"`
+
+	// Seller details as they were when the invoice was issued
+	SellerName    string `json:"seller_name"`
+	SellerGSTIN   string `json:"seller_gstin,omitempty"`
+	SellerAddress string `json:"seller_address,omitempty"`
+	SellerState   string `json:"seller_state,omitempty"`
+}
@@ -8,7 +8,7 @@ import (
 type Order struct {
 	ID              string                 `json:"id"`
 	UserID          string                 `json:"user_id,omitempty"`
-	SubtotalCents   int64                  `json:"subtotal_cents"`
+	SubtotalCents   int64                  `json:"subtotal_cents"` // taxable value, before GST
 	ShippingCents   int64                  `json:"shipping_cents"`
 	TaxCents        int64                  `json:"tax_cents"`
 	TotalCents      int64                  `json:"total_cents"`
@@ -16,7 +16,13 @@ type Order struct {
 	PlacedAt        time.Time              `json:"placed_at"`
 	ShippingAddress map[string]interface{} `json:"shipping_address,omitempty"`
 	PaymentMetadata map[string]interface{} `json:"payment_metadata,omitempty"`
+	StockStatus     string                 `json:"stock_status,omitempty"`
+	DeliverySlot    string                 `json:"delivery_slot,omitempty"`
+	DeliveryDate    string                 `json:"delivery_date,omitempty"` // YYYY-MM-DD (IST), set on subscription orders
+	ShippingFees    []ShippingFee          `json:"shipping_fees,omitempty"`
+	DeliveryAgentID string                 `json:"delivery_agent_id,omitempty"` // the only agent who may move the order
 	Items           []OrderItem            `json:"items,omitempty"`
+	Timeline        []OrderEvent           `json:"timeline,omitempty"`
 }
 
 type OrderItem struct {
@@ -25,23 +31,74 @@ type OrderItem struct {
 	ProductID      string `json:"product_id"`
 	ProductName    string `json:"product_name"`
 	ProductImage   string `json:"product_image,omitempty"`
+	VariantID      string `json:"variant_id,omitempty"`
+	VariantName    string `json:"variant_name,omitempty"`
 	Quantity       int    `json:"quantity"`
 	UnitPriceCents int64  `json:"unit_price_cents"`
+	WeightGrams    int    `json:"weight_grams,omitempty"` // per unit, for shipping
+
+	// GST for the line, fixed when the order is placed. Taxable plus the
+	// three taxes is what the line costs.
+	HSNCode          string  `json:"hsn_code,omitempty"`
+	GSTRate          float64 `json:"gst_rate"` // percent
+	PriceIncludesTax bool    `json:"price_includes_tax"`
+	TaxableCents     int64   `json:"taxable_cents"`
+	CGSTCents        int64   `json:"cgst_cents"`
+	SGSTCents        int64   `json:"sgst_cents"`
+	IGSTCents        int64   `json:"igst_cents"`
+	TaxCents         int64   `json:"tax_cents"`
 }
 
 type CreateOrderRequest struct {
 	ShippingAddress map[string]interface{} `json:"shipping_address"`
 	PaymentMetadata map[string]interface{} `json:"payment_metadata,omitempty"`
+	PaymentMethod   string                 `json:"payment_method,omitempty"` // "upi" (default) or "cod"
+	DeliverySlot    string                 `json:"delivery_slot,omitempty"`  // "standard" (default) or one from the shipping rules
 	Items           []CreateOrderItem      `json:"items"`
 }
 
 type CreateOrderItem struct {
 	ProductID string `json:"product_id"`
+	VariantID string `json:"variant_id,omitempty"`
 	Quantity  int    `json:"quantity"`
 }
 
+// UpdateOrderItemRequest lowers an order item's quantity; 0 removes it
+type UpdateOrderItemRequest struct {
+	Quantity int `json:"quantity"`
+}
+
 type UpdateOrderStatus struct {
 	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type AssignDeliveryAgent struct {
+	AgentID string `json:"agent_id"`
+}
+
+// OrderActorSystem is the actor recorded for status changes made by the
+// server itself (payments, expiry) rather than by a user
+const OrderActorSystem = "system"
+
+// Roles that may trigger order status transitions
+const (
+	OrderRoleCustomer      = "customer"
+	OrderRoleAdmin         = "admin"
+	OrderRoleSystem        = "system"
+	OrderRoleDeliveryAgent = "delivery_agent"
+)
+
+// OrderEvent is one status change in an order's timeline. Edits to an order's
+// items are recorded with the same from and to status.
+type OrderEvent struct {
+	ID         string    `json:"id"`
+	OrderID    string    `json:"order_id"`
+	FromStatus string    `json:"from_status,omitempty"` // empty when the order was created
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor"` // user ID, or OrderActorSystem
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // Order statuses
@@ -54,6 +111,13 @@ const (
 	OrderStatusCancelled  = "cancelled"
 )
 
+// Stock reservation statuses
+const (
+	StockStatusReserved  = "reserved"  // Stock held for an unpaid order
+	StockStatusCommitted = "committed" // Payment confirmed, stock is sold
+	StockStatusReleased  = "released"  // Stock returned to inventory
+)
+
 // Payment statuses
 const (
 	PaymentStatusPending   = "payment_pending"
@@ -61,8 +125,29 @@ const (
 	PaymentStatusUpdated   = "payment_updated"   // UPI ref submitted
 	PaymentStatusCompleted = "payment_completed"  // Payment confirmed
 	PaymentStatusFailed    = "payment_failed"
+
+	// Cash on delivery
+	PaymentStatusCODPending   = "cod_pending"   // Cash due at the door
+	PaymentStatusCODCollected = "cod_collected" // Cash received on delivery
+	PaymentStatusCODRefused   = "cod_refused"   // Customer refused the delivery
+)
+
+// Payment methods, stored in PaymentMetadata["payment_method"]
+const (
+	PaymentMethodUPI = "upi"
+	PaymentMethodCOD = "cod"
+	// Subscription delivery paid from the customer's wallet when placed
+	PaymentMethodWallet = "wallet"
+	// Free replacement for an approved return; nothing to pay
+	PaymentMethodReplacement = "replacement"
 )
 
+// CODEligibilityResponse tells checkout whether cash on delivery can be offered.
+type CODEligibilityResponse struct {
+	Eligible bool   `json:"eligible"`
+	Reason   string `json:"reason,omitempty"`
+}
+
 // UroPay API types
 
 type UroPayGenerateRequest struct {
@@ -126,11 +211,14 @@ type InitiatePaymentRequest struct {
 	OrderID       string  `json:"order_id"`
 	CustomerName  string  `json:"customer_name"`
 	CustomerEmail string  `json:"customer_email"`
-	Amount        float64 `json:"amount"` // amount in rupees from frontend
+	Amount        float64 `json:"amount"`            // optional, in rupees; must match the order total if sent
+	Gateway       string  `json:"gateway,omitempty"` // defaults to PAYMENT_GATEWAY_DEFAULT
 }
 
 type InitiatePaymentResponse struct {
-	UroPayOrderId  string `json:"uropay_order_id"`
+	Gateway        string `json:"gateway"`
+	GatewayOrderID string `json:"gateway_order_id"`
+	UroPayOrderId  string `json:"uropay_order_id,omitempty"`
 	UPIString      string `json:"upi_string"`
 	QRCode         string `json:"qr_code"`
 	AmountInRupees string `json:"amount_in_rupees"`
@@ -145,6 +233,9 @@ type SubmitReferenceRequest struct {
 type PaymentStatusResponse struct {
 	OrderID        string `json:"order_id"`
 	PaymentStatus  string `json:"payment_status"`
+	Gateway        string `json:"gateway,omitempty"`
+	GatewayOrderID string `json:"gateway_order_id,omitempty"`
+	GatewayStatus  string `json:"gateway_status,omitempty"`
 	UroPayOrderId  string `json:"uropay_order_id,omitempty"`
 	UroPayStatus   string `json:"uropay_status,omitempty"`
 }
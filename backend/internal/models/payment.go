@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// Payment lookup kinds
+const (
+	PaymentLookupGatewayOrderID = "gateway_order_id"
+	PaymentLookupReference      = "reference_number"
+)
+
+// PaymentLookup maps something a gateway tells us about a payment (its own
+// order ID, or the UPI reference the customer submitted) to our order, so
+// webhooks are matched without scanning orders.
+type PaymentLookup struct {
+	Gateway   string    `json:"gateway"`
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	OrderID   string    `json:"order_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Quarantine statuses
+const (
+	QuarantineOpen      = "open"
+	QuarantineResolved  = "resolved"  // Applied to an order by an admin
+	QuarantineDismissed = "dismissed" // Looked at and deliberately ignored
+)
+
+// Why a webhook was quarantined
+const (
+	QuarantineReasonUnmatched       = "unmatched"         // No order for the reference / gateway order ID
+	QuarantineReasonAmountMismatch  = "amount_mismatch"   // Paid amount differs from the order total
+	QuarantineReasonInvalidAmount   = "invalid_amount"    // Amount could not be parsed
+	QuarantineReasonOrderNotPayable = "order_not_payable" // Order was cancelled or otherwise closed
+	QuarantineReasonAlreadyPaid     = "already_paid"      // Order was already paid with another reference
+)
+
+// QuarantinedWebhook is a signed webhook that could not be applied to an
+// order automatically and is waiting for an admin.
+type QuarantinedWebhook struct {
+	ID              string     `json:"id"`
+	EventID         string     `json:"event_id,omitempty"` // webhook_events row it came from
+	Gateway         string     `json:"gateway"`
+	ReferenceNumber string     `json:"reference_number,omitempty"`
+	GatewayOrderID  string     `json:"gateway_order_id,omitempty"`
+	Amount          string     `json:"amount,omitempty"` // in rupees, as sent
+	Payer           string     `json:"payer,omitempty"`
+	OrderID         string     `json:"order_id,omitempty"` // matched or resolved order, if any
+	Reason          string     `json:"reason"`
+	Detail          string     `json:"detail,omitempty"`
+	Payload         string     `json:"payload"`
+	Status          string     `json:"status"`
+	ResolutionNote  string     `json:"resolution_note,omitempty"`
+	ResolvedBy      string     `json:"resolved_by,omitempty"`
+	ReceivedAt      time.Time  `json:"received_at"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ResolveQuarantineRequest is sent by an admin to settle a quarantined
+// webhook: "apply" marks OrderID as paid, "dismiss" just closes the entry.
+type ResolveQuarantineRequest struct {
+	Action  string `json:"action"`
+	OrderID string `json:"order_id,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// Webhook event statuses
+const (
+	WebhookEventReceived    = "received"    // Stored, not yet processed
+	WebhookEventProcessed   = "processed"   // Applied (or a harmless repeat for a paid order)
+	WebhookEventQuarantined = "quarantined" // Valid but parked for an admin
+	WebhookEventDuplicate   = "duplicate"   // Same payment as an earlier event
+	WebhookEventRejected    = "rejected"    // Bad signature, payload or stale
+	WebhookEventFailed      = "failed"      // Processing error; the gateway may retry
+)
+
+// WebhookEventRecord is one inbound webhook call, kept whatever its outcome.
+// DedupeKey is set (and unique) while an event owns a payment, which is how
+// replays of the same payment are detected.
+type WebhookEventRecord struct {
+	ID              string            `json:"id"`
+	Gateway         string            `json:"gateway"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	SignatureValid  bool              `json:"signature_valid"`
+	ReferenceNumber string            `json:"reference_number,omitempty"`
+	DedupeKey       *string           `json:"dedupe_key"`
+	Status          string            `json:"status"`
+	Outcome         string            `json:"outcome,omitempty"`
+	Attempts        int               `json:"attempts"`
+	ReceivedAt      time.Time         `json:"received_at"`
+	ProcessedAt     *time.Time        `json:"processed_at,omitempty"`
+}
@@ -20,8 +20,22 @@ type Product struct {
 	DeliveryMinutes *int                   `json:"delivery_minutes,omitempty"`
 	Weight          string                 `json:"weight,omitempty"`
 	MRPCents        *int64                 `json:"mrp_cents,omitempty"`
+
+	// GST: the tax class wins over the HSN code; PriceIncludesTax defaults
+	// to PRICES_INCLUDE_TAX when unset
+	HSNCode          string `json:"hsn_code,omitempty"`
+	TaxClass         string `json:"tax_class,omitempty"`
+	PriceIncludesTax *bool  `json:"price_includes_tax,omitempty"`
 }
 
+// GST tax classes
+const (
+	TaxClassExempt = "exempt"
+	TaxClassGST5   = "gst_5"
+	TaxClassGST12  = "gst_12"
+	TaxClassGST18  = "gst_18"
+)
+
 type ProductCategory struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -35,6 +49,7 @@ type ProductVariant struct {
 	PriceCents int64  `json:"price_cents"`
 	Weight     string `json:"weight,omitempty"`
 	MRPCents   *int64 `json:"mrp_cents,omitempty"`
+	Stock      *int   `json:"stock,omitempty"` // nil: sold from the product's stock
 }
 
 type AddProductVariant struct {
@@ -42,6 +57,7 @@ type AddProductVariant struct {
 	PriceCents int64  `json:"price_cents"`
 	Weight     string `json:"weight,omitempty"`
 	MRPCents   *int64 `json:"mrp_cents,omitempty"`
+	Stock      *int   `json:"stock,omitempty"`
 }
 
 type AddProduct struct {
@@ -57,6 +73,10 @@ type AddProduct struct {
 	Weight      string              `json:"weight,omitempty"`
 	Variants    []AddProductVariant `json:"variants,omitempty"`
 	Images      []AddProductImage   `json:"images,omitempty"`
+
+	HSNCode          string `json:"hsn_code,omitempty"`
+	TaxClass         string `json:"tax_class,omitempty"`
+	PriceIncludesTax *bool  `json:"price_includes_tax,omitempty"`
 }
 
 type UpdateProduct struct {
@@ -72,6 +92,10 @@ type UpdateProduct struct {
 	Weight      *string             `json:"weight,omitempty"`
 	Variants    []AddProductVariant `json:"variants,omitempty"` // NEW: replace variants
 	Images      []AddProductImage   `json:"images,omitempty"`   // NEW: replace images
+
+	HSNCode          *string `json:"hsn_code,omitempty"`
+	TaxClass         *string `json:"tax_class,omitempty"`
+	PriceIncludesTax *bool   `json:"price_includes_tax,omitempty"`
 }
 
 type ProductSearchParams struct {
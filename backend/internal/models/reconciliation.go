@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Payment status set by the reconciler when an intent was never paid in time
+const PaymentStatusExpired = "payment_expired"
+
+// Reconciliation mismatch kinds
+const (
+	MismatchPaidAtGatewayOnly = "paid_at_gateway_only" // Gateway completed it, we didn't mark the order paid
+	MismatchPaidLocallyOnly   = "paid_locally_only"    // We marked the order paid, the gateway doesn't show it completed
+	MismatchAmount            = "amount_mismatch"      // Both paid, but for different amounts
+	MismatchUnverified        = "unverified"           // The gateway couldn't be asked
+)
+
+// ReconcileResult summarises one pass over orders with a payment in flight.
+type ReconcileResult struct {
+	Checked   int `json:"checked"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Expired   int `json:"expired"`
+	Errors    int `json:"errors"`
+}
+
+// ReconciliationReport compares what each gateway says was paid for orders
+// initiated on Date (UTC, YYYY-MM-DD) with what our orders say.
+type ReconciliationReport struct {
+	Date       string                   `json:"date"`
+	Totals     []ReconciliationTotals   `json:"totals"`
+	Mismatches []ReconciliationMismatch `json:"mismatches"`
+	CreatedAt  time.Time                `json:"created_at"`
+}
+
+type ReconciliationTotals struct {
+	Gateway               string `json:"gateway"`
+	Orders                int    `json:"orders"`
+	GatewayCompletedCents int64  `json:"gateway_completed_cents"`
+	OrdersPaidCents       int64  `json:"orders_paid_cents"`
+}
+
+type ReconciliationMismatch struct {
+	OrderID        string `json:"order_id"`
+	Gateway        string `json:"gateway"`
+	GatewayOrderID string `json:"gateway_order_id"`
+	Kind           string `json:"kind"`
+	PaymentStatus  string `json:"payment_status"`
+	GatewayStatus  string `json:"gateway_status,omitempty"`
+	OrderCents     int64  `json:"order_cents"`
+	GatewayCents   int64  `json:"gateway_cents,omitempty"`
+	Detail         string `json:"detail,omitempty"`
+}
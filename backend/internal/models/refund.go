@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Refund statuses
+const (
+	RefundRequested  = "requested"  // Created; waiting to be sent or paid out
+	RefundProcessing = "processing" // Accepted by the gateway, not settled yet
+	RefundCompleted  = "completed"
+	RefundFailed     = "failed"
+)
+
+// How a refund reaches the customer
+const (
+	RefundMethodGateway = "gateway" // Through the gateway's refund API
+	RefundMethodManual  = "manual"  // UPI payout by an admin, who records its reference
+	RefundMethodWallet  = "wallet"  // Credited back to the customer's wallet
+)
+
+type Refund struct {
+	ID              string     `json:"id"`
+	OrderID         string     `json:"order_id"`
+	UserID          string     `json:"user_id"`
+	AmountCents     int64      `json:"amount_cents"`
+	Reason          string     `json:"reason,omitempty"`
+	Status          string     `json:"status"`
+	Method          string     `json:"method"`
+	Gateway         string     `json:"gateway,omitempty"`
+	GatewayRefundID string     `json:"gateway_refund_id,omitempty"`
+	PayoutReference string     `json:"payout_reference,omitempty"`
+	FailureReason   string     `json:"failure_reason,omitempty"`
+	RequestedBy     string     `json:"requested_by"` // user ID, or "system" for automatic refunds
+	ProcessedBy     string     `json:"processed_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
+
+type CreateRefundRequest struct {
+	AmountCents int64  `json:"amount_cents,omitempty"` // 0 refunds everything still refundable
+	Reason      string `json:"reason"`
+}
+
+type CompleteRefundRequest struct {
+	PayoutReference string `json:"payout_reference"`
+}
+
+type FailRefundRequest struct {
+	Reason string `json:"reason"`
+}
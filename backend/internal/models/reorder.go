@@ -0,0 +1,69 @@
+package models
+
+// Where a reorder puts the items
+const (
+	ReorderToCart  = "cart"
+	ReorderToOrder = "order"
+)
+
+// ReorderRequest repeats a past order. Its items go into the cart unless
+// Target is "order", which places a new order straight away; that order
+// ships to the original address, slot and payment method unless the request
+// gives others.
+type ReorderRequest struct {
+	Target          string                 `json:"target,omitempty"` // "cart" (default) or "order"
+	UseSubstitutes  bool                   `json:"use_substitutes,omitempty"`
+	ShippingAddress map[string]interface{} `json:"shipping_address,omitempty"`
+	PaymentMethod   string                 `json:"payment_method,omitempty"`
+	PaymentMetadata map[string]interface{} `json:"payment_metadata,omitempty"`
+	DeliverySlot    string                 `json:"delivery_slot,omitempty"`
+}
+
+// What happened to each line of the original order
+const (
+	ReorderLineAdded       = "added"
+	ReorderLineReduced     = "reduced" // less in stock than was ordered
+	ReorderLineSubstituted = "substituted"
+	ReorderLineSkipped     = "skipped"
+)
+
+// ReorderLine compares a line of the original order with what was added
+// this time. Prices are per unit; PriceChangeCents is positive when the item
+// (or its substitute) now costs more.
+type ReorderLine struct {
+	ProductID              string              `json:"product_id"`
+	VariantID              string              `json:"variant_id,omitempty"`
+	ProductName            string              `json:"product_name"`
+	VariantName            string              `json:"variant_name,omitempty"`
+	Status                 string              `json:"status"`
+	Reason                 string              `json:"reason,omitempty"`
+	OriginalQuantity       int                 `json:"original_quantity"`
+	Quantity               int                 `json:"quantity"`
+	OriginalUnitPriceCents int64               `json:"original_unit_price_cents"`
+	UnitPriceCents         int64               `json:"unit_price_cents,omitempty"`
+	PriceChangeCents       int64               `json:"price_change_cents,omitempty"`
+	Substitute             *ReorderSubstitute  `json:"substitute,omitempty"`  // added in place of the product
+	Suggestions            []ReorderSubstitute `json:"suggestions,omitempty"` // for skipped or substituted lines
+}
+
+// ReorderSubstitute is an in-stock product from the same category.
+type ReorderSubstitute struct {
+	ProductID      string `json:"product_id"`
+	ProductName    string `json:"product_name"`
+	ProductImage   string `json:"product_image,omitempty"`
+	UnitPriceCents int64  `json:"unit_price_cents"`
+	Stock          int    `json:"stock"`
+}
+
+// ReorderResponse is the line-by-line diff against the original order, with
+// the cart or order the items went into. When nothing could be added,
+// neither is set.
+type ReorderResponse struct {
+	OriginalOrderID    string        `json:"original_order_id"`
+	Target             string        `json:"target"`
+	Lines              []ReorderLine `json:"lines"`
+	OriginalItemsCents int64         `json:"original_items_cents"` // the original lines at their old prices
+	ItemsCents         int64         `json:"items_cents"`          // what was added, at today's prices
+	Cart               *CartResponse `json:"cart,omitempty"`
+	Order              *Order        `json:"order,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Return statuses
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+)
+
+// What the customer gets back for an approved return
+const (
+	ReturnResolutionRefund      = "refund"
+	ReturnResolutionReplacement = "replacement"
+)
+
+// ReturnRequest is a customer asking to send back items from a delivered
+// order.
+type ReturnRequest struct {
+	ID                 string       `json:"id"`
+	OrderID            string       `json:"order_id"`
+	UserID             string       `json:"user_id"`
+	Items              []ReturnItem `json:"items"`
+	Reason             string       `json:"reason"`
+	PhotoURLs          []string     `json:"photo_urls,omitempty"`
+	Resolution         string       `json:"resolution"`
+	Status             string       `json:"status"`
+	AdminNote          string       `json:"admin_note,omitempty"`
+	Restocked          bool         `json:"restocked"`
+	RefundID           string       `json:"refund_id,omitempty"`
+	ReplacementOrderID string       `json:"replacement_order_id,omitempty"`
+	DecidedBy          string       `json:"decided_by,omitempty"`
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+	DecidedAt          *time.Time   `json:"decided_at,omitempty"`
+}
+
+// ReturnItem is part of an order item being returned; prices are copied from
+// the order.
+type ReturnItem struct {
+	OrderItemID    string `json:"order_item_id"`
+	ProductID      string `json:"product_id"`
+	ProductName    string `json:"product_name"`
+	VariantID      string `json:"variant_id,omitempty"`
+	Quantity       int    `json:"quantity"`
+	UnitPriceCents int64  `json:"unit_price_cents"`
+}
+
+type CreateReturnRequest struct {
+	Items      []CreateReturnItem `json:"items"`
+	Reason     string             `json:"reason"`
+	PhotoURLs  []string           `json:"photo_urls,omitempty"`
+	Resolution string             `json:"resolution,omitempty"` // "refund" (default) or "replacement"
+}
+
+type CreateReturnItem struct {
+	OrderItemID string `json:"order_item_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+type ApproveReturnRequest struct {
+	Restock bool   `json:"restock"` // put the returned items back on sale
+	Note    string `json:"note,omitempty"`
+}
+
+type RejectReturnRequest struct {
+	Note string `json:"note"`
+}
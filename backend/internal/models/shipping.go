@@ -0,0 +1,34 @@
+package models
+
+// ShippingFee is one line of a delivery charge breakdown.
+type ShippingFee struct {
+	Code        string `json:"code"`
+	Label       string `json:"label"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+// Shipping fee codes
+const (
+	ShippingFeeDelivery  = "delivery"   // the zone's fee, waived above its free delivery threshold
+	ShippingFeeSmallCart = "small_cart" // carts below the minimum value
+	ShippingFeeWeight    = "weight"     // heavy orders
+	ShippingFeeDistance  = "distance"   // far from the store
+	ShippingFeeSlot      = "slot"       // delivery slots other than standard
+	ShippingFeeSurge     = "surge"      // slot booked during peak hours
+)
+
+// DeliverySlotStandard is the slot used when checkout doesn't pick one
+const DeliverySlotStandard = "standard"
+
+// CheckoutQuote is what an order would cost if placed now.
+type CheckoutQuote struct {
+	Items                  []OrderItem   `json:"items"`
+	Zone                   string        `json:"zone"`
+	DeliverySlot           string        `json:"delivery_slot"`
+	SubtotalCents          int64         `json:"subtotal_cents"`
+	ShippingCents          int64         `json:"shipping_cents"`
+	TaxCents               int64         `json:"tax_cents"`
+	TotalCents             int64         `json:"total_cents"`
+	ShippingFees           []ShippingFee `json:"shipping_fees"`
+	FreeDeliveryAboveCents int64         `json:"free_delivery_above_cents,omitempty"`
+}
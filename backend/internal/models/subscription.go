@@ -0,0 +1,125 @@
+package models
+
+import "time"
+
+// How often a subscription delivers
+const (
+	SubscriptionDaily         = "daily"
+	SubscriptionAlternateDays = "alternate_days" // every other day from the start date
+	SubscriptionWeekdays      = "weekdays"       // on the days listed in Weekdays
+)
+
+// Subscription statuses
+const (
+	SubscriptionActive    = "active"
+	SubscriptionPaused    = "paused"
+	SubscriptionCancelled = "cancelled"
+)
+
+// Subscription is a standing order for a product, delivered on a schedule.
+// Dates are YYYY-MM-DD in IST. Each delivery is placed as an ordinary order
+// ahead of its date and paid from the wallet, or in cash when PaymentMethod
+// is "cod" or the wallet runs short and CODFallback is set.
+type Subscription struct {
+	ID              string                 `json:"id"`
+	UserID          string                 `json:"user_id"`
+	ProductID       string                 `json:"product_id"`
+	VariantID       string                 `json:"variant_id,omitempty"`
+	Quantity        int                    `json:"quantity"`
+	Frequency       string                 `json:"frequency"`
+	Weekdays        []int                  `json:"weekdays,omitempty"` // 0 = Sunday
+	StartDate       string                 `json:"start_date"`
+	EndDate         string                 `json:"end_date,omitempty"`
+	Status          string                 `json:"status"`
+	PausedUntil     string                 `json:"paused_until,omitempty"` // last paused day; empty pauses until resumed
+	SkipDates       []string               `json:"skip_dates,omitempty"`
+	ShippingAddress map[string]interface{} `json:"shipping_address"`
+	DeliverySlot    string                 `json:"delivery_slot,omitempty"`
+	PaymentMethod   string                 `json:"payment_method"` // "wallet" or "cod"
+	CODFallback     bool                   `json:"cod_fallback"`
+	LastError       string                 `json:"last_error,omitempty"` // why the latest order couldn't be placed
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+
+	// Filled in on reads, not stored
+	ProductName      string `json:"product_name,omitempty"`
+	NextDeliveryDate string `json:"next_delivery_date,omitempty"`
+}
+
+// Subscription delivery statuses
+const (
+	SubscriptionDeliveryPending = "pending" // claimed, order OrderID being placed
+	SubscriptionDeliveryOrdered = "ordered"
+	SubscriptionDeliveryMissed  = "missed" // couldn't be placed before the day began
+)
+
+// SubscriptionDelivery records one scheduled date of a subscription; there
+// is at most one per subscription and date.
+type SubscriptionDelivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	UserID         string    `json:"user_id"`
+	DeliveryDate   string    `json:"delivery_date"`
+	Status         string    `json:"status"`
+	OrderID        string    `json:"order_id,omitempty"`
+	PaymentMethod  string    `json:"payment_method,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	ClaimedAt      time.Time `json:"claimed_at"` // when a pass last took the pending claim
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SubscriptionVacation holds all of a user's subscriptions from StartDate to
+// EndDate, inclusive.
+type SubscriptionVacation struct {
+	UserID    string    `json:"user_id"`
+	StartDate string    `json:"start_date"`
+	EndDate   string    `json:"end_date"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateSubscriptionRequest struct {
+	ProductID       string                 `json:"product_id"`
+	VariantID       string                 `json:"variant_id,omitempty"`
+	Quantity        int                    `json:"quantity"`
+	Frequency       string                 `json:"frequency"`
+	Weekdays        []int                  `json:"weekdays,omitempty"`
+	StartDate       string                 `json:"start_date,omitempty"` // default tomorrow
+	EndDate         string                 `json:"end_date,omitempty"`
+	ShippingAddress map[string]interface{} `json:"shipping_address"`
+	DeliverySlot    string                 `json:"delivery_slot,omitempty"`
+	PaymentMethod   string                 `json:"payment_method,omitempty"` // default "wallet"
+	CODFallback     bool                   `json:"cod_fallback,omitempty"`
+}
+
+// UpdateSubscriptionRequest changes only the fields that are set. Frequency
+// and Weekdays are checked together; an empty EndDate removes it.
+type UpdateSubscriptionRequest struct {
+	Quantity        *int                   `json:"quantity,omitempty"`
+	Frequency       *string                `json:"frequency,omitempty"`
+	Weekdays        []int                  `json:"weekdays,omitempty"`
+	EndDate         *string                `json:"end_date,omitempty"`
+	ShippingAddress map[string]interface{} `json:"shipping_address,omitempty"`
+	DeliverySlot    *string                `json:"delivery_slot,omitempty"`
+	PaymentMethod   *string                `json:"payment_method,omitempty"`
+	CODFallback     *bool                  `json:"cod_fallback,omitempty"`
+}
+
+type PauseSubscriptionRequest struct {
+	Until string `json:"until,omitempty"` // last day paused; empty pauses until resumed
+}
+
+type SkipSubscriptionRequest struct {
+	Date string `json:"date"`
+}
+
+type SubscriptionVacationRequest struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// SubscriptionRunSummary counts what one pass of the order generator did.
+type SubscriptionRunSummary struct {
+	Ordered int `json:"ordered"`
+	Failed  int `json:"failed"` // retried on the next pass until the day begins
+	Missed  int `json:"missed"`
+}
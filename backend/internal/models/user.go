@@ -11,6 +11,7 @@ type User struct {
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 	CreatedAt       time.Time              `json:"created_at,omitempty"`
 	IsAdmin         bool                   `json:"is_admin,omitempty"`
+	IsDeliveryAgent bool                   `json:"is_delivery_agent,omitempty"`
 	ResetOTP        string                 `json:"reset_otp,omitempty"`
 	ResetOTPExpiry  *time.Time             `json:"reset_otp_expiry,omitempty"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Wallet transaction kinds
+const (
+	WalletCredit       = "credit"        // Top-up added by an admin
+	WalletOrderPayment = "order_payment" // Spent on an order
+	WalletRefund       = "refund"        // Money back for a wallet-paid order
+)
+
+// Wallet is a customer's prepaid balance. It pays for orders placed with the
+// "wallet" payment method, which subscriptions use by default.
+type Wallet struct {
+	UserID       string              `json:"user_id"`
+	BalanceCents int64               `json:"balance_cents"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	Transactions []WalletTransaction `json:"transactions,omitempty"`
+}
+
+// WalletTransaction is one line of a wallet's ledger: credits are positive,
+// spending is negative.
+type WalletTransaction struct {
+	ID                string    `json:"id"`
+	UserID            string    `json:"user_id"`
+	AmountCents       int64     `json:"amount_cents"`
+	BalanceAfterCents int64     `json:"balance_after_cents"`
+	Kind              string    `json:"kind"`
+	OrderID           string    `json:"order_id,omitempty"`
+	RefundID          string    `json:"refund_id,omitempty"` // set on refund credits, at most one per refund
+	Note              string    `json:"note,omitempty"`
+	CreatedBy         string    `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type CreditWalletRequest struct {
+	AmountCents int64  `json:"amount_cents"`
+	Note        string `json:"note,omitempty"`
+}
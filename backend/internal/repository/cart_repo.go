@@ -1 +1,170 @@
 package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrCartNotFound is returned when the user or guest token has no cart.
+var ErrCartNotFound = errors.New("cart not found")
+
+type CartRepository struct {
+	db *supabase.Client
+}
+
+func NewCartRepository(db *supabase.Client) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+// GetCartByUserID fetches a user's cart WITH its items
+func (r *CartRepository) GetCartByUserID(ctx context.Context, userID string) (*models.Cart, error) {
+	return r.getCart(ctx, "user_id", userID)
+}
+
+// GetCartByGuestToken fetches an anonymous cart WITH its items
+func (r *CartRepository) GetCartByGuestToken(ctx context.Context, token string) (*models.Cart, error) {
+	return r.getCart(ctx, "guest_token", token)
+}
+
+func (r *CartRepository) GetCartByID(ctx context.Context, id string) (*models.Cart, error) {
+	return r.getCart(ctx, "id", id)
+}
+
+func (r *CartRepository) getCart(ctx context.Context, column, value string) (*models.Cart, error) {
+	var carts []models.Cart
+	err := r.db.From("carts").
+		Select("*,items:cart_items(*)").
+		Eq(column, value).
+		Get(ctx, &carts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(carts) == 0 {
+		return nil, ErrCartNotFound
+	}
+
+	cart := &carts[0]
+	if cart.Items == nil {
+		cart.Items = []models.CartItem{}
+	}
+	sort.SliceStable(cart.Items, func(i, j int) bool {
+		return cart.Items[i].AddedAt.Before(cart.Items[j].AddedAt)
+	})
+
+	return cart, nil
+}
+
+func (r *CartRepository) CreateCart(ctx context.Context, cart *models.Cart) error {
+	cartData := map[string]interface{}{
+		"id":         cart.ID,
+		"created_at": cart.CreatedAt,
+		"updated_at": cart.UpdatedAt,
+	}
+	// Exactly one of user_id / guest_token identifies the cart
+	if cart.UserID != "" {
+		cartData["user_id"] = cart.UserID
+	} else {
+		cartData["guest_token"] = cart.GuestToken
+	}
+
+	var carts []models.Cart
+	if err := r.db.From("carts").Insert(ctx, cartData, &carts); err != nil {
+		return fmt.Errorf("failed to create cart: %w", err)
+	}
+
+	if len(carts) > 0 {
+		cart.ID = carts[0].ID
+		cart.CreatedAt = carts[0].CreatedAt
+	}
+
+	return nil
+}
+
+// TouchCart bumps updated_at so stale carts can be identified
+func (r *CartRepository) TouchCart(ctx context.Context, cartID string) error {
+	updates := map[string]interface{}{
+		"updated_at": time.Now(),
+	}
+
+	if err := r.db.From("carts").Eq("id", cartID).Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CartRepository) GetCartItems(ctx context.Context, cartID string) ([]models.CartItem, error) {
+	var items []models.CartItem
+	err := r.db.From("cart_items").
+		Eq("cart_id", cartID).
+		Order("added_at", true).
+		Get(ctx, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *CartRepository) AddCartItem(ctx context.Context, item *models.CartItem) error {
+	var items []models.CartItem
+	if err := r.db.From("cart_items").Insert(ctx, item, &items); err != nil {
+		return fmt.Errorf("failed to add cart item: %w", err)
+	}
+
+	if len(items) > 0 {
+		*item = items[0]
+	}
+
+	return nil
+}
+
+func (r *CartRepository) UpdateCartItem(ctx context.Context, id string, updates map[string]interface{}) (*models.CartItem, error) {
+	var items []models.CartItem
+	if err := r.db.From("cart_items").Eq("id", id).Update(ctx, updates, &items); err != nil {
+		return nil, fmt.Errorf("failed to update cart item: %w", err)
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("cart item not found")
+	}
+
+	return &items[0], nil
+}
+
+func (r *CartRepository) DeleteCartItem(ctx context.Context, id string) error {
+	if err := r.db.From("cart_items").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete cart item: %w", err)
+	}
+
+	return nil
+}
+
+// ClearCart removes every item but keeps the cart row
+func (r *CartRepository) ClearCart(ctx context.Context, cartID string) error {
+	if err := r.db.From("cart_items").Eq("cart_id", cartID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCart removes a cart and its items
+func (r *CartRepository) DeleteCart(ctx context.Context, cartID string) error {
+	if err := r.ClearCart(ctx, cartID); err != nil {
+		return err
+	}
+
+	if err := r.db.From("carts").Eq("id", cartID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	return nil
+}
@@ -1,130 +1,46 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
 )
 
 type CategoryRepository struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	db *supabase.Client
 }
 
-func NewCategoryRepository() *CategoryRepository {
-	return &CategoryRepository{
-		baseURL:    os.Getenv("SUPABASE_URL"),
-		apiKey:     os.Getenv("SUPABASE_KEY"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+func NewCategoryRepository(db *supabase.Client) *CategoryRepository {
+	return &CategoryRepository{db: db}
 }
 
-func (r *CategoryRepository) GetAllCategories() ([]models.Category, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?select=*&order=position.asc", r.baseURL)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *CategoryRepository) GetAllCategories(ctx context.Context) ([]models.Category, error) {
 	var categories []models.Category
-	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
+	if err := r.db.From("categories").Select("*").Order("position", true).Get(ctx, &categories); err != nil {
 		return nil, err
 	}
 
 	return categories, nil
 }
 
-func (r *CategoryRepository) GetCategoryByID(id string) (*models.Category, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var categories []models.Category
-	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
-		return nil, err
-	}
-
-	if len(categories) == 0 {
-		return nil, errors.New("category not found")
-	}
-
-	return &categories[0], nil
+func (r *CategoryRepository) GetCategoryByID(ctx context.Context, id string) (*models.Category, error) {
+	return r.getOne(ctx, "id", id)
 }
 
-func (r *CategoryRepository) GetCategoryBySlug(slug string) (*models.Category, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?slug=eq.%s", r.baseURL, slug)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var categories []models.Category
-	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
-		return nil, err
-	}
-
-	if len(categories) == 0 {
-		return nil, errors.New("category not found")
-	}
-
-	return &categories[0], nil
+func (r *CategoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	return r.getOne(ctx, "slug", slug)
 }
 
-func (r *CategoryRepository) GetSubcategories(parentID string) ([]models.Category, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?parent_id=eq.%s&order=position.asc", r.baseURL, parentID)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *CategoryRepository) GetSubcategories(ctx context.Context, parentID string) ([]models.Category, error) {
 	var categories []models.Category
-	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
+	err := r.db.From("categories").
+		Eq("parent_id", parentID).
+		Order("position", true).
+		Get(ctx, &categories)
+	if err != nil {
 		return nil, err
 	}
 
@@ -132,71 +48,29 @@ func (r *CategoryRepository) GetSubcategories(parentID string) ([]models.Categor
 }
 
 // GetRootCategories fetches root categories with optional position range filter.
-func (r *CategoryRepository) GetRootCategories(minPosition, maxPosition *int) ([]models.Category, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?parent_id=is.null", r.baseURL)
+func (r *CategoryRepository) GetRootCategories(ctx context.Context, minPosition, maxPosition *int) ([]models.Category, error) {
+	q := r.db.From("categories").IsNull("parent_id")
 
 	// Optional position range filters
 	if minPosition != nil {
-		urlStr += fmt.Sprintf("&position=gte.%d", *minPosition)
+		q.Gte("position", *minPosition)
 	}
 	if maxPosition != nil {
-		urlStr += fmt.Sprintf("&position=lte.%d", *maxPosition)
-	}
-
-	urlStr += "&order=position.asc"
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
+		q.Lte("position", *maxPosition)
 	}
 
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var categories []models.Category
-	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
+	if err := q.Order("position", true).Get(ctx, &categories); err != nil {
 		return nil, err
 	}
 
 	return categories, nil
 }
 
-func (r *CategoryRepository) CreateCategory(category *models.Category) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories", r.baseURL)
-
-	body, err := json.Marshal(category)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create category: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *CategoryRepository) CreateCategory(ctx context.Context, category *models.Category) error {
 	var categories []models.Category
-	if err := json.Unmarshal(respBody, &categories); err != nil {
-		return fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("categories").Insert(ctx, category, &categories); err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
 	}
 
 	if len(categories) > 0 {
@@ -206,37 +80,10 @@ func (r *CategoryRepository) CreateCategory(category *models.Category) error {
 	return nil
 }
 
-func (r *CategoryRepository) UpdateCategory(id string, updates map[string]interface{}) (*models.Category, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?id=eq.%s", r.baseURL, id)
-
-	body, err := json.Marshal(updates)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(http.MethodPatch, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update category: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, id string, updates map[string]interface{}) (*models.Category, error) {
 	var categories []models.Category
-	if err := json.Unmarshal(respBody, &categories); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("categories").Eq("id", id).Update(ctx, updates, &categories); err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
 	}
 
 	if len(categories) == 0 {
@@ -246,33 +93,23 @@ func (r *CategoryRepository) UpdateCategory(id string, updates map[string]interf
 	return &categories[0], nil
 }
 
-func (r *CategoryRepository) DeleteCategory(id string) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/categories?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
-	if err != nil {
-		return err
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id string) error {
+	if err := r.db.From("categories").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
 	}
 
-	r.setHeaders(req)
+	return nil
+}
 
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
+func (r *CategoryRepository) getOne(ctx context.Context, column, value string) (*models.Category, error) {
+	var categories []models.Category
+	if err := r.db.From("categories").Eq(column, value).Get(ctx, &categories); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete category: status %d, body: %s", resp.StatusCode, string(respBody))
+	if len(categories) == 0 {
+		return nil, errors.New("category not found")
 	}
 
-	return nil
-}
-
-func (r *CategoryRepository) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("apikey", r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	return &categories[0], nil
 }
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrIdempotencyKeyExists is returned when a record for the user+key already
+// exists, i.e. another request claimed the key first.
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// ErrIdempotencyRecordNotFound is returned when no record exists for the
+// user+key.
+var ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+
+type IdempotencyRepository struct {
+	db *supabase.Client
+}
+
+func NewIdempotencyRepository(db *supabase.Client) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) GetIdempotencyRecord(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	var records []models.IdempotencyRecord
+	err := r.db.From("idempotency_keys").
+		Eq("user_id", userID).
+		Eq("key", key).
+		Get(ctx, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, ErrIdempotencyRecordNotFound
+	}
+
+	return &records[0], nil
+}
+
+// CreateIdempotencyRecord claims a key. The (user_id, key) primary key makes
+// this the point where concurrent requests with the same key are serialised.
+func (r *IdempotencyRepository) CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	if err := r.db.From("idempotency_keys").Insert(ctx, record, nil); err != nil {
+		if supabase.IsCode(err, supabase.CodeUniqueViolation) {
+			return ErrIdempotencyKeyExists
+		}
+		return fmt.Errorf("failed to create idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// TakeOverIdempotencyRecord hands an abandoned in-progress record to a new
+// request. The heartbeat filter makes it a compare-and-set, so only one
+// request wins and a record whose owner is still heartbeating is left alone.
+func (r *IdempotencyRepository) TakeOverIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	updates := map[string]interface{}{
+		"method":       record.Method,
+		"path":         record.Path,
+		"request_hash": record.RequestHash,
+		"owner":        record.Owner,
+		"heartbeat_at": record.HeartbeatAt.UTC().Format(time.RFC3339Nano),
+		"created_at":   record.CreatedAt.UTC().Format(time.RFC3339Nano),
+		"expires_at":   record.ExpiresAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	var out []models.IdempotencyRecord
+	err := r.db.From("idempotency_keys").
+		Eq("user_id", record.UserID).
+		Eq("key", record.Key).
+		Eq("state", models.IdempotencyInProgress).
+		Lt("heartbeat_at", staleBefore.UTC().Format(time.RFC3339Nano)).
+		Update(ctx, updates, &out)
+	if err != nil {
+		return false, fmt.Errorf("failed to take over idempotency record: %w", err)
+	}
+
+	return len(out) > 0, nil
+}
+
+func (r *IdempotencyRepository) TouchIdempotencyRecord(ctx context.Context, userID, key, owner string, at time.Time) error {
+	updates := map[string]interface{}{"heartbeat_at": at.UTC().Format(time.RFC3339Nano)}
+
+	if err := r.db.From("idempotency_keys").Eq("user_id", userID).Eq("key", key).Eq("owner", owner).Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to refresh idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, userID, key, owner string, statusCode int, contentType, body string) error {
+	updates := map[string]interface{}{
+		"state":         models.IdempotencyCompleted,
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+	}
+
+	if err := r.db.From("idempotency_keys").Eq("user_id", userID).Eq("key", key).Eq("owner", owner).Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, userID, key, owner string) error {
+	if err := r.db.From("idempotency_keys").Eq("user_id", userID).Eq("key", key).Eq("owner", owner).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpiredIdempotencyRecords(ctx context.Context, before time.Time) error {
+	if err := r.db.From("idempotency_keys").Lt("expires_at", before.UTC().Format(time.RFC3339)).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete expired idempotency records: %w", err)
+	}
+
+	return nil
+}
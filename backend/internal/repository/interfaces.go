@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// Storage-agnostic views of each repository. Services, handlers and
+// middleware depend on these so the Supabase-backed repositories in this
+// package can be swapped for the in-memory ones in repository/memory.
+// Implementations must return the same "<thing> not found" errors, since
+// callers match on them. Every method takes the caller's context so request
+// deadlines and cancellation reach the storage layer.
+
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	UpdateUser(ctx context.Context, userID string, fields map[string]interface{}) error
+}
+
+type CategoryStore interface {
+	GetAllCategories(ctx context.Context) ([]models.Category, error)
+	GetCategoryByID(ctx context.Context, id string) (*models.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error)
+	GetSubcategories(ctx context.Context, parentID string) ([]models.Category, error)
+	GetRootCategories(ctx context.Context, minPosition, maxPosition *int) ([]models.Category, error)
+	CreateCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, id string, updates map[string]interface{}) (*models.Category, error)
+	DeleteCategory(ctx context.Context, id string) error
+}
+
+type ProductStore interface {
+	CreateProduct(ctx context.Context, product *models.Product) error
+	LinkProductCategories(ctx context.Context, productID string, categoryIDs []string) error
+	UnlinkAllProductCategories(ctx context.Context, productID string) error
+	GetProductByID(ctx context.Context, id string) (*models.Product, error)
+	GetAllProducts(ctx context.Context, params *models.ProductSearchParams) ([]models.Product, error)
+	SearchProducts(ctx context.Context, query string) ([]models.Product, error)
+	SearchProductsWithLimit(ctx context.Context, query string, limit, offset int) ([]models.Product, error)
+	GetAllProductNames(ctx context.Context) ([]string, error)
+	UpdateProduct(ctx context.Context, id string, updates map[string]interface{}) (*models.Product, error)
+	GetProductsByCategorySQL(ctx context.Context, categoryID string, limit, offset int) ([]models.Product, error)
+	DeleteProduct(ctx context.Context, id string) error
+	ReplaceProductVariants(ctx context.Context, productID string, variants []models.AddProductVariant) error
+	ReplaceProductImages(ctx context.Context, productID string, images []models.AddProductImage) error
+	// ReserveStock must fail with ErrInsufficientStock rather than oversell.
+	// Both draw on the variant's stock when it keeps its own, else the
+	// product's.
+	ReserveStock(ctx context.Context, productID, variantID string, quantity int) error
+	ReleaseStock(ctx context.Context, productID, variantID string, quantity int) error
+}
+
+type ProductImageStore interface {
+	GetImagesByProductID(ctx context.Context, productID string) ([]models.ProductImage, error)
+	GetImageByID(ctx context.Context, id string) (*models.ProductImage, error)
+	CreateImage(ctx context.Context, image *models.ProductImage) error
+	CreateImages(ctx context.Context, images []models.ProductImage) error
+	UpdateImage(ctx context.Context, id string, updates map[string]interface{}) (*models.ProductImage, error)
+	DeleteImage(ctx context.Context, id string) error
+	DeleteImagesByProductID(ctx context.Context, productID string) error
+	GetMaxPosition(ctx context.Context, productID string) (int, error)
+}
+
+type OrderStore interface {
+	CreateOrder(ctx context.Context, order *models.Order) error
+	CreateOrderItems(ctx context.Context, items []models.OrderItem) error
+	GetOrderByID(ctx context.Context, id string) (*models.Order, error)
+	GetOrderItems(ctx context.Context, orderID string) ([]models.OrderItem, error)
+	GetOrdersByUserID(ctx context.Context, userID string) ([]models.Order, error)
+	CountOrdersByPaymentStatus(ctx context.Context, userID, paymentStatus string) (int, error)
+	GetAllOrders(ctx context.Context, status string, limit, offset int) ([]models.Order, error)
+	GetOrdersEnteredStatus(ctx context.Context, entered, status string, from, to time.Time, limit, offset int) ([]models.Order, error)
+	UpdateOrderStatus(ctx context.Context, id, from, status string) (*models.Order, error)
+	UpdateDeliveryAgent(ctx context.Context, id, agentID string) (*models.Order, error)
+	DeleteOrder(ctx context.Context, id string) error
+	UpdatePaymentMetadata(ctx context.Context, orderID string, metadata map[string]interface{}) error
+	UpdateStockStatus(ctx context.Context, orderID, from, to string) (bool, error)
+	UpdateOrderItem(ctx context.Context, item *models.OrderItem) error
+	UpdateOrderTotals(ctx context.Context, order, from *models.Order) error
+}
+
+type OrderEventStore interface {
+	CreateOrderEvent(ctx context.Context, event *models.OrderEvent) error
+	GetOrderEvents(ctx context.Context, orderID string) ([]models.OrderEvent, error)
+}
+
+type UserAddressStore interface {
+	ListByUserID(ctx context.Context, userID string) ([]models.UserAddress, error)
+	GetByID(ctx context.Context, id string) (*models.UserAddress, error)
+	Create(ctx context.Context, addr *models.UserAddress) error
+	Update(ctx context.Context, id string, updates map[string]interface{}) (*models.UserAddress, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type CartStore interface {
+	GetCartByUserID(ctx context.Context, userID string) (*models.Cart, error)
+	GetCartByGuestToken(ctx context.Context, token string) (*models.Cart, error)
+	GetCartByID(ctx context.Context, id string) (*models.Cart, error)
+	CreateCart(ctx context.Context, cart *models.Cart) error
+	TouchCart(ctx context.Context, cartID string) error
+	GetCartItems(ctx context.Context, cartID string) ([]models.CartItem, error)
+	AddCartItem(ctx context.Context, item *models.CartItem) error
+	UpdateCartItem(ctx context.Context, id string, updates map[string]interface{}) (*models.CartItem, error)
+	DeleteCartItem(ctx context.Context, id string) error
+	ClearCart(ctx context.Context, cartID string) error
+	DeleteCart(ctx context.Context, cartID string) error
+}
+
+type IdempotencyStore interface {
+	GetIdempotencyRecord(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error)
+	// CreateIdempotencyRecord must fail with ErrIdempotencyKeyExists if the key is taken
+	CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	// TakeOverIdempotencyRecord replaces an in-progress record whose
+	// heartbeat is older than staleBefore, reporting whether it did
+	TakeOverIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error)
+	// The writes below only apply while owner still holds the key
+	TouchIdempotencyRecord(ctx context.Context, userID, key, owner string, at time.Time) error
+	CompleteIdempotencyRecord(ctx context.Context, userID, key, owner string, statusCode int, contentType, body string) error
+	DeleteIdempotencyRecord(ctx context.Context, userID, key, owner string) error
+	DeleteExpiredIdempotencyRecords(ctx context.Context, before time.Time) error
+}
+
+type PaymentLookupStore interface {
+	// CreatePaymentLookup must fail with ErrPaymentLookupExists if the key is taken
+	CreatePaymentLookup(ctx context.Context, lookup *models.PaymentLookup) error
+	GetPaymentLookup(ctx context.Context, gateway, kind, value string) (*models.PaymentLookup, error)
+}
+
+type WebhookQuarantineStore interface {
+	CreateQuarantinedWebhook(ctx context.Context, q *models.QuarantinedWebhook) error
+	GetQuarantinedWebhook(ctx context.Context, id string) (*models.QuarantinedWebhook, error)
+	ListQuarantinedWebhooks(ctx context.Context, status string, limit, offset int) ([]models.QuarantinedWebhook, error)
+	UpdateQuarantinedWebhook(ctx context.Context, id string, updates map[string]interface{}) (*models.QuarantinedWebhook, error)
+}
+
+type WebhookEventStore interface {
+	CreateWebhookEvent(ctx context.Context, event *models.WebhookEventRecord) error
+	GetWebhookEvent(ctx context.Context, id string) (*models.WebhookEventRecord, error)
+	ListWebhookEvents(ctx context.Context, gateway, status string, limit, offset int) ([]models.WebhookEventRecord, error)
+	UpdateWebhookEvent(ctx context.Context, id string, updates map[string]interface{}) (*models.WebhookEventRecord, error)
+	// ClaimWebhookEvent must fail with ErrWebhookEventDuplicate if another
+	// event holds dedupeKey
+	ClaimWebhookEvent(ctx context.Context, id, dedupeKey string) error
+}
+
+type RefundStore interface {
+	CreateRefund(ctx context.Context, refund *models.Refund) error
+	GetRefundByID(ctx context.Context, id string) (*models.Refund, error)
+	GetRefundsByOrderID(ctx context.Context, orderID string) ([]models.Refund, error)
+	ListRefunds(ctx context.Context, status string, limit, offset int) ([]models.Refund, error)
+	UpdateRefund(ctx context.Context, id string, updates map[string]interface{}) (*models.Refund, error)
+	ClaimRefund(ctx context.Context, id, from, to string) (bool, error)
+}
+
+type ReturnStore interface {
+	CreateReturn(ctx context.Context, ret *models.ReturnRequest) error
+	GetReturnByID(ctx context.Context, id string) (*models.ReturnRequest, error)
+	GetReturnsByOrderID(ctx context.Context, orderID string) ([]models.ReturnRequest, error)
+	ListReturns(ctx context.Context, status string, limit, offset int) ([]models.ReturnRequest, error)
+	UpdateReturn(ctx context.Context, id string, updates map[string]interface{}) (*models.ReturnRequest, error)
+}
+
+type InvoiceStore interface {
+	CreateInvoice(ctx context.Context, invoice *models.Invoice) error
+	GetInvoiceByOrderID(ctx context.Context, orderID string) (*models.Invoice, error)
+	LastInvoiceSequence(ctx context.Context, financialYear string) (int, error)
+}
+
+type WalletStore interface {
+	GetWallet(ctx context.Context, userID string) (*models.Wallet, error)
+	AdjustWalletBalance(ctx context.Context, userID string, delta int64) (int64, error)
+	CreateWalletTransaction(ctx context.Context, txn *models.WalletTransaction) error
+	UpdateWalletTransaction(ctx context.Context, id string, updates map[string]interface{}) error
+	DeleteWalletTransaction(ctx context.Context, id string) error
+	GetWalletTransactions(ctx context.Context, userID string, limit, offset int) ([]models.WalletTransaction, error)
+}
+
+type SubscriptionStore interface {
+	CreateSubscription(ctx context.Context, sub *models.Subscription) error
+	GetSubscriptionByID(ctx context.Context, id string) (*models.Subscription, error)
+	GetSubscriptionsByUserID(ctx context.Context, userID string) ([]models.Subscription, error)
+	ListSubscriptions(ctx context.Context, status string, limit, offset int) ([]models.Subscription, error)
+	UpdateSubscription(ctx context.Context, id string, updates map[string]interface{}) (*models.Subscription, error)
+
+	CreateSubscriptionDelivery(ctx context.Context, delivery *models.SubscriptionDelivery) error
+	GetSubscriptionDelivery(ctx context.Context, subscriptionID, date string) (*models.SubscriptionDelivery, error)
+	GetSubscriptionDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]models.SubscriptionDelivery, error)
+	UpdateSubscriptionDelivery(ctx context.Context, id string, updates map[string]interface{}) error
+	ReclaimSubscriptionDelivery(ctx context.Context, id string, staleBefore, now time.Time) (bool, error)
+	DeleteSubscriptionDelivery(ctx context.Context, id string) error
+
+	GetSubscriptionVacation(ctx context.Context, userID string) (*models.SubscriptionVacation, error)
+	SaveSubscriptionVacation(ctx context.Context, vacation *models.SubscriptionVacation) error
+	DeleteSubscriptionVacation(ctx context.Context, userID string) error
+}
+
+type ReconciliationReportStore interface {
+	SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) error
+	GetReconciliationReport(ctx context.Context, date string) (*models.ReconciliationReport, error)
+	ListReconciliationReports(ctx context.Context, limit, offset int) ([]models.ReconciliationReport, error)
+}
+
+var (
+	_ UserStore         = (*UserRepository)(nil)
+	_ CategoryStore     = (*CategoryRepository)(nil)
+	_ ProductStore      = (*ProductRepository)(nil)
+	_ ProductImageStore = (*ProductImageRepository)(nil)
+	_ OrderStore        = (*OrderRepository)(nil)
+	_ OrderEventStore   = (*OrderEventRepository)(nil)
+	_ UserAddressStore  = (*UserAddressRepository)(nil)
+	_ CartStore         = (*CartRepository)(nil)
+	_ IdempotencyStore  = (*IdempotencyRepository)(nil)
+
+	_ PaymentLookupStore     = (*PaymentLookupRepository)(nil)
+	_ WebhookQuarantineStore = (*WebhookQuarantineRepository)(nil)
+	_ WebhookEventStore      = (*WebhookEventRepository)(nil)
+	_ RefundStore            = (*RefundRepository)(nil)
+	_ ReturnStore            = (*ReturnRepository)(nil)
+	_ InvoiceStore           = (*InvoiceRepository)(nil)
+	_ WalletStore            = (*WalletRepository)(nil)
+	_ SubscriptionStore      = (*SubscriptionRepository)(nil)
+
+	_ ReconciliationReportStore = (*ReconciliationReportRepository)(nil)
+)
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrInvoiceExists is returned when the order already has an invoice or the
+// invoice number was taken first.
+var ErrInvoiceExists = errors.New("invoice already exists")
+
+type InvoiceRepository struct {
+	db *supabase.Client
+}
+
+func NewInvoiceRepository(db *supabase.Client) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// CreateInvoice relies on unique indexes on order_id and on
+// (financial_year, sequence) to keep one invoice per order and numbers unique.
+func (r *InvoiceRepository) CreateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	if err := r.db.From("invoices").Insert(ctx, invoice, nil); err != nil {
+		if supabase.IsCode(err, supabase.CodeUniqueViolation) {
+			return ErrInvoiceExists
+		}
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	return nil
+}
+
+func (r *InvoiceRepository) GetInvoiceByOrderID(ctx context.Context, orderID string) (*models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := r.db.From("invoices").Eq("order_id", orderID).Get(ctx, &invoices); err != nil {
+		return nil, err
+	}
+
+	if len(invoices) == 0 {
+		return nil, errors.New("invoice not found")
+	}
+
+	return &invoices[0], nil
+}
+
+// LastInvoiceSequence returns the highest sequence issued in a financial
+// year, or 0 before the first invoice.
+func (r *InvoiceRepository) LastInvoiceSequence(ctx context.Context, financialYear string) (int, error) {
+	var invoices []models.Invoice
+	err := r.db.From("invoices").
+		Eq("financial_year", financialYear).
+		Order("sequence", false).
+		Limit(1).
+		Get(ctx, &invoices)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(invoices) == 0 {
+		return 0, nil
+	}
+	return invoices[0].Sequence, nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type UserAddressRepository struct {
+	db *DB
+}
+
+func NewUserAddressRepository(db *DB) *UserAddressRepository {
+	return &UserAddressRepository{db: db}
+}
+
+func (r *UserAddressRepository) ListByUserID(ctx context.Context, userID string) ([]models.UserAddress, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := make([]models.UserAddress, 0)
+	for _, a := range r.db.addresses {
+		if a.UserID == userID {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *UserAddressRepository) GetByID(ctx context.Context, id string) (*models.UserAddress, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	a, ok := r.db.addresses[id]
+	if !ok {
+		return nil, errors.New("address not found")
+	}
+	return &a, nil
+}
+
+func (r *UserAddressRepository) Create(ctx context.Context, addr *models.UserAddress) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if addr.ID == "" {
+		addr.ID = uuid.New().String()
+	}
+	if addr.CreatedAt.IsZero() {
+		addr.CreatedAt = time.Now()
+	}
+	r.db.addresses[addr.ID] = *addr
+	return nil
+}
+
+func (r *UserAddressRepository) Update(ctx context.Context, id string, updates map[string]interface{}) (*models.UserAddress, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	a, ok := r.db.addresses[id]
+	if !ok {
+		return nil, errors.New("address not found")
+	}
+	updated, err := applyUpdates(a, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.addresses[id] = updated
+	return &updated, nil
+}
+
+func (r *UserAddressRepository) Delete(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.addresses, id)
+	return nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type CartRepository struct {
+	db *DB
+}
+
+func NewCartRepository(db *DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+func (r *CartRepository) GetCartByUserID(ctx context.Context, userID string) (*models.Cart, error) {
+	return r.find(func(c models.Cart) bool { return c.UserID == userID })
+}
+
+func (r *CartRepository) GetCartByGuestToken(ctx context.Context, token string) (*models.Cart, error) {
+	return r.find(func(c models.Cart) bool { return c.GuestToken == token })
+}
+
+func (r *CartRepository) GetCartByID(ctx context.Context, id string) (*models.Cart, error) {
+	return r.find(func(c models.Cart) bool { return c.ID == id })
+}
+
+func (r *CartRepository) CreateCart(ctx context.Context, cart *models.Cart) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, c := range r.db.carts {
+		if cart.UserID != "" && c.UserID == cart.UserID {
+			return errors.New("failed to create cart: user already has a cart")
+		}
+	}
+
+	if cart.ID == "" {
+		cart.ID = uuid.New().String()
+	}
+	if cart.CreatedAt.IsZero() {
+		cart.CreatedAt = time.Now()
+	}
+	row := *cart
+	row.Items = nil
+	r.db.carts[cart.ID] = row
+	return nil
+}
+
+func (r *CartRepository) TouchCart(ctx context.Context, cartID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if c, ok := r.db.carts[cartID]; ok {
+		c.UpdatedAt = time.Now()
+		r.db.carts[cartID] = c
+	}
+	return nil
+}
+
+func (r *CartRepository) GetCartItems(ctx context.Context, cartID string) ([]models.CartItem, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.items(cartID), nil
+}
+
+func (r *CartRepository) AddCartItem(ctx context.Context, item *models.CartItem) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.carts[item.CartID]; !ok {
+		return fmt.Errorf("failed to add cart item: %w", repository.ErrCartNotFound)
+	}
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	if item.AddedAt.IsZero() {
+		item.AddedAt = time.Now()
+	}
+	r.db.cartItems[item.ID] = *item
+	return nil
+}
+
+func (r *CartRepository) UpdateCartItem(ctx context.Context, id string, updates map[string]interface{}) (*models.CartItem, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	item, ok := r.db.cartItems[id]
+	if !ok {
+		return nil, errors.New("cart item not found")
+	}
+	updated, err := applyUpdates(item, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.cartItems[id] = updated
+	return &updated, nil
+}
+
+func (r *CartRepository) DeleteCartItem(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.cartItems, id)
+	return nil
+}
+
+func (r *CartRepository) ClearCart(ctx context.Context, cartID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, item := range r.db.cartItems {
+		if item.CartID == cartID {
+			delete(r.db.cartItems, id)
+		}
+	}
+	return nil
+}
+
+func (r *CartRepository) DeleteCart(ctx context.Context, cartID string) error {
+	if err := r.ClearCart(ctx, cartID); err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.carts, cartID)
+	return nil
+}
+
+func (r *CartRepository) find(match func(models.Cart) bool) (*models.Cart, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, c := range r.db.carts {
+		if match(c) {
+			c.Items = r.items(c.ID)
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrCartNotFound
+}
+
+// items returns a cart's items oldest first. Callers hold the lock.
+func (r *CartRepository) items(cartID string) []models.CartItem {
+	out := make([]models.CartItem, 0)
+	for _, item := range r.db.cartItems {
+		if item.CartID == cartID {
+			out = append(out, item)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].AddedAt.Before(out[j].AddedAt) })
+	return out
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type CategoryRepository struct {
+	db *DB
+}
+
+func NewCategoryRepository(db *DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+func (r *CategoryRepository) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	return r.filter(func(models.Category) bool { return true }), nil
+}
+
+func (r *CategoryRepository) GetCategoryByID(ctx context.Context, id string) (*models.Category, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	c, ok := r.db.categories[id]
+	if !ok {
+		return nil, errors.New("category not found")
+	}
+	return &c, nil
+}
+
+func (r *CategoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	out := r.filter(func(c models.Category) bool { return c.Slug == slug })
+	if len(out) == 0 {
+		return nil, errors.New("category not found")
+	}
+	return &out[0], nil
+}
+
+func (r *CategoryRepository) GetSubcategories(ctx context.Context, parentID string) ([]models.Category, error) {
+	return r.filter(func(c models.Category) bool { return c.ParentID == parentID }), nil
+}
+
+func (r *CategoryRepository) GetRootCategories(ctx context.Context, minPosition, maxPosition *int) ([]models.Category, error) {
+	return r.filter(func(c models.Category) bool {
+		if c.ParentID != "" {
+			return false
+		}
+		if minPosition != nil && c.Position < *minPosition {
+			return false
+		}
+		if maxPosition != nil && c.Position > *maxPosition {
+			return false
+		}
+		return true
+	}), nil
+}
+
+func (r *CategoryRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, c := range r.db.categories {
+		if c.Slug == category.Slug {
+			return errors.New("failed to create category: slug already exists")
+		}
+	}
+
+	if category.ID == "" {
+		category.ID = uuid.New().String()
+	}
+	r.db.categories[category.ID] = *category
+	return nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, id string, updates map[string]interface{}) (*models.Category, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.categories[id]
+	if !ok {
+		return nil, errors.New("category not found")
+	}
+	updated, err := applyUpdates(c, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.categories[id] = updated
+	return &updated, nil
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.categories, id)
+	for productID, ids := range r.db.productCategories {
+		r.db.productCategories[productID] = removeString(ids, id)
+	}
+	return nil
+}
+
+// filter returns matching categories ordered by position, like the
+// order=position.asc used by the Supabase repository.
+func (r *CategoryRepository) filter(match func(models.Category) bool) []models.Category {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := make([]models.Category, 0)
+	for _, c := range r.db.categories {
+		if match(c) {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Position != out[j].Position {
+			return out[i].Position < out[j].Position
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func removeString(list []string, s string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
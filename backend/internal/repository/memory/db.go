@@ -0,0 +1,220 @@
+// Package memory is a thread-safe, in-process implementation of the
+// repository interfaces. It backs STORAGE=memory for local development and
+// lets services be exercised without a live Supabase project.
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+// DB holds every table. All repositories built on the same DB share one lock,
+// which keeps cross-table reads (e.g. products with their images) consistent.
+type DB struct {
+	mu sync.RWMutex
+
+	users             map[string]models.User
+	categories        map[string]models.Category
+	products          map[string]models.Product
+	productCategories map[string][]string // product ID -> category IDs
+	variants          map[string][]models.ProductVariant
+	images            map[string]models.ProductImage
+	orders            map[string]models.Order
+	orderItems        map[string][]models.OrderItem // order ID -> items
+	orderEvents       []models.OrderEvent           // append-only
+	addresses         map[string]models.UserAddress
+	carts             map[string]models.Cart
+	cartItems         map[string]models.CartItem
+	idempotency       map[string]models.IdempotencyRecord // user ID + key
+	paymentLookups    map[string]models.PaymentLookup     // gateway + kind + value
+	quarantine        map[string]models.QuarantinedWebhook
+	webhookEvents     map[string]models.WebhookEventRecord
+	refunds           map[string]models.Refund
+	returns           map[string]models.ReturnRequest
+	invoices          map[string]models.Invoice
+	reconciliation    map[string]models.ReconciliationReport // by date
+	wallets           map[string]models.Wallet               // by user ID
+	walletTxns        []models.WalletTransaction             // append-only
+	subscriptions     map[string]models.Subscription
+	subDeliveries     map[string]models.SubscriptionDelivery
+	vacations         map[string]models.SubscriptionVacation // by user ID
+}
+
+func NewDB() *DB {
+	return &DB{
+		users:             make(map[string]models.User),
+		categories:        make(map[string]models.Category),
+		products:          make(map[string]models.Product),
+		productCategories: make(map[string][]string),
+		variants:          make(map[string][]models.ProductVariant),
+		images:            make(map[string]models.ProductImage),
+		orders:            make(map[string]models.Order),
+		orderItems:        make(map[string][]models.OrderItem),
+		addresses:         make(map[string]models.UserAddress),
+		carts:             make(map[string]models.Cart),
+		cartItems:         make(map[string]models.CartItem),
+		idempotency:       make(map[string]models.IdempotencyRecord),
+		paymentLookups:    make(map[string]models.PaymentLookup),
+		quarantine:        make(map[string]models.QuarantinedWebhook),
+		webhookEvents:     make(map[string]models.WebhookEventRecord),
+		refunds:           make(map[string]models.Refund),
+		returns:           make(map[string]models.ReturnRequest),
+		invoices:          make(map[string]models.Invoice),
+		reconciliation:    make(map[string]models.ReconciliationReport),
+		wallets:           make(map[string]models.Wallet),
+		subscriptions:     make(map[string]models.Subscription),
+		subDeliveries:     make(map[string]models.SubscriptionDelivery),
+		vacations:         make(map[string]models.SubscriptionVacation),
+	}
+}
+
+// Seed is the JSON layout accepted by LoadSeed. Products may carry variants,
+// images and categories (by ID) inline.
+type Seed struct {
+	Users      []models.User     `json:"users"`
+	Categories []models.Category `json:"categories"`
+	Products   []models.Product  `json:"products"`
+}
+
+// LoadSeed populates the store from a JSON file so a memory-backed server
+// starts with a usable catalogue and admin account.
+func (db *DB) LoadSeed(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read seed file: %w", err)
+	}
+
+	var seed Seed
+	if err := json.Unmarshal(raw, &seed); err != nil {
+		return fmt.Errorf("failed to parse seed file: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range seed.Users {
+		if u.ID == "" {
+			u.ID = uuid.New().String()
+		}
+		db.users[u.ID] = u
+	}
+	for _, c := range seed.Categories {
+		if c.ID == "" {
+			c.ID = uuid.New().String()
+		}
+		db.categories[c.ID] = c
+	}
+	for _, p := range seed.Products {
+		if p.ID == "" {
+			p.ID = uuid.New().String()
+		}
+		if p.CreatedAt.IsZero() {
+			p.CreatedAt = time.Now()
+		}
+		for _, c := range p.Categories {
+			db.productCategories[p.ID] = append(db.productCategories[p.ID], c.ID)
+		}
+		for i, v := range p.Variants {
+			if v.ID == "" {
+				p.Variants[i].ID = uuid.New().String()
+			}
+		}
+		db.variants[p.ID] = p.Variants
+		for _, img := range p.Images {
+			if img.ID == "" {
+				img.ID = uuid.New().String()
+			}
+			img.ProductID = p.ID
+			db.images[img.ID] = img
+		}
+		p.Categories, p.Variants, p.Images = nil, nil, nil
+		db.products[p.ID] = p
+	}
+
+	return nil
+}
+
+// clone deep-copies a value through JSON so callers can never mutate stored
+// rows (or their metadata maps) without going through the repository.
+func clone[T any](v T) T {
+	var out T
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// applyUpdates emulates a PostgREST PATCH: the JSON keys in updates replace
+// the matching fields of current, and a nil value clears the field.
+func applyUpdates[T any](current T, updates map[string]interface{}) (T, error) {
+	var out T
+
+	b, err := json.Marshal(current)
+	if err != nil {
+		return out, err
+	}
+	row := map[string]interface{}{}
+	if err := json.Unmarshal(b, &row); err != nil {
+		return out, err
+	}
+	for k, v := range updates {
+		row[k] = v
+	}
+
+	b, err = json.Marshal(row)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, fmt.Errorf("invalid update: %v", err)
+	}
+	return out, nil
+}
+
+// page applies limit/offset the way PostgREST does (zero means unset).
+func page[T any](rows []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return []T{}
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+var (
+	_ repository.UserStore         = (*UserRepository)(nil)
+	_ repository.CategoryStore     = (*CategoryRepository)(nil)
+	_ repository.ProductStore      = (*ProductRepository)(nil)
+	_ repository.ProductImageStore = (*ProductImageRepository)(nil)
+	_ repository.OrderStore        = (*OrderRepository)(nil)
+	_ repository.OrderEventStore   = (*OrderEventRepository)(nil)
+	_ repository.UserAddressStore  = (*UserAddressRepository)(nil)
+	_ repository.CartStore         = (*CartRepository)(nil)
+	_ repository.IdempotencyStore  = (*IdempotencyRepository)(nil)
+
+	_ repository.PaymentLookupStore     = (*PaymentLookupRepository)(nil)
+	_ repository.WebhookQuarantineStore = (*WebhookQuarantineRepository)(nil)
+	_ repository.WebhookEventStore      = (*WebhookEventRepository)(nil)
+	_ repository.RefundStore            = (*RefundRepository)(nil)
+	_ repository.ReturnStore            = (*ReturnRepository)(nil)
+	_ repository.InvoiceStore           = (*InvoiceRepository)(nil)
+	_ repository.WalletStore            = (*WalletRepository)(nil)
+	_ repository.SubscriptionStore      = (*SubscriptionRepository)(nil)
+
+	_ repository.ReconciliationReportStore = (*ReconciliationReportRepository)(nil)
+)
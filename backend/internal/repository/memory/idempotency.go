@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type IdempotencyRepository struct {
+	db *DB
+}
+
+func NewIdempotencyRepository(db *DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func idempotencyID(userID, key string) string {
+	return userID + "\x00" + key
+}
+
+func (r *IdempotencyRepository) GetIdempotencyRecord(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rec, ok := r.db.idempotency[idempotencyID(userID, key)]
+	if !ok {
+		return nil, repository.ErrIdempotencyRecordNotFound
+	}
+	return &rec, nil
+}
+
+func (r *IdempotencyRepository) CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id := idempotencyID(record.UserID, record.Key)
+	if _, ok := r.db.idempotency[id]; ok {
+		return repository.ErrIdempotencyKeyExists
+	}
+	r.db.idempotency[id] = *record
+	return nil
+}
+
+func (r *IdempotencyRepository) TakeOverIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id := idempotencyID(record.UserID, record.Key)
+	rec, ok := r.db.idempotency[id]
+	if !ok || rec.State != models.IdempotencyInProgress || !rec.HeartbeatAt.Before(staleBefore) {
+		return false, nil
+	}
+	r.db.idempotency[id] = *record
+	return true, nil
+}
+
+func (r *IdempotencyRepository) TouchIdempotencyRecord(ctx context.Context, userID, key, owner string, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id := idempotencyID(userID, key)
+	rec, ok := r.db.idempotency[id]
+	if !ok || rec.Owner != owner {
+		return nil
+	}
+	rec.HeartbeatAt = at
+	r.db.idempotency[id] = rec
+	return nil
+}
+
+func (r *IdempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, userID, key, owner string, statusCode int, contentType, body string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id := idempotencyID(userID, key)
+	rec, ok := r.db.idempotency[id]
+	if !ok || rec.Owner != owner {
+		return nil
+	}
+	rec.State = models.IdempotencyCompleted
+	rec.StatusCode = statusCode
+	rec.ContentType = contentType
+	rec.ResponseBody = body
+	r.db.idempotency[id] = rec
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, userID, key, owner string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id := idempotencyID(userID, key)
+	if rec, ok := r.db.idempotency[id]; ok && rec.Owner == owner {
+		delete(r.db.idempotency, id)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpiredIdempotencyRecords(ctx context.Context, before time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, rec := range r.db.idempotency {
+		if rec.ExpiresAt.Before(before) {
+			delete(r.db.idempotency, id)
+		}
+	}
+	return nil
+}
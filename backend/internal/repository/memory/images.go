@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type ProductImageRepository struct {
+	db *DB
+}
+
+func NewProductImageRepository(db *DB) *ProductImageRepository {
+	return &ProductImageRepository{db: db}
+}
+
+func (r *ProductImageRepository) GetImagesByProductID(ctx context.Context, productID string) ([]models.ProductImage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := make([]models.ProductImage, 0)
+	for _, img := range r.db.images {
+		if img.ProductID == productID {
+			out = append(out, img)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Position < out[j].Position })
+	return out, nil
+}
+
+func (r *ProductImageRepository) GetImageByID(ctx context.Context, id string) (*models.ProductImage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	img, ok := r.db.images[id]
+	if !ok {
+		return nil, errors.New("image not found")
+	}
+	return &img, nil
+}
+
+func (r *ProductImageRepository) CreateImage(ctx context.Context, image *models.ProductImage) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if image.ID == "" {
+		image.ID = uuid.New().String()
+	}
+	r.db.images[image.ID] = *image
+	return nil
+}
+
+func (r *ProductImageRepository) CreateImages(ctx context.Context, images []models.ProductImage) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, img := range images {
+		if img.ID == "" {
+			img.ID = uuid.New().String()
+		}
+		r.db.images[img.ID] = img
+	}
+	return nil
+}
+
+func (r *ProductImageRepository) UpdateImage(ctx context.Context, id string, updates map[string]interface{}) (*models.ProductImage, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	img, ok := r.db.images[id]
+	if !ok {
+		return nil, errors.New("image not found")
+	}
+	updated, err := applyUpdates(img, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.images[id] = updated
+	return &updated, nil
+}
+
+func (r *ProductImageRepository) DeleteImage(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.images, id)
+	return nil
+}
+
+func (r *ProductImageRepository) DeleteImagesByProductID(ctx context.Context, productID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, img := range r.db.images {
+		if img.ProductID == productID {
+			delete(r.db.images, id)
+		}
+	}
+	return nil
+}
+
+func (r *ProductImageRepository) GetMaxPosition(ctx context.Context, productID string) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	max := 0
+	for _, img := range r.db.images {
+		if img.ProductID == productID && img.Position > max {
+			max = img.Position
+		}
+	}
+	return max, nil
+}
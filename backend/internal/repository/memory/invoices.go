@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type InvoiceRepository struct {
+	db *DB
+}
+
+func NewInvoiceRepository(db *DB) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+func (r *InvoiceRepository) CreateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, inv := range r.db.invoices {
+		if inv.OrderID == invoice.OrderID ||
+			(inv.FinancialYear == invoice.FinancialYear && inv.Sequence == invoice.Sequence) {
+			return repository.ErrInvoiceExists
+		}
+	}
+	r.db.invoices[invoice.ID] = clone(*invoice)
+	return nil
+}
+
+func (r *InvoiceRepository) GetInvoiceByOrderID(ctx context.Context, orderID string) (*models.Invoice, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, inv := range r.db.invoices {
+		if inv.OrderID == orderID {
+			out := clone(inv)
+			return &out, nil
+		}
+	}
+	return nil, errors.New("invoice not found")
+}
+
+func (r *InvoiceRepository) LastInvoiceSequence(ctx context.Context, financialYear string) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	last := 0
+	for _, inv := range r.db.invoices {
+		if inv.FinancialYear == financialYear && inv.Sequence > last {
+			last = inv.Sequence
+		}
+	}
+	return last, nil
+}
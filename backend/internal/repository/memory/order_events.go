@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type OrderEventRepository struct {
+	db *DB
+}
+
+func NewOrderEventRepository(db *DB) *OrderEventRepository {
+	return &OrderEventRepository{db: db}
+}
+
+func (r *OrderEventRepository) CreateOrderEvent(ctx context.Context, event *models.OrderEvent) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.orderEvents = append(r.db.orderEvents, clone(*event))
+	return nil
+}
+
+func (r *OrderEventRepository) GetOrderEvents(ctx context.Context, orderID string) ([]models.OrderEvent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.OrderEvent{}
+	for _, e := range r.db.orderEvents {
+		if e.OrderID == orderID {
+			out = append(out, clone(e))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
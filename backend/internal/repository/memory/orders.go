@@ -0,0 +1,270 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type OrderRepository struct {
+	db *DB
+}
+
+func NewOrderRepository(db *DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if order.ID == "" {
+		order.ID = uuid.New().String()
+	}
+	if _, ok := r.db.orders[order.ID]; ok {
+		return errors.New("failed to create order: duplicate id")
+	}
+	if order.PlacedAt.IsZero() {
+		order.PlacedAt = time.Now()
+	}
+
+	row := clone(*order)
+	row.Items = nil
+	r.db.orders[order.ID] = row
+	return nil
+}
+
+func (r *OrderRepository) CreateOrderItems(ctx context.Context, items []models.OrderItem) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, item := range items {
+		if _, ok := r.db.orders[item.OrderID]; !ok {
+			return errors.New("failed to create order items: order not found")
+		}
+	}
+	for _, item := range items {
+		if item.ID == "" {
+			item.ID = uuid.New().String()
+		}
+		r.db.orderItems[item.OrderID] = append(r.db.orderItems[item.OrderID], item)
+	}
+	return nil
+}
+
+func (r *OrderRepository) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	if _, ok := r.db.orders[id]; !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+	order := r.withItems(id)
+	return &order, nil
+}
+
+func (r *OrderRepository) GetOrderItems(ctx context.Context, orderID string) ([]models.OrderItem, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return clone(r.db.orderItems[orderID]), nil
+}
+
+func (r *OrderRepository) GetOrdersByUserID(ctx context.Context, userID string) ([]models.Order, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.sorted(func(o models.Order) bool { return o.UserID == userID }), nil
+}
+
+func (r *OrderRepository) CountOrdersByPaymentStatus(ctx context.Context, userID, paymentStatus string) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	n := 0
+	for _, o := range r.db.orders {
+		if o.UserID == userID && o.PaymentMetadata["payment_status"] == paymentStatus {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *OrderRepository) GetAllOrders(ctx context.Context, status string, limit, offset int) ([]models.Order, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := r.sorted(func(o models.Order) bool { return status == "" || o.Status == status })
+	return page(out, limit, offset), nil
+}
+
+func (r *OrderRepository) GetOrdersEnteredStatus(ctx context.Context, entered, status string, from, to time.Time, limit, offset int) ([]models.Order, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	// Latest move into entered per order, as the database function does
+	latest := map[string]time.Time{}
+	for _, e := range r.db.orderEvents {
+		if e.ToStatus != entered || e.FromStatus == entered {
+			continue
+		}
+		if (!from.IsZero() && e.CreatedAt.Before(from)) || (!to.IsZero() && !e.CreatedAt.Before(to)) {
+			continue
+		}
+		o, ok := r.db.orders[e.OrderID]
+		if !ok || (status != "" && o.Status != status) {
+			continue
+		}
+		if e.CreatedAt.After(latest[e.OrderID]) {
+			latest[e.OrderID] = e.CreatedAt
+		}
+	}
+
+	ids := make([]string, 0, len(latest))
+	for id := range latest {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if !latest[ids[i]].Equal(latest[ids[j]]) {
+			return latest[ids[i]].After(latest[ids[j]])
+		}
+		return ids[i] < ids[j]
+	})
+
+	out := []models.Order{}
+	for _, id := range page(ids, limit, offset) {
+		out = append(out, r.withItems(id))
+	}
+	return out, nil
+}
+
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, id, from, status string) (*models.Order, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	o, ok := r.db.orders[id]
+	if !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+	if o.Status != from {
+		return nil, repository.ErrOrderStatusChanged
+	}
+	o.Status = status
+	r.db.orders[id] = o
+
+	order := r.withItems(id)
+	return &order, nil
+}
+
+func (r *OrderRepository) UpdateDeliveryAgent(ctx context.Context, id, agentID string) (*models.Order, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	o, ok := r.db.orders[id]
+	if !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+	o.DeliveryAgentID = agentID
+	r.db.orders[id] = o
+
+	order := r.withItems(id)
+	return &order, nil
+}
+
+func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.orderItems, id)
+	delete(r.db.orders, id)
+	return nil
+}
+
+func (r *OrderRepository) UpdatePaymentMetadata(ctx context.Context, orderID string, metadata map[string]interface{}) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	o, ok := r.db.orders[orderID]
+	if !ok {
+		return nil
+	}
+	o.PaymentMetadata = clone(metadata)
+	r.db.orders[orderID] = o
+	return nil
+}
+
+func (r *OrderRepository) UpdateStockStatus(ctx context.Context, orderID, from, to string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	o, ok := r.db.orders[orderID]
+	if !ok || o.StockStatus != from {
+		return false, nil
+	}
+	o.StockStatus = to
+	r.db.orders[orderID] = o
+	return true, nil
+}
+
+func (r *OrderRepository) UpdateOrderItem(ctx context.Context, item *models.OrderItem) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	items := r.db.orderItems[item.OrderID]
+	for i := range items {
+		if items[i].ID != item.ID {
+			continue
+		}
+		if item.Quantity == 0 {
+			r.db.orderItems[item.OrderID] = append(items[:i:i], items[i+1:]...)
+		} else {
+			items[i] = *item
+		}
+		return nil
+	}
+	return errors.New("order item not found")
+}
+
+func (r *OrderRepository) UpdateOrderTotals(ctx context.Context, order, from *models.Order) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	o, ok := r.db.orders[order.ID]
+	if !ok {
+		return repository.ErrOrderNotFound
+	}
+	if o.Status != from.Status || o.StockStatus != from.StockStatus || o.TotalCents != from.TotalCents {
+		return repository.ErrOrderStatusChanged
+	}
+	o.SubtotalCents = order.SubtotalCents
+	o.ShippingCents = order.ShippingCents
+	o.TaxCents = order.TaxCents
+	o.TotalCents = order.TotalCents
+	o.ShippingFees = order.ShippingFees
+	r.db.orders[order.ID] = o
+	return nil
+}
+
+// withItems returns a copy of the order with its items. Callers hold the lock.
+func (r *OrderRepository) withItems(id string) models.Order {
+	order := clone(r.db.orders[id])
+	order.Items = clone(r.db.orderItems[id])
+	return order
+}
+
+// sorted returns matching orders newest first. Callers hold the lock.
+func (r *OrderRepository) sorted(match func(models.Order) bool) []models.Order {
+	out := make([]models.Order, 0)
+	for id, o := range r.db.orders {
+		if match(o) {
+			out = append(out, r.withItems(id))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].PlacedAt.After(out[j].PlacedAt) })
+	return out
+}
@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type PaymentLookupRepository struct {
+	db *DB
+}
+
+func NewPaymentLookupRepository(db *DB) *PaymentLookupRepository {
+	return &PaymentLookupRepository{db: db}
+}
+
+func paymentLookupID(gateway, kind, value string) string {
+	return gateway + "\x00" + kind + "\x00" + value
+}
+
+func (r *PaymentLookupRepository) CreatePaymentLookup(ctx context.Context, lookup *models.PaymentLookup) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	id := paymentLookupID(lookup.Gateway, lookup.Kind, lookup.Value)
+	if _, ok := r.db.paymentLookups[id]; ok {
+		return repository.ErrPaymentLookupExists
+	}
+	r.db.paymentLookups[id] = *lookup
+	return nil
+}
+
+func (r *PaymentLookupRepository) GetPaymentLookup(ctx context.Context, gateway, kind, value string) (*models.PaymentLookup, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	lookup, ok := r.db.paymentLookups[paymentLookupID(gateway, kind, value)]
+	if !ok {
+		return nil, repository.ErrPaymentLookupNotFound
+	}
+	return &lookup, nil
+}
+
+type WebhookQuarantineRepository struct {
+	db *DB
+}
+
+func NewWebhookQuarantineRepository(db *DB) *WebhookQuarantineRepository {
+	return &WebhookQuarantineRepository{db: db}
+}
+
+func (r *WebhookQuarantineRepository) CreateQuarantinedWebhook(ctx context.Context, q *models.QuarantinedWebhook) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.quarantine[q.ID] = clone(*q)
+	return nil
+}
+
+func (r *WebhookQuarantineRepository) GetQuarantinedWebhook(ctx context.Context, id string) (*models.QuarantinedWebhook, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	q, ok := r.db.quarantine[id]
+	if !ok {
+		return nil, errors.New("quarantined webhook not found")
+	}
+	out := clone(q)
+	return &out, nil
+}
+
+func (r *WebhookQuarantineRepository) ListQuarantinedWebhooks(ctx context.Context, status string, limit, offset int) ([]models.QuarantinedWebhook, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.QuarantinedWebhook{}
+	for _, q := range r.db.quarantine {
+		if status == "" || q.Status == status {
+			out = append(out, clone(q))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ReceivedAt.After(out[j].ReceivedAt) })
+	return page(out, limit, offset), nil
+}
+
+func (r *WebhookQuarantineRepository) UpdateQuarantinedWebhook(ctx context.Context, id string, updates map[string]interface{}) (*models.QuarantinedWebhook, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	q, ok := r.db.quarantine[id]
+	if !ok {
+		return nil, errors.New("quarantined webhook not found")
+	}
+	updated, err := applyUpdates(q, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.quarantine[id] = updated
+	return &updated, nil
+}
+
+type WebhookEventRepository struct {
+	db *DB
+}
+
+func NewWebhookEventRepository(db *DB) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+func (r *WebhookEventRepository) CreateWebhookEvent(ctx context.Context, event *models.WebhookEventRecord) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.webhookEvents[event.ID] = clone(*event)
+	return nil
+}
+
+func (r *WebhookEventRepository) GetWebhookEvent(ctx context.Context, id string) (*models.WebhookEventRecord, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	e, ok := r.db.webhookEvents[id]
+	if !ok {
+		return nil, errors.New("webhook event not found")
+	}
+	out := clone(e)
+	return &out, nil
+}
+
+func (r *WebhookEventRepository) ListWebhookEvents(ctx context.Context, gateway, status string, limit, offset int) ([]models.WebhookEventRecord, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.WebhookEventRecord{}
+	for _, e := range r.db.webhookEvents {
+		if (gateway == "" || e.Gateway == gateway) && (status == "" || e.Status == status) {
+			out = append(out, clone(e))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ReceivedAt.After(out[j].ReceivedAt) })
+	return page(out, limit, offset), nil
+}
+
+func (r *WebhookEventRepository) UpdateWebhookEvent(ctx context.Context, id string, updates map[string]interface{}) (*models.WebhookEventRecord, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	e, ok := r.db.webhookEvents[id]
+	if !ok {
+		return nil, errors.New("webhook event not found")
+	}
+	updated, err := applyUpdates(e, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.webhookEvents[id] = updated
+	return &updated, nil
+}
+
+func (r *WebhookEventRepository) ClaimWebhookEvent(ctx context.Context, id, dedupeKey string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for otherID, other := range r.db.webhookEvents {
+		if otherID != id && other.DedupeKey != nil && *other.DedupeKey == dedupeKey {
+			return repository.ErrWebhookEventDuplicate
+		}
+	}
+	e, ok := r.db.webhookEvents[id]
+	if !ok {
+		return errors.New("webhook event not found")
+	}
+	e.DedupeKey = &dedupeKey
+	r.db.webhookEvents[id] = e
+	return nil
+}
@@ -0,0 +1,322 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type ProductRepository struct {
+	db *DB
+}
+
+func NewProductRepository(db *DB) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+func (r *ProductRepository) CreateProduct(ctx context.Context, product *models.Product) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if product.ID == "" {
+		product.ID = uuid.New().String()
+	}
+	if product.CreatedAt.IsZero() {
+		product.CreatedAt = time.Now()
+	}
+
+	row := clone(*product)
+	row.Categories, row.Images, row.Variants = nil, nil, nil
+	r.db.products[product.ID] = row
+	return nil
+}
+
+func (r *ProductRepository) LinkProductCategories(ctx context.Context, productID string, categoryIDs []string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.products[productID]; !ok {
+		return errors.New("failed to link categories: product not found")
+	}
+	for _, id := range categoryIDs {
+		if _, ok := r.db.categories[id]; !ok {
+			return errors.New("failed to link categories: category not found: " + id)
+		}
+	}
+	r.db.productCategories[productID] = append(r.db.productCategories[productID], categoryIDs...)
+	return nil
+}
+
+func (r *ProductRepository) UnlinkAllProductCategories(ctx context.Context, productID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.productCategories, productID)
+	return nil
+}
+
+func (r *ProductRepository) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	if _, ok := r.db.products[id]; !ok {
+		return nil, errors.New("product not found")
+	}
+	p := r.assemble(id)
+	return &p, nil
+}
+
+func (r *ProductRepository) GetAllProducts(ctx context.Context, params *models.ProductSearchParams) ([]models.Product, error) {
+	if params == nil {
+		params = &models.ProductSearchParams{}
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := r.sorted(func(p models.Product) bool {
+		if params.ActiveOnly && !p.Active {
+			return false
+		}
+		if len(params.CategoryIDs) > 0 && !r.inAnyCategory(p.ID, params.CategoryIDs) {
+			return false
+		}
+		return true
+	})
+	return page(out, params.Limit, params.Offset), nil
+}
+
+func (r *ProductRepository) SearchProducts(ctx context.Context, query string) ([]models.Product, error) {
+	return r.SearchProductsWithLimit(ctx, query, 50, 0)
+}
+
+func (r *ProductRepository) SearchProductsWithLimit(ctx context.Context, query string, limit, offset int) ([]models.Product, error) {
+	q := strings.ToLower(query)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := r.sorted(func(p models.Product) bool {
+		return p.Active && (strings.Contains(strings.ToLower(p.Name), q) ||
+			strings.Contains(strings.ToLower(p.Description), q))
+	})
+	return page(out, limit, offset), nil
+}
+
+func (r *ProductRepository) GetAllProductNames(ctx context.Context) ([]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	names := make([]string, 0, len(r.db.products))
+	for _, p := range r.db.products {
+		if p.Active {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *ProductRepository) UpdateProduct(ctx context.Context, id string, updates map[string]interface{}) (*models.Product, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p, ok := r.db.products[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	updated, err := applyUpdates(p, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.products[id] = updated
+
+	out := r.assemble(id)
+	return &out, nil
+}
+
+// GetProductsByCategorySQL mirrors the get_products_by_category RPC: active
+// products in the category or any of its descendants.
+func (r *ProductRepository) GetProductsByCategorySQL(ctx context.Context, categoryID string, limit, offset int) ([]models.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	ids := r.categoryTree(categoryID)
+	out := r.sorted(func(p models.Product) bool {
+		return p.Active && r.inAnyCategory(p.ID, ids)
+	})
+	return page(out, limit, offset), nil
+}
+
+func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.products, id)
+	delete(r.db.productCategories, id)
+	delete(r.db.variants, id)
+	for imgID, img := range r.db.images {
+		if img.ProductID == id {
+			delete(r.db.images, imgID)
+		}
+	}
+	return nil
+}
+
+func (r *ProductRepository) ReplaceProductVariants(ctx context.Context, productID string, variants []models.AddProductVariant) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	out := make([]models.ProductVariant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, models.ProductVariant{
+			ID:         uuid.New().String(),
+			Name:       v.Name,
+			PriceCents: v.PriceCents,
+			Weight:     v.Weight,
+			MRPCents:   v.MRPCents,
+			Stock:      clone(v.Stock),
+		})
+	}
+	r.db.variants[productID] = out
+	return nil
+}
+
+func (r *ProductRepository) ReplaceProductImages(ctx context.Context, productID string, images []models.AddProductImage) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, img := range r.db.images {
+		if img.ProductID == productID {
+			delete(r.db.images, id)
+		}
+	}
+	for _, img := range images {
+		id := uuid.New().String()
+		r.db.images[id] = models.ProductImage{
+			ID:        id,
+			ProductID: productID,
+			URL:       img.URL,
+			Position:  img.Position,
+		}
+	}
+	return nil
+}
+
+// ReserveStock decrements stock under the write lock, so concurrent
+// reservations are serialised and can never oversell.
+func (r *ProductRepository) ReserveStock(ctx context.Context, productID, variantID string, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.adjustStock(productID, variantID, -quantity)
+}
+
+func (r *ProductRepository) ReleaseStock(ctx context.Context, productID, variantID string, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.adjustStock(productID, variantID, quantity)
+}
+
+// adjustStock adds delta to the variant's stock when it keeps its own, else
+// to the product's, like the adjust_stock function. Callers hold the lock.
+func (r *ProductRepository) adjustStock(productID, variantID string, delta int) error {
+	p, ok := r.db.products[productID]
+	if !ok {
+		return errors.New("product not found")
+	}
+
+	stock := &p.Stock
+	variants := r.db.variants[productID]
+	for i := range variants {
+		if variants[i].ID == variantID && variants[i].Stock != nil {
+			stock = variants[i].Stock
+		}
+	}
+	if *stock+delta < 0 {
+		return repository.ErrInsufficientStock
+	}
+	*stock += delta
+	r.db.products[productID] = p
+	return nil
+}
+
+// assemble builds a product with its categories, images and variants, like
+// the embedded select in the Supabase repository. Callers hold the lock.
+func (r *ProductRepository) assemble(id string) models.Product {
+	p := clone(r.db.products[id])
+
+	for _, catID := range r.db.productCategories[id] {
+		if c, ok := r.db.categories[catID]; ok {
+			pos := c.Position
+			p.Categories = append(p.Categories, models.ProductCategory{
+				ID:       c.ID,
+				Name:     c.Name,
+				Slug:     c.Slug,
+				Position: &pos,
+			})
+		}
+	}
+
+	for _, img := range r.db.images {
+		if img.ProductID == id {
+			p.Images = append(p.Images, img)
+		}
+	}
+	sort.Slice(p.Images, func(i, j int) bool { return p.Images[i].Position < p.Images[j].Position })
+
+	p.Variants = clone(r.db.variants[id])
+	return p
+}
+
+// sorted returns assembled products matching fn, newest first. Callers hold
+// the lock.
+func (r *ProductRepository) sorted(match func(models.Product) bool) []models.Product {
+	out := make([]models.Product, 0)
+	for id, p := range r.db.products {
+		if match(p) {
+			out = append(out, r.assemble(id))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+func (r *ProductRepository) inAnyCategory(productID string, categoryIDs []string) bool {
+	for _, have := range r.db.productCategories[productID] {
+		for _, want := range categoryIDs {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// categoryTree returns categoryID and all of its descendants.
+func (r *ProductRepository) categoryTree(categoryID string) []string {
+	ids := []string{categoryID}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range r.db.categories {
+			if c.ParentID == ids[i] {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
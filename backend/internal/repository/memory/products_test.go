@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+func TestReserveStockConcurrentNeverOversells(t *testing.T) {
+	ctx := context.Background()
+	repo := NewProductRepository(NewDB())
+	if err := repo.CreateProduct(ctx, &models.Product{ID: "p1", Name: "Milk", Stock: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.ReserveStock(ctx, "p1", "", 1)
+			if err != nil && !errors.Is(err, repository.ErrInsufficientStock) {
+				t.Errorf("ReserveStock: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	p, _ := repo.GetProductByID(ctx, "p1")
+	if reserved != 10 || p.Stock != 0 {
+		t.Fatalf("reserved %d with %d left, want 10 with 0 left", reserved, p.Stock)
+	}
+}
+
+func TestReserveAndReleaseConcurrentKeepsStockConsistent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewProductRepository(NewDB())
+	if err := repo.CreateProduct(ctx, &models.Product{ID: "p1", Name: "Curd", Stock: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.ReserveStock(ctx, "p1", "", 2); err != nil {
+				return
+			}
+			p, _ := repo.GetProductByID(ctx, "p1")
+			if p.Stock < 0 {
+				t.Errorf("stock went negative: %d", p.Stock)
+			}
+			if err := repo.ReleaseStock(ctx, "p1", "", 2); err != nil {
+				t.Errorf("ReleaseStock: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p, _ := repo.GetProductByID(ctx, "p1"); p.Stock != 5 {
+		t.Fatalf("stock = %d after every reservation was released, want 5", p.Stock)
+	}
+}
+
+func TestReserveStockUsesVariantStock(t *testing.T) {
+	ctx := context.Background()
+	repo := NewProductRepository(NewDB())
+	if err := repo.CreateProduct(ctx, &models.Product{ID: "p1", Name: "Rice", Stock: 10}); err != nil {
+		t.Fatal(err)
+	}
+	two := 2
+	if err := repo.ReplaceProductVariants(ctx, "p1", []models.AddProductVariant{{Name: "5 kg", Stock: &two}, {Name: "1 kg"}}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := repo.GetProductByID(ctx, "p1")
+	bag, loose := p.Variants[0].ID, p.Variants[1].ID
+
+	if err := repo.ReserveStock(ctx, "p1", bag, 3); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Fatalf("reserving 3 of 2 bags = %v, want ErrInsufficientStock", err)
+	}
+	if err := repo.ReserveStock(ctx, "p1", bag, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReserveStock(ctx, "p1", loose, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ = repo.GetProductByID(ctx, "p1")
+	if *p.Variants[0].Stock != 0 || p.Stock != 6 {
+		t.Fatalf("bag stock %d, product stock %d; want 0 and 6", *p.Variants[0].Stock, p.Stock)
+	}
+
+	if err := repo.ReleaseStock(ctx, "p1", bag, 2); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ = repo.GetProductByID(ctx, "p1"); *p.Variants[0].Stock != 2 || p.Stock != 6 {
+		t.Fatalf("after release: bag stock %d, product stock %d; want 2 and 6", *p.Variants[0].Stock, p.Stock)
+	}
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type ReconciliationReportRepository struct {
+	db *DB
+}
+
+func NewReconciliationReportRepository(db *DB) *ReconciliationReportRepository {
+	return &ReconciliationReportRepository{db: db}
+}
+
+func (r *ReconciliationReportRepository) SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.reconciliation[report.Date] = clone(*report)
+	return nil
+}
+
+func (r *ReconciliationReportRepository) GetReconciliationReport(ctx context.Context, date string) (*models.ReconciliationReport, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	report, ok := r.db.reconciliation[date]
+	if !ok {
+		return nil, errors.New("reconciliation report not found")
+	}
+	out := clone(report)
+	return &out, nil
+}
+
+func (r *ReconciliationReportRepository) ListReconciliationReports(ctx context.Context, limit, offset int) ([]models.ReconciliationReport, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.ReconciliationReport{}
+	for _, report := range r.db.reconciliation {
+		out = append(out, clone(report))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date > out[j].Date })
+	return page(out, limit, offset), nil
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type RefundRepository struct {
+	db *DB
+}
+
+func NewRefundRepository(db *DB) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+func (r *RefundRepository) CreateRefund(ctx context.Context, refund *models.Refund) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.refunds[refund.ID] = clone(*refund)
+	return nil
+}
+
+func (r *RefundRepository) GetRefundByID(ctx context.Context, id string) (*models.Refund, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	refund, ok := r.db.refunds[id]
+	if !ok {
+		return nil, errors.New("refund not found")
+	}
+	out := clone(refund)
+	return &out, nil
+}
+
+func (r *RefundRepository) GetRefundsByOrderID(ctx context.Context, orderID string) ([]models.Refund, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.Refund{}
+	for _, refund := range r.db.refunds {
+		if refund.OrderID == orderID {
+			out = append(out, clone(refund))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *RefundRepository) ListRefunds(ctx context.Context, status string, limit, offset int) ([]models.Refund, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.Refund{}
+	for _, refund := range r.db.refunds {
+		if status == "" || refund.Status == status {
+			out = append(out, clone(refund))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return page(out, limit, offset), nil
+}
+
+func (r *RefundRepository) UpdateRefund(ctx context.Context, id string, updates map[string]interface{}) (*models.Refund, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	refund, ok := r.db.refunds[id]
+	if !ok {
+		return nil, errors.New("refund not found")
+	}
+	updated, err := applyUpdates(refund, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.refunds[id] = updated
+	return &updated, nil
+}
+
+func (r *RefundRepository) ClaimRefund(ctx context.Context, id, from, to string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	refund, ok := r.db.refunds[id]
+	if !ok || refund.Status != from {
+		return false, nil
+	}
+	refund.Status = to
+	refund.UpdatedAt = time.Now().UTC()
+	r.db.refunds[id] = refund
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type ReturnRepository struct {
+	db *DB
+}
+
+func NewReturnRepository(db *DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+func (r *ReturnRepository) CreateReturn(ctx context.Context, ret *models.ReturnRequest) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.returns[ret.ID] = clone(*ret)
+	return nil
+}
+
+func (r *ReturnRepository) GetReturnByID(ctx context.Context, id string) (*models.ReturnRequest, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	ret, ok := r.db.returns[id]
+	if !ok {
+		return nil, errors.New("return not found")
+	}
+	out := clone(ret)
+	return &out, nil
+}
+
+func (r *ReturnRepository) GetReturnsByOrderID(ctx context.Context, orderID string) ([]models.ReturnRequest, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.ReturnRequest{}
+	for _, ret := range r.db.returns {
+		if ret.OrderID == orderID {
+			out = append(out, clone(ret))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *ReturnRepository) ListReturns(ctx context.Context, status string, limit, offset int) ([]models.ReturnRequest, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.ReturnRequest{}
+	for _, ret := range r.db.returns {
+		if status == "" || ret.Status == status {
+			out = append(out, clone(ret))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return page(out, limit, offset), nil
+}
+
+func (r *ReturnRepository) UpdateReturn(ctx context.Context, id string, updates map[string]interface{}) (*models.ReturnRequest, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	ret, ok := r.db.returns[id]
+	if !ok {
+		return nil, errors.New("return not found")
+	}
+	updated, err := applyUpdates(ret, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.returns[id] = updated
+	return &updated, nil
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type SubscriptionRepository struct {
+	db *DB
+}
+
+func NewSubscriptionRepository(db *DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, sub *models.Subscription) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	row := clone(*sub)
+	row.ProductName, row.NextDeliveryDate = "", ""
+	r.db.subscriptions[sub.ID] = row
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionByID(ctx context.Context, id string) (*models.Subscription, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sub, ok := r.db.subscriptions[id]
+	if !ok {
+		return nil, errors.New("subscription not found")
+	}
+	out := clone(sub)
+	return &out, nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionsByUserID(ctx context.Context, userID string) ([]models.Subscription, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.Subscription{}
+	for _, sub := range r.db.subscriptions {
+		if sub.UserID == userID {
+			out = append(out, clone(sub))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *SubscriptionRepository) ListSubscriptions(ctx context.Context, status string, limit, offset int) ([]models.Subscription, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.Subscription{}
+	for _, sub := range r.db.subscriptions {
+		if status == "" || sub.Status == status {
+			out = append(out, clone(sub))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return page(out, limit, offset), nil
+}
+
+func (r *SubscriptionRepository) UpdateSubscription(ctx context.Context, id string, updates map[string]interface{}) (*models.Subscription, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sub, ok := r.db.subscriptions[id]
+	if !ok {
+		return nil, errors.New("subscription not found")
+	}
+	updated, err := applyUpdates(sub, updates)
+	if err != nil {
+		return nil, err
+	}
+	r.db.subscriptions[id] = updated
+	out := clone(updated)
+	return &out, nil
+}
+
+func (r *SubscriptionRepository) CreateSubscriptionDelivery(ctx context.Context, delivery *models.SubscriptionDelivery) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, d := range r.db.subDeliveries {
+		if d.SubscriptionID == delivery.SubscriptionID && d.DeliveryDate == delivery.DeliveryDate {
+			return repository.ErrSubscriptionDeliveryExists
+		}
+	}
+	r.db.subDeliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionDelivery(ctx context.Context, subscriptionID, date string) (*models.SubscriptionDelivery, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, d := range r.db.subDeliveries {
+		if d.SubscriptionID == subscriptionID && d.DeliveryDate == date {
+			return &d, nil
+		}
+	}
+	return nil, errors.New("subscription delivery not found")
+}
+
+func (r *SubscriptionRepository) GetSubscriptionDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]models.SubscriptionDelivery, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.SubscriptionDelivery{}
+	for _, d := range r.db.subDeliveries {
+		if d.SubscriptionID == subscriptionID {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DeliveryDate > out[j].DeliveryDate })
+	return page(out, limit, offset), nil
+}
+
+func (r *SubscriptionRepository) UpdateSubscriptionDelivery(ctx context.Context, id string, updates map[string]interface{}) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	d, ok := r.db.subDeliveries[id]
+	if !ok {
+		return errors.New("subscription delivery not found")
+	}
+	updated, err := applyUpdates(d, updates)
+	if err != nil {
+		return err
+	}
+	r.db.subDeliveries[id] = updated
+	return nil
+}
+
+func (r *SubscriptionRepository) ReclaimSubscriptionDelivery(ctx context.Context, id string, staleBefore, now time.Time) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	d, ok := r.db.subDeliveries[id]
+	if !ok || d.Status != models.SubscriptionDeliveryPending || !d.ClaimedAt.Before(staleBefore) {
+		return false, nil
+	}
+	d.ClaimedAt = now.UTC()
+	r.db.subDeliveries[id] = d
+	return true, nil
+}
+
+func (r *SubscriptionRepository) DeleteSubscriptionDelivery(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.subDeliveries, id)
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionVacation(ctx context.Context, userID string) (*models.SubscriptionVacation, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	v, ok := r.db.vacations[userID]
+	if !ok {
+		return nil, errors.New("vacation not found")
+	}
+	return &v, nil
+}
+
+func (r *SubscriptionRepository) SaveSubscriptionVacation(ctx context.Context, vacation *models.SubscriptionVacation) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.vacations[vacation.UserID] = *vacation
+	return nil
+}
+
+func (r *SubscriptionRepository) DeleteSubscriptionVacation(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.vacations, userID)
+	return nil
+}
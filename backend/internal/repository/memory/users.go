@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+type UserRepository struct {
+	db *DB
+}
+
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, u := range r.db.users {
+		if strings.EqualFold(u.Email, user.Email) {
+			return errors.New("failed to create user: email already exists")
+		}
+	}
+
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	r.db.users[user.ID] = clone(*user)
+	return nil
+}
+
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, u := range r.db.users {
+		if u.Email == email {
+			out := clone(u)
+			return &out, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	u, ok := r.db.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	out := clone(u)
+	return &out, nil
+}
+
+func (r *UserRepository) UpdateUser(ctx context.Context, userID string, fields map[string]interface{}) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	u, ok := r.db.users[userID]
+	if !ok {
+		// PostgREST PATCH matching no rows is not an error
+		return nil
+	}
+	updated, err := applyUpdates(u, fields)
+	if err != nil {
+		return err
+	}
+	r.db.users[userID] = updated
+	return nil
+}
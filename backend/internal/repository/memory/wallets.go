@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type WalletRepository struct {
+	db *DB
+}
+
+func NewWalletRepository(db *DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+func (r *WalletRepository) GetWallet(ctx context.Context, userID string) (*models.Wallet, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	w, ok := r.db.wallets[userID]
+	if !ok {
+		return &models.Wallet{UserID: userID}, nil
+	}
+	return &w, nil
+}
+
+func (r *WalletRepository) AdjustWalletBalance(ctx context.Context, userID string, delta int64) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	w := r.db.wallets[userID]
+	next := w.BalanceCents + delta
+	if next < 0 {
+		return 0, repository.ErrInsufficientBalance
+	}
+	r.db.wallets[userID] = models.Wallet{UserID: userID, BalanceCents: next, UpdatedAt: time.Now().UTC()}
+	return next, nil
+}
+
+func (r *WalletRepository) CreateWalletTransaction(ctx context.Context, txn *models.WalletTransaction) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if txn.RefundID != "" {
+		for _, existing := range r.db.walletTxns {
+			if existing.RefundID == txn.RefundID {
+				return repository.ErrWalletRefundExists
+			}
+		}
+	}
+	r.db.walletTxns = append(r.db.walletTxns, *txn)
+	return nil
+}
+
+func (r *WalletRepository) UpdateWalletTransaction(ctx context.Context, id string, updates map[string]interface{}) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.walletTxns {
+		if r.db.walletTxns[i].ID == id {
+			updated, err := applyUpdates(r.db.walletTxns[i], updates)
+			if err != nil {
+				return err
+			}
+			r.db.walletTxns[i] = updated
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *WalletRepository) DeleteWalletTransaction(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i, txn := range r.db.walletTxns {
+		if txn.ID == id {
+			r.db.walletTxns = append(r.db.walletTxns[:i], r.db.walletTxns[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *WalletRepository) GetWalletTransactions(ctx context.Context, userID string, limit, offset int) ([]models.WalletTransaction, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	out := []models.WalletTransaction{}
+	for _, txn := range r.db.walletTxns {
+		if txn.UserID == userID {
+			out = append(out, txn)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return page(out, limit, offset), nil
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+type OrderEventRepository struct {
+	db *supabase.Client
+}
+
+func NewOrderEventRepository(db *supabase.Client) *OrderEventRepository {
+	return &OrderEventRepository{db: db}
+}
+
+func (r *OrderEventRepository) CreateOrderEvent(ctx context.Context, event *models.OrderEvent) error {
+	if err := r.db.From("order_events").Insert(ctx, event, nil); err != nil {
+		return fmt.Errorf("failed to create order event: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrderEvents returns an order's timeline, oldest first.
+func (r *OrderEventRepository) GetOrderEvents(ctx context.Context, orderID string) ([]models.OrderEvent, error) {
+	var events []models.OrderEvent
+	if err := r.db.From("order_events").Eq("order_id", orderID).Order("created_at", true).Get(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
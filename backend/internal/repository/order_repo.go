@@ -1,35 +1,34 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
 )
 
+// ErrOrderStatusChanged is returned when an order's status moved on between
+// reading it and writing the new one.
+var ErrOrderStatusChanged = errors.New("order status has changed, please retry")
+
+// ErrOrderNotFound is returned when no order has the requested ID.
+var ErrOrderNotFound = errors.New("order not found")
+
+// orderSelect embeds the line items so an order is fetched in one request.
+const orderSelect = "*,items:order_items(*)"
+
 type OrderRepository struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	db *supabase.Client
 }
 
-func NewOrderRepository() *OrderRepository {
-	return &OrderRepository{
-		baseURL:    os.Getenv("SUPABASE_URL"),
-		apiKey:     os.Getenv("SUPABASE_KEY"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+func NewOrderRepository(db *supabase.Client) *OrderRepository {
+	return &OrderRepository{db: db}
 }
 
-func (r *OrderRepository) CreateOrder(order *models.Order) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/orders", r.baseURL)
-
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
 	// Create order without items for DB insertion
 	orderData := map[string]interface{}{
 		"id":               order.ID,
@@ -41,36 +40,17 @@ func (r *OrderRepository) CreateOrder(order *models.Order) error {
 		"status":           order.Status,
 		"shipping_address": order.ShippingAddress,
 		"payment_metadata": order.PaymentMetadata,
+		"stock_status":     order.StockStatus,
+		"delivery_slot":    order.DeliverySlot,
+		"shipping_fees":    order.ShippingFees,
 	}
-
-	body, err := json.Marshal(orderData)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create order: status %d, body: %s", resp.StatusCode, string(respBody))
+	if order.DeliveryDate != "" {
+		orderData["delivery_date"] = order.DeliveryDate
 	}
 
 	var orders []models.Order
-	if err := json.Unmarshal(respBody, &orders); err != nil {
-		return fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("orders").Insert(ctx, orderData, &orders); err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
 	}
 
 	if len(orders) > 0 {
@@ -81,297 +61,294 @@ func (r *OrderRepository) CreateOrder(order *models.Order) error {
 	return nil
 }
 
-func (r *OrderRepository) CreateOrderItems(items []models.OrderItem) error {
+func (r *OrderRepository) CreateOrderItems(ctx context.Context, items []models.OrderItem) error {
 	if len(items) == 0 {
 		return nil
 	}
 
-	urlStr := fmt.Sprintf("%s/rest/v1/order_items", r.baseURL)
-
-	body, err := json.Marshal(items)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create order items: status %d, body: %s", resp.StatusCode, string(respBody))
+	if err := r.db.From("order_items").Insert(ctx, items, nil); err != nil {
+		return fmt.Errorf("failed to create order items: %w", err)
 	}
 
 	return nil
 }
 
-func (r *OrderRepository) GetOrderByID(id string) (*models.Order, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/orders?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *OrderRepository) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
 	var orders []models.Order
-	if err := json.NewDecoder(resp.Body).Decode(&orders); err != nil {
+	if err := r.db.From("orders").Select(orderSelect).Eq("id", id).Get(ctx, &orders); err != nil {
 		return nil, err
 	}
 
 	if len(orders) == 0 {
-		return nil, errors.New("order not found")
-	}
-
-	// Fetch order items
-	items, err := r.GetOrderItems(id)
-	if err == nil {
-		orders[0].Items = items
+		return nil, ErrOrderNotFound
 	}
 
 	return &orders[0], nil
 }
 
-func (r *OrderRepository) GetOrderItems(orderID string) ([]models.OrderItem, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/order_items?order_id=eq.%s", r.baseURL, orderID)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
+func (r *OrderRepository) GetOrderItems(ctx context.Context, orderID string) ([]models.OrderItem, error) {
+	var items []models.OrderItem
+	if err := r.db.From("order_items").Eq("order_id", orderID).Get(ctx, &items); err != nil {
 		return nil, err
 	}
 
-	r.setHeaders(req)
+	return items, nil
+}
 
-	resp, err := r.httpClient.Do(req)
+func (r *OrderRepository) GetOrdersByUserID(ctx context.Context, userID string) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.From("orders").
+		Select(orderSelect).
+		Eq("user_id", userID).
+		Order("placed_at", false).
+		Get(ctx, &orders)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var items []models.OrderItem
-	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
-		return nil, err
-	}
 
-	return items, nil
+	return orders, nil
 }
 
-func (r *OrderRepository) GetOrdersByUserID(userID string) ([]models.Order, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/orders?user_id=eq.%s&order=placed_at.desc", r.baseURL, userID)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+// CountOrdersByPaymentStatus counts the user's orders whose
+// payment_metadata.payment_status is paymentStatus, fetching only their IDs.
+func (r *OrderRepository) CountOrdersByPaymentStatus(ctx context.Context, userID, paymentStatus string) (int, error) {
+	var orders []struct {
+		ID string `json:"id"`
+	}
+	err := r.db.From("orders").
+		Select("id").
+		Eq("user_id", userID).
+		Eq("payment_metadata->>payment_status", paymentStatus).
+		Get(ctx, &orders)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	r.setHeaders(req)
+	return len(orders), nil
+}
 
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+func (r *OrderRepository) GetAllOrders(ctx context.Context, status string, limit, offset int) ([]models.Order, error) {
+	q := r.db.From("orders").Select(orderSelect).Order("placed_at", false)
+
+	if status != "" {
+		q.Eq("status", status)
 	}
-	defer resp.Body.Close()
+	q.Limit(limit).Offset(offset)
 
 	var orders []models.Order
-	if err := json.NewDecoder(resp.Body).Decode(&orders); err != nil {
+	if err := q.Get(ctx, &orders); err != nil {
 		return nil, err
 	}
 
-	// Fetch items for each order
-	for i := range orders {
-		items, err := r.GetOrderItems(orders[i].ID)
-		if err == nil {
-			orders[i].Items = items
-		}
-	}
-
 	return orders, nil
 }
 
-func (r *OrderRepository) GetAllOrders(status string, limit, offset int) ([]models.Order, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/orders?select=*&order=placed_at.desc", r.baseURL)
-
+// GetOrdersEnteredStatus returns the orders that moved into entered within
+// [from, to), most recent move first; a non-empty status also filters on the
+// current status and a zero from or to leaves that end open. The
+// orders_entered_status function (internal/database/orders_entered_status.sql)
+// picks the page of order IDs and the orders are then fetched in one request.
+func (r *OrderRepository) GetOrdersEnteredStatus(ctx context.Context, entered, status string, from, to time.Time, limit, offset int) ([]models.Order, error) {
+	params := map[string]interface{}{
+		"p_to_status": entered,
+		"p_status":    nil,
+		"p_from":      nil,
+		"p_to":        nil,
+		"p_limit":     nil,
+		"p_offset":    offset,
+	}
 	if status != "" {
-		urlStr += fmt.Sprintf("&status=eq.%s", status)
+		params["p_status"] = status
 	}
-	if limit > 0 {
-		urlStr += fmt.Sprintf("&limit=%d", limit)
+	if !from.IsZero() {
+		params["p_from"] = from.UTC().Format(time.RFC3339Nano)
 	}
-	if offset > 0 {
-		urlStr += fmt.Sprintf("&offset=%d", offset)
+	if !to.IsZero() {
+		params["p_to"] = to.UTC().Format(time.RFC3339Nano)
 	}
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
+	if limit > 0 {
+		params["p_limit"] = limit
 	}
 
-	r.setHeaders(req)
+	var rows []struct {
+		OrderID string `json:"order_id"`
+	}
+	if err := r.db.RPC(ctx, "orders_entered_status", params, &rows); err != nil {
+		return nil, fmt.Errorf("failed to find orders by status entered: %w", err)
+	}
+	if len(rows) == 0 {
+		return []models.Order{}, nil
+	}
 
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.OrderID
 	}
-	defer resp.Body.Close()
 
 	var orders []models.Order
-	if err := json.NewDecoder(resp.Body).Decode(&orders); err != nil {
+	if err := r.db.From("orders").Select(orderSelect).In("id", ids).Get(ctx, &orders); err != nil {
 		return nil, err
 	}
 
-	// Fetch items for each order
-	for i := range orders {
-		items, err := r.GetOrderItems(orders[i].ID)
-		if err == nil {
-			orders[i].Items = items
+	// Back into the order the function ranked them in
+	byID := make(map[string]models.Order, len(orders))
+	for _, o := range orders {
+		byID[o.ID] = o
+	}
+	out := make([]models.Order, 0, len(orders))
+	for _, id := range ids {
+		if o, ok := byID[id]; ok {
+			out = append(out, o)
 		}
 	}
 
-	return orders, nil
+	return out, nil
 }
 
-func (r *OrderRepository) UpdateOrderStatus(id string, status string) (*models.Order, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/orders?id=eq.%s", r.baseURL, id)
-
+// UpdateOrderStatus moves an order from one status to another. The filter on
+// the current status makes it a compare-and-set: if another request changed
+// the order first, nothing is written and ErrOrderStatusChanged is returned.
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, id, from, status string) (*models.Order, error) {
 	updates := map[string]interface{}{
 		"status": status,
 	}
 
-	body, err := json.Marshal(updates)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(http.MethodPatch, urlStr, bytes.NewBuffer(body))
+	var orders []models.Order
+	err := r.db.From("orders").
+		Select(orderSelect).
+		Eq("id", id).
+		Eq("status", from).
+		Update(ctx, updates, &orders)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to update order: %w", err)
 	}
 
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	if len(orders) == 0 {
+		return nil, ErrOrderStatusChanged
 	}
-	defer resp.Body.Close()
 
-	respBody, _ := io.ReadAll(resp.Body)
+	return &orders[0], nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update order: status %d, body: %s", resp.StatusCode, string(respBody))
+// UpdateDeliveryAgent assigns the order to a delivery agent.
+func (r *OrderRepository) UpdateDeliveryAgent(ctx context.Context, id, agentID string) (*models.Order, error) {
+	updates := map[string]interface{}{
+		"delivery_agent_id": agentID,
 	}
 
 	var orders []models.Order
-	if err := json.Unmarshal(respBody, &orders); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("orders").Select(orderSelect).Eq("id", id).Update(ctx, updates, &orders); err != nil {
+		return nil, fmt.Errorf("failed to assign delivery agent: %w", err)
 	}
 
 	if len(orders) == 0 {
-		return nil, errors.New("order not found")
-	}
-
-	// Fetch order items
-	items, err := r.GetOrderItems(id)
-	if err == nil {
-		orders[0].Items = items
+		return nil, ErrOrderNotFound
 	}
 
 	return &orders[0], nil
 }
 
-func (r *OrderRepository) DeleteOrder(id string) error {
+func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
 	// First delete order items
-	itemsURL := fmt.Sprintf("%s/rest/v1/order_items?order_id=eq.%s", r.baseURL, id)
-	req, err := http.NewRequest(http.MethodDelete, itemsURL, nil)
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
+	if err := r.db.From("order_items").Eq("order_id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete order items: %w", err)
 	}
-	resp.Body.Close()
 
 	// Then delete the order
-	orderURL := fmt.Sprintf("%s/rest/v1/orders?id=eq.%s", r.baseURL, id)
-	req, err = http.NewRequest(http.MethodDelete, orderURL, nil)
-	if err != nil {
-		return err
+	if err := r.db.From("orders").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
 	}
 
-	r.setHeaders(req)
+	return nil
+}
 
-	resp, err = r.httpClient.Do(req)
-	if err != nil {
-		return err
+func (r *OrderRepository) UpdatePaymentMetadata(ctx context.Context, orderID string, metadata map[string]interface{}) error {
+	updates := map[string]interface{}{
+		"payment_metadata": metadata,
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete order: status %d, body: %s", resp.StatusCode, string(respBody))
+	if err := r.db.From("orders").Eq("id", orderID).Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to update payment metadata: %w", err)
 	}
 
 	return nil
 }
 
-func (r *OrderRepository) UpdatePaymentMetadata(orderID string, metadata map[string]interface{}) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/orders?id=eq.%s", r.baseURL, orderID)
-
+// UpdateStockStatus moves an order's stock_status from one value to another.
+// The filter on the current value makes the transition a compare-and-set, so
+// only one caller can e.g. release a reservation; it reports whether it won.
+func (r *OrderRepository) UpdateStockStatus(ctx context.Context, orderID, from, to string) (bool, error) {
 	updates := map[string]interface{}{
-		"payment_metadata": metadata,
+		"stock_status": to,
 	}
 
-	body, err := json.Marshal(updates)
+	var orders []models.Order
+	err := r.db.From("orders").
+		Select("id").
+		Eq("id", orderID).
+		Eq("stock_status", from).
+		Update(ctx, updates, &orders)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("failed to update stock status: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPatch, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
+	return len(orders) > 0, nil
+}
+
+// UpdateOrderItem saves a line item's quantity and tax; quantity zero
+// removes it.
+func (r *OrderRepository) UpdateOrderItem(ctx context.Context, item *models.OrderItem) error {
+	q := r.db.From("order_items").Eq("id", item.ID).Eq("order_id", item.OrderID)
+	if item.Quantity == 0 {
+		if err := q.Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete order item: %w", err)
+		}
+		return nil
 	}
 
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=minimal")
+	updates := map[string]interface{}{
+		"quantity":      item.Quantity,
+		"taxable_cents": item.TaxableCents,
+		"cgst_cents":    item.CGSTCents,
+		"sgst_cents":    item.SGSTCents,
+		"igst_cents":    item.IGSTCents,
+		"tax_cents":     item.TaxCents,
+	}
+	if err := q.Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to update order item: %w", err)
+	}
+	return nil
+}
 
-	resp, err := r.httpClient.Do(req)
+// UpdateOrderTotals saves the order's subtotal, shipping, tax and total, and
+// its shipping fee breakdown. from is the order the totals were worked out
+// from: the write is a compare-and-set on its status, stock_status and total,
+// so if the order was edited, cancelled or released since, nothing is written
+// and ErrOrderStatusChanged is returned.
+func (r *OrderRepository) UpdateOrderTotals(ctx context.Context, order, from *models.Order) error {
+	updates := map[string]interface{}{
+		"subtotal_cents": order.SubtotalCents,
+		"shipping_cents": order.ShippingCents,
+		"tax_cents":      order.TaxCents,
+		"total_cents":    order.TotalCents,
+		"shipping_fees":  order.ShippingFees,
+	}
+
+	var orders []models.Order
+	err := r.db.From("orders").
+		Select("id").
+		Eq("id", order.ID).
+		Eq("status", from.Status).
+		Eq("stock_status", from.StockStatus).
+		Eq("total_cents", from.TotalCents).
+		Update(ctx, updates, &orders)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to update order totals: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update payment metadata: status %d, body: %s", resp.StatusCode, string(respBody))
+	if len(orders) == 0 {
+		return ErrOrderStatusChanged
 	}
 
 	return nil
 }
-
-func (r *OrderRepository) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("apikey", r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+func TestGetOrdersEnteredStatusKeepsRankedOrder(t *testing.T) {
+	var params map[string]interface{}
+	var idFilter string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/v1/rpc/orders_entered_status":
+			json.NewDecoder(r.Body).Decode(&params)
+			w.Write([]byte(`[{"order_id":"o2","entered_at":"2026-10-02T00:00:00Z"},{"order_id":"o1","entered_at":"2026-10-01T00:00:00Z"}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/v1/orders":
+			idFilter = r.URL.Query().Get("id")
+			// In whatever order the database likes
+			w.Write([]byte(`[{"id":"o1","status":"delivered"},{"id":"o2","status":"delivered"}]`))
+		default:
+			http.Error(w, "unexpected request", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	repo := NewOrderRepository(supabase.NewClient(srv.URL, "key", srv.Client()))
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	orders, err := repo.GetOrdersEnteredStatus(context.Background(), "delivered", "", from, time.Time{}, 0, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 2 || orders[0].ID != "o2" || orders[1].ID != "o1" {
+		t.Fatalf("orders = %+v, want o2 then o1", orders)
+	}
+	if idFilter != "in.(o2,o1)" {
+		t.Fatalf("id filter = %q, want in.(o2,o1)", idFilter)
+	}
+	if params["p_to_status"] != "delivered" || params["p_from"] != "2026-10-01T00:00:00Z" || params["p_offset"] != float64(20) {
+		t.Fatalf("params = %v", params)
+	}
+	// Unset filters and no limit are passed as null so the function skips them
+	for _, key := range []string{"p_status", "p_to", "p_limit"} {
+		if v, ok := params[key]; !ok || v != nil {
+			t.Fatalf("%s = %v, want null", key, v)
+		}
+	}
+}
+
+func TestCountOrdersByPaymentStatusFiltersInTheDatabase(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`[{"id":"o1"},{"id":"o2"}]`))
+	}))
+	defer srv.Close()
+	repo := NewOrderRepository(supabase.NewClient(srv.URL, "key", srv.Client()))
+
+	n, err := repo.CountOrdersByPaymentStatus(context.Background(), "u1", "cod_refused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("count = %d, want 2", n)
+	}
+	if query.Get("select") != "id" || query.Get("user_id") != "eq.u1" || query.Get("payment_metadata->>payment_status") != "eq.cod_refused" {
+		t.Fatalf("query = %v, want only IDs of u1's cod_refused orders", query)
+	}
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrPaymentLookupExists is returned when the gateway+kind+value is already
+// mapped to an order.
+var ErrPaymentLookupExists = errors.New("payment lookup already exists")
+
+// ErrPaymentLookupNotFound is returned when nothing maps the gateway+kind+value
+// to an order.
+var ErrPaymentLookupNotFound = errors.New("payment lookup not found")
+
+type PaymentLookupRepository struct {
+	db *supabase.Client
+}
+
+func NewPaymentLookupRepository(db *supabase.Client) *PaymentLookupRepository {
+	return &PaymentLookupRepository{db: db}
+}
+
+func (r *PaymentLookupRepository) CreatePaymentLookup(ctx context.Context, lookup *models.PaymentLookup) error {
+	if err := r.db.From("payment_lookups").Insert(ctx, lookup, nil); err != nil {
+		if supabase.IsCode(err, supabase.CodeUniqueViolation) {
+			return ErrPaymentLookupExists
+		}
+		return fmt.Errorf("failed to create payment lookup: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PaymentLookupRepository) GetPaymentLookup(ctx context.Context, gateway, kind, value string) (*models.PaymentLookup, error) {
+	var lookups []models.PaymentLookup
+	err := r.db.From("payment_lookups").
+		Eq("gateway", gateway).
+		Eq("kind", kind).
+		Eq("value", value).
+		Get(ctx, &lookups)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lookups) == 0 {
+		return nil, ErrPaymentLookupNotFound
+	}
+
+	return &lookups[0], nil
+}
@@ -1,74 +1,38 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
 )
 
 type ProductImageRepository struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	db *supabase.Client
 }
 
-func NewProductImageRepository() *ProductImageRepository {
-	return &ProductImageRepository{
-		baseURL:    os.Getenv("SUPABASE_URL"),
-		apiKey:     os.Getenv("SUPABASE_KEY"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+func NewProductImageRepository(db *supabase.Client) *ProductImageRepository {
+	return &ProductImageRepository{db: db}
 }
 
-func (r *ProductImageRepository) GetImagesByProductID(productID string) ([]models.ProductImage, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images?product_id=eq.%s&order=position.asc", r.baseURL, productID)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *ProductImageRepository) GetImagesByProductID(ctx context.Context, productID string) ([]models.ProductImage, error) {
 	var images []models.ProductImage
-	if err := json.NewDecoder(resp.Body).Decode(&images); err != nil {
+	err := r.db.From("product_images").
+		Eq("product_id", productID).
+		Order("position", true).
+		Get(ctx, &images)
+	if err != nil {
 		return nil, err
 	}
 
 	return images, nil
 }
 
-func (r *ProductImageRepository) GetImageByID(id string) (*models.ProductImage, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *ProductImageRepository) GetImageByID(ctx context.Context, id string) (*models.ProductImage, error) {
 	var images []models.ProductImage
-	if err := json.NewDecoder(resp.Body).Decode(&images); err != nil {
+	if err := r.db.From("product_images").Eq("id", id).Get(ctx, &images); err != nil {
 		return nil, err
 	}
 
@@ -79,37 +43,10 @@ func (r *ProductImageRepository) GetImageByID(id string) (*models.ProductImage,
 	return &images[0], nil
 }
 
-func (r *ProductImageRepository) CreateImage(image *models.ProductImage) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images", r.baseURL)
-
-	body, err := json.Marshal(image)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create image: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *ProductImageRepository) CreateImage(ctx context.Context, image *models.ProductImage) error {
 	var images []models.ProductImage
-	if err := json.Unmarshal(respBody, &images); err != nil {
-		return fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("product_images").Insert(ctx, image, &images); err != nil {
+		return fmt.Errorf("failed to create image: %w", err)
 	}
 
 	if len(images) > 0 {
@@ -119,72 +56,22 @@ func (r *ProductImageRepository) CreateImage(image *models.ProductImage) error {
 	return nil
 }
 
-func (r *ProductImageRepository) CreateImages(images []models.ProductImage) error {
+func (r *ProductImageRepository) CreateImages(ctx context.Context, images []models.ProductImage) error {
 	if len(images) == 0 {
 		return nil
 	}
 
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images", r.baseURL)
-
-	body, err := json.Marshal(images)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create images: status %d, body: %s", resp.StatusCode, string(respBody))
+	if err := r.db.From("product_images").Insert(ctx, images, nil); err != nil {
+		return fmt.Errorf("failed to create images: %w", err)
 	}
 
 	return nil
 }
 
-func (r *ProductImageRepository) UpdateImage(id string, updates map[string]interface{}) (*models.ProductImage, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images?id=eq.%s", r.baseURL, id)
-
-	body, err := json.Marshal(updates)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(http.MethodPatch, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update image: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *ProductImageRepository) UpdateImage(ctx context.Context, id string, updates map[string]interface{}) (*models.ProductImage, error) {
 	var images []models.ProductImage
-	if err := json.Unmarshal(respBody, &images); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("product_images").Eq("id", id).Update(ctx, updates, &images); err != nil {
+		return nil, fmt.Errorf("failed to update image: %w", err)
 	}
 
 	if len(images) == 0 {
@@ -194,72 +81,30 @@ func (r *ProductImageRepository) UpdateImage(id string, updates map[string]inter
 	return &images[0], nil
 }
 
-func (r *ProductImageRepository) DeleteImage(id string) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete image: status %d, body: %s", resp.StatusCode, string(respBody))
+func (r *ProductImageRepository) DeleteImage(ctx context.Context, id string) error {
+	if err := r.db.From("product_images").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
 	}
 
 	return nil
 }
 
-func (r *ProductImageRepository) DeleteImagesByProductID(productID string) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images?product_id=eq.%s", r.baseURL, productID)
-
-	req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete images: status %d, body: %s", resp.StatusCode, string(respBody))
+func (r *ProductImageRepository) DeleteImagesByProductID(ctx context.Context, productID string) error {
+	if err := r.db.From("product_images").Eq("product_id", productID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete images: %w", err)
 	}
 
 	return nil
 }
 
-func (r *ProductImageRepository) GetMaxPosition(productID string) (int, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_images?product_id=eq.%s&order=position.desc&limit=1", r.baseURL, productID)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return 0, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
+func (r *ProductImageRepository) GetMaxPosition(ctx context.Context, productID string) (int, error) {
 	var images []models.ProductImage
-	if err := json.NewDecoder(resp.Body).Decode(&images); err != nil {
+	err := r.db.From("product_images").
+		Eq("product_id", productID).
+		Order("position", false).
+		Limit(1).
+		Get(ctx, &images)
+	if err != nil {
 		return 0, err
 	}
 
@@ -269,10 +114,3 @@ func (r *ProductImageRepository) GetMaxPosition(productID string) (int, error) {
 
 	return images[0].Position, nil
 }
-
-func (r *ProductImageRepository) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("apikey", r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-}
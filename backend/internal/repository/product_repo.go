@@ -1,38 +1,28 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
 )
 
+// productSelect embeds images, categories and variants in one request.
+const productSelect = "*,images:product_images(id,url,position),categories:product_categories(category_id,categories(id,name,slug,position)),variants:product_variants(*)"
+
 type ProductRepository struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	db *supabase.Client
 }
 
-func NewProductRepository() *ProductRepository {
-	return &ProductRepository{
-		baseURL:    os.Getenv("SUPABASE_URL"),
-		apiKey:     os.Getenv("SUPABASE_KEY"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+func NewProductRepository(db *supabase.Client) *ProductRepository {
+	return &ProductRepository{db: db}
 }
 
 // CreateProduct inserts product WITHOUT categories
-func (r *ProductRepository) CreateProduct(product *models.Product) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/products", r.baseURL)
-
+func (r *ProductRepository) CreateProduct(ctx context.Context, product *models.Product) error {
 	// Build product data WITHOUT category_id
 	productData := map[string]interface{}{
 		"id":          product.ID,
@@ -46,36 +36,15 @@ func (r *ProductRepository) CreateProduct(product *models.Product) error {
 		"metadata":    product.Metadata,
 		"mrp_cents":   product.MRPCents,
 		"weight":      product.Weight,
-	}
-
-	body, err := json.Marshal(productData)
-	if err != nil {
-		return err
-	}
 
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create product: status %d, body: %s", resp.StatusCode, string(respBody))
+		"hsn_code":           product.HSNCode,
+		"tax_class":          product.TaxClass,
+		"price_includes_tax": product.PriceIncludesTax,
 	}
 
 	var products []models.Product
-	if err := json.Unmarshal(respBody, &products); err != nil {
-		return fmt.Errorf("failed to decode response: %v", err)
+	if err := r.db.From("products").Insert(ctx, productData, &products); err != nil {
+		return fmt.Errorf("failed to create product: %w", err)
 	}
 
 	if len(products) > 0 {
@@ -86,13 +55,11 @@ func (r *ProductRepository) CreateProduct(product *models.Product) error {
 }
 
 // LinkProductCategories inserts mappings into product_categories
-func (r *ProductRepository) LinkProductCategories(productID string, categoryIDs []string) error {
+func (r *ProductRepository) LinkProductCategories(ctx context.Context, productID string, categoryIDs []string) error {
 	if len(categoryIDs) == 0 {
 		return nil
 	}
 
-	urlStr := fmt.Sprintf("%s/rest/v1/product_categories", r.baseURL)
-
 	// Build batch insert
 	mappings := make([]map[string]interface{}, 0, len(categoryIDs))
 	for _, catID := range categoryIDs {
@@ -102,78 +69,26 @@ func (r *ProductRepository) LinkProductCategories(productID string, categoryIDs
 		})
 	}
 
-	body, err := json.Marshal(mappings)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to link categories: status %d, body: %s", resp.StatusCode, string(respBody))
+	if err := r.db.From("product_categories").Insert(ctx, mappings, nil); err != nil {
+		return fmt.Errorf("failed to link categories: %w", err)
 	}
 
 	return nil
 }
 
 // UnlinkAllProductCategories removes all category mappings for a product
-func (r *ProductRepository) UnlinkAllProductCategories(productID string) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/product_categories?product_id=eq.%s", r.baseURL, productID)
-
-	req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to unlink categories: status %d, body: %s", resp.StatusCode, string(respBody))
+func (r *ProductRepository) UnlinkAllProductCategories(ctx context.Context, productID string) error {
+	if err := r.db.From("product_categories").Eq("product_id", productID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to unlink categories: %w", err)
 	}
 
 	return nil
 }
 
 // GetProductByID fetches product WITH categories using JOIN
-func (r *ProductRepository) GetProductByID(id string) (*models.Product, error) {
-	// Supabase REST API: select with embedded categories and images
-	urlStr := fmt.Sprintf("%s/rest/v1/products?id=eq.%s&select=*,images:product_images(id,url,position),categories:product_categories(category_id,categories(id,name,slug,position)),variants:product_variants(*)", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *ProductRepository) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
 	var rawProducts []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&rawProducts); err != nil {
+	if err := r.db.From("products").Select(productSelect).Eq("id", id).Get(ctx, &rawProducts); err != nil {
 		return nil, err
 	}
 
@@ -190,7 +105,7 @@ func (r *ProductRepository) GetProductByID(id string) (*models.Product, error) {
 }
 
 // GetAllProducts fetches products WITH optional category filter
-func (r *ProductRepository) GetAllProducts(params *models.ProductSearchParams) ([]models.Product, error) {
+func (r *ProductRepository) GetAllProducts(ctx context.Context, params *models.ProductSearchParams) ([]models.Product, error) {
 	// Standard select fields
 	selectFields := "*,images:product_images(id,url,position),variants:product_variants(*)"
 
@@ -201,130 +116,65 @@ func (r *ProductRepository) GetAllProducts(params *models.ProductSearchParams) (
 		categoryJoin = "categories:product_categories!inner(category_id,categories(id,name,slug,position))"
 	}
 
-	urlStr := fmt.Sprintf("%s/rest/v1/products?select=%s,%s", r.baseURL, selectFields, categoryJoin)
+	q := r.db.From("products").Select(selectFields + "," + categoryJoin)
 
 	// Add filters
 	if params != nil {
 		if params.ActiveOnly {
-			urlStr += "&active=eq.true"
+			q.Eq("active", true)
 		}
 
 		if len(params.CategoryIDs) > 0 {
-			categoryFilter := strings.Join(params.CategoryIDs, ",")
-			urlStr += fmt.Sprintf("&categories.category_id=in.(%s)", categoryFilter)
+			q.In("categories.category_id", params.CategoryIDs)
 		}
 
-		if params.Limit > 0 {
-			urlStr += fmt.Sprintf("&limit=%d", params.Limit)
-		}
-		if params.Offset > 0 {
-			urlStr += fmt.Sprintf("&offset=%d", params.Offset)
-		}
-	}
-
-	urlStr += "&order=created_at.desc"
-
-	fmt.Printf("[DEBUG] GetAllProducts URL: %s\n", urlStr)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
+		q.Limit(params.Limit).Offset(params.Offset)
 	}
 
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var rawProducts []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&rawProducts); err != nil {
+	if err := q.Order("created_at", false).Get(ctx, &rawProducts); err != nil {
 		return nil, err
 	}
 
-	products := make([]models.Product, 0, len(rawProducts))
-	for _, raw := range rawProducts {
-		product, err := r.parseProductWithCategories(raw)
-		if err != nil {
-			continue
-		}
-		products = append(products, *product)
-	}
-
-	return products, nil
+	return r.parseProducts(rawProducts), nil
 }
 
 // SearchProducts searches by name/description WITH categories
-func (r *ProductRepository) SearchProducts(query string) ([]models.Product, error) {
-	return r.SearchProductsWithLimit(query, 50, 0)
+func (r *ProductRepository) SearchProducts(ctx context.Context, query string) ([]models.Product, error) {
+	return r.SearchProductsWithLimit(ctx, query, 50, 0)
 }
 
 // SearchProductsWithLimit searches by name/description WITH categories, with pagination.
-func (r *ProductRepository) SearchProductsWithLimit(query string, limit, offset int) ([]models.Product, error) {
-	encodedQuery := url.QueryEscape("%" + query + "%")
-	urlStr := fmt.Sprintf("%s/rest/v1/products?or=(name.ilike.%s,description.ilike.%s)&active=eq.true&select=*,images:product_images(id,url,position),categories:product_categories(category_id,categories(id,name,slug,position)),variants:product_variants(*)&order=created_at.desc",
-		r.baseURL, encodedQuery, encodedQuery)
-
-	if limit > 0 {
-		urlStr += fmt.Sprintf("&limit=%d", limit)
-	}
-	if offset > 0 {
-		urlStr += fmt.Sprintf("&offset=%d", offset)
-	}
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (r *ProductRepository) SearchProductsWithLimit(ctx context.Context, query string, limit, offset int) ([]models.Product, error) {
+	pattern := "%" + query + "%"
 
 	var rawProducts []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&rawProducts); err != nil {
+	err := r.db.From("products").
+		Select(productSelect).
+		Or(supabase.Cond("name", "ilike", pattern), supabase.Cond("description", "ilike", pattern)).
+		Eq("active", true).
+		Order("created_at", false).
+		Limit(limit).
+		Offset(offset).
+		Get(ctx, &rawProducts)
+	if err != nil {
 		return nil, err
 	}
 
-	products := make([]models.Product, 0, len(rawProducts))
-	for _, raw := range rawProducts {
-		product, err := r.parseProductWithCategories(raw)
-		if err != nil {
-			continue
-		}
-		products = append(products, *product)
-	}
-
-	return products, nil
+	return r.parseProducts(rawProducts), nil
 }
 
 // GetAllProductNames fetches all active product names for indexing.
-func (r *ProductRepository) GetAllProductNames() ([]string, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/products?active=eq.true&select=name&order=name.asc", r.baseURL)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *ProductRepository) GetAllProductNames(ctx context.Context) ([]string, error) {
 	var products []struct {
 		Name string `json:"name"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+	err := r.db.From("products").
+		Select("name").
+		Eq("active", true).
+		Order("name", true).
+		Get(ctx, &products)
+	if err != nil {
 		return nil, err
 	}
 
@@ -337,53 +187,81 @@ func (r *ProductRepository) GetAllProductNames() ([]string, error) {
 }
 
 // UpdateProduct updates product fields (NOT categories - use LinkProductCategories separately)
-func (r *ProductRepository) UpdateProduct(id string, updates map[string]interface{}) (*models.Product, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/products?id=eq.%s", r.baseURL, id)
-
-
-	body, err := json.Marshal(updates)
-	if err != nil {
-		return nil, err
+func (r *ProductRepository) UpdateProduct(ctx context.Context, id string, updates map[string]interface{}) (*models.Product, error) {
+	var products []models.Product
+	if err := r.db.From("products").Eq("id", id).Update(ctx, updates, &products); err != nil {
+		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPatch, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
+	if len(products) == 0 {
+		return nil, errors.New("product not found")
 	}
 
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
+	// Re-fetch to get categories
+	return r.GetProductByID(ctx, id)
+}
 
-	resp, err := r.httpClient.Do(req)
+// ErrInsufficientStock is returned when a reservation would take stock below zero.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ReserveStock atomically takes quantity units out of the stock of a
+// product, or of its variant when the variant keeps its own.
+func (r *ProductRepository) ReserveStock(ctx context.Context, productID, variantID string, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	ok, err := r.adjustStock(ctx, productID, variantID, -quantity)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
+	if !ok {
+		return ErrInsufficientStock
+	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update product: status %d, body: %s", resp.StatusCode, string(respBody))
+// ReleaseStock atomically returns quantity units to where ReserveStock took
+// them from.
+func (r *ProductRepository) ReleaseStock(ctx context.Context, productID, variantID string, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	ok, err := r.adjustStock(ctx, productID, variantID, quantity)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("product not found")
 	}
+	return nil
+}
 
-	var products []models.Product
-	if err := json.Unmarshal(respBody, &products); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+// adjustStock adds delta to the stock in one statement through the
+// adjust_stock function (internal/database/adjust_stock.sql), which updates
+// "stock = stock + delta" only where that stays at or above zero. There is no
+// read-modify-write, so contention can't make it fail. It reports false when
+// the row is missing or would go below zero.
+func (r *ProductRepository) adjustStock(ctx context.Context, productID, variantID string, delta int) (bool, error) {
+	params := map[string]interface{}{
+		"p_product_id": productID,
+		"p_variant_id": nil,
+		"p_delta":      delta,
+	}
+	if variantID != "" {
+		params["p_variant_id"] = variantID
 	}
 
-	if len(products) == 0 {
-		return nil, errors.New("product not found")
+	var stock *int
+	if err := r.db.RPC(ctx, "adjust_stock", params, &stock); err != nil {
+		return false, fmt.Errorf("failed to update stock: %w", err)
 	}
 
-	// Re-fetch to get categories
-	return r.GetProductByID(id)
+	return stock != nil, nil
 }
 
 // GetProductsByCategorySQL fetches products using raw SQL via RPC
 // Returns full product data including categories, images, and variants
-func (r *ProductRepository) GetProductsByCategorySQL(categoryID string, limit, offset int) ([]models.Product, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/rpc/get_products_by_category", r.baseURL)
-
+func (r *ProductRepository) GetProductsByCategorySQL(ctx context.Context, categoryID string, limit, offset int) ([]models.Product, error) {
 	// Build request body for RPC call
 	requestBody := map[string]interface{}{
 		"p_category_id": categoryID,
@@ -391,30 +269,6 @@ func (r *ProductRepository) GetProductsByCategorySQL(categoryID string, limit, o
 		"p_offset":      offset,
 	}
 
-	body, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("RPC call failed: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
 	// Parse the RPC response with full JSON fields
 	var rpcResults []struct {
 		ID          string                 `json:"id"`
@@ -447,8 +301,8 @@ func (r *ProductRepository) GetProductsByCategorySQL(categoryID string, limit, o
 		} `json:"variants"`
 	}
 
-	if err := json.Unmarshal(respBody, &rpcResults); err != nil {
-		return nil, fmt.Errorf("failed to parse RPC response: %v", err)
+	if err := r.db.RPC(ctx, "get_products_by_category", requestBody, &rpcResults); err != nil {
+		return nil, fmt.Errorf("RPC call failed: %w", err)
 	}
 
 	// Convert to Product models
@@ -507,46 +361,19 @@ func (r *ProductRepository) GetProductsByCategorySQL(categoryID string, limit, o
 }
 
 // DeleteProduct deletes product (cascade will remove product_categories)
-func (r *ProductRepository) DeleteProduct(id string) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/products?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete product: status %d, body: %s", resp.StatusCode, string(respBody))
+func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	if err := r.db.From("products").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
 	}
 
 	return nil
 }
 
 // ReplaceProductVariants replaces all variants for a product
-func (r *ProductRepository) ReplaceProductVariants(productID string, variants []models.AddProductVariant) error {
+func (r *ProductRepository) ReplaceProductVariants(ctx context.Context, productID string, variants []models.AddProductVariant) error {
 	// 1. Delete existing variants
-	deleteURL := fmt.Sprintf("%s/rest/v1/product_variants?product_id=eq.%s", r.baseURL, productID)
-	req, err := http.NewRequest(http.MethodDelete, deleteURL, nil)
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to delete variants: %d", resp.StatusCode)
+	if err := r.db.From("product_variants").Eq("product_id", productID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete variants: %w", err)
 	}
 
 	if len(variants) == 0 {
@@ -554,7 +381,6 @@ func (r *ProductRepository) ReplaceProductVariants(productID string, variants []
 	}
 
 	// 2. Insert new variants
-	insertURL := fmt.Sprintf("%s/rest/v1/product_variants", r.baseURL)
 	variantData := make([]map[string]interface{}, len(variants))
 	for i, v := range variants {
 		variantData[i] = map[string]interface{}{
@@ -563,49 +389,22 @@ func (r *ProductRepository) ReplaceProductVariants(productID string, variants []
 			"price_cents": v.PriceCents,
 			"weight":      v.Weight,
 			"mrp_cents":   v.MRPCents,
+			"stock":       v.Stock,
 		}
 	}
 
-	body, err := json.Marshal(variantData)
-	if err != nil {
-		return err
-	}
-
-	req, err = http.NewRequest(http.MethodPost, insertURL, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-	resp, err = r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to insert variants: %d %s", resp.StatusCode, string(b))
+	if err := r.db.From("product_variants").Insert(ctx, variantData, nil); err != nil {
+		return fmt.Errorf("failed to insert variants: %w", err)
 	}
 
 	return nil
 }
 
 // ReplaceProductImages replaces all images for a product
-func (r *ProductRepository) ReplaceProductImages(productID string, images []models.AddProductImage) error {
+func (r *ProductRepository) ReplaceProductImages(ctx context.Context, productID string, images []models.AddProductImage) error {
 	// 1. Delete existing images
-	deleteURL := fmt.Sprintf("%s/rest/v1/product_images?product_id=eq.%s", r.baseURL, productID)
-	req, err := http.NewRequest(http.MethodDelete, deleteURL, nil)
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to delete images: %d", resp.StatusCode)
+	if err := r.db.From("product_images").Eq("product_id", productID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete images: %w", err)
 	}
 
 	if len(images) == 0 {
@@ -613,7 +412,6 @@ func (r *ProductRepository) ReplaceProductImages(productID string, images []mode
 	}
 
 	// 2. Insert new images
-	insertURL := fmt.Sprintf("%s/rest/v1/product_images", r.baseURL)
 	imageData := make([]map[string]interface{}, len(images))
 	for i, img := range images {
 		imageData[i] = map[string]interface{}{
@@ -623,29 +421,26 @@ func (r *ProductRepository) ReplaceProductImages(productID string, images []mode
 		}
 	}
 
-	body, err := json.Marshal(imageData)
-	if err != nil {
-		return err
+	if err := r.db.From("product_images").Insert(ctx, imageData, nil); err != nil {
+		return fmt.Errorf("failed to insert images: %w", err)
 	}
 
-	req, err = http.NewRequest(http.MethodPost, insertURL, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-	resp, err = r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to insert images: %d %s", resp.StatusCode, string(b))
+// parseProducts converts embedded-select rows, skipping any that fail to parse
+func (r *ProductRepository) parseProducts(rawProducts []map[string]interface{}) []models.Product {
+	products := make([]models.Product, 0, len(rawProducts))
+	for _, raw := range rawProducts {
+		product, err := r.parseProductWithCategories(raw)
+		if err != nil {
+			continue
+		}
+		products = append(products, *product)
 	}
-
-	return nil
+	return products
 }
+
 func (r *ProductRepository) parseProductWithCategories(raw map[string]interface{}) (*models.Product, error) {
 	product := &models.Product{
 		ID:          getString(raw, "id"),
@@ -657,12 +452,17 @@ func (r *ProductRepository) parseProductWithCategories(raw map[string]interface{
 		Active:      getBool(raw, "active"),
 		CreatedAt:   getTime(raw, "created_at"),
 		Weight:      getString(raw, "weight"),
+		HSNCode:     getString(raw, "hsn_code"),
+		TaxClass:    getString(raw, "tax_class"),
 	}
 
 	if mrp, ok := raw["mrp_cents"].(float64); ok {
 		v := int64(mrp)
 		product.MRPCents = &v
 	}
+	if inclusive, ok := raw["price_includes_tax"].(bool); ok {
+		product.PriceIncludesTax = &inclusive
+	}
 
 	if metadata, ok := raw["metadata"].(map[string]interface{}); ok {
 		product.Metadata = metadata
@@ -733,13 +533,6 @@ func (r *ProductRepository) parseProductWithCategories(raw map[string]interface{
 	return product, nil
 }
 
-func (r *ProductRepository) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("apikey", r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-}
-
 // Helper functions for type assertions
 func getString(m map[string]interface{}, key string) string {
 	if v, ok := m[key].(string); ok {
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// fakeStockServer stands in for the adjust_stock function: it applies the
+// delta in one step unless that would take the stock below zero, in which
+// case it returns null.
+type fakeStockServer struct {
+	mu    sync.Mutex
+	stock int
+	calls []map[string]interface{}
+}
+
+func (f *fakeStockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.URL.Path != "/rest/v1/rpc/adjust_stock" {
+		http.Error(w, "unexpected request", http.StatusNotFound)
+		return
+	}
+	var params map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&params)
+	f.calls = append(f.calls, params)
+
+	delta := int(params["p_delta"].(float64))
+	if f.stock+delta < 0 {
+		w.Write([]byte("null"))
+		return
+	}
+	f.stock += delta
+	json.NewEncoder(w).Encode(f.stock)
+}
+
+func TestAdjustStockConcurrentNeverOversells(t *testing.T) {
+	fake := &fakeStockServer{stock: 5}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	repo := NewProductRepository(supabase.NewClient(srv.URL, "key", srv.Client()))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.ReserveStock(context.Background(), "p1", "", 1)
+			switch {
+			case err == nil:
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			case errors.Is(err, ErrInsufficientStock):
+			default:
+				t.Errorf("ReserveStock: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Contention never fails a reservation that stock could cover
+	if reserved != 5 || fake.stock != 0 {
+		t.Fatalf("reserved %d with %d left, want 5 with 0 left", reserved, fake.stock)
+	}
+}
+
+func TestReleaseStockReturnsToVariant(t *testing.T) {
+	fake := &fakeStockServer{stock: 0}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	repo := NewProductRepository(supabase.NewClient(srv.URL, "key", srv.Client()))
+
+	if err := repo.ReleaseStock(context.Background(), "p1", "v1", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReleaseStock(context.Background(), "p1", "", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.calls[0]; got["p_product_id"] != "p1" || got["p_variant_id"] != "v1" || got["p_delta"] != 3.0 {
+		t.Fatalf("first call = %v, want p1/v1/+3", got)
+	}
+	if got := fake.calls[1]; got["p_variant_id"] != nil {
+		t.Fatalf("second call = %v, want no variant", got)
+	}
+}
+
+func TestAdjustStockFailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"boom"}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	repo := NewProductRepository(supabase.NewClient(srv.URL, "key", srv.Client()))
+
+	err := repo.ReserveStock(context.Background(), "p1", "", 1)
+	if err == nil || errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("ReserveStock on a 500 = %v, want an upstream error", err)
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+type ReconciliationReportRepository struct {
+	db *supabase.Client
+}
+
+func NewReconciliationReportRepository(db *supabase.Client) *ReconciliationReportRepository {
+	return &ReconciliationReportRepository{db: db}
+}
+
+// SaveReconciliationReport stores the report for its date, replacing any
+// earlier report for the same day.
+func (r *ReconciliationReportRepository) SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) error {
+	if err := r.db.From("payment_reconciliation_reports").OnConflict("date").Upsert(ctx, report, nil); err != nil {
+		return fmt.Errorf("failed to save reconciliation report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ReconciliationReportRepository) GetReconciliationReport(ctx context.Context, date string) (*models.ReconciliationReport, error) {
+	var reports []models.ReconciliationReport
+	if err := r.db.From("payment_reconciliation_reports").Eq("date", date).Get(ctx, &reports); err != nil {
+		return nil, err
+	}
+
+	if len(reports) == 0 {
+		return nil, errors.New("reconciliation report not found")
+	}
+
+	return &reports[0], nil
+}
+
+// ListReconciliationReports returns the most recent days first.
+func (r *ReconciliationReportRepository) ListReconciliationReports(ctx context.Context, limit, offset int) ([]models.ReconciliationReport, error) {
+	var reports []models.ReconciliationReport
+	if err := r.db.From("payment_reconciliation_reports").Order("date", false).Limit(limit).Offset(offset).Get(ctx, &reports); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
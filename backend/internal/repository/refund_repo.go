@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+type RefundRepository struct {
+	db *supabase.Client
+}
+
+func NewRefundRepository(db *supabase.Client) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+func (r *RefundRepository) CreateRefund(ctx context.Context, refund *models.Refund) error {
+	if err := r.db.From("refunds").Insert(ctx, refund, nil); err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RefundRepository) GetRefundByID(ctx context.Context, id string) (*models.Refund, error) {
+	var refunds []models.Refund
+	if err := r.db.From("refunds").Eq("id", id).Get(ctx, &refunds); err != nil {
+		return nil, err
+	}
+
+	if len(refunds) == 0 {
+		return nil, errors.New("refund not found")
+	}
+
+	return &refunds[0], nil
+}
+
+func (r *RefundRepository) GetRefundsByOrderID(ctx context.Context, orderID string) ([]models.Refund, error) {
+	var refunds []models.Refund
+	if err := r.db.From("refunds").Eq("order_id", orderID).Order("created_at", true).Get(ctx, &refunds); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// ListRefunds returns the newest refunds first; an empty status lists all.
+func (r *RefundRepository) ListRefunds(ctx context.Context, status string, limit, offset int) ([]models.Refund, error) {
+	q := r.db.From("refunds").Order("created_at", false)
+
+	if status != "" {
+		q.Eq("status", status)
+	}
+	q.Limit(limit).Offset(offset)
+
+	var refunds []models.Refund
+	if err := q.Get(ctx, &refunds); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+func (r *RefundRepository) UpdateRefund(ctx context.Context, id string, updates map[string]interface{}) (*models.Refund, error) {
+	var refunds []models.Refund
+	if err := r.db.From("refunds").Eq("id", id).Update(ctx, updates, &refunds); err != nil {
+		return nil, fmt.Errorf("failed to update refund: %w", err)
+	}
+	if len(refunds) == 0 {
+		return nil, errors.New("refund not found")
+	}
+	return &refunds[0], nil
+}
+
+// ClaimRefund moves a refund from one status to another only if it is still
+// in from, so two retries can't both send it; it reports whether it won.
+func (r *RefundRepository) ClaimRefund(ctx context.Context, id, from, to string) (bool, error) {
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": time.Now().UTC(),
+	}
+
+	var refunds []models.Refund
+	err := r.db.From("refunds").
+		Select("id").
+		Eq("id", id).
+		Eq("status", from).
+		Update(ctx, updates, &refunds)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim refund: %w", err)
+	}
+
+	return len(refunds) > 0, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+type ReturnRepository struct {
+	db *supabase.Client
+}
+
+func NewReturnRepository(db *supabase.Client) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+func (r *ReturnRepository) CreateReturn(ctx context.Context, ret *models.ReturnRequest) error {
+	if err := r.db.From("returns").Insert(ctx, ret, nil); err != nil {
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ReturnRepository) GetReturnByID(ctx context.Context, id string) (*models.ReturnRequest, error) {
+	var returns []models.ReturnRequest
+	if err := r.db.From("returns").Eq("id", id).Get(ctx, &returns); err != nil {
+		return nil, err
+	}
+
+	if len(returns) == 0 {
+		return nil, errors.New("return not found")
+	}
+
+	return &returns[0], nil
+}
+
+func (r *ReturnRepository) GetReturnsByOrderID(ctx context.Context, orderID string) ([]models.ReturnRequest, error) {
+	var returns []models.ReturnRequest
+	if err := r.db.From("returns").Eq("order_id", orderID).Order("created_at", true).Get(ctx, &returns); err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+// ListReturns returns the newest returns first; an empty status lists all.
+func (r *ReturnRepository) ListReturns(ctx context.Context, status string, limit, offset int) ([]models.ReturnRequest, error) {
+	q := r.db.From("returns").Order("created_at", false)
+
+	if status != "" {
+		q.Eq("status", status)
+	}
+	q.Limit(limit).Offset(offset)
+
+	var returns []models.ReturnRequest
+	if err := q.Get(ctx, &returns); err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+func (r *ReturnRepository) UpdateReturn(ctx context.Context, id string, updates map[string]interface{}) (*models.ReturnRequest, error) {
+	var returns []models.ReturnRequest
+	if err := r.db.From("returns").Eq("id", id).Update(ctx, updates, &returns); err != nil {
+		return nil, fmt.Errorf("failed to update return: %w", err)
+	}
+	if len(returns) == 0 {
+		return nil, errors.New("return not found")
+	}
+	return &returns[0], nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrSubscriptionDeliveryExists is returned when a subscription's date has
+// already been claimed, by this server or another.
+var ErrSubscriptionDeliveryExists = errors.New("subscription delivery already exists")
+
+type SubscriptionRepository struct {
+	db *supabase.Client
+}
+
+func NewSubscriptionRepository(db *supabase.Client) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, sub *models.Subscription) error {
+	row := *sub
+	row.ProductName, row.NextDeliveryDate = "", ""
+	if err := r.db.From("subscriptions").Insert(ctx, row, nil); err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionByID(ctx context.Context, id string) (*models.Subscription, error) {
+	var subs []models.Subscription
+	if err := r.db.From("subscriptions").Eq("id", id).Get(ctx, &subs); err != nil {
+		return nil, err
+	}
+
+	if len(subs) == 0 {
+		return nil, errors.New("subscription not found")
+	}
+
+	return &subs[0], nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionsByUserID(ctx context.Context, userID string) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	if err := r.db.From("subscriptions").Eq("user_id", userID).Order("created_at", false).Get(ctx, &subs); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// ListSubscriptions returns the oldest subscriptions first; an empty status
+// lists all.
+func (r *SubscriptionRepository) ListSubscriptions(ctx context.Context, status string, limit, offset int) ([]models.Subscription, error) {
+	q := r.db.From("subscriptions").Order("created_at", true)
+
+	if status != "" {
+		q.Eq("status", status)
+	}
+	q.Limit(limit).Offset(offset)
+
+	var subs []models.Subscription
+	if err := q.Get(ctx, &subs); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+func (r *SubscriptionRepository) UpdateSubscription(ctx context.Context, id string, updates map[string]interface{}) (*models.Subscription, error) {
+	var subs []models.Subscription
+	if err := r.db.From("subscriptions").Eq("id", id).Update(ctx, updates, &subs); err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil, errors.New("subscription not found")
+	}
+	return &subs[0], nil
+}
+
+// CreateSubscriptionDelivery relies on a unique index on
+// (subscription_id, delivery_date) so each date is claimed once.
+func (r *SubscriptionRepository) CreateSubscriptionDelivery(ctx context.Context, delivery *models.SubscriptionDelivery) error {
+	if err := r.db.From("subscription_deliveries").Insert(ctx, delivery, nil); err != nil {
+		if supabase.IsCode(err, supabase.CodeUniqueViolation) {
+			return ErrSubscriptionDeliveryExists
+		}
+		return fmt.Errorf("failed to create subscription delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionDelivery(ctx context.Context, subscriptionID, date string) (*models.SubscriptionDelivery, error) {
+	var deliveries []models.SubscriptionDelivery
+	err := r.db.From("subscription_deliveries").
+		Eq("subscription_id", subscriptionID).
+		Eq("delivery_date", date).
+		Get(ctx, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return nil, errors.New("subscription delivery not found")
+	}
+
+	return &deliveries[0], nil
+}
+
+// GetSubscriptionDeliveries returns the latest dates first.
+func (r *SubscriptionRepository) GetSubscriptionDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]models.SubscriptionDelivery, error) {
+	var deliveries []models.SubscriptionDelivery
+	err := r.db.From("subscription_deliveries").
+		Eq("subscription_id", subscriptionID).
+		Order("delivery_date", false).
+		Limit(limit).
+		Offset(offset).
+		Get(ctx, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *SubscriptionRepository) UpdateSubscriptionDelivery(ctx context.Context, id string, updates map[string]interface{}) error {
+	if err := r.db.From("subscription_deliveries").Eq("id", id).Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to update subscription delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ReclaimSubscriptionDelivery takes over a pending claim last taken before
+// staleBefore, moving its claimed_at to now. The filters make it a
+// compare-and-set, so only one pass takes over an abandoned claim; it
+// reports whether this one did.
+func (r *SubscriptionRepository) ReclaimSubscriptionDelivery(ctx context.Context, id string, staleBefore, now time.Time) (bool, error) {
+	updates := map[string]interface{}{
+		"claimed_at": now.UTC(),
+	}
+
+	var deliveries []models.SubscriptionDelivery
+	err := r.db.From("subscription_deliveries").
+		Select("id").
+		Eq("id", id).
+		Eq("status", models.SubscriptionDeliveryPending).
+		Lt("claimed_at", staleBefore.UTC().Format(time.RFC3339Nano)).
+		Update(ctx, updates, &deliveries)
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim subscription delivery: %w", err)
+	}
+
+	return len(deliveries) > 0, nil
+}
+
+func (r *SubscriptionRepository) DeleteSubscriptionDelivery(ctx context.Context, id string) error {
+	if err := r.db.From("subscription_deliveries").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete subscription delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionVacation(ctx context.Context, userID string) (*models.SubscriptionVacation, error) {
+	var vacations []models.SubscriptionVacation
+	if err := r.db.From("subscription_vacations").Eq("user_id", userID).Get(ctx, &vacations); err != nil {
+		return nil, err
+	}
+
+	if len(vacations) == 0 {
+		return nil, errors.New("vacation not found")
+	}
+
+	return &vacations[0], nil
+}
+
+// SaveSubscriptionVacation replaces the user's vacation, if any.
+func (r *SubscriptionRepository) SaveSubscriptionVacation(ctx context.Context, vacation *models.SubscriptionVacation) error {
+	if err := r.db.From("subscription_vacations").OnConflict("user_id").Upsert(ctx, vacation, nil); err != nil {
+		return fmt.Errorf("failed to save vacation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepository) DeleteSubscriptionVacation(ctx context.Context, userID string) error {
+	if err := r.db.From("subscription_vacations").Eq("user_id", userID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete vacation: %w", err)
+	}
+
+	return nil
+}
@@ -1,78 +1,37 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
 )
 
 type UserAddressRepository struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	db *supabase.Client
 }
 
-func NewUserAddressRepository() *UserAddressRepository {
-	return &UserAddressRepository{
-		baseURL:    os.Getenv("SUPABASE_URL"),
-		apiKey:     os.Getenv("SUPABASE_KEY"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
-}
-
-func (r *UserAddressRepository) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("apikey", r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+func NewUserAddressRepository(db *supabase.Client) *UserAddressRepository {
+	return &UserAddressRepository{db: db}
 }
 
-func (r *UserAddressRepository) ListByUserID(userID string) ([]models.UserAddress, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/user_addresses?user_id=eq.%s&order=created_at.desc", r.baseURL, userID)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *UserAddressRepository) ListByUserID(ctx context.Context, userID string) ([]models.UserAddress, error) {
 	var out []models.UserAddress
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	err := r.db.From("user_addresses").
+		Eq("user_id", userID).
+		Order("created_at", false).
+		Get(ctx, &out)
+	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (r *UserAddressRepository) GetByID(id string) (*models.UserAddress, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/user_addresses?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *UserAddressRepository) GetByID(ctx context.Context, id string) (*models.UserAddress, error) {
 	var out []models.UserAddress
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	if err := r.db.From("user_addresses").Eq("id", id).Get(ctx, &out); err != nil {
 		return nil, err
 	}
 	if len(out) == 0 {
@@ -81,35 +40,10 @@ func (r *UserAddressRepository) GetByID(id string) (*models.UserAddress, error)
 	return &out[0], nil
 }
 
-func (r *UserAddressRepository) Create(addr *models.UserAddress) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/user_addresses", r.baseURL)
-
-	body, err := json.Marshal(addr)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create address: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *UserAddressRepository) Create(ctx context.Context, addr *models.UserAddress) error {
 	var out []models.UserAddress
-	if err := json.Unmarshal(respBody, &out); err != nil {
-		return err
+	if err := r.db.From("user_addresses").Insert(ctx, addr, &out); err != nil {
+		return fmt.Errorf("failed to create address: %w", err)
 	}
 	if len(out) > 0 {
 		*addr = out[0]
@@ -117,35 +51,10 @@ func (r *UserAddressRepository) Create(addr *models.UserAddress) error {
 	return nil
 }
 
-func (r *UserAddressRepository) Update(id string, updates map[string]interface{}) (*models.UserAddress, error) {
-	urlStr := fmt.Sprintf("%s/rest/v1/user_addresses?id=eq.%s", r.baseURL, id)
-
-	body, err := json.Marshal(updates)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(http.MethodPatch, urlStr, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update address: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *UserAddressRepository) Update(ctx context.Context, id string, updates map[string]interface{}) (*models.UserAddress, error) {
 	var out []models.UserAddress
-	if err := json.Unmarshal(respBody, &out); err != nil {
-		return nil, err
+	if err := r.db.From("user_addresses").Eq("id", id).Update(ctx, updates, &out); err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
 	}
 	if len(out) == 0 {
 		return nil, errors.New("address not found")
@@ -153,24 +62,9 @@ func (r *UserAddressRepository) Update(id string, updates map[string]interface{}
 	return &out[0], nil
 }
 
-func (r *UserAddressRepository) Delete(id string) error {
-	urlStr := fmt.Sprintf("%s/rest/v1/user_addresses?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
-	if err != nil {
-		return err
-	}
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete address: status %d, body: %s", resp.StatusCode, string(respBody))
+func (r *UserAddressRepository) Delete(ctx context.Context, id string) error {
+	if err := r.db.From("user_addresses").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
 	}
 	return nil
 }
@@ -1,64 +1,26 @@
 package repository
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
 )
 
 type UserRepository struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	db *supabase.Client
 }
 
-func NewUserRepository() *UserRepository {
-	return &UserRepository{
-		baseURL:    os.Getenv("SUPABASE_URL"),
-		apiKey:     os.Getenv("SUPABASE_KEY"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+func NewUserRepository(db *supabase.Client) *UserRepository {
+	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
-	url := fmt.Sprintf("%s/rest/v1/users", r.baseURL)
-
-	body, err := json.Marshal(user)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=representation")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	// Read response body for error details
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create user: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	var users []models.User
-	if err := json.Unmarshal(respBody, &users); err != nil {
-		return fmt.Errorf("failed to decode response: %v, body: %s", err, string(respBody))
+	if err := r.db.From("users").Insert(ctx, user, &users); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	if len(users) > 0 {
@@ -68,24 +30,9 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 	return nil
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
-	url := fmt.Sprintf("%s/rest/v1/users?email=eq.%s", r.baseURL, email)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var users []models.User
-	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+	if err := r.db.From("users").Eq("email", email).Get(ctx, &users); err != nil {
 		return nil, err
 	}
 
@@ -96,24 +43,9 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return &users[0], nil
 }
 
-func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
-	url := fmt.Sprintf("%s/rest/v1/users?id=eq.%s", r.baseURL, id)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	r.setHeaders(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	var users []models.User
-	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+	if err := r.db.From("users").Eq("id", id).Get(ctx, &users); err != nil {
 		return nil, err
 	}
 
@@ -124,39 +56,10 @@ func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 	return &users[0], nil
 }
 
-func (r *UserRepository) UpdateUser(userID string, fields map[string]interface{}) error {
-	url := fmt.Sprintf("%s/rest/v1/users?id=eq.%s", r.baseURL, userID)
-
-	body, err := json.Marshal(fields)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	r.setHeaders(req)
-	req.Header.Set("Prefer", "return=minimal")
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update user: status %d, body: %s", resp.StatusCode, string(respBody))
+func (r *UserRepository) UpdateUser(ctx context.Context, userID string, fields map[string]interface{}) error {
+	if err := r.db.From("users").Eq("id", userID).Update(ctx, fields, nil); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
 	}
 
 	return nil
 }
-
-func (r *UserRepository) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("apikey", r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-}
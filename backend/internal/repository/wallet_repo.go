@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrInsufficientBalance is returned when a wallet can't cover a debit.
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
+// ErrWalletRefundExists is returned when a refund has already been credited.
+var ErrWalletRefundExists = errors.New("refund already credited to wallet")
+
+type WalletRepository struct {
+	db *supabase.Client
+}
+
+func NewWalletRepository(db *supabase.Client) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// GetWallet returns the user's wallet, or an empty one if they have never
+// had a balance.
+func (r *WalletRepository) GetWallet(ctx context.Context, userID string) (*models.Wallet, error) {
+	var wallets []models.Wallet
+	if err := r.db.From("wallets").Select("user_id,balance_cents,updated_at").Eq("user_id", userID).Get(ctx, &wallets); err != nil {
+		return nil, err
+	}
+
+	if len(wallets) == 0 {
+		return &models.Wallet{UserID: userID}, nil
+	}
+	return &wallets[0], nil
+}
+
+// walletUpdateAttempts bounds the compare-and-set retries under contention.
+const walletUpdateAttempts = 10
+
+// AdjustWalletBalance adds delta (negative to spend) to the balance with a
+// conditional PATCH (balance_cents=eq.<read value>), so concurrent debits
+// can't spend the same money twice. It returns the new balance.
+func (r *WalletRepository) AdjustWalletBalance(ctx context.Context, userID string, delta int64) (int64, error) {
+	for attempt := 0; attempt < walletUpdateAttempts; attempt++ {
+		var wallets []models.Wallet
+		if err := r.db.From("wallets").Select("user_id,balance_cents").Eq("user_id", userID).Get(ctx, &wallets); err != nil {
+			return 0, err
+		}
+
+		var current int64
+		if len(wallets) > 0 {
+			current = wallets[0].BalanceCents
+		}
+		next := current + delta
+		if next < 0 {
+			return 0, ErrInsufficientBalance
+		}
+
+		row := map[string]interface{}{
+			"user_id":       userID,
+			"balance_cents": next,
+			"updated_at":    time.Now().UTC(),
+		}
+		if len(wallets) == 0 {
+			// First credit; another one racing us makes the insert fail and
+			// we retry as an update
+			err := r.db.From("wallets").Insert(ctx, row, nil)
+			if err == nil {
+				return next, nil
+			}
+			if !supabase.IsCode(err, supabase.CodeUniqueViolation) {
+				return 0, fmt.Errorf("failed to create wallet: %w", err)
+			}
+		} else {
+			var updated []models.Wallet
+			err := r.db.From("wallets").
+				Select("user_id").
+				Eq("user_id", userID).
+				Eq("balance_cents", current).
+				Update(ctx, row, &updated)
+			if err != nil {
+				return 0, fmt.Errorf("failed to update wallet: %w", err)
+			}
+			if len(updated) > 0 {
+				return next, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 5 * time.Millisecond):
+		}
+	}
+
+	return 0, errors.New("wallet update conflict, please retry")
+}
+
+// CreateWalletTransaction relies on a unique index on refund_id so a refund
+// is credited once.
+func (r *WalletRepository) CreateWalletTransaction(ctx context.Context, txn *models.WalletTransaction) error {
+	if err := r.db.From("wallet_transactions").Insert(ctx, txn, nil); err != nil {
+		if txn.RefundID != "" && supabase.IsCode(err, supabase.CodeUniqueViolation) {
+			return ErrWalletRefundExists
+		}
+		return fmt.Errorf("failed to create wallet transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WalletRepository) UpdateWalletTransaction(ctx context.Context, id string, updates map[string]interface{}) error {
+	if err := r.db.From("wallet_transactions").Eq("id", id).Update(ctx, updates, nil); err != nil {
+		return fmt.Errorf("failed to update wallet transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WalletRepository) DeleteWalletTransaction(ctx context.Context, id string) error {
+	if err := r.db.From("wallet_transactions").Eq("id", id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete wallet transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetWalletTransactions returns the newest transactions first.
+func (r *WalletRepository) GetWalletTransactions(ctx context.Context, userID string, limit, offset int) ([]models.WalletTransaction, error) {
+	var txns []models.WalletTransaction
+	err := r.db.From("wallet_transactions").
+		Eq("user_id", userID).
+		Order("created_at", false).
+		Limit(limit).
+		Offset(offset).
+		Get(ctx, &txns)
+	if err != nil {
+		return nil, err
+	}
+
+	return txns, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+// ErrWebhookEventDuplicate is returned when another event already holds the
+// dedupe key, i.e. the same payment has been processed before.
+var ErrWebhookEventDuplicate = errors.New("webhook event already processed")
+
+type WebhookEventRepository struct {
+	db *supabase.Client
+}
+
+func NewWebhookEventRepository(db *supabase.Client) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+func (r *WebhookEventRepository) CreateWebhookEvent(ctx context.Context, event *models.WebhookEventRecord) error {
+	if err := r.db.From("webhook_events").Insert(ctx, event, nil); err != nil {
+		return fmt.Errorf("failed to store webhook event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookEventRepository) GetWebhookEvent(ctx context.Context, id string) (*models.WebhookEventRecord, error) {
+	var out []models.WebhookEventRecord
+	if err := r.db.From("webhook_events").Eq("id", id).Get(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, errors.New("webhook event not found")
+	}
+
+	return &out[0], nil
+}
+
+// ListWebhookEvents returns the newest events first; empty filters match all.
+func (r *WebhookEventRepository) ListWebhookEvents(ctx context.Context, gateway, status string, limit, offset int) ([]models.WebhookEventRecord, error) {
+	q := r.db.From("webhook_events").Order("received_at", false)
+
+	if gateway != "" {
+		q.Eq("gateway", gateway)
+	}
+	if status != "" {
+		q.Eq("status", status)
+	}
+	q.Limit(limit).Offset(offset)
+
+	var out []models.WebhookEventRecord
+	if err := q.Get(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *WebhookEventRepository) UpdateWebhookEvent(ctx context.Context, id string, updates map[string]interface{}) (*models.WebhookEventRecord, error) {
+	var out []models.WebhookEventRecord
+	if err := r.db.From("webhook_events").Eq("id", id).Update(ctx, updates, &out); err != nil {
+		return nil, fmt.Errorf("failed to update webhook event: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("webhook event not found")
+	}
+	return &out[0], nil
+}
+
+// ClaimWebhookEvent gives the event ownership of dedupeKey. The unique index
+// on dedupe_key makes this atomic across concurrent deliveries.
+func (r *WebhookEventRepository) ClaimWebhookEvent(ctx context.Context, id, dedupeKey string) error {
+	updates := map[string]interface{}{"dedupe_key": dedupeKey}
+
+	if err := r.db.From("webhook_events").Eq("id", id).Update(ctx, updates, nil); err != nil {
+		if supabase.IsCode(err, supabase.CodeUniqueViolation) {
+			return ErrWebhookEventDuplicate
+		}
+		return fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/supabase"
+)
+
+type WebhookQuarantineRepository struct {
+	db *supabase.Client
+}
+
+func NewWebhookQuarantineRepository(db *supabase.Client) *WebhookQuarantineRepository {
+	return &WebhookQuarantineRepository{db: db}
+}
+
+func (r *WebhookQuarantineRepository) CreateQuarantinedWebhook(ctx context.Context, q *models.QuarantinedWebhook) error {
+	if err := r.db.From("webhook_quarantine").Insert(ctx, q, nil); err != nil {
+		return fmt.Errorf("failed to quarantine webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookQuarantineRepository) GetQuarantinedWebhook(ctx context.Context, id string) (*models.QuarantinedWebhook, error) {
+	var out []models.QuarantinedWebhook
+	if err := r.db.From("webhook_quarantine").Eq("id", id).Get(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, errors.New("quarantined webhook not found")
+	}
+
+	return &out[0], nil
+}
+
+// ListQuarantinedWebhooks returns the newest entries first; an empty status
+// lists all of them.
+func (r *WebhookQuarantineRepository) ListQuarantinedWebhooks(ctx context.Context, status string, limit, offset int) ([]models.QuarantinedWebhook, error) {
+	q := r.db.From("webhook_quarantine").Order("received_at", false)
+
+	if status != "" {
+		q.Eq("status", status)
+	}
+	q.Limit(limit).Offset(offset)
+
+	var out []models.QuarantinedWebhook
+	if err := q.Get(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *WebhookQuarantineRepository) UpdateQuarantinedWebhook(ctx context.Context, id string, updates map[string]interface{}) (*models.QuarantinedWebhook, error) {
+	var out []models.QuarantinedWebhook
+	if err := r.db.From("webhook_quarantine").Eq("id", id).Update(ctx, updates, &out); err != nil {
+		return nil, fmt.Errorf("failed to update quarantined webhook: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("quarantined webhook not found")
+	}
+	return &out[0], nil
+}
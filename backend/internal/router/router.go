@@ -16,8 +16,16 @@ func SetupRoutes(
 	productImageHandler *handlers.ProductImageHandler,
 	userAddressHandler *handlers.UserAddressHandler,
 	paymentHandler *handlers.PaymentHandler,
+	cartHandler *handlers.CartHandler,
+	refundHandler *handlers.RefundHandler,
+	reconciliationHandler *handlers.ReconciliationHandler,
+	returnHandler *handlers.ReturnHandler,
+	invoiceHandler *handlers.InvoiceHandler,
+	walletHandler *handlers.WalletHandler,
+	subscriptionHandler *handlers.SubscriptionHandler,
 	authMiddleware *middleware.AuthMiddleware,
 	adminMiddleware *middleware.AdminMiddleware,
+	idempotencyMiddleware *middleware.IdempotencyMiddleware,
 ) *http.ServeMux {
 	mux := http.NewServeMux()
 
@@ -72,23 +80,103 @@ func SetupRoutes(
 	mux.Handle("DELETE /api/product-images/{id}", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(productImageHandler.DeleteImage))))
 	mux.Handle("DELETE /api/products/{productId}/images", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(productImageHandler.DeleteAllProductImages))))
 
-	// Order routes (authenticated users)
-	mux.Handle("POST /api/orders", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CreateOrder)))
+	// Order routes (authenticated users; POSTs honour Idempotency-Key)
+	mux.Handle("POST /api/orders", authMiddleware.Authenticate(idempotencyMiddleware.Handle(http.HandlerFunc(orderHandler.CreateOrder))))
 	mux.Handle("GET /api/orders/my", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.GetMyOrders)))
+	mux.Handle("GET /api/orders/cod-eligibility", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CODEligibility)))
 	mux.Handle("GET /api/orders/{id}", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.GetOrderByID)))
 	mux.Handle("POST /api/orders/{id}/cancel", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.CancelOrder)))
 
 	// Order routes (admin only)
 	mux.Handle("GET /api/orders", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(orderHandler.GetAllOrders))))
-	mux.Handle("PUT /api/orders/{id}/status", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(orderHandler.UpdateOrderStatus))))
+	mux.Handle("PUT /api/orders/{id}/status", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.UpdateOrderStatus)))
+	mux.Handle("PUT /api/orders/{id}/delivery-agent", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(orderHandler.AssignDeliveryAgent))))
+	mux.Handle("PATCH /api/orders/{id}/items/{itemId}", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.UpdateOrderItem)))
+	mux.Handle("DELETE /api/orders/{id}/items/{itemId}", authMiddleware.Authenticate(http.HandlerFunc(orderHandler.RemoveOrderItem)))
+	mux.Handle("POST /api/orders/{id}/cod-refused", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(orderHandler.MarkCODRefused))))
+
+	// Refund routes
+	mux.Handle("GET /api/orders/{id}/refunds", authMiddleware.Authenticate(http.HandlerFunc(refundHandler.GetOrderRefunds)))
+	mux.Handle("POST /api/orders/{id}/refunds", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(refundHandler.CreateRefund))))
+	mux.Handle("GET /api/refunds", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(refundHandler.ListRefunds))))
+	mux.Handle("POST /api/refunds/{id}/complete", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(refundHandler.CompleteRefund))))
+	mux.Handle("POST /api/refunds/{id}/fail", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(refundHandler.FailRefund))))
+	mux.Handle("POST /api/refunds/{id}/retry", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(refundHandler.RetryRefund))))
+
+	// Returns: customers open them, admins decide
+	mux.Handle("GET /api/orders/{id}/returns", authMiddleware.Authenticate(http.HandlerFunc(returnHandler.GetOrderReturns)))
+	mux.Handle("POST /api/orders/{id}/returns", authMiddleware.Authenticate(http.HandlerFunc(returnHandler.CreateReturn)))
+	mux.Handle("GET /api/returns", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(returnHandler.ListReturns))))
+	mux.Handle("GET /api/returns/{id}", authMiddleware.Authenticate(http.HandlerFunc(returnHandler.GetReturn)))
+	mux.Handle("POST /api/returns/{id}/approve", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(returnHandler.ApproveReturn))))
+	mux.Handle("POST /api/returns/{id}/reject", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(returnHandler.RejectReturn))))
+
+	// Invoices are issued on delivery; owners and admins can download them
+	mux.Handle("GET /api/orders/{id}/invoice", authMiddleware.Authenticate(http.HandlerFunc(invoiceHandler.GetInvoice)))
 
 	// Payment routes (authenticated users)
-	mux.Handle("POST /api/payments/initiate", authMiddleware.Authenticate(http.HandlerFunc(paymentHandler.InitiatePayment)))
-	mux.Handle("POST /api/payments/reference", authMiddleware.Authenticate(http.HandlerFunc(paymentHandler.SubmitReference)))
+	mux.Handle("POST /api/payments/initiate", authMiddleware.Authenticate(idempotencyMiddleware.Handle(http.HandlerFunc(paymentHandler.InitiatePayment))))
+	mux.Handle("POST /api/payments/reference", authMiddleware.Authenticate(idempotencyMiddleware.Handle(http.HandlerFunc(paymentHandler.SubmitReference))))
 	mux.Handle("GET /api/payments/status/{orderId}", authMiddleware.Authenticate(http.HandlerFunc(paymentHandler.GetPaymentStatus)))
 
+	// Payment quarantine (admin only)
+	mux.Handle("GET /api/payments/quarantine", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(paymentHandler.ListQuarantine))))
+	mux.Handle("POST /api/payments/quarantine/{id}/resolve", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(paymentHandler.ResolveQuarantine))))
+
+	// Webhook event log (admin only)
+	mux.Handle("GET /api/payments/webhook-events", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(paymentHandler.ListWebhookEvents))))
+	mux.Handle("GET /api/payments/webhook-events/{id}", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(paymentHandler.GetWebhookEvent))))
+	mux.Handle("POST /api/payments/webhook-events/{id}/reprocess", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(paymentHandler.ReprocessWebhookEvent))))
+
+	// Payment reconciliation (admin only)
+	mux.Handle("POST /api/payments/reconciliation/run", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(reconciliationHandler.Run))))
+	mux.Handle("GET /api/payments/reconciliation/reports", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(reconciliationHandler.ListReports))))
+	mux.Handle("GET /api/payments/reconciliation/reports/{date}", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(reconciliationHandler.GetReport))))
+	mux.Handle("POST /api/payments/reconciliation/reports/{date}", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(reconciliationHandler.GenerateReport))))
+
 	// Payment webhook (public - called by UroPay servers)
 	mux.HandleFunc("POST /api/payments/webhook", paymentHandler.Webhook)
+	mux.HandleFunc("POST /api/payments/webhook/{gateway}", paymentHandler.Webhook)
+
+	// Cart routes (users, or guests identified by X-Cart-Token)
+	mux.Handle("GET /api/cart", authMiddleware.OptionalAuthenticate(http.HandlerFunc(cartHandler.GetCart)))
+	mux.Handle("DELETE /api/cart", authMiddleware.OptionalAuthenticate(http.HandlerFunc(cartHandler.ClearCart)))
+	mux.Handle("POST /api/cart/items", authMiddleware.OptionalAuthenticate(http.HandlerFunc(cartHandler.AddItem)))
+	mux.Handle("PUT /api/cart/items/{id}", authMiddleware.OptionalAuthenticate(http.HandlerFunc(cartHandler.UpdateItem)))
+	mux.Handle("DELETE /api/cart/items/{id}", authMiddleware.OptionalAuthenticate(http.HandlerFunc(cartHandler.RemoveItem)))
+
+	// Cart checkout (authenticated users)
+	mux.Handle("POST /api/cart/checkout", authMiddleware.Authenticate(http.HandlerFunc(cartHandler.Checkout)))
+
+	// Repeat a past order into the cart, or as a new order
+	mux.Handle("POST /api/orders/{id}/reorder", authMiddleware.Authenticate(idempotencyMiddleware.Handle(http.HandlerFunc(cartHandler.Reorder))))
+
+	// Price an order, delivery charges included, before placing it (public)
+	mux.HandleFunc("POST /api/checkout/quote", orderHandler.Quote)
+
+	// Wallet: customers see theirs, admins top up
+	mux.Handle("GET /api/wallet", authMiddleware.Authenticate(http.HandlerFunc(walletHandler.GetMyWallet)))
+	mux.Handle("GET /api/wallets/{userId}", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(walletHandler.GetWallet))))
+	mux.Handle("POST /api/wallets/{userId}/credit", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(walletHandler.Credit))))
+
+	// Subscriptions (authenticated users)
+	mux.Handle("POST /api/subscriptions", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Create)))
+	mux.Handle("GET /api/subscriptions/my", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.GetMine)))
+	mux.Handle("GET /api/subscriptions/vacation", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.GetVacation)))
+	mux.Handle("PUT /api/subscriptions/vacation", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.SetVacation)))
+	mux.Handle("DELETE /api/subscriptions/vacation", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.EndVacation)))
+	mux.Handle("GET /api/subscriptions/{id}", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Get)))
+	mux.Handle("PATCH /api/subscriptions/{id}", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Update)))
+	mux.Handle("POST /api/subscriptions/{id}/pause", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Pause)))
+	mux.Handle("POST /api/subscriptions/{id}/resume", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Resume)))
+	mux.Handle("POST /api/subscriptions/{id}/cancel", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Cancel)))
+	mux.Handle("POST /api/subscriptions/{id}/skip", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Skip)))
+	mux.Handle("DELETE /api/subscriptions/{id}/skip/{date}", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.Unskip)))
+	mux.Handle("GET /api/subscriptions/{id}/deliveries", authMiddleware.Authenticate(http.HandlerFunc(subscriptionHandler.GetDeliveries)))
+
+	// Subscriptions (admin only)
+	mux.Handle("GET /api/subscriptions", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(subscriptionHandler.List))))
+	mux.Handle("POST /api/subscriptions/generate", authMiddleware.Authenticate(adminMiddleware.RequireAdmin(http.HandlerFunc(subscriptionHandler.Generate))))
 
 	// User Address routes (authenticated user)
 	mux.Handle("GET /api/user/addresses", authMiddleware.Authenticate(http.HandlerFunc(userAddressHandler.List)))
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"regexp"
 	"strings"
@@ -12,26 +13,27 @@ import (
 )
 
 type UserAddressService struct {
-	repo *repository.UserAddressRepository
+	repo repository.UserAddressStore
 }
 
-func NewUserAddressService(repo *repository.UserAddressRepository) *UserAddressService {
+func NewUserAddressService(repo repository.UserAddressStore) *UserAddressService {
 	return &UserAddressService{repo: repo}
 }
 
 var (
 	reINPincode = regexp.MustCompile(`^[1-9][0-9]{5}$`)
 	reINPhone   = regexp.MustCompile(`^(\+91[- ]?)?[6-9][0-9]{9}$`)
+	reGSTIN     = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
 )
 
-func (s *UserAddressService) List(userID string) ([]models.UserAddress, error) {
+func (s *UserAddressService) List(ctx context.Context, userID string) ([]models.UserAddress, error) {
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
-	return s.repo.ListByUserID(userID)
+	return s.repo.ListByUserID(ctx, userID)
 }
 
-func (s *UserAddressService) Create(userID string, req *models.CreateUserAddressRequest) (*models.UserAddress, error) {
+func (s *UserAddressService) Create(ctx context.Context, userID string, req *models.CreateUserAddressRequest) (*models.UserAddress, error) {
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
@@ -53,6 +55,10 @@ func (s *UserAddressService) Create(userID string, req *models.CreateUserAddress
 	if !reINPhone.MatchString(strings.TrimSpace(req.Phone)) {
 		return nil, errors.New("invalid phone (India): must be valid Indian mobile")
 	}
+	gstin := strings.ToUpper(strings.TrimSpace(req.GSTIN))
+	if gstin != "" && !reGSTIN.MatchString(gstin) {
+		return nil, errors.New("invalid GSTIN")
+	}
 
 	addr := &models.UserAddress{
 		ID:           uuid.New().String(),
@@ -69,25 +75,26 @@ func (s *UserAddressService) Create(userID string, req *models.CreateUserAddress
 		State:        req.State,
 		Pincode:      req.Pincode,
 		CountryCode:  "IN",
+		GSTIN:        gstin,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
 	// If setting default, unset any old default (unique index also protects)
 	if addr.IsDefault {
-		if err := s.unsetDefaultForUser(userID); err != nil {
+		if err := s.unsetDefaultForUser(ctx, userID); err != nil {
 			return nil, err
 		}
 	}
 
-	if err := s.repo.Create(addr); err != nil {
+	if err := s.repo.Create(ctx, addr); err != nil {
 		return nil, err
 	}
 
 	return addr, nil
 }
 
-func (s *UserAddressService) Update(userID, addressID string, req *models.UpdateUserAddressRequest) (*models.UserAddress, error) {
+func (s *UserAddressService) Update(ctx context.Context, userID, addressID string, req *models.UpdateUserAddressRequest) (*models.UserAddress, error) {
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
@@ -95,7 +102,7 @@ func (s *UserAddressService) Update(userID, addressID string, req *models.Update
 		return nil, errors.New("address ID is required")
 	}
 
-	existing, err := s.repo.GetByID(addressID)
+	existing, err := s.repo.GetByID(ctx, addressID)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +117,7 @@ func (s *UserAddressService) Update(userID, addressID string, req *models.Update
 	}
 	if req.IsDefault != nil {
 		if *req.IsDefault {
-			if err := s.unsetDefaultForUser(userID); err != nil {
+			if err := s.unsetDefaultForUser(ctx, userID); err != nil {
 				return nil, err
 			}
 		}
@@ -161,6 +168,13 @@ func (s *UserAddressService) Update(userID, addressID string, req *models.Update
 		}
 		updates["pincode"] = *req.Pincode
 	}
+	if req.GSTIN != nil {
+		gstin := strings.ToUpper(strings.TrimSpace(*req.GSTIN))
+		if gstin != "" && !reGSTIN.MatchString(gstin) {
+			return nil, errors.New("invalid GSTIN")
+		}
+		updates["gstin"] = gstin
+	}
 
 	updates["country_code"] = "IN"
 	updates["updated_at"] = time.Now()
@@ -169,10 +183,10 @@ func (s *UserAddressService) Update(userID, addressID string, req *models.Update
 		return nil, errors.New("no fields to update")
 	}
 
-	return s.repo.Update(addressID, updates)
+	return s.repo.Update(ctx, addressID, updates)
 }
 
-func (s *UserAddressService) Delete(userID, addressID string) error {
+func (s *UserAddressService) Delete(ctx context.Context, userID, addressID string) error {
 	if userID == "" {
 		return errors.New("user ID is required")
 	}
@@ -180,7 +194,7 @@ func (s *UserAddressService) Delete(userID, addressID string) error {
 		return errors.New("address ID is required")
 	}
 
-	existing, err := s.repo.GetByID(addressID)
+	existing, err := s.repo.GetByID(ctx, addressID)
 	if err != nil {
 		return err
 	}
@@ -188,19 +202,19 @@ func (s *UserAddressService) Delete(userID, addressID string) error {
 		return errors.New("access denied")
 	}
 
-	return s.repo.Delete(addressID)
+	return s.repo.Delete(ctx, addressID)
 }
 
 // unsetDefaultForUser sets all addresses is_default=false for the user.
 // (Uses list+update, keeps repository simple.)
-func (s *UserAddressService) unsetDefaultForUser(userID string) error {
-	addrs, err := s.repo.ListByUserID(userID)
+func (s *UserAddressService) unsetDefaultForUser(ctx context.Context, userID string) error {
+	addrs, err := s.repo.ListByUserID(ctx, userID)
 	if err != nil {
 		return err
 	}
 	for _, a := range addrs {
 		if a.IsDefault {
-			_, err := s.repo.Update(a.ID, map[string]interface{}{
+			_, err := s.repo.Update(ctx, a.ID, map[string]interface{}{
 				"is_default": false,
 			})
 			if err != nil {
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -17,12 +18,12 @@ import (
 )
 
 type AuthService struct {
-	userRepo     *repository.UserRepository
+	userRepo     repository.UserStore
 	emailService *EmailService
 	jwtSecret    []byte
 }
 
-func NewAuthService(userRepo *repository.UserRepository, emailService *EmailService) *AuthService {
+func NewAuthService(userRepo repository.UserStore, emailService *EmailService) *AuthService {
 	return &AuthService{
 		userRepo:     userRepo,
 		emailService: emailService,
@@ -36,9 +37,9 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
 	// Check if user already exists
-	existingUser, _ := s.userRepo.GetUserByEmail(req.Email)
+	existingUser, _ := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if existingUser != nil {
 		return nil, errors.New("user with this email already exists")
 	}
@@ -59,7 +60,7 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		CreatedAt: time.Now(),
 	}
 
-	if err := s.userRepo.CreateUser(user); err != nil {
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
 		return nil, err
 	}
 
@@ -75,9 +76,9 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthRespons
 	}, nil
 }
 
-func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
 	// Get user by email
-	user, err := s.userRepo.GetUserByEmail(req.Email)
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, errors.New("invalid email or password")
 	}
@@ -99,8 +100,8 @@ func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 	}, nil
 }
 
-func (s *AuthService) ForgotPassword(email string) error {
-	user, err := s.userRepo.GetUserByEmail(email)
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		// Don't reveal if user exists or not for security
 		log.Printf("Forgot password request for non-existent email: %s", email)
@@ -120,7 +121,7 @@ func (s *AuthService) ForgotPassword(email string) error {
 		"reset_otp":        otp,
 		"reset_otp_expiry": expiry.Format(time.RFC3339),
 	}
-	if err := s.userRepo.UpdateUser(user.ID, fields); err != nil {
+	if err := s.userRepo.UpdateUser(ctx, user.ID, fields); err != nil {
 		return fmt.Errorf("failed to save OTP: %w", err)
 	}
 
@@ -133,8 +134,8 @@ func (s *AuthService) ForgotPassword(email string) error {
 	return nil
 }
 
-func (s *AuthService) ResetPassword(email, otp, newPassword string) error {
-	user, err := s.userRepo.GetUserByEmail(email)
+func (s *AuthService) ResetPassword(ctx context.Context, email, otp, newPassword string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		return errors.New("invalid email")
 	}
@@ -161,7 +162,7 @@ func (s *AuthService) ResetPassword(email, otp, newPassword string) error {
 		"reset_otp":        nil,
 		"reset_otp_expiry": nil,
 	}
-	if err := s.userRepo.UpdateUser(user.ID, fields); err != nil {
+	if err := s.userRepo.UpdateUser(ctx, user.ID, fields); err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
@@ -1 +1,517 @@
 package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+// ErrCartChanged is returned by Checkout when prices or availability moved
+// since the customer last saw the cart; the cart is refreshed so a second
+// checkout goes through at the new prices.
+var ErrCartChanged = errors.New("cart has changed, please review before checkout")
+
+type CartService struct {
+	cfg          *config.Config
+	cartRepo     repository.CartStore
+	productRepo  repository.ProductStore
+	orderService *OrderService
+}
+
+func NewCartService(cfg *config.Config, cartRepo repository.CartStore, productRepo repository.ProductStore, orderService *OrderService) *CartService {
+	return &CartService{
+		cfg:          cfg,
+		cartRepo:     cartRepo,
+		productRepo:  productRepo,
+		orderService: orderService,
+	}
+}
+
+// GetCart returns the owner's cart. Anonymous callers without a token get a
+// fresh guest cart whose token is returned in the response.
+func (s *CartService) GetCart(ctx context.Context, owner models.CartOwner) (*models.CartResponse, error) {
+	cart, err := s.getOrCreateCart(ctx, owner, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildResponse(ctx, cart), nil
+}
+
+func (s *CartService) AddItem(ctx context.Context, owner models.CartOwner, req *models.AddCartItemRequest) (*models.CartResponse, error) {
+	if req.ProductID == "" {
+		return nil, errors.New("product_id is required")
+	}
+	if req.Quantity <= 0 {
+		return nil, errors.New("item quantity must be positive")
+	}
+
+	product, err := s.productRepo.GetProductByID(ctx, req.ProductID)
+	if err != nil {
+		return nil, errors.New("product not found: " + req.ProductID)
+	}
+	if !product.Active {
+		return nil, errors.New("product is not available: " + product.Name)
+	}
+
+	unitPriceCents, err := priceFor(product, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.getOrCreateCart(ctx, owner, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same product and variant already in cart: bump quantity instead
+	if existing := findCartItem(cart.Items, req.ProductID, req.VariantID); existing != nil {
+		if _, err := s.cartRepo.UpdateCartItem(ctx, existing.ID, map[string]interface{}{
+			"quantity":         existing.Quantity + req.Quantity,
+			"unit_price_cents": unitPriceCents,
+			"updated_at":       time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+	} else {
+		item := &models.CartItem{
+			ID:             uuid.New().String(),
+			CartID:         cart.ID,
+			ProductID:      req.ProductID,
+			VariantID:      req.VariantID,
+			Quantity:       req.Quantity,
+			UnitPriceCents: unitPriceCents,
+			AddedAt:        time.Now(),
+			UpdatedAt:      time.Now(),
+		}
+		if err := s.cartRepo.AddCartItem(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.reload(ctx, cart)
+}
+
+// UpdateItem changes quantity and/or variant of a cart line. A quantity of
+// zero removes the line.
+func (s *CartService) UpdateItem(ctx context.Context, owner models.CartOwner, itemID string, req *models.UpdateCartItemRequest) (*models.CartResponse, error) {
+	if itemID == "" {
+		return nil, errors.New("cart item ID is required")
+	}
+	if req.Quantity < 0 {
+		return nil, errors.New("item quantity cannot be negative")
+	}
+
+	cart, err := s.getOrCreateCart(ctx, owner, false)
+	if err != nil {
+		return nil, err
+	}
+
+	item := findCartItemByID(cart.Items, itemID)
+	if item == nil {
+		return nil, errors.New("cart item not found")
+	}
+
+	if req.Quantity == 0 {
+		if err := s.cartRepo.DeleteCartItem(ctx, itemID); err != nil {
+			return nil, err
+		}
+		return s.reload(ctx, cart)
+	}
+
+	updates := map[string]interface{}{
+		"quantity":   req.Quantity,
+		"updated_at": time.Now(),
+	}
+
+	if req.VariantID != nil && *req.VariantID != item.VariantID {
+		product, err := s.productRepo.GetProductByID(ctx, item.ProductID)
+		if err != nil {
+			return nil, errors.New("product not found: " + item.ProductID)
+		}
+		unitPriceCents, err := priceFor(product, *req.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		updates["variant_id"] = nullIfEmpty(*req.VariantID)
+		updates["unit_price_cents"] = unitPriceCents
+	}
+
+	if _, err := s.cartRepo.UpdateCartItem(ctx, itemID, updates); err != nil {
+		return nil, err
+	}
+
+	return s.reload(ctx, cart)
+}
+
+func (s *CartService) RemoveItem(ctx context.Context, owner models.CartOwner, itemID string) (*models.CartResponse, error) {
+	return s.UpdateItem(ctx, owner, itemID, &models.UpdateCartItemRequest{Quantity: 0})
+}
+
+func (s *CartService) ClearCart(ctx context.Context, owner models.CartOwner) error {
+	cart, err := s.getOrCreateCart(ctx, owner, false)
+	if err != nil {
+		return err
+	}
+	return s.cartRepo.ClearCart(ctx, cart.ID)
+}
+
+// Checkout revalidates the cart and turns it into an order via OrderService,
+// emptying the cart on success.
+func (s *CartService) Checkout(ctx context.Context, userID string, req *models.CheckoutCartRequest) (*models.Order, error) {
+	cart, err := s.getOrCreateCart(ctx, models.CartOwner{UserID: userID}, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	view := s.buildResponse(ctx, cart)
+
+	changed := false
+	orderItems := make([]models.CreateOrderItem, 0, len(view.Items))
+	for _, line := range view.Items {
+		if !line.Available || line.AvailableStock < line.Quantity {
+			return nil, errors.New("cart has unavailable items: " + line.ProductName)
+		}
+		if line.PriceChanged {
+			changed = true
+			s.refreshPrice(ctx, line.ID, line.UnitPriceCents)
+		}
+		orderItems = append(orderItems, models.CreateOrderItem{
+			ProductID: line.ProductID,
+			VariantID: line.VariantID,
+			Quantity:  line.Quantity,
+		})
+	}
+
+	if changed {
+		return nil, ErrCartChanged
+	}
+
+	order, err := s.orderService.CreateOrder(ctx, userID, &models.CreateOrderRequest{
+		ShippingAddress: req.ShippingAddress,
+		PaymentMetadata: req.PaymentMetadata,
+		PaymentMethod:   req.PaymentMethod,
+		DeliverySlot:    req.DeliverySlot,
+		Items:           orderItems,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.ClearCart(ctx, cart.ID); err != nil {
+		log.Printf("Failed to clear cart %s after checkout: %v", cart.ID, err)
+	}
+
+	return order, nil
+}
+
+// MergeGuestCart folds a guest cart into the user's cart after login or
+// registration, then deletes the guest cart. Lines present in both carts are
+// combined according to the configured merge strategy.
+func (s *CartService) MergeGuestCart(ctx context.Context, userID, guestToken string) error {
+	if userID == "" || guestToken == "" {
+		return nil
+	}
+
+	guest, err := s.cartRepo.GetCartByGuestToken(ctx, guestToken)
+	if err != nil {
+		if errors.Is(err, repository.ErrCartNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	userCart, err := s.getOrCreateCart(ctx, models.CartOwner{UserID: userID}, true)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range guest.Items {
+		existing := findCartItem(userCart.Items, item.ProductID, item.VariantID)
+		if existing == nil {
+			quantity := s.capQuantity(ctx, item.ProductID, item.VariantID, item.Quantity)
+			if _, err := s.cartRepo.UpdateCartItem(ctx, item.ID, map[string]interface{}{
+				"cart_id":    userCart.ID,
+				"quantity":   quantity,
+				"updated_at": time.Now(),
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		quantity := existing.Quantity + item.Quantity
+		if s.cfg.CartMergeStrategy == models.CartMergeLatest {
+			quantity = existing.Quantity
+			if item.UpdatedAt.After(existing.UpdatedAt) {
+				quantity = item.Quantity
+			}
+		}
+		quantity = s.capQuantity(ctx, item.ProductID, item.VariantID, quantity)
+
+		if _, err := s.cartRepo.UpdateCartItem(ctx, existing.ID, map[string]interface{}{
+			"quantity":   quantity,
+			"updated_at": time.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.cartRepo.DeleteCart(ctx, guest.ID); err != nil {
+		return err
+	}
+	return s.cartRepo.TouchCart(ctx, userCart.ID)
+}
+
+// capQuantity limits a merged quantity to what is in stock when the
+// cap-at-stock rule is enabled. Out-of-stock lines are kept as-is so the
+// cart can still warn about them.
+func (s *CartService) capQuantity(ctx context.Context, productID, variantID string, quantity int) int {
+	if !s.cfg.CartMergeCapAtStock {
+		return quantity
+	}
+	product, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		return quantity
+	}
+	stock := stockFor(product, variantID)
+	if stock <= 0 {
+		return quantity
+	}
+	return min(quantity, stock)
+}
+
+// getOrCreateCart resolves the owner's cart. With create set, a missing cart
+// is created; for anonymous owners that means a new guest cart and token.
+func (s *CartService) getOrCreateCart(ctx context.Context, owner models.CartOwner, create bool) (*models.Cart, error) {
+	var (
+		cart *models.Cart
+		err  error
+	)
+	switch {
+	case owner.UserID != "":
+		cart, err = s.cartRepo.GetCartByUserID(ctx, owner.UserID)
+	case owner.GuestToken != "":
+		cart, err = s.cartRepo.GetCartByGuestToken(ctx, owner.GuestToken)
+	default:
+		err = repository.ErrCartNotFound
+	}
+	if err == nil {
+		return cart, nil
+	}
+	if !errors.Is(err, repository.ErrCartNotFound) {
+		return nil, err
+	}
+	if !create {
+		return nil, err
+	}
+
+	cart = &models.Cart{
+		ID:        uuid.New().String(),
+		UserID:    owner.UserID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if owner.UserID == "" {
+		token, err := generateGuestToken()
+		if err != nil {
+			return nil, err
+		}
+		cart.GuestToken = token
+	}
+	if err := s.cartRepo.CreateCart(ctx, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (s *CartService) reload(ctx context.Context, cart *models.Cart) (*models.CartResponse, error) {
+	if err := s.cartRepo.TouchCart(ctx, cart.ID); err != nil {
+		log.Printf("Failed to touch cart %s: %v", cart.ID, err)
+	}
+
+	fresh, err := s.cartRepo.GetCartByID(ctx, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildResponse(ctx, fresh), nil
+}
+
+func (s *CartService) refreshPrice(ctx context.Context, itemID string, priceCents int64) {
+	if _, err := s.cartRepo.UpdateCartItem(ctx, itemID, map[string]interface{}{
+		"unit_price_cents": priceCents,
+		"updated_at":       time.Now(),
+	}); err != nil {
+		log.Printf("Failed to refresh price for cart item %s: %v", itemID, err)
+	}
+}
+
+// buildResponse prices every line at the current catalogue price and
+// attaches availability warnings. Totals use the same rules as CreateOrder.
+func (s *CartService) buildResponse(ctx context.Context, cart *models.Cart) *models.CartResponse {
+	resp := &models.CartResponse{
+		ID:         cart.ID,
+		GuestToken: cart.GuestToken,
+		Items:      make([]models.CartLine, 0, len(cart.Items)),
+	}
+
+	// Checkout knows the shipping state; the preview taxes as a local sale,
+	// which only changes how the GST splits, not how much it is
+	var taxed []models.OrderItem
+	for _, item := range cart.Items {
+		line := models.CartLine{
+			ID:              item.ID,
+			ProductID:       item.ProductID,
+			VariantID:       item.VariantID,
+			Quantity:        item.Quantity,
+			UnitPriceCents:  item.UnitPriceCents,
+			AddedPriceCents: item.UnitPriceCents,
+		}
+
+		product, err := s.productRepo.GetProductByID(ctx, item.ProductID)
+		if err != nil {
+			line.Warning = "product no longer exists"
+			resp.Items = append(resp.Items, line)
+			resp.Warnings = append(resp.Warnings, line.Warning)
+			continue
+		}
+
+		line.ProductName = product.Name
+		if len(product.Images) > 0 {
+			line.ProductImage = product.Images[0].URL
+		}
+		line.AvailableStock = stockFor(product, item.VariantID)
+		line.Available = product.Active
+
+		currentPrice, err := priceFor(product, item.VariantID)
+		if err != nil {
+			line.Available = false
+			line.Warning = "selected variant is no longer available"
+		} else {
+			line.UnitPriceCents = currentPrice
+			line.PriceChanged = currentPrice != item.UnitPriceCents
+		}
+		if v := findVariant(product, item.VariantID); v != nil {
+			line.VariantName = v.Name
+		}
+		rate, inclusive, taxErr := gstFor(s.cfg, product)
+		if taxErr != nil && line.Warning == "" {
+			line.Available = false
+			line.Warning = "can't be sold until its GST rate is set"
+		}
+
+		switch {
+		case line.Warning != "":
+		case !product.Active:
+			line.Warning = "product is no longer available"
+		case line.AvailableStock <= 0:
+			line.Warning = "out of stock"
+		case line.AvailableStock < item.Quantity:
+			line.Warning = fmt.Sprintf("only %d left in stock", line.AvailableStock)
+		case line.PriceChanged:
+			line.Warning = fmt.Sprintf("price changed from %d to %d", item.UnitPriceCents, currentPrice)
+		}
+		if line.Warning != "" {
+			resp.Warnings = append(resp.Warnings, product.Name+": "+line.Warning)
+		}
+
+		if line.Available {
+			line.LineTotalCents = line.UnitPriceCents * int64(line.Quantity)
+			resp.ItemCount += line.Quantity
+
+			taxedLine := models.OrderItem{UnitPriceCents: line.UnitPriceCents, Quantity: line.Quantity, WeightGrams: weightGrams(weightOf(product, item.VariantID))}
+			taxedLine.GSTRate, taxedLine.PriceIncludesTax = rate, inclusive
+			applyGST(&taxedLine, false)
+			taxed = append(taxed, taxedLine)
+		}
+		resp.Items = append(resp.Items, line)
+	}
+
+	if len(taxed) > 0 {
+		quote, err := s.orderService.priceOrder(taxed, ShippingDestination{}, models.DeliverySlotStandard, time.Now())
+		if err != nil {
+			log.Printf("Failed to price delivery for cart %s: %v", cart.ID, err)
+			resp.SubtotalCents, resp.TaxCents = taxTotals(taxed)
+		} else {
+			resp.SubtotalCents, resp.ShippingCents, resp.TaxCents = quote.SubtotalCents, quote.ShippingCents, quote.TaxCents
+			resp.ShippingFees = quote.ShippingFees
+		}
+	}
+	resp.TotalCents = resp.SubtotalCents + resp.ShippingCents + resp.TaxCents
+
+	return resp
+}
+
+// weightOf returns the weight label of a product or one of its variants.
+func weightOf(product *models.Product, variantID string) string {
+	if v := findVariant(product, variantID); v != nil && v.Weight != "" {
+		return v.Weight
+	}
+	return product.Weight
+}
+
+// stockFor returns how many of a product, or of one of its variants, can be
+// sold. Variants without stock of their own sell from the product's.
+func stockFor(product *models.Product, variantID string) int {
+	if v := findVariant(product, variantID); v != nil && v.Stock != nil {
+		return *v.Stock
+	}
+	return product.Stock
+}
+
+// priceFor returns the current unit price of a product or one of its variants.
+func priceFor(product *models.Product, variantID string) (int64, error) {
+	if variantID == "" {
+		return product.PriceCents, nil
+	}
+	variant := findVariant(product, variantID)
+	if variant == nil {
+		return 0, errors.New("variant not found for product: " + product.Name)
+	}
+	return variant.PriceCents, nil
+}
+
+func findCartItem(items []models.CartItem, productID, variantID string) *models.CartItem {
+	for i := range items {
+		if items[i].ProductID == productID && items[i].VariantID == variantID {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+func findCartItemByID(items []models.CartItem, id string) *models.CartItem {
+	for i := range items {
+		if items[i].ID == id {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+// generateGuestToken returns an unguessable token identifying a guest cart.
+func generateGuestToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate cart token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"net/url"
 	"regexp"
@@ -12,49 +13,49 @@ import (
 )
 
 type CategoryService struct {
-	categoryRepo *repository.CategoryRepository
+	categoryRepo repository.CategoryStore
 }
 
-func NewCategoryService(categoryRepo *repository.CategoryRepository) *CategoryService {
+func NewCategoryService(categoryRepo repository.CategoryStore) *CategoryService {
 	return &CategoryService{
 		categoryRepo: categoryRepo,
 	}
 }
 
-func (s *CategoryService) GetAllCategories() ([]models.Category, error) {
-	return s.categoryRepo.GetAllCategories()
+func (s *CategoryService) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	return s.categoryRepo.GetAllCategories(ctx)
 }
 
-func (s *CategoryService) GetCategoryByID(id string) (*models.Category, error) {
+func (s *CategoryService) GetCategoryByID(ctx context.Context, id string) (*models.Category, error) {
 	if id == "" {
 		return nil, errors.New("category ID is required")
 	}
-	return s.categoryRepo.GetCategoryByID(id)
+	return s.categoryRepo.GetCategoryByID(ctx, id)
 }
 
-func (s *CategoryService) GetCategoryBySlug(slug string) (*models.Category, error) {
+func (s *CategoryService) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
 	if slug == "" {
 		return nil, errors.New("category slug is required")
 	}
-	return s.categoryRepo.GetCategoryBySlug(slug)
+	return s.categoryRepo.GetCategoryBySlug(ctx, slug)
 }
 
-func (s *CategoryService) GetSubcategories(parentID string) ([]models.Category, error) {
+func (s *CategoryService) GetSubcategories(ctx context.Context, parentID string) ([]models.Category, error) {
 	if parentID == "" {
 		return nil, errors.New("parent ID is required")
 	}
-	return s.categoryRepo.GetSubcategories(parentID)
+	return s.categoryRepo.GetSubcategories(ctx, parentID)
 }
 
-func (s *CategoryService) GetRootCategories(minPosition, maxPosition *int) ([]models.Category, error) {
+func (s *CategoryService) GetRootCategories(ctx context.Context, minPosition, maxPosition *int) ([]models.Category, error) {
 	// Basic validation if both provided
 	if minPosition != nil && maxPosition != nil && *minPosition > *maxPosition {
 		return nil, errors.New("min_position cannot be greater than max_position")
 	}
-	return s.categoryRepo.GetRootCategories(minPosition, maxPosition)
+	return s.categoryRepo.GetRootCategories(ctx, minPosition, maxPosition)
 }
 
-func (s *CategoryService) CreateCategory(req *models.AddCategory) (*models.Category, error) {
+func (s *CategoryService) CreateCategory(ctx context.Context, req *models.AddCategory) (*models.Category, error) {
 	if req.Name == "" {
 		return nil, errors.New("category name is required")
 	}
@@ -68,14 +69,14 @@ func (s *CategoryService) CreateCategory(req *models.AddCategory) (*models.Categ
 	}
 
 	// Check if slug already exists
-	existing, _ := s.categoryRepo.GetCategoryBySlug(req.Slug)
+	existing, _ := s.categoryRepo.GetCategoryBySlug(ctx, req.Slug)
 	if existing != nil {
 		return nil, errors.New("category with this slug already exists")
 	}
 
 	// Validate parent_id if provided
 	if req.ParentID != "" {
-		_, err := s.categoryRepo.GetCategoryByID(req.ParentID)
+		_, err := s.categoryRepo.GetCategoryByID(ctx, req.ParentID)
 		if err != nil {
 			return nil, errors.New("parent category not found")
 		}
@@ -88,23 +89,28 @@ func (s *CategoryService) CreateCategory(req *models.AddCategory) (*models.Categ
 		}
 	}
 
+	if req.ReturnWindowDays != nil && *req.ReturnWindowDays < 0 {
+		return nil, errors.New("return window cannot be negative")
+	}
+
 	category := &models.Category{
-		ID:       uuid.New().String(),
-		Name:     req.Name,
-		Slug:     req.Slug,
-		ParentID: req.ParentID,
-		Position: req.Position,
-		ImageURL: req.ImageURL, // NEW
+		ID:               uuid.New().String(),
+		Name:             req.Name,
+		Slug:             req.Slug,
+		ParentID:         req.ParentID,
+		Position:         req.Position,
+		ImageURL:         req.ImageURL, // NEW
+		ReturnWindowDays: req.ReturnWindowDays,
 	}
 
-	if err := s.categoryRepo.CreateCategory(category); err != nil {
+	if err := s.categoryRepo.CreateCategory(ctx, category); err != nil {
 		return nil, err
 	}
 
 	return category, nil
 }
 
-func (s *CategoryService) UpdateCategory(id string, req *models.UpdateCategory) (*models.Category, error) {
+func (s *CategoryService) UpdateCategory(ctx context.Context, id string, req *models.UpdateCategory) (*models.Category, error) {
 	if id == "" {
 		return nil, errors.New("category ID is required")
 	}
@@ -127,7 +133,7 @@ func (s *CategoryService) UpdateCategory(id string, req *models.UpdateCategory)
 			return nil, errors.New("invalid slug format: use lowercase letters, numbers, and hyphens only")
 		}
 		// Check if slug already exists for a different category
-		existing, _ := s.categoryRepo.GetCategoryBySlug(*req.Slug)
+		existing, _ := s.categoryRepo.GetCategoryBySlug(ctx, *req.Slug)
 		if existing != nil && existing.ID != id {
 			return nil, errors.New("category with this slug already exists")
 		}
@@ -141,7 +147,7 @@ func (s *CategoryService) UpdateCategory(id string, req *models.UpdateCategory)
 				return nil, errors.New("category cannot be its own parent")
 			}
 			// Validate parent exists
-			_, err := s.categoryRepo.GetCategoryByID(*req.ParentID)
+			_, err := s.categoryRepo.GetCategoryByID(ctx, *req.ParentID)
 			if err != nil {
 				return nil, errors.New("parent category not found")
 			}
@@ -161,25 +167,32 @@ func (s *CategoryService) UpdateCategory(id string, req *models.UpdateCategory)
 		updates["image_url"] = *req.ImageURL
 	}
 
+	if req.ReturnWindowDays != nil {
+		if *req.ReturnWindowDays < 0 {
+			return nil, errors.New("return window cannot be negative")
+		}
+		updates["return_window_days"] = *req.ReturnWindowDays
+	}
+
 	if len(updates) == 0 {
 		return nil, errors.New("no fields to update")
 	}
 
-	return s.categoryRepo.UpdateCategory(id, updates)
+	return s.categoryRepo.UpdateCategory(ctx, id, updates)
 }
 
-func (s *CategoryService) DeleteCategory(id string) error {
+func (s *CategoryService) DeleteCategory(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("category ID is required")
 	}
 
 	// Check if category has subcategories
-	subcategories, err := s.categoryRepo.GetSubcategories(id)
+	subcategories, err := s.categoryRepo.GetSubcategories(ctx, id)
 	if err == nil && len(subcategories) > 0 {
 		return errors.New("cannot delete category with subcategories")
 	}
 
-	return s.categoryRepo.DeleteCategory(id)
+	return s.categoryRepo.DeleteCategory(ctx, id)
 }
 
 // isValidSlug checks if the slug is valid (lowercase, alphanumeric, hyphens)
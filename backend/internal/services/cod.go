@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// CODEligibility reports whether userID may pay cash on delivery for an order
+// of totalCents shipped to pincode. The returned reason is shown to customers.
+func (s *OrderService) CODEligibility(ctx context.Context, userID string, totalCents int64, pincode string) (*models.CODEligibilityResponse, error) {
+	if !s.cfg.CODEnabled {
+		return &models.CODEligibilityResponse{Reason: "cash on delivery is not available"}, nil
+	}
+	if s.cfg.CODMaxOrderCents > 0 && totalCents > s.cfg.CODMaxOrderCents {
+		return &models.CODEligibilityResponse{
+			Reason: fmt.Sprintf("cash on delivery is only available for orders up to ₹%d", s.cfg.CODMaxOrderCents/100),
+		}, nil
+	}
+	if len(s.cfg.CODPincodes) > 0 && !slices.Contains(s.cfg.CODPincodes, pincode) {
+		return &models.CODEligibilityResponse{Reason: "cash on delivery is not available for this pincode"}, nil
+	}
+
+	if s.cfg.CODMaxRefusals > 0 {
+		refusals, err := s.orderRepo.CountOrdersByPaymentStatus(ctx, userID, models.PaymentStatusCODRefused)
+		if err != nil {
+			return nil, err
+		}
+		if refusals >= s.cfg.CODMaxRefusals {
+			return &models.CODEligibilityResponse{Reason: "cash on delivery is not available for this account"}, nil
+		}
+	}
+
+	return &models.CODEligibilityResponse{Eligible: true}, nil
+}
+
+// MarkCODRefused records that the customer refused a cash on delivery order
+// at the door. The order is cancelled and its stock returned; refusals count
+// against the user's future COD eligibility.
+func (s *OrderService) MarkCODRefused(ctx context.Context, id, adminID string) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stringFromMap(order.PaymentMetadata, "payment_method") != models.PaymentMethodCOD {
+		return nil, errors.New("order is not cash on delivery")
+	}
+	if order.Status != models.OrderStatusShipped {
+		return nil, errors.New("only shipped orders can be refused")
+	}
+
+	// The transition's record_cod_refusal effect marks the payment refused
+	return s.workflow.transition(ctx, order, models.OrderStatusCancelled, adminID, models.OrderRoleAdmin, "cash on delivery refused")
+}
+
+// pincodeFromAddress reads the pincode from a free-form shipping address.
+func pincodeFromAddress(address map[string]interface{}) string {
+	switch v := address["pincode"].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+func TestCODEligibility(t *testing.T) {
+	tests := []struct {
+		name       string
+		total      int64
+		pincode    string
+		pincodes   []string
+		refused    int
+		wantReason string
+	}{
+		{name: "eligible", total: 100000, pincode: "560001"},
+		{name: "at the max order value", total: 500000, pincode: "560001"},
+		{name: "over the max order value", total: 500001, pincode: "560001", wantReason: "cash on delivery is only available for orders up to ₹5000"},
+		{name: "pincode on the allowlist", total: 100000, pincode: "560001", pincodes: []string{"560001", "560002"}},
+		{name: "pincode off the allowlist", total: 100000, pincode: "110001", pincodes: []string{"560001", "560002"}, wantReason: "cash on delivery is not available for this pincode"},
+		{name: "under the refusal threshold", total: 100000, pincode: "560001", refused: 1},
+		{name: "refusal threshold reached", total: 100000, pincode: "560001", refused: 2, wantReason: "cash on delivery is not available for this account"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServices(t)
+			ts.cfg.CODMaxOrderCents = 500000
+			ts.cfg.CODPincodes = tt.pincodes
+			ts.cfg.CODMaxRefusals = 2
+			ts.addProduct(t, "p1", 3000, 10)
+			for i := 0; i < tt.refused; i++ {
+				order := ts.placeOrder(t, "u1", "p1", 1, models.PaymentMethodCOD)
+				ts.setPaymentMetadata(t, order.ID, map[string]interface{}{"payment_status": models.PaymentStatusCODRefused})
+			}
+			// Other users' refusals don't count
+			other := ts.placeOrder(t, "u2", "p1", 1, models.PaymentMethodCOD)
+			ts.setPaymentMetadata(t, other.ID, map[string]interface{}{"payment_status": models.PaymentStatusCODRefused})
+
+			got, err := ts.orderService.CODEligibility(context.Background(), "u1", tt.total, tt.pincode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Eligible != (tt.wantReason == "") || got.Reason != tt.wantReason {
+				t.Fatalf("got eligible=%v reason %q, want reason %q", got.Eligible, got.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCODEligibilityWhenDisabled(t *testing.T) {
+	ts := newTestServices(t)
+	ts.cfg.CODEnabled = false
+
+	got, err := ts.orderService.CODEligibility(context.Background(), "u1", 100, "560001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Eligible {
+		t.Fatal("cash on delivery offered while disabled")
+	}
+}
+
+func TestMarkCODRefused(t *testing.T) {
+	ts := newTestServices(t)
+	ts.cfg.CODMaxRefusals = 1
+	ts.addProduct(t, "p1", 3000, 10)
+	ctx := context.Background()
+
+	order := ts.placeOrder(t, "u1", "p1", 2, models.PaymentMethodCOD)
+	if _, err := ts.orderService.MarkCODRefused(ctx, order.ID, "admin"); err == nil {
+		t.Fatal("refused an order that hasn't shipped")
+	}
+
+	for _, status := range []string{models.OrderStatusProcessing, models.OrderStatusShipped} {
+		if _, err := ts.orderService.UpdateOrderStatus(ctx, order.ID, status, "admin", models.OrderRoleAdmin, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refused, err := ts.orderService.MarkCODRefused(ctx, order.ID, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refused.Status != models.OrderStatusCancelled {
+		t.Fatalf("status = %q, want cancelled", refused.Status)
+	}
+
+	stored, _ := ts.orders.GetOrderByID(ctx, order.ID)
+	if stored.PaymentMetadata["payment_status"] != models.PaymentStatusCODRefused {
+		t.Fatalf("payment_status = %v, want %s", stored.PaymentMetadata["payment_status"], models.PaymentStatusCODRefused)
+	}
+	if got := ts.stockOf(t, "p1"); got != 10 {
+		t.Fatalf("stock = %d, want 10", got)
+	}
+
+	elig, err := ts.orderService.CODEligibility(ctx, "u1", 100, "560001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elig.Eligible {
+		t.Fatal("still eligible for cash on delivery after a refusal")
+	}
+}
+
+func TestMarkCODRefusedRejectsPrepaidOrder(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	order := ts.placeOrder(t, "u1", "p1", 1, "")
+
+	if _, err := ts.orderService.MarkCODRefused(context.Background(), order.ID, "admin"); err == nil || err.Error() != "order is not cash on delivery" {
+		t.Fatalf("err = %v, want order is not cash on delivery", err)
+	}
+}
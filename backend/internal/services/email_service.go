@@ -25,6 +25,11 @@ func NewEmailService() *EmailService {
 	}
 }
 
+// Configured reports whether an SMTP server has been set up
+func (s *EmailService) Configured() bool {
+	return s.host != ""
+}
+
 func (s *EmailService) SendOTP(toEmail, otp string) error {
 	subject := "Daily Bazaar - Password Reset Code"
 	body := fmt.Sprintf(
@@ -32,6 +37,36 @@ func (s *EmailService) SendOTP(toEmail, otp string) error {
 		otp,
 	)
 
+	if err := s.send(toEmail, subject, body); err != nil {
+		return err
+	}
+
+	log.Printf("OTP email sent to %s", toEmail)
+	return nil
+}
+
+// SendOrderStatus tells a customer their order has moved to a new status
+func (s *EmailService) SendOrderStatus(toEmail, orderID, status string) error {
+	subject := fmt.Sprintf("Daily Bazaar - Your order is %s", status)
+	body := fmt.Sprintf(
+		"Hi,\n\nYour order %s is now %s.\n\nThanks,\nDaily Bazaar Team",
+		orderID, status,
+	)
+	return s.send(toEmail, subject, body)
+}
+
+// SendSubscriptionFailure tells a customer a subscription delivery couldn't
+// be ordered, and why
+func (s *EmailService) SendSubscriptionFailure(toEmail, productName, date, reason string) error {
+	subject := "Daily Bazaar - Your subscription order could not be placed"
+	body := fmt.Sprintf(
+		"Hi,\n\nWe couldn't place your %s delivery for %s: %s.\n\nWe'll keep trying until the day begins.\n\nThanks,\nDaily Bazaar Team",
+		productName, date, reason,
+	)
+	return s.send(toEmail, subject, body)
+}
+
+func (s *EmailService) send(toEmail, subject, body string) error {
 	msg := fmt.Sprintf(
 		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"utf-8\"\r\n\r\n%s",
 		s.from, toEmail, subject, body,
@@ -44,7 +79,5 @@ func (s *EmailService) SendOTP(toEmail, otp string) error {
 		log.Printf("Failed to send email to %s: %v", toEmail, err)
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-
-	log.Printf("OTP email sent to %s", toEmail)
 	return nil
 }
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeGateway is a deterministic in-process gateway for local development
+// and tests. Intents are named "fake_<order id>"; submitting a reference
+// completes the payment unless the reference starts with "FAIL". Webhooks are
+// JSON bodies signed with HMAC-SHA256 in the X-Fake-Signature header (see
+// Sign), optionally carrying a unix "timestamp". Refunds always succeed.
+type FakeGateway struct {
+	secret string
+
+	mu      sync.Mutex
+	orders  map[string]*fakeGatewayOrder
+	refunds map[string]*RefundResult
+}
+
+type fakeGatewayOrder struct {
+	amountPaise int64
+	reference   string
+	status      string
+}
+
+// fakeWebhookPayload is the body FakeGateway expects on its webhook.
+type fakeWebhookPayload struct {
+	GatewayOrderID  string `json:"gateway_order_id"`
+	ReferenceNumber string `json:"reference_number"`
+	Amount          string `json:"amount"`
+	From            string `json:"from"`
+	Timestamp       int64  `json:"timestamp,omitempty"` // unix seconds
+}
+
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{
+		secret:  secret,
+		orders:  map[string]*fakeGatewayOrder{},
+		refunds: map[string]*RefundResult{},
+	}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) CreateIntent(ctx context.Context, in PaymentIntentRequest) (*PaymentIntent, error) {
+	if in.AmountPaise <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	id := "fake_" + in.OrderID
+	amount := formatRupees(in.AmountPaise)
+
+	g.mu.Lock()
+	if _, ok := g.orders[id]; !ok {
+		g.orders[id] = &fakeGatewayOrder{amountPaise: in.AmountPaise, status: GatewayStatusPending}
+	}
+	g.mu.Unlock()
+
+	return &PaymentIntent{
+		GatewayOrderID: id,
+		UPIString:      fmt.Sprintf("upi://pay?pa=fake@upi&pn=DailyBazaar&am=%s&tr=%s", amount, id),
+		AmountInRupees: amount,
+	}, nil
+}
+
+func (g *FakeGateway) SubmitReference(ctx context.Context, gatewayOrderID, referenceNumber string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o, ok := g.orders[gatewayOrderID]
+	if !ok {
+		return errors.New("fake gateway: unknown order")
+	}
+	o.reference = referenceNumber
+	if strings.HasPrefix(referenceNumber, "FAIL") {
+		o.status = GatewayStatusFailed
+	} else {
+		o.status = GatewayStatusCompleted
+	}
+	return nil
+}
+
+func (g *FakeGateway) QueryStatus(ctx context.Context, gatewayOrderID string) (*GatewayStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o, ok := g.orders[gatewayOrderID]
+	if !ok {
+		return nil, errors.New("fake gateway: unknown order")
+	}
+	return &GatewayStatus{Status: o.status, RawStatus: strings.ToUpper(o.status), AmountPaise: o.amountPaise}, nil
+}
+
+func (g *FakeGateway) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if !hmac.Equal([]byte(g.Sign(body)), []byte(header.Get("X-Fake-Signature"))) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var payload fakeWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("invalid webhook payload")
+	}
+
+	event := &WebhookEvent{
+		GatewayOrderID:  payload.GatewayOrderID,
+		ReferenceNumber: payload.ReferenceNumber,
+		Amount:          payload.Amount,
+		Payer:           payload.From,
+	}
+	if payload.Timestamp > 0 {
+		event.SentAt = time.Unix(payload.Timestamp, 0)
+	}
+	return event, nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Same refund ID, same result
+	if res, ok := g.refunds[req.RefundID]; ok {
+		return res, nil
+	}
+	res := &RefundResult{GatewayRefundID: "fake_rfnd_" + req.RefundID, Status: GatewayStatusCompleted}
+	g.refunds[req.RefundID] = res
+	return res, nil
+}
+
+// Sign returns the X-Fake-Signature value for a webhook body.
+func (g *FakeGateway) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// formatRupees renders paise as rupees with two decimals, e.g. 15300 -> "153.00".
+func formatRupees(paise int64) string {
+	sign := ""
+	if paise < 0 {
+		sign = "-"
+		paise = -paise
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, paise/100, paise%100)
+}
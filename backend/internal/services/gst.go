@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// gstRates are the GST slabs, in percent
+var gstRates = map[string]float64{
+	models.TaxClassExempt: 0,
+	models.TaxClassGST5:   5,
+	models.TaxClassGST12:  12,
+	models.TaxClassGST18:  18,
+}
+
+// validateTaxData checks a product's HSN code (4, 6 or 8 digits) and tax
+// class; either may be empty.
+func validateTaxData(hsnCode, taxClass string) error {
+	if hsnCode != "" {
+		if n := len(hsnCode); (n != 4 && n != 6 && n != 8) || strings.Trim(hsnCode, "0123456789") != "" {
+			return errors.New("invalid HSN code")
+		}
+	}
+	if _, ok := gstRates[taxClass]; taxClass != "" && !ok {
+		return errors.New("invalid tax class")
+	}
+	return nil
+}
+
+// gstFor returns the GST rate a product is sold at and whether its price
+// already includes it. A product with no tax class, no matching HSN prefix
+// and no configured default has no rate, and isn't sold rather than taxed
+// at a guess.
+func gstFor(cfg *config.Config, product *models.Product) (rate float64, inclusive bool, err error) {
+	class := product.TaxClass
+	if class == "" {
+		class = cfg.GSTDefaultTaxClass
+		longest := 0
+		for prefix, c := range cfg.GSTHSNTaxClasses {
+			if len(prefix) > longest && strings.HasPrefix(product.HSNCode, prefix) {
+				class, longest = c, len(prefix)
+			}
+		}
+	}
+
+	if class == "" {
+		return 0, false, fmt.Errorf("%s has no GST tax class", product.Name)
+	}
+
+	inclusive = cfg.PricesIncludeTax
+	if product.PriceIncludesTax != nil {
+		inclusive = *product.PriceIncludesTax
+	}
+	return gstRates[class], inclusive, nil
+}
+
+// isInterState reports whether an order shipped to address crosses state
+// lines, which makes it IGST rather than CGST plus SGST. Without a seller
+// state or an address state the sale is treated as local.
+func isInterState(cfg *config.Config, address map[string]interface{}) bool {
+	state, _ := address["state"].(string)
+	seller, buyer := normalizeState(cfg.GSTSellerState), normalizeState(state)
+	return seller != "" && buyer != "" && seller != buyer
+}
+
+// normalizeState keeps only the letters of a state name, lower-cased, so
+// "Tamil Nadu" and "tamilnadu" match
+func normalizeState(state string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, state)
+}
+
+// applyGST works out an order line's taxable value and GST from its unit
+// price, quantity, rate and whether the price includes tax. An inclusive
+// price has the tax taken out of it; otherwise the tax is added on top.
+func applyGST(item *models.OrderItem, interState bool) {
+	gross := item.UnitPriceCents * int64(item.Quantity)
+	if item.PriceIncludesTax {
+		item.TaxableCents = int64(math.Round(float64(gross) * 100 / (100 + item.GSTRate)))
+		item.TaxCents = gross - item.TaxableCents
+	} else {
+		item.TaxableCents = gross
+		item.TaxCents = int64(math.Round(float64(gross) * item.GSTRate / 100))
+	}
+
+	item.CGSTCents, item.SGSTCents, item.IGSTCents = 0, 0, 0
+	if interState {
+		item.IGSTCents = item.TaxCents
+	} else {
+		item.CGSTCents = item.TaxCents / 2
+		item.SGSTCents = item.TaxCents - item.CGSTCents
+	}
+}
+
+// taxTotals adds up taxed order lines. The subtotal is their taxable value,
+// so subtotal + shipping + tax is what the customer pays.
+func taxTotals(items []models.OrderItem) (subtotalCents, taxCents int64) {
+	for _, item := range items {
+		subtotalCents += item.TaxableCents
+		taxCents += item.TaxCents
+	}
+	return subtotalCents, taxCents
+}
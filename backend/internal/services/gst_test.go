@@ -0,0 +1,114 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+func TestApplyGST(t *testing.T) {
+	tests := []struct {
+		name        string
+		unitCents   int64
+		quantity    int
+		rate        float64
+		inclusive   bool
+		interState  bool
+		wantTaxable int64
+		wantTax     int64
+		wantCGST    int64
+		wantSGST    int64
+		wantIGST    int64
+	}{
+		{"inclusive 5%", 10000, 1, 5, true, false, 9524, 476, 238, 238, 0},
+		{"inclusive 18%, odd paise to SGST", 999, 3, 18, true, false, 2540, 457, 228, 229, 0},
+		{"inclusive, exact split", 21, 1, 5, true, false, 20, 1, 0, 1, 0},
+		{"exclusive 12% inter-state", 1999, 1, 12, false, true, 1999, 240, 0, 0, 240},
+		{"exclusive, half a paisa rounds up", 10, 1, 5, false, false, 10, 1, 0, 1, 0},
+		{"exclusive 18% on the line, not the unit", 333, 3, 18, false, false, 999, 180, 90, 90, 0},
+		{"exempt", 4500, 2, 0, true, false, 9000, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := models.OrderItem{UnitPriceCents: tt.unitCents, Quantity: tt.quantity, GSTRate: tt.rate, PriceIncludesTax: tt.inclusive}
+			applyGST(&item, tt.interState)
+
+			if item.TaxableCents != tt.wantTaxable || item.TaxCents != tt.wantTax {
+				t.Fatalf("taxable %d, tax %d; want %d, %d", item.TaxableCents, item.TaxCents, tt.wantTaxable, tt.wantTax)
+			}
+			if item.CGSTCents != tt.wantCGST || item.SGSTCents != tt.wantSGST || item.IGSTCents != tt.wantIGST {
+				t.Fatalf("CGST %d, SGST %d, IGST %d; want %d, %d, %d", item.CGSTCents, item.SGSTCents, item.IGSTCents, tt.wantCGST, tt.wantSGST, tt.wantIGST)
+			}
+			if tt.inclusive && item.TaxableCents+item.TaxCents != tt.unitCents*int64(tt.quantity) {
+				t.Fatalf("inclusive line no longer adds up to its price")
+			}
+		})
+	}
+}
+
+func TestIsInterState(t *testing.T) {
+	cfg := &config.Config{GSTSellerState: "Tamil Nadu"}
+	tests := []struct {
+		state string
+		want  bool
+	}{
+		{"Tamil Nadu", false},
+		{"tamilnadu", false},
+		{"Karnataka", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isInterState(cfg, map[string]interface{}{"state": tt.state}); got != tt.want {
+			t.Errorf("isInterState(%q) = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+
+	if isInterState(&config.Config{}, map[string]interface{}{"state": "Karnataka"}) {
+		t.Error("sale without a seller state should be local")
+	}
+}
+
+func TestGSTForPicksLongestHSNPrefix(t *testing.T) {
+	exclusive := false
+	cfg := &config.Config{
+		GSTDefaultTaxClass: models.TaxClassGST5,
+		GSTHSNTaxClasses:   map[string]string{"04": models.TaxClassGST12, "0401": models.TaxClassExempt},
+		PricesIncludeTax:   true,
+	}
+	tests := []struct {
+		name          string
+		product       models.Product
+		wantRate      float64
+		wantInclusive bool
+	}{
+		{"longest prefix wins", models.Product{HSNCode: "04011000"}, 0, true},
+		{"shorter prefix", models.Product{HSNCode: "04061000"}, 12, true},
+		{"default class", models.Product{HSNCode: "19053100"}, 5, true},
+		{"product class beats HSN", models.Product{HSNCode: "04011000", TaxClass: models.TaxClassGST18}, 18, true},
+		{"product overrides inclusive", models.Product{PriceIncludesTax: &exclusive}, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, inclusive, err := gstFor(cfg, &tt.product)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rate != tt.wantRate || inclusive != tt.wantInclusive {
+				t.Fatalf("got %v%% inclusive=%v, want %v%% inclusive=%v", rate, inclusive, tt.wantRate, tt.wantInclusive)
+			}
+		})
+	}
+}
+
+func TestGSTForRefusesToGuess(t *testing.T) {
+	cfg := &config.Config{GSTHSNTaxClasses: map[string]string{"0401": models.TaxClassExempt}}
+
+	if _, _, err := gstFor(cfg, &models.Product{Name: "Bread", HSNCode: "19053100"}); err == nil {
+		t.Fatal("got a rate for a product with no tax class, HSN mapping or default")
+	}
+	if rate, _, err := gstFor(cfg, &models.Product{Name: "Milk", HSNCode: "04011000"}); err != nil || rate != 0 {
+		t.Fatalf("mapped HSN code got %v%%, %v; want 0%%", rate, err)
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/pkg/pdf"
+)
+
+const (
+	invoiceLeft   = 40.0
+	invoiceRight  = pdf.PageWidth - 40
+	invoiceBottom = pdf.PageHeight - 60
+)
+
+// invoice table columns: left edge for text, right edge for amounts
+var invoiceColumns = struct {
+	no, item, hsn                                    float64
+	qty, rate, taxable, gst, cgst, sgst, igst, total float64
+}{
+	no: invoiceLeft, item: invoiceLeft + 18, hsn: 212,
+	qty: 278, rate: 326, taxable: 378, gst: 408, cgst: 455, sgst: 500, igst: 500, total: invoiceRight,
+}
+
+// renderInvoice lays out a GST tax invoice for order as a PDF.
+func renderInvoice(invoice *models.Invoice, order *models.Order) []byte {
+	doc := pdf.New()
+	interState := false
+	for _, item := range order.Items {
+		if item.IGSTCents > 0 {
+			interState = true
+		}
+	}
+
+	y := 50.0
+	doc.TextCenter(pdf.PageWidth/2, y, 16, true, "TAX INVOICE")
+	y += 28
+
+	// Seller on the left, invoice details on the right
+	sellerY := y
+	doc.Text(invoiceLeft, sellerY, 12, true, invoice.SellerName)
+	sellerY += 14
+	for _, line := range pdf.Wrap(invoice.SellerAddress, 9, false, 260) {
+		if line != "" {
+			doc.Text(invoiceLeft, sellerY, 9, false, line)
+			sellerY += 12
+		}
+	}
+	if invoice.SellerGSTIN != "" {
+		doc.Text(invoiceLeft, sellerY, 9, false, "GSTIN: "+invoice.SellerGSTIN)
+		sellerY += 12
+	}
+	if invoice.SellerState != "" {
+		doc.Text(invoiceLeft, sellerY, 9, false, "State: "+invoice.SellerState)
+		sellerY += 12
+	}
+
+	detailsY := y
+	placeOfSupply := addressField(order.ShippingAddress, "state")
+	if placeOfSupply == "" {
+		placeOfSupply = invoice.SellerState
+	}
+	for _, row := range [][2]string{
+		{"Invoice No:", invoice.Number},
+		{"Invoice Date:", invoice.IssuedAt.In(ist).Format("02 Jan 2006")},
+		{"Order Date:", order.PlacedAt.In(ist).Format("02 Jan 2006")},
+		{"Place of Supply:", placeOfSupply},
+	} {
+		doc.Text(330, detailsY, 9, true, row[0])
+		doc.Text(410, detailsY, 9, false, row[1])
+		detailsY += 12
+	}
+	doc.Text(330, detailsY, 9, true, "Order ID:")
+	doc.Text(410, detailsY, 7, false, order.ID)
+	detailsY += 12
+
+	y = max(sellerY, detailsY) + 6
+	doc.Line(invoiceLeft, y, invoiceRight, y, 0.5)
+	y += 16
+
+	// Buyer
+	doc.Text(invoiceLeft, y, 10, true, "Bill To / Ship To")
+	y += 14
+	if name := addressField(order.ShippingAddress, "full_name"); name != "" {
+		doc.Text(invoiceLeft, y, 9, true, name)
+		y += 12
+	}
+	for _, line := range pdf.Wrap(buyerAddress(order.ShippingAddress), 9, false, invoiceRight-invoiceLeft) {
+		if line != "" {
+			doc.Text(invoiceLeft, y, 9, false, line)
+			y += 12
+		}
+	}
+	if phone := addressField(order.ShippingAddress, "phone"); phone != "" {
+		doc.Text(invoiceLeft, y, 9, false, "Phone: "+phone)
+		y += 12
+	}
+	if gstin := addressField(order.ShippingAddress, "gstin"); gstin != "" {
+		doc.Text(invoiceLeft, y, 9, false, "GSTIN: "+strings.ToUpper(gstin))
+		y += 12
+	}
+	y += 10
+
+	// Items
+	y = invoiceTableHeader(doc, y, interState)
+	var cgst, sgst, igst int64
+	for i, item := range order.Items {
+		name := item.ProductName
+		if item.VariantName != "" {
+			name += " (" + item.VariantName + ")"
+		}
+		lines := pdf.Wrap(name, 8, false, invoiceColumns.hsn-invoiceColumns.item-6)
+		height := float64(len(lines))*10 + 4
+		if y+height > invoiceBottom {
+			doc.AddPage()
+			y = invoiceTableHeader(doc, 50, interState)
+		}
+
+		c := invoiceColumns
+		doc.Text(c.no, y, 8, false, fmt.Sprint(i+1))
+		for j, line := range lines {
+			doc.Text(c.item, y+float64(j)*10, 8, false, line)
+		}
+		doc.Text(c.hsn, y, 8, false, item.HSNCode)
+		doc.TextRight(c.qty, y, 8, false, fmt.Sprint(item.Quantity))
+		doc.TextRight(c.rate, y, 8, false, formatINR(item.UnitPriceCents))
+		doc.TextRight(c.taxable, y, 8, false, formatINR(item.TaxableCents))
+		doc.TextRight(c.gst, y, 8, false, strings.TrimSuffix(fmt.Sprintf("%.2f", item.GSTRate), ".00")+"%")
+		if interState {
+			doc.TextRight(c.igst, y, 8, false, formatINR(item.IGSTCents))
+		} else {
+			doc.TextRight(c.cgst, y, 8, false, formatINR(item.CGSTCents))
+			doc.TextRight(c.sgst, y, 8, false, formatINR(item.SGSTCents))
+		}
+		doc.TextRight(c.total, y, 8, false, formatINR(item.TaxableCents+item.TaxCents))
+
+		cgst += item.CGSTCents
+		sgst += item.SGSTCents
+		igst += item.IGSTCents
+		y += height
+	}
+	doc.Line(invoiceLeft, y-6, invoiceRight, y-6, 0.5)
+	y += 8
+
+	// Totals
+	totals := [][2]string{{"Taxable Value", formatINR(order.SubtotalCents)}}
+	if interState {
+		totals = append(totals, [2]string{"IGST", formatINR(igst)})
+	} else {
+		totals = append(totals, [2]string{"CGST", formatINR(cgst)}, [2]string{"SGST", formatINR(sgst)})
+	}
+	if order.ShippingCents > 0 {
+		totals = append(totals, [2]string{"Delivery Charges", formatINR(order.ShippingCents)})
+	}
+	if y+float64(len(totals)+4)*14 > invoiceBottom {
+		doc.AddPage()
+		y = 50
+	}
+	for _, row := range totals {
+		doc.Text(360, y, 9, false, row[0])
+		doc.TextRight(invoiceRight, y, 9, false, row[1])
+		y += 14
+	}
+	doc.Line(360, y-8, invoiceRight, y-8, 0.5)
+	y += 4
+	doc.Text(360, y, 10, true, "Total (Rs.)")
+	doc.TextRight(invoiceRight, y, 10, true, formatINR(order.TotalCents))
+	y += 22
+
+	for _, line := range pdf.Wrap("Amount in words: "+amountInWords(order.TotalCents), 9, false, invoiceRight-invoiceLeft) {
+		doc.Text(invoiceLeft, y, 9, false, line)
+		y += 12
+	}
+
+	doc.Text(invoiceLeft, pdf.PageHeight-40, 7, false, "This is a computer-generated invoice and does not need a signature.")
+	return doc.Bytes()
+}
+
+func invoiceTableHeader(doc *pdf.Document, y float64, interState bool) float64 {
+	c := invoiceColumns
+	doc.Line(invoiceLeft, y-10, invoiceRight, y-10, 0.5)
+	doc.Text(c.no, y, 8, true, "#")
+	doc.Text(c.item, y, 8, true, "Item")
+	doc.Text(c.hsn, y, 8, true, "HSN")
+	doc.TextRight(c.qty, y, 8, true, "Qty")
+	doc.TextRight(c.rate, y, 8, true, "Rate")
+	doc.TextRight(c.taxable, y, 8, true, "Taxable")
+	doc.TextRight(c.gst, y, 8, true, "GST")
+	if interState {
+		doc.TextRight(c.igst, y, 8, true, "IGST")
+	} else {
+		doc.TextRight(c.cgst, y, 8, true, "CGST")
+		doc.TextRight(c.sgst, y, 8, true, "SGST")
+	}
+	doc.TextRight(c.total, y, 8, true, "Amount")
+	doc.Line(invoiceLeft, y+5, invoiceRight, y+5, 0.5)
+	return y + 18
+}
+
+func addressField(address map[string]interface{}, key string) string {
+	v, _ := address[key].(string)
+	return strings.TrimSpace(v)
+}
+
+// buyerAddress joins the parts of a shipping address into one line
+func buyerAddress(address map[string]interface{}) string {
+	var parts []string
+	for _, key := range []string{"address_line1", "address_line2", "landmark", "city", "district", "state"} {
+		if v := addressField(address, key); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	out := strings.Join(parts, ", ")
+	if pincode := pincodeFromAddress(address); pincode != "" {
+		out += " - " + pincode
+	}
+	return out
+}
+
+// formatINR formats paise as rupees with Indian digit grouping, e.g.
+// 12345678 -> "1,23,456.78"
+func formatINR(paise int64) string {
+	sign := ""
+	if paise < 0 {
+		sign = "-"
+		paise = -paise
+	}
+	rupees := fmt.Sprint(paise / 100)
+	if len(rupees) > 3 {
+		head, tail := rupees[:len(rupees)-3], rupees[len(rupees)-3:]
+		var groups []string
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		groups = append([]string{head}, groups...)
+		rupees = strings.Join(groups, ",") + "," + tail
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, rupees, paise%100)
+}
+
+var (
+	wordsOnes = []string{"", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten",
+		"Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen"}
+	wordsTens = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+)
+
+// amountInWords spells out an amount the Indian way, e.g. 150050 ->
+// "Rupees One Thousand Five Hundred and Fifty Paise Only"
+func amountInWords(paise int64) string {
+	rupees, rest := paise/100, paise%100
+	out := "Rupees " + numberInWords(rupees)
+	if rupees == 0 {
+		out = "Rupees Zero"
+	}
+	if rest > 0 {
+		out += " and " + numberInWords(rest) + " Paise"
+	}
+	return out + " Only"
+}
+
+// numberInWords uses crore, lakh and thousand
+func numberInWords(n int64) string {
+	var parts []string
+	for _, unit := range []struct {
+		size int64
+		name string
+	}{{10000000, "Crore"}, {100000, "Lakh"}, {1000, "Thousand"}, {100, "Hundred"}} {
+		if n >= unit.size {
+			parts = append(parts, numberInWords(n/unit.size)+" "+unit.name)
+			n %= unit.size
+		}
+	}
+	switch {
+	case n >= 20:
+		words := wordsTens[n/10]
+		if n%10 > 0 {
+			words += " " + wordsOnes[n%10]
+		}
+		parts = append(parts, words)
+	case n > 0:
+		parts = append(parts, wordsOnes[n])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+type InvoiceService struct {
+	cfg         *config.Config
+	invoiceRepo repository.InvoiceStore
+	orderRepo   repository.OrderStore
+
+	// Issues one invoice at a time so numbers are taken in order
+	mu sync.Mutex
+}
+
+func NewInvoiceService(cfg *config.Config, invoiceRepo repository.InvoiceStore, orderRepo repository.OrderStore) *InvoiceService {
+	return &InvoiceService{
+		cfg:         cfg,
+		invoiceRepo: invoiceRepo,
+		orderRepo:   orderRepo,
+	}
+}
+
+// IssueInvoice returns the invoice of a delivered order, giving it the next
+// number of the financial year if it doesn't have one yet. A number is only
+// used once its invoice is saved, so there are no gaps; when another server
+// takes the same number first, the next one is tried.
+func (s *InvoiceService) IssueInvoice(ctx context.Context, order *models.Order) (*models.Invoice, error) {
+	if order.Status != models.OrderStatusDelivered {
+		return nil, errors.New("order has not been delivered")
+	}
+	if paymentMethodOf(order) == models.PaymentMethodReplacement {
+		return nil, errors.New("replacement orders are not invoiced")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if existing, err := s.invoiceRepo.GetInvoiceByOrderID(ctx, order.ID); err == nil {
+			return existing, nil
+		}
+
+		issuedAt := time.Now().UTC()
+		fy, short := financialYear(issuedAt)
+		last, err := s.invoiceRepo.LastInvoiceSequence(ctx, fy)
+		if err != nil {
+			return nil, err
+		}
+
+		invoice := &models.Invoice{
+			ID:            uuid.New().String(),
+			OrderID:       order.ID,
+			Number:        fmt.Sprintf("%s/%s/%06d", s.cfg.InvoicePrefix, short, last+1),
+			FinancialYear: fy,
+			Sequence:      last + 1,
+			IssuedAt:      issuedAt,
+			SellerName:    s.cfg.SellerName,
+			SellerGSTIN:   s.cfg.SellerGSTIN,
+			SellerAddress: s.cfg.SellerAddress,
+			SellerState:   s.cfg.GSTSellerState,
+		}
+		err = s.invoiceRepo.CreateInvoice(ctx, invoice)
+		if errors.Is(err, repository.ErrInvoiceExists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("Issued invoice %s for order %s", invoice.Number, order.ID)
+		return invoice, nil
+	}
+
+	return nil, errors.New("could not allocate an invoice number")
+}
+
+// GetInvoicePDF renders the invoice of an order for its owner or an admin.
+// Orders delivered without one, say because issuing failed at the time, get
+// it now.
+func (s *InvoiceService) GetInvoicePDF(ctx context.Context, orderID, userID string, isAdmin bool) (*models.Invoice, []byte, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isAdmin && order.UserID != userID {
+		return nil, nil, errors.New("access denied")
+	}
+
+	invoice, err := s.invoiceRepo.GetInvoiceByOrderID(ctx, order.ID)
+	if err != nil {
+		if invoice, err = s.IssueInvoice(ctx, order); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return invoice, renderInvoice(invoice, order), nil
+}
+
+// financialYear returns the Indian financial year (April to March, IST)
+// that t falls in, as "2026-27", and the short form "26-27" used in
+// invoice numbers.
+func financialYear(t time.Time) (string, string) {
+	local := t.In(ist)
+	start := local.Year()
+	if local.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100), fmt.Sprintf("%02d-%02d", start%100, (start+1)%100)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// ReduceOrderItem lowers the quantity of one item in a pending or confirmed
+// order, or removes it when quantity is 0. The order's totals are worked out
+// again, the freed stock goes back on sale and, if the order was already
+// paid, the difference is refunded. The edit is recorded in the timeline.
+//
+// Saving the new totals is a compare-and-set on the order as it was read, so
+// of two concurrent edits, or an edit racing a cancel, only one goes ahead and
+// the stock is released once.
+func (s *OrderService) ReduceOrderItem(ctx context.Context, orderID, itemID string, quantity int, userID string, isAdmin bool) (*models.Order, error) {
+	if quantity < 0 {
+		return nil, errors.New("quantity cannot be negative")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && order.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusConfirmed {
+		return nil, errors.New("order can no longer be edited")
+	}
+
+	// The gateway was asked for the old total; changing it now would make the
+	// payment look wrong when it arrives
+	switch stringFromMap(order.PaymentMetadata, "payment_status") {
+	case models.PaymentStatusCreated, models.PaymentStatusUpdated:
+		return nil, errors.New("order has a payment in progress")
+	}
+
+	item := findOrderItem(order, itemID)
+	if item == nil {
+		return nil, errors.New("order item not found")
+	}
+	if quantity >= item.Quantity {
+		return nil, errors.New("quantity can only be reduced")
+	}
+	if quantity == 0 && len(order.Items) == 1 {
+		return nil, errors.New("cannot remove the last item, cancel the order instead")
+	}
+
+	// Tax the line again at the rate it was sold at
+	removed := item.Quantity - quantity
+	item.Quantity = quantity
+	applyGST(item, isInterState(s.cfg, order.ShippingAddress))
+	remaining := order.Items
+	if quantity == 0 {
+		remaining = slices.DeleteFunc(slices.Clone(order.Items), func(i models.OrderItem) bool { return i.ID == item.ID })
+	}
+	// Delivery is priced as it was when the order was placed
+	slot := order.DeliverySlot
+	if slot == "" {
+		slot = models.DeliverySlotStandard
+	}
+	quote, err := s.priceOrder(remaining, destinationOf(order.ShippingAddress), slot, order.PlacedAt)
+	if err != nil {
+		return nil, err
+	}
+	totalCents := quote.TotalCents
+
+	// Dropping below the free shipping threshold can cost more than the item
+	paid := paidAmount(order) > 0
+	if paid && totalCents > order.TotalCents {
+		return nil, errors.New("removing this item would raise the order total")
+	}
+
+	// Pin down what was charged before the total changes under it
+	if paid {
+		if _, ok := centsFromMap(order.PaymentMetadata, "paid_cents"); !ok {
+			order.PaymentMetadata["paid_cents"] = amountDue(order)
+			if err := s.orderRepo.UpdatePaymentMetadata(ctx, order.ID, order.PaymentMetadata); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	totals := &models.Order{
+		ID:            order.ID,
+		SubtotalCents: quote.SubtotalCents,
+		ShippingCents: quote.ShippingCents,
+		TaxCents:      quote.TaxCents,
+		TotalCents:    quote.TotalCents,
+		ShippingFees:  quote.ShippingFees,
+	}
+	if err := s.orderRepo.UpdateOrderTotals(ctx, totals, order); err != nil {
+		return nil, err
+	}
+
+	if err := s.orderRepo.UpdateOrderItem(ctx, item); err != nil {
+		return nil, err
+	}
+
+	reason := fmt.Sprintf("%s reduced from %d to %d", item.ProductName, quantity+removed, quantity)
+	if quantity == 0 {
+		reason = fmt.Sprintf("%s removed", item.ProductName)
+	}
+	s.workflow.record(ctx, order.ID, order.Status, order.Status, userID, reason)
+
+	if order.StockStatus == models.StockStatusReserved || order.StockStatus == models.StockStatusCommitted {
+		if err := releaseItems(ctx, s.productRepo, []models.OrderItem{{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: removed}}); err != nil {
+			log.Printf("Failed to return stock removed from order %s: %v", order.ID, err)
+		}
+	}
+
+	if paid && totalCents < order.TotalCents {
+		if refund, err := s.workflow.refunds.CreateRefund(ctx, order.ID, order.TotalCents-totalCents, "items removed from order", userID); err != nil {
+			log.Printf("Failed to refund removed items on order %s: %v", order.ID, err)
+		} else {
+			log.Printf("Created %s refund %s of ₹%s for items removed from order %s", refund.Method, refund.ID, formatRupees(refund.AmountCents), order.ID)
+		}
+	}
+
+	return s.orderRepo.GetOrderByID(ctx, order.ID)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// placeTwoItemOrder orders 5 of p1 and 2 of p2.
+func placeTwoItemOrder(t *testing.T, ts *testServices) *models.Order {
+	t.Helper()
+	ts.addProduct(t, "p1", 3000, 10)
+	ts.addProduct(t, "p2", 5000, 10)
+	order, err := ts.orderService.CreateOrder(context.Background(), "u1", &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		PaymentMethod:   models.PaymentMethodCOD,
+		Items: []models.CreateOrderItem{
+			{ProductID: "p1", Quantity: 5},
+			{ProductID: "p2", Quantity: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func itemFor(order *models.Order, productID string) *models.OrderItem {
+	for i := range order.Items {
+		if order.Items[i].ProductID == productID {
+			return &order.Items[i]
+		}
+	}
+	return nil
+}
+
+func TestReduceOrderItem(t *testing.T) {
+	tests := []struct {
+		name      string
+		product   string
+		quantity  int
+		wantItems int
+		wantStock int
+		reason    string
+	}{
+		{name: "reduce", product: "p1", quantity: 2, wantItems: 2, wantStock: 8, reason: "p1 reduced from 5 to 2"},
+		{name: "remove", product: "p2", quantity: 0, wantItems: 1, wantStock: 10, reason: "p2 removed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServices(t)
+			order := placeTwoItemOrder(t, ts)
+			item := itemFor(order, tt.product)
+			ctx := context.Background()
+
+			edited, err := ts.orderService.ReduceOrderItem(ctx, order.ID, item.ID, tt.quantity, "u1", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(edited.Items) != tt.wantItems {
+				t.Fatalf("order has %d items, want %d", len(edited.Items), tt.wantItems)
+			}
+			if tt.quantity > 0 {
+				if got := itemFor(edited, tt.product).Quantity; got != tt.quantity {
+					t.Fatalf("quantity = %d, want %d", got, tt.quantity)
+				}
+			}
+			if got := ts.stockOf(t, tt.product); got != tt.wantStock {
+				t.Fatalf("stock = %d, want %d", got, tt.wantStock)
+			}
+			if edited.TotalCents >= order.TotalCents {
+				t.Fatalf("total = %d, want less than %d", edited.TotalCents, order.TotalCents)
+			}
+
+			events, _ := ts.events.GetOrderEvents(ctx, order.ID)
+			last := events[len(events)-1]
+			if last.FromStatus != order.Status || last.ToStatus != order.Status || last.Actor != "u1" || last.Reason != tt.reason {
+				t.Fatalf("last event = %+v, want %q by u1 in %s", last, tt.reason, order.Status)
+			}
+
+			// An edit isn't the order entering its status again
+			entered, err := ts.orderService.GetOrdersEnteredStatus(ctx, order.Status, "", last.CreatedAt, time.Time{}, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entered) != 0 {
+				t.Fatalf("edit was listed as entering %s", order.Status)
+			}
+		})
+	}
+}
+
+func TestReduceOrderItemRejectsChangedOrder(t *testing.T) {
+	ts := newTestServices(t)
+	order := placeTwoItemOrder(t, ts)
+	ctx := context.Background()
+
+	// Cancelled after the edit read the order
+	if _, err := ts.orderService.CancelOrder(ctx, order.ID, "u1", false); err != nil {
+		t.Fatal(err)
+	}
+	err := ts.orders.UpdateOrderTotals(ctx, &models.Order{ID: order.ID}, order)
+	if err == nil {
+		t.Fatal("totals were saved over a cancelled order")
+	}
+}
+
+func TestReduceOrderItemReleasesStockOnceWhenRacing(t *testing.T) {
+	ts := newTestServices(t)
+	order := placeTwoItemOrder(t, ts)
+	item := itemFor(order, "p1")
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(quantity int) {
+			defer wg.Done()
+			ts.orderService.ReduceOrderItem(ctx, order.ID, item.ID, quantity, "u1", false)
+		}(i % 5)
+	}
+	wg.Wait()
+
+	stored, err := ts.orders.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := 0
+	if i := itemFor(stored, "p1"); i != nil {
+		left = i.Quantity
+	}
+	if got := ts.stockOf(t, "p1"); got+left != 10 {
+		t.Fatalf("stock %d + %d still ordered = %d, want 10", got, left, got+left)
+	}
+}
@@ -1,27 +1,57 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
 	"github.com/namanjain.3009/daily_bazaar/internal/repository"
 )
 
 type OrderService struct {
-	orderRepo   *repository.OrderRepository
-	productRepo *repository.ProductRepository
+	cfg         *config.Config
+	orderRepo   repository.OrderStore
+	productRepo repository.ProductStore
+	eventRepo   repository.OrderEventStore
+	walletRepo  repository.WalletStore
+	workflow    *OrderWorkflow
+	shipping    *ShippingCalculator
 }
 
-func NewOrderService(orderRepo *repository.OrderRepository, productRepo *repository.ProductRepository) *OrderService {
+func NewOrderService(cfg *config.Config, orderRepo repository.OrderStore, productRepo repository.ProductStore, eventRepo repository.OrderEventStore, walletRepo repository.WalletStore, workflow *OrderWorkflow, shipping *ShippingCalculator) *OrderService {
 	return &OrderService{
+		cfg:         cfg,
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
+		eventRepo:   eventRepo,
+		walletRepo:  walletRepo,
+		workflow:    workflow,
+		shipping:    shipping,
 	}
 }
 
-func (s *OrderService) CreateOrder(userID string, req *models.CreateOrderRequest) (*models.Order, error) {
+// CreateOrder places a customer's order. The wallet only pays for
+// subscription deliveries, which go through placeSubscriptionOrder.
+func (s *OrderService) CreateOrder(ctx context.Context, userID string, req *models.CreateOrderRequest) (*models.Order, error) {
+	if req.PaymentMethod == models.PaymentMethodWallet {
+		return nil, errors.New("wallet payment is only available for subscriptions")
+	}
+	return s.createOrder(ctx, userID, req, uuid.New().String(), "")
+}
+
+// placeSubscriptionOrder places one delivery of a subscription for
+// deliveryDate, paid from the wallet unless the request says cod. The
+// caller picks orderID so it can tell later whether the order was saved.
+func (s *OrderService) placeSubscriptionOrder(ctx context.Context, userID, orderID, deliveryDate string, req *models.CreateOrderRequest) (*models.Order, error) {
+	return s.createOrder(ctx, userID, req, orderID, deliveryDate)
+}
+
+func (s *OrderService) createOrder(ctx context.Context, userID string, req *models.CreateOrderRequest, orderID, deliveryDate string) (*models.Order, error) {
 	if len(req.Items) == 0 {
 		return nil, errors.New("order must contain at least one item")
 	}
@@ -30,17 +60,162 @@ func (s *OrderService) CreateOrder(userID string, req *models.CreateOrderRequest
 		return nil, errors.New("shipping address is required")
 	}
 
-	// Calculate order totals
-	var subtotalCents int64 = 0
-	orderItems := make([]models.OrderItem, 0, len(req.Items))
+	paymentMethod := req.PaymentMethod
+	switch paymentMethod {
+	case "":
+		paymentMethod = models.PaymentMethodUPI
+	case models.PaymentMethodUPI, models.PaymentMethodCOD, models.PaymentMethodWallet:
+	default:
+		return nil, errors.New("invalid payment method")
+	}
+
+	placedAt := time.Now()
+	quote, err := s.quote(ctx, req, placedAt)
+	if err != nil {
+		return nil, err
+	}
+	orderItems := quote.Items
+	totalCents := quote.TotalCents
+
+	paymentMeta := map[string]interface{}{}
+	for k, v := range req.PaymentMetadata {
+		paymentMeta[k] = v
+	}
+	paymentMeta["payment_method"] = paymentMethod
+
+	status := models.OrderStatusPending
+	stockStatus := models.StockStatusReserved
+	switch paymentMethod {
+	case models.PaymentMethodCOD:
+		eligibility, err := s.CODEligibility(ctx, userID, totalCents, pincodeFromAddress(req.ShippingAddress))
+		if err != nil {
+			return nil, err
+		}
+		if !eligibility.Eligible {
+			return nil, errors.New(eligibility.Reason)
+		}
+
+		// Nothing to wait for: the order is confirmed and the stock sold now,
+		// with cash collected on delivery
+		paymentMeta["payment_status"] = models.PaymentStatusCODPending
+		status = models.OrderStatusConfirmed
+		stockStatus = models.StockStatusCommitted
+	case models.PaymentMethodWallet:
+		// Paid in full below, before the order exists
+		paymentMeta["payment_status"] = models.PaymentStatusCompleted
+		paymentMeta["paid_cents"] = totalCents
+		status = models.OrderStatusConfirmed
+		stockStatus = models.StockStatusCommitted
+	}
+
+	// Reserve stock up front; a failed reservation undoes the earlier ones
+	if err := s.reserveItems(ctx, orderItems); err != nil {
+		return nil, err
+	}
+
+	var walletTxn *models.WalletTransaction
+	if paymentMethod == models.PaymentMethodWallet {
+		walletTxn, err = moveWalletBalance(ctx, s.walletRepo, userID, -totalCents, models.WalletOrderPayment, orderID, "", userID)
+		if err != nil {
+			s.undoReservation(ctx, orderID, orderItems)
+			return nil, err
+		}
+		paymentMeta["wallet_transaction_id"] = walletTxn.ID
+	}
+	// Gives the money back if the order can't be saved
+	refundWallet := func() {
+		if walletTxn == nil {
+			return
+		}
+		if _, err := moveWalletBalance(ctx, s.walletRepo, userID, totalCents, models.WalletRefund, orderID, "order could not be placed", "system"); err != nil {
+			log.Printf("Failed to return ₹%s to the wallet of user %s for unplaced order %s: %v", formatRupees(totalCents), userID, orderID, err)
+		}
+	}
+
+	// Create order
+	order := &models.Order{
+		ID:              orderID,
+		UserID:          userID,
+		SubtotalCents:   quote.SubtotalCents,
+		ShippingCents:   quote.ShippingCents,
+		TaxCents:        quote.TaxCents,
+		TotalCents:      totalCents,
+		Status:          status,
+		PlacedAt:        placedAt,
+		ShippingAddress: req.ShippingAddress,
+		PaymentMetadata: paymentMeta,
+		StockStatus:     stockStatus,
+		DeliverySlot:    quote.DeliverySlot,
+		DeliveryDate:    deliveryDate,
+		ShippingFees:    quote.ShippingFees,
+	}
+
+	if err := s.orderRepo.CreateOrder(ctx, order); err != nil {
+		s.undoReservation(ctx, orderID, orderItems)
+		refundWallet()
+		return nil, err
+	}
+
+	// Set order ID for items and create them
+	for i := range orderItems {
+		orderItems[i].OrderID = order.ID
+	}
+
+	if err := s.orderRepo.CreateOrderItems(ctx, orderItems); err != nil {
+		// Attempt to rollback order creation
+		s.orderRepo.DeleteOrder(ctx, order.ID)
+		s.undoReservation(ctx, orderID, orderItems)
+		refundWallet()
+		return nil, errors.New("failed to create order items")
+	}
+
+	reason := "order placed"
+	switch paymentMethod {
+	case models.PaymentMethodCOD:
+		reason = "cash on delivery order placed"
+	case models.PaymentMethodWallet:
+		reason = "order placed and paid from wallet"
+	}
+	s.workflow.record(ctx, order.ID, "", status, userID, reason)
+
+	order.Items = orderItems
+	return order, nil
+}
+
+// Quote prices an order without placing it, with the same items, tax and
+// delivery charges CreateOrder would use right now.
+func (s *OrderService) Quote(ctx context.Context, req *models.CreateOrderRequest) (*models.CheckoutQuote, error) {
+	if len(req.Items) == 0 {
+		return nil, errors.New("order must contain at least one item")
+	}
+	return s.quote(ctx, req, time.Now())
+}
+
+func (s *OrderService) quote(ctx context.Context, req *models.CreateOrderRequest, at time.Time) (*models.CheckoutQuote, error) {
+	items, err := s.orderItemsFor(ctx, req.Items, isInterState(s.cfg, req.ShippingAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	slot := req.DeliverySlot
+	if slot == "" {
+		slot = models.DeliverySlotStandard
+	}
+	return s.priceOrder(items, destinationOf(req.ShippingAddress), slot, at)
+}
+
+// orderItemsFor turns requested items into order lines at the current
+// price, taxed for where the order is going.
+func (s *OrderService) orderItemsFor(ctx context.Context, items []models.CreateOrderItem, interState bool) ([]models.OrderItem, error) {
+	orderItems := make([]models.OrderItem, 0, len(items))
 
-	for _, item := range req.Items {
+	for _, item := range items {
 		if item.Quantity <= 0 {
 			return nil, errors.New("item quantity must be positive")
 		}
 
 		// Fetch product to get current price
-		product, err := s.productRepo.GetProductByID(item.ProductID)
+		product, err := s.productRepo.GetProductByID(ctx, item.ProductID)
 		if err != nil {
 			return nil, errors.New("product not found: " + item.ProductID)
 		}
@@ -49,12 +224,20 @@ func (s *OrderService) CreateOrder(userID string, req *models.CreateOrderRequest
 			return nil, errors.New("product is not available: " + product.Name)
 		}
 
-		if product.Stock < item.Quantity {
+		if stockFor(product, item.VariantID) < item.Quantity {
 			return nil, errors.New("insufficient stock for product: " + product.Name)
 		}
 
-		itemTotal := product.PriceCents * int64(item.Quantity)
-		subtotalCents += itemTotal
+		unitPriceCents := product.PriceCents
+		var variantName string
+		if item.VariantID != "" {
+			variant := findVariant(product, item.VariantID)
+			if variant == nil {
+				return nil, errors.New("variant not found for product: " + product.Name)
+			}
+			unitPriceCents = variant.PriceCents
+			variantName = variant.Name
+		}
 
 		// Get primary image URL if available
 		var imageURL string
@@ -62,64 +245,57 @@ func (s *OrderService) CreateOrder(userID string, req *models.CreateOrderRequest
 			imageURL = product.Images[0].URL
 		}
 
-		orderItems = append(orderItems, models.OrderItem{
+		orderItem := models.OrderItem{
 			ID:             uuid.New().String(),
 			ProductID:      item.ProductID,
 			ProductName:    product.Name,
 			ProductImage:   imageURL,
+			VariantID:      item.VariantID,
+			VariantName:    variantName,
 			Quantity:       item.Quantity,
-			UnitPriceCents: product.PriceCents,
-		})
-	}
-
-	// Calculate shipping and tax (you can customize these calculations)
-	var shippingCents int64 = 0
-	if subtotalCents < 50000 { // Free shipping over $500
-		shippingCents = 999 // $9.99 shipping
+			UnitPriceCents: unitPriceCents,
+			WeightGrams:    weightGrams(weightOf(product, item.VariantID)),
+			HSNCode:        product.HSNCode,
+		}
+		if orderItem.GSTRate, orderItem.PriceIncludesTax, err = gstFor(s.cfg, product); err != nil {
+			return nil, err
+		}
+		applyGST(&orderItem, interState)
+		orderItems = append(orderItems, orderItem)
 	}
 
-	taxCents := subtotalCents * 10 / 100 // 10% tax
-	totalCents := subtotalCents + shippingCents + taxCents
-
-	// Create order
-	order := &models.Order{
-		ID:              uuid.New().String(),
-		UserID:          userID,
-		SubtotalCents:   subtotalCents,
-		ShippingCents:   shippingCents,
-		TaxCents:        taxCents,
-		TotalCents:      totalCents,
-		Status:          models.OrderStatusPending,
-		PlacedAt:        time.Now(),
-		ShippingAddress: req.ShippingAddress,
-		PaymentMetadata: req.PaymentMetadata,
-	}
+	return orderItems, nil
+}
 
-	if err := s.orderRepo.CreateOrder(order); err != nil {
+// priceOrder totals taxed order lines and adds the delivery charges for
+// dest and slot at the given time.
+func (s *OrderService) priceOrder(items []models.OrderItem, dest ShippingDestination, slot string, at time.Time) (*models.CheckoutQuote, error) {
+	zone, fees, err := s.shipping.Quote(items, dest, slot, at)
+	if err != nil {
 		return nil, err
 	}
 
-	// Set order ID for items and create them
-	for i := range orderItems {
-		orderItems[i].OrderID = order.ID
+	quote := &models.CheckoutQuote{
+		Items:                  items,
+		Zone:                   zone.Name,
+		DeliverySlot:           slot,
+		ShippingFees:           fees,
+		FreeDeliveryAboveCents: zone.FreeAboveCents,
 	}
-
-	if err := s.orderRepo.CreateOrderItems(orderItems); err != nil {
-		// Attempt to rollback order creation
-		s.orderRepo.DeleteOrder(order.ID)
-		return nil, errors.New("failed to create order items")
+	quote.SubtotalCents, quote.TaxCents = taxTotals(items)
+	for _, fee := range fees {
+		quote.ShippingCents += fee.AmountCents
 	}
-
-	order.Items = orderItems
-	return order, nil
+	quote.TotalCents = quote.SubtotalCents + quote.ShippingCents + quote.TaxCents
+	return quote, nil
 }
 
-func (s *OrderService) GetOrderByID(id string, userID string, isAdmin bool) (*models.Order, error) {
+func (s *OrderService) GetOrderByID(ctx context.Context, id string, userID string, isAdmin bool) (*models.Order, error) {
 	if id == "" {
 		return nil, errors.New("order ID is required")
 	}
 
-	order, err := s.orderRepo.GetOrderByID(id)
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -129,53 +305,92 @@ func (s *OrderService) GetOrderByID(id string, userID string, isAdmin bool) (*mo
 		return nil, errors.New("access denied")
 	}
 
+	timeline, err := s.eventRepo.GetOrderEvents(ctx, id)
+	if err != nil {
+		log.Printf("Failed to load timeline for order %s: %v", id, err)
+	}
+	order.Timeline = timeline
+
 	return order, nil
 }
 
-func (s *OrderService) GetUserOrders(userID string) ([]models.Order, error) {
+func (s *OrderService) GetUserOrders(ctx context.Context, userID string) ([]models.Order, error) {
 	if userID == "" {
 		return nil, errors.New("user ID is required")
 	}
-	return s.orderRepo.GetOrdersByUserID(userID)
+	return s.orderRepo.GetOrdersByUserID(ctx, userID)
 }
 
-func (s *OrderService) GetAllOrders(status string, limit, offset int) ([]models.Order, error) {
+func (s *OrderService) GetAllOrders(ctx context.Context, status string, limit, offset int) ([]models.Order, error) {
 	// Validate status if provided
-	if status != "" && !isValidStatus(status) {
+	if status != "" && !s.workflow.machine.HasState(status) {
+		return nil, errors.New("invalid order status")
+	}
+	return s.orderRepo.GetAllOrders(ctx, status, limit, offset)
+}
+
+// GetOrdersEnteredStatus returns orders that moved into entered within
+// [from, to), most recent transition first. A non-empty status also filters
+// on the order's current status.
+func (s *OrderService) GetOrdersEnteredStatus(ctx context.Context, entered, status string, from, to time.Time, limit, offset int) ([]models.Order, error) {
+	machine := s.workflow.machine
+	if !machine.HasState(entered) || (status != "" && !machine.HasState(status)) {
 		return nil, errors.New("invalid order status")
 	}
-	return s.orderRepo.GetAllOrders(status, limit, offset)
+
+	return s.orderRepo.GetOrdersEnteredStatus(ctx, entered, status, from, to, limit, offset)
 }
 
-func (s *OrderService) UpdateOrderStatus(id string, status string) (*models.Order, error) {
+// UpdateOrderStatus moves an order to status on behalf of actorID acting as
+// role; the state machine decides whether that is allowed.
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, id, status, actorID, role, reason string) (*models.Order, error) {
 	if id == "" {
 		return nil, errors.New("order ID is required")
 	}
 
-	if !isValidStatus(status) {
+	if !s.workflow.machine.HasState(status) {
 		return nil, errors.New("invalid order status")
 	}
 
 	// Check if order exists
-	existingOrder, err := s.orderRepo.GetOrderByID(id)
+	existingOrder, err := s.orderRepo.GetOrderByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Validate status transition
-	if !isValidStatusTransition(existingOrder.Status, status) {
-		return nil, errors.New("invalid status transition")
+	// Customers can only touch their own orders
+	if role == models.OrderRoleCustomer && existingOrder.UserID != actorID {
+		return nil, errors.New("access denied")
+	}
+
+	return s.workflow.transition(ctx, existingOrder, status, actorID, role, reason)
+}
+
+// AssignDeliveryAgent hands an order that hasn't been delivered or cancelled
+// to a delivery agent, who can then mark it delivered or refused.
+func (s *OrderService) AssignDeliveryAgent(ctx context.Context, id, agentID string) (*models.Order, error) {
+	agent, err := s.workflow.userRepo.GetUserByID(ctx, agentID)
+	if err != nil || !agent.IsDeliveryAgent {
+		return nil, errors.New("user is not a delivery agent")
 	}
 
-	return s.orderRepo.UpdateOrderStatus(id, status)
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == models.OrderStatusDelivered || order.Status == models.OrderStatusCancelled {
+		return nil, errors.New("order is already " + order.Status)
+	}
+
+	return s.orderRepo.UpdateDeliveryAgent(ctx, id, agentID)
 }
 
-func (s *OrderService) CancelOrder(id string, userID string, isAdmin bool) (*models.Order, error) {
+func (s *OrderService) CancelOrder(ctx context.Context, id string, userID string, isAdmin bool) (*models.Order, error) {
 	if id == "" {
 		return nil, errors.New("order ID is required")
 	}
 
-	order, err := s.orderRepo.GetOrderByID(id)
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -185,50 +400,122 @@ func (s *OrderService) CancelOrder(id string, userID string, isAdmin bool) (*mod
 		return nil, errors.New("access denied")
 	}
 
-	// Can only cancel pending or confirmed orders
-	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusConfirmed {
+	role, reason := models.OrderRoleCustomer, "cancelled by customer"
+	if order.UserID != userID {
+		role, reason = models.OrderRoleAdmin, "cancelled by admin"
+	}
+	if !s.workflow.machine.Allows(order.Status, models.OrderStatusCancelled, role) {
 		return nil, errors.New("order cannot be cancelled")
 	}
 
-	return s.orderRepo.UpdateOrderStatus(id, models.OrderStatusCancelled)
+	return s.workflow.transition(ctx, order, models.OrderStatusCancelled, userID, role, reason)
 }
 
-func isValidStatus(status string) bool {
-	validStatuses := []string{
-		models.OrderStatusPending,
-		models.OrderStatusConfirmed,
-		models.OrderStatusProcessing,
-		models.OrderStatusShipped,
-		models.OrderStatusDelivered,
-		models.OrderStatusCancelled,
+// ReleaseExpiredReservations cancels pending orders whose reservation is older
+// than ttl and that have no payment in flight, returning their stock.
+func (s *OrderService) ReleaseExpiredReservations(ctx context.Context, ttl time.Duration) error {
+	orders, err := s.orderRepo.GetAllOrders(ctx, models.OrderStatusPending, 0, 0)
+	if err != nil {
+		return err
 	}
-	for _, s := range validStatuses {
-		if s == status {
-			return true
+
+	cutoff := time.Now().Add(-ttl)
+	for i := range orders {
+		order := &orders[i]
+		if order.StockStatus != models.StockStatusReserved || order.PlacedAt.After(cutoff) {
+			continue
+		}
+
+		// The customer may already have paid; leave those to the payment flow.
+		// When the reconciler runs it also owns open intents, since it asks
+		// the gateway before expiring them.
+		paymentStatus, _ := order.PaymentMetadata["payment_status"].(string)
+		if paymentStatus == models.PaymentStatusUpdated || paymentStatus == models.PaymentStatusCompleted {
+			continue
 		}
+		if paymentStatus == models.PaymentStatusCreated && s.cfg.PaymentReconcileInterval > 0 {
+			continue
+		}
+
+		if _, err := s.workflow.transition(ctx, order, models.OrderStatusCancelled, models.OrderActorSystem, models.OrderRoleSystem, "stock reservation expired"); err != nil {
+			log.Printf("Failed to expire unpaid order %s: %v", order.ID, err)
+			continue
+		}
+		log.Printf("Expired unpaid order %s and released its stock", order.ID)
 	}
-	return false
+
+	return nil
 }
 
-func isValidStatusTransition(from, to string) bool {
-	transitions := map[string][]string{
-		models.OrderStatusPending:    {models.OrderStatusConfirmed, models.OrderStatusCancelled},
-		models.OrderStatusConfirmed:  {models.OrderStatusProcessing, models.OrderStatusCancelled},
-		models.OrderStatusProcessing: {models.OrderStatusShipped, models.OrderStatusCancelled},
-		models.OrderStatusShipped:    {models.OrderStatusDelivered},
-		models.OrderStatusDelivered:  {},
-		models.OrderStatusCancelled:  {},
+// RunReservationSweeper periodically expires unpaid reservations until ctx is
+// cancelled. It blocks, so callers should start it in its own goroutine.
+func (s *OrderService) RunReservationSweeper(ctx context.Context, ttl time.Duration) {
+	interval := ttl / 2
+	if interval < time.Minute {
+		interval = time.Minute
 	}
 
-	allowedTransitions, exists := transitions[from]
-	if !exists {
-		return false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Bound each sweep so a hung upstream can't stall the next one
+		sweepCtx, cancel := context.WithTimeout(ctx, interval)
+		if err := s.ReleaseExpiredReservations(sweepCtx, ttl); err != nil {
+			log.Printf("Reservation sweep failed: %v", err)
+		}
+		cancel()
+	}
+}
+
+// reserveItems takes stock for every item, undoing earlier reservations if
+// any single item cannot be satisfied.
+func (s *OrderService) reserveItems(ctx context.Context, items []models.OrderItem) error {
+	for i, item := range items {
+		if err := s.productRepo.ReserveStock(ctx, item.ProductID, item.VariantID, item.Quantity); err != nil {
+			if err := releaseItems(ctx, s.productRepo, items[:i]); err != nil {
+				log.Printf("Failed to undo a partial reservation: %v", err)
+			}
+			if errors.Is(err, repository.ErrInsufficientStock) {
+				return errors.New("insufficient stock for product: " + item.ProductName)
+			}
+			return err
+		}
 	}
+	return nil
+}
+
+// undoReservation gives back the stock taken for an order that couldn't be
+// placed.
+func (s *OrderService) undoReservation(ctx context.Context, orderID string, items []models.OrderItem) {
+	if err := releaseItems(ctx, s.productRepo, items); err != nil {
+		log.Printf("Failed to return stock for unplaced order %s: %v", orderID, err)
+	}
+}
+
+// releaseItems returns every item's stock, carrying on past failures, and
+// reports the ones that couldn't be returned.
+func releaseItems(ctx context.Context, productRepo repository.ProductStore, items []models.OrderItem) error {
+	var errs []error
+	for _, item := range items {
+		if err := productRepo.ReleaseStock(ctx, item.ProductID, item.VariantID, item.Quantity); err != nil {
+			errs = append(errs, fmt.Errorf("release %d of product %s: %w", item.Quantity, item.ProductID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	for _, allowed := range allowedTransitions {
-		if allowed == to {
-			return true
+func findVariant(product *models.Product, variantID string) *models.ProductVariant {
+	for i := range product.Variants {
+		if product.Variants[i].ID == variantID {
+			return &product.Variants[i]
 		}
 	}
-	return false
+	return nil
 }
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+func TestReleaseStockOnceUnderCancelAndSweeper(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	order := ts.placeOrder(t, "u1", "p1", 3, "")
+	if got := ts.stockOf(t, "p1"); got != 7 {
+		t.Fatalf("stock after order = %d, want 7", got)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			ts.orderService.CancelOrder(ctx, order.ID, "u1", false)
+		}()
+		go func() {
+			defer wg.Done()
+			ts.orderService.ReleaseExpiredReservations(ctx, 0)
+		}()
+		go func() {
+			defer wg.Done()
+			ts.workflow.releaseOrderStock(ctx, order)
+		}()
+	}
+	wg.Wait()
+
+	if got := ts.stockOf(t, "p1"); got != 10 {
+		t.Fatalf("stock after competing releases = %d, want 10", got)
+	}
+	stored, _ := ts.orders.GetOrderByID(ctx, order.ID)
+	if stored.StockStatus != models.StockStatusReleased {
+		t.Fatalf("stock_status = %q, want released", stored.StockStatus)
+	}
+}
+
+func TestReleaseStockLoadsMissingItems(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	order := ts.placeOrder(t, "u1", "p1", 4, "")
+
+	// As listed without embedded lines
+	bare := *order
+	bare.Items = nil
+	ts.workflow.releaseOrderStock(context.Background(), &bare)
+
+	if got := ts.stockOf(t, "p1"); got != 10 {
+		t.Fatalf("stock = %d, want 10", got)
+	}
+}
+
+func TestVariantOrderReservesVariantStock(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	ctx := context.Background()
+	three := 3
+	if err := ts.products.ReplaceProductVariants(ctx, "p1", []models.AddProductVariant{{Name: "5 kg", PriceCents: 12000, Stock: &three}}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := ts.products.GetProductByID(ctx, "p1")
+	bag := p.Variants[0].ID
+	variantStock := func() int {
+		p, _ := ts.products.GetProductByID(ctx, "p1")
+		return *p.Variants[0].Stock
+	}
+
+	req := &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		Items:           []models.CreateOrderItem{{ProductID: "p1", VariantID: bag, Quantity: 4}},
+	}
+	if _, err := ts.orderService.CreateOrder(ctx, "u1", req); err == nil {
+		t.Fatal("ordered 4 of a variant with 3 in stock")
+	}
+	req.Items[0].Quantity = 2
+	order, err := ts.orderService.CreateOrder(ctx, "u1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variantStock() != 1 || ts.stockOf(t, "p1") != 10 {
+		t.Fatalf("variant stock %d, product stock %d; want 1 and 10", variantStock(), ts.stockOf(t, "p1"))
+	}
+
+	if _, err := ts.orderService.CancelOrder(ctx, order.ID, "u1", false); err != nil {
+		t.Fatal(err)
+	}
+	if variantStock() != 3 || ts.stockOf(t, "p1") != 10 {
+		t.Fatalf("after cancel: variant stock %d, product stock %d; want 3 and 10", variantStock(), ts.stockOf(t, "p1"))
+	}
+}
+
+func TestWalletOnlyPaysForSubscriptions(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	ctx := context.Background()
+	if _, err := ts.wallets.AdjustWalletBalance(ctx, "u1", 100000); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		PaymentMethod:   models.PaymentMethodWallet,
+		Items:           []models.CreateOrderItem{{ProductID: "p1", Quantity: 1}},
+	}
+	if _, err := ts.orderService.CreateOrder(ctx, "u1", req); err == nil {
+		t.Fatal("checkout accepted wallet payment")
+	}
+
+	order, err := ts.orderService.placeSubscriptionOrder(ctx, "u1", "o1", "2026-10-18", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.ID != "o1" || order.DeliveryDate != "2026-10-18" {
+		t.Fatalf("order %s for %q, want o1 for 2026-10-18", order.ID, order.DeliveryDate)
+	}
+	if order.Status != models.OrderStatusConfirmed || paidAmount(order) != order.TotalCents {
+		t.Fatalf("subscription order is %s with ₹%s paid, want confirmed and paid", order.Status, formatRupees(paidAmount(order)))
+	}
+}
+
+func TestGetOrdersEnteredStatus(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	ctx := context.Background()
+	a := ts.placeOrder(t, "u1", "p1", 1, models.PaymentMethodCOD)
+	b := ts.placeOrder(t, "u1", "p1", 1, models.PaymentMethodCOD)
+	c := ts.placeOrder(t, "u1", "p1", 1, models.PaymentMethodCOD)
+	if _, err := ts.orders.UpdateOrderStatus(ctx, c.ID, c.Status, models.OrderStatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	moves := []struct {
+		order *models.Order
+		hour  int
+	}{{a, 1}, {c, 2}, {b, 3}, {a, 4}}
+	for _, m := range moves {
+		err := ts.events.CreateOrderEvent(ctx, &models.OrderEvent{
+			OrderID: m.order.ID, FromStatus: models.OrderStatusShipped, ToStatus: models.OrderStatusDelivered,
+			CreatedAt: base.Add(time.Duration(m.hour) * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		status        string
+		from          time.Time
+		limit, offset int
+		want          []*models.Order
+	}{
+		{name: "latest move first, once per order", want: []*models.Order{a, b, c}},
+		{name: "current status", status: models.OrderStatusConfirmed, want: []*models.Order{a, b}},
+		{name: "window", from: base.Add(150 * time.Minute), want: []*models.Order{a, b}},
+		{name: "page", limit: 1, offset: 1, want: []*models.Order{b}},
+		{name: "past the end", offset: 3, want: []*models.Order{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.orderService.GetOrdersEnteredStatus(ctx, models.OrderStatusDelivered, tt.status, tt.from, time.Time{}, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d orders, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].ID != tt.want[i].ID {
+					t.Fatalf("order %d = %s, want %s", i, got[i].ID, tt.want[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderRefusesProductWithoutGSTRate(t *testing.T) {
+	ts := newTestServices(t)
+	ts.cfg.GSTDefaultTaxClass = ""
+	ts.addProduct(t, "p1", 3000, 10)
+
+	_, err := ts.orderService.CreateOrder(context.Background(), "u1", &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		Items:           []models.CreateOrderItem{{ProductID: "p1", Quantity: 1}},
+	})
+	if err == nil {
+		t.Fatal("placed an order for a product with no GST rate")
+	}
+	if got := ts.stockOf(t, "p1"); got != 10 {
+		t.Fatalf("stock = %d, want 10", got)
+	}
+}
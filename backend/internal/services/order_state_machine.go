@@ -0,0 +1,259 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// OrderTransition is one allowed status change: who may make it, the guards
+// that must pass first and the effects run once it has happened. Guards and
+// effects are referred to by name; see orderGuards and orderEffects.
+type OrderTransition struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Roles   []string `json:"roles"`
+	Guards  []string `json:"guards,omitempty"`
+	Effects []string `json:"effects,omitempty"`
+}
+
+// OrderStateMachineConfig is the JSON form of the order state machine, as
+// read from ORDER_STATE_MACHINE_FILE.
+type OrderStateMachineConfig struct {
+	States      []string          `json:"states"`
+	Transitions []OrderTransition `json:"transitions"`
+}
+
+// OrderStateMachine says which status changes an order may go through. It is
+// built once at startup and read-only afterwards.
+type OrderStateMachine struct {
+	states      map[string]bool
+	transitions map[string]map[string]*OrderTransition // from -> to
+}
+
+// orderGuards can stop a transition; the error is shown to whoever tried it.
+var orderGuards = map[string]func(order *models.Order) error{
+	// Only cash on delivery orders, and those with nothing to pay such as
+	// replacements, may leave before they are paid
+	"paid_or_cod": func(order *models.Order) error {
+		if paymentMethodOf(order) == models.PaymentMethodCOD || amountDue(order) == 0 ||
+			stringFromMap(order.PaymentMetadata, "payment_status") == models.PaymentStatusCompleted {
+			return nil
+		}
+		return errors.New("order has not been paid")
+	},
+	"cod_only": func(order *models.Order) error {
+		if paymentMethodOf(order) != models.PaymentMethodCOD {
+			return errors.New("order is not cash on delivery")
+		}
+		return nil
+	},
+}
+
+// orderRoles are the roles a transition may name
+var orderRoles = []string{
+	models.OrderRoleCustomer,
+	models.OrderRoleAdmin,
+	models.OrderRoleSystem,
+	models.OrderRoleDeliveryAgent,
+}
+
+// requiredOrderTransitions are the transitions the server makes itself, with
+// the effects it relies on them having: payments confirm orders, expiry and
+// failed payments cancel them and give their stock back, and an admin
+// recording a COD refusal cancels a shipped order.
+var requiredOrderTransitions = []struct {
+	from, to, role string
+	effects        []string
+}{
+	{models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderRoleSystem, []string{"commit_stock"}},
+	{models.OrderStatusPending, models.OrderStatusCancelled, models.OrderRoleSystem, []string{"release_stock"}},
+	{models.OrderStatusShipped, models.OrderStatusCancelled, models.OrderRoleAdmin, []string{"record_cod_refusal", "release_stock"}},
+}
+
+// DefaultOrderStateMachine is the flow used when no file is configured.
+// Delivery agents may only move orders assigned to them.
+func DefaultOrderStateMachine() OrderStateMachineConfig {
+	var (
+		customer = models.OrderRoleCustomer
+		admin    = models.OrderRoleAdmin
+		system   = models.OrderRoleSystem
+		agent    = models.OrderRoleDeliveryAgent
+	)
+	cancelEffects := []string{"release_stock", "refund", "notify"}
+
+	return OrderStateMachineConfig{
+		States: []string{
+			models.OrderStatusPending,
+			models.OrderStatusConfirmed,
+			models.OrderStatusProcessing,
+			models.OrderStatusShipped,
+			models.OrderStatusDelivered,
+			models.OrderStatusCancelled,
+		},
+		Transitions: []OrderTransition{
+			{From: models.OrderStatusPending, To: models.OrderStatusConfirmed, Roles: []string{admin, system}, Effects: []string{"commit_stock", "notify"}},
+			{From: models.OrderStatusPending, To: models.OrderStatusCancelled, Roles: []string{customer, admin, system}, Effects: cancelEffects},
+			{From: models.OrderStatusConfirmed, To: models.OrderStatusProcessing, Roles: []string{admin}},
+			{From: models.OrderStatusConfirmed, To: models.OrderStatusCancelled, Roles: []string{customer, admin}, Effects: cancelEffects},
+			{From: models.OrderStatusProcessing, To: models.OrderStatusShipped, Roles: []string{admin}, Guards: []string{"paid_or_cod"}, Effects: []string{"notify"}},
+			{From: models.OrderStatusProcessing, To: models.OrderStatusCancelled, Roles: []string{admin}, Effects: cancelEffects},
+			{From: models.OrderStatusShipped, To: models.OrderStatusDelivered, Roles: []string{admin, agent}, Effects: []string{"record_cod_collection", "issue_invoice", "notify"}},
+			// Refused at the door
+			{From: models.OrderStatusShipped, To: models.OrderStatusCancelled, Roles: []string{admin, agent}, Guards: []string{"cod_only"}, Effects: []string{"record_cod_refusal", "release_stock", "notify"}},
+		},
+	}
+}
+
+// LoadOrderStateMachine reads the state machine from a JSON file, or uses
+// the default one when path is empty.
+func LoadOrderStateMachine(path string) (*OrderStateMachine, error) {
+	cfg := DefaultOrderStateMachine()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read order state machine: %w", err)
+		}
+		cfg = OrderStateMachineConfig{}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parse order state machine: %w", err)
+		}
+	}
+	return NewOrderStateMachine(cfg)
+}
+
+// NewOrderStateMachine validates cfg: every state is named once, the
+// statuses and transitions the server itself uses exist, and transitions only
+// use known states, roles, guards and effects.
+func NewOrderStateMachine(cfg OrderStateMachineConfig) (*OrderStateMachine, error) {
+	m := &OrderStateMachine{
+		states:      map[string]bool{},
+		transitions: map[string]map[string]*OrderTransition{},
+	}
+
+	for _, state := range cfg.States {
+		if state == "" {
+			return nil, errors.New("order state machine: empty state name")
+		}
+		if m.states[state] {
+			return nil, fmt.Errorf("order state machine: state %q listed twice", state)
+		}
+		m.states[state] = true
+	}
+	// Orders are created in these and payments, expiry and COD move them
+	// between them, so they can't be left out
+	for _, state := range []string{
+		models.OrderStatusPending,
+		models.OrderStatusConfirmed,
+		models.OrderStatusShipped,
+		models.OrderStatusDelivered,
+		models.OrderStatusCancelled,
+	} {
+		if !m.states[state] {
+			return nil, fmt.Errorf("order state machine: missing required state %q", state)
+		}
+	}
+
+	var problems []string
+	for i := range cfg.Transitions {
+		t := cfg.Transitions[i]
+		name := fmt.Sprintf("%s -> %s", t.From, t.To)
+		if !m.states[t.From] || !m.states[t.To] {
+			problems = append(problems, name+": unknown state")
+			continue
+		}
+		if t.From == t.To {
+			problems = append(problems, name+": transition to the same state")
+			continue
+		}
+		if m.transitions[t.From][t.To] != nil {
+			problems = append(problems, name+": listed twice")
+			continue
+		}
+		if len(t.Roles) == 0 {
+			problems = append(problems, name+": no roles")
+		}
+		for _, role := range t.Roles {
+			if !slices.Contains(orderRoles, role) {
+				problems = append(problems, fmt.Sprintf("%s: unknown role %q", name, role))
+			}
+		}
+		for _, guard := range t.Guards {
+			if orderGuards[guard] == nil {
+				problems = append(problems, fmt.Sprintf("%s: unknown guard %q", name, guard))
+			}
+		}
+		for _, effect := range t.Effects {
+			if orderEffects[effect] == nil {
+				problems = append(problems, fmt.Sprintf("%s: unknown effect %q", name, effect))
+			}
+		}
+
+		if m.transitions[t.From] == nil {
+			m.transitions[t.From] = map[string]*OrderTransition{}
+		}
+		m.transitions[t.From][t.To] = &t
+	}
+	for _, req := range requiredOrderTransitions {
+		name := fmt.Sprintf("%s -> %s", req.from, req.to)
+		t := m.transitions[req.from][req.to]
+		if t == nil {
+			problems = append(problems, name+": required transition missing")
+			continue
+		}
+		if !slices.Contains(t.Roles, req.role) {
+			problems = append(problems, fmt.Sprintf("%s: role %q is required", name, req.role))
+		}
+		for _, effect := range req.effects {
+			if !slices.Contains(t.Effects, effect) {
+				problems = append(problems, fmt.Sprintf("%s: effect %q is required", name, effect))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("order state machine: %s", strings.Join(problems, "; "))
+	}
+
+	return m, nil
+}
+
+// HasState reports whether status is a state of the machine.
+func (m *OrderStateMachine) HasState(status string) bool {
+	return m.states[status]
+}
+
+// Allows reports whether role may move an order from one status to another,
+// without running guards.
+func (m *OrderStateMachine) Allows(from, to, role string) bool {
+	t := m.transitions[from][to]
+	return t != nil && slices.Contains(t.Roles, role)
+}
+
+// check returns the transition that moves order to status on behalf of actor
+// acting as role, or why it can't happen.
+func (m *OrderStateMachine) check(order *models.Order, status, actor, role string) (*OrderTransition, error) {
+	if !m.states[status] {
+		return nil, errors.New("invalid order status")
+	}
+	t := m.transitions[order.Status][status]
+	if t == nil {
+		return nil, errors.New("invalid status transition")
+	}
+	if !slices.Contains(t.Roles, role) {
+		return nil, errors.New("not allowed to make this status change")
+	}
+	if role == models.OrderRoleDeliveryAgent && order.DeliveryAgentID != actor {
+		return nil, errors.New("not allowed to make this status change")
+	}
+	for _, guard := range t.Guards {
+		if err := orderGuards[guard](order); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
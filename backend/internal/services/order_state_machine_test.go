@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+func TestOrderStateMachineCheck(t *testing.T) {
+	machine, err := NewOrderStateMachine(DefaultOrderStateMachine())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := func(status, method, paymentStatus string) *models.Order {
+		return &models.Order{
+			Status:          status,
+			TotalCents:      10000,
+			DeliveryAgentID: "a1",
+			PaymentMetadata: map[string]interface{}{
+				"payment_method": method,
+				"payment_status": paymentStatus,
+			},
+		}
+	}
+
+	unassigned := order("shipped", "cod", models.PaymentStatusCODPending)
+	unassigned.DeliveryAgentID = "a2"
+
+	tests := []struct {
+		name    string
+		order   *models.Order
+		to      string
+		role    string
+		wantErr string
+	}{
+		{"system confirms pending", order("pending", "upi", models.PaymentStatusPending), "confirmed", models.OrderRoleSystem, ""},
+		{"customer cancels pending", order("pending", "upi", models.PaymentStatusPending), "cancelled", models.OrderRoleCustomer, ""},
+		{"customer can't confirm", order("pending", "upi", models.PaymentStatusPending), "confirmed", models.OrderRoleCustomer, "not allowed to make this status change"},
+		{"customer can't cancel processing", order("processing", "upi", models.PaymentStatusCompleted), "cancelled", models.OrderRoleCustomer, "not allowed to make this status change"},
+		{"no skipping to delivered", order("confirmed", "upi", models.PaymentStatusCompleted), "delivered", models.OrderRoleAdmin, "invalid status transition"},
+		{"unknown status", order("pending", "upi", models.PaymentStatusPending), "lost", models.OrderRoleAdmin, "invalid order status"},
+		{"ship paid order", order("processing", "upi", models.PaymentStatusCompleted), "shipped", models.OrderRoleAdmin, ""},
+		{"ship cod order", order("processing", "cod", models.PaymentStatusCODPending), "shipped", models.OrderRoleAdmin, ""},
+		{"unpaid order can't ship", order("processing", "upi", models.PaymentStatusPending), "shipped", models.OrderRoleAdmin, "order has not been paid"},
+		{"agent delivers", order("shipped", "cod", models.PaymentStatusCODPending), "delivered", models.OrderRoleDeliveryAgent, ""},
+		{"cod refused at the door", order("shipped", "cod", models.PaymentStatusCODPending), "cancelled", models.OrderRoleDeliveryAgent, ""},
+		{"prepaid can't be refused", order("shipped", "upi", models.PaymentStatusCompleted), "cancelled", models.OrderRoleAdmin, "order is not cash on delivery"},
+		{"agent can't deliver another agent's order", unassigned, "delivered", models.OrderRoleDeliveryAgent, "not allowed to make this status change"},
+		{"delivered is final", order("delivered", "upi", models.PaymentStatusCompleted), "cancelled", models.OrderRoleAdmin, "invalid status transition"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := machine.check(tt.order, tt.to, "a1", tt.role)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Fatalf("check(%s -> %s as %s) error = %q, want %q", tt.order.Status, tt.to, tt.role, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewOrderStateMachineRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *OrderStateMachineConfig)
+	}{
+		{"missing required state", func(cfg *OrderStateMachineConfig) {
+			cfg.States = cfg.States[1:]
+		}},
+		{"unknown guard", func(cfg *OrderStateMachineConfig) {
+			cfg.Transitions[0].Guards = []string{"never"}
+		}},
+		{"unknown effect", func(cfg *OrderStateMachineConfig) {
+			cfg.Transitions[0].Effects = []string{"launch"}
+		}},
+		{"unknown role", func(cfg *OrderStateMachineConfig) {
+			cfg.Transitions[0].Roles = []string{"intern"}
+		}},
+		{"missing required transition", func(cfg *OrderStateMachineConfig) {
+			cfg.Transitions = slices.DeleteFunc(cfg.Transitions, func(t OrderTransition) bool {
+				return t.From == models.OrderStatusPending && t.To == models.OrderStatusCancelled
+			})
+		}},
+		{"required role removed", func(cfg *OrderStateMachineConfig) {
+			cfg.Transitions[0].Roles = []string{models.OrderRoleAdmin}
+		}},
+		{"required effect removed", func(cfg *OrderStateMachineConfig) {
+			for i, t := range cfg.Transitions {
+				if t.From == models.OrderStatusShipped && t.To == models.OrderStatusCancelled {
+					cfg.Transitions[i].Effects = []string{"notify"}
+				}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultOrderStateMachine()
+			tt.modify(&cfg)
+			if _, err := NewOrderStateMachine(cfg); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestTransitionRunsOnceWhenRacing(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 10)
+	order := ts.placeOrder(t, "u1", "p1", 2, models.PaymentMethodCOD)
+
+	// Every caller read the order before any of them wrote
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stale := *order
+			if _, err := ts.workflow.transition(ctx, &stale, models.OrderStatusCancelled, "u1", models.OrderRoleCustomer, "changed my mind"); err == nil {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if won != 1 {
+		t.Fatalf("%d transitions succeeded, want 1", won)
+	}
+	events, _ := ts.events.GetOrderEvents(ctx, order.ID)
+	cancels := 0
+	for _, e := range events {
+		if e.ToStatus == models.OrderStatusCancelled {
+			cancels++
+		}
+	}
+	if cancels != 1 {
+		t.Fatalf("%d cancellation events recorded, want 1", cancels)
+	}
+	if got := ts.stockOf(t, "p1"); got != 10 {
+		t.Fatalf("stock = %d, want 10", got)
+	}
+}
+
+func TestOnlyAssignedAgentMovesOrder(t *testing.T) {
+	ts := newTestServices(t)
+	ctx := context.Background()
+	for _, id := range []string{"a1", "a2"} {
+		if err := ts.users.CreateUser(ctx, &models.User{ID: id, Email: id + "@example.com", IsDeliveryAgent: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts.addProduct(t, "p1", 3000, 10)
+	// Cash on delivery orders are confirmed when placed
+	order := ts.placeOrder(t, "u1", "p1", 2, models.PaymentMethodCOD)
+	for _, status := range []string{models.OrderStatusProcessing, models.OrderStatusShipped} {
+		if _, err := ts.orderService.UpdateOrderStatus(ctx, order.ID, status, "admin", models.OrderRoleAdmin, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := ts.orderService.AssignDeliveryAgent(ctx, order.ID, "u1"); err == nil {
+		t.Fatal("assigned the order to a customer")
+	}
+	if _, err := ts.orderService.AssignDeliveryAgent(ctx, order.ID, "a1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.orderService.UpdateOrderStatus(ctx, order.ID, models.OrderStatusDelivered, "a2", models.OrderRoleDeliveryAgent, ""); err == nil {
+		t.Fatal("an unassigned agent delivered the order")
+	}
+	delivered, err := ts.orderService.UpdateOrderStatus(ctx, order.ID, models.OrderStatusDelivered, "a1", models.OrderRoleDeliveryAgent, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Status != models.OrderStatusDelivered {
+		t.Fatalf("status = %q, want delivered", delivered.Status)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+// OrderWorkflow is how services change an order's status. Every change is
+// checked against the state machine, appended to the order's timeline in
+// order_events and followed by the transition's effects.
+type OrderWorkflow struct {
+	machine     *OrderStateMachine
+	orderRepo   repository.OrderStore
+	eventRepo   repository.OrderEventStore
+	productRepo repository.ProductStore
+	userRepo    repository.UserStore
+	email       *EmailService
+	refunds     *RefundService
+	invoices    *InvoiceService
+}
+
+func NewOrderWorkflow(machine *OrderStateMachine, orderRepo repository.OrderStore, eventRepo repository.OrderEventStore, productRepo repository.ProductStore, userRepo repository.UserStore, email *EmailService, refunds *RefundService, invoices *InvoiceService) *OrderWorkflow {
+	return &OrderWorkflow{
+		machine:     machine,
+		orderRepo:   orderRepo,
+		eventRepo:   eventRepo,
+		productRepo: productRepo,
+		userRepo:    userRepo,
+		email:       email,
+		refunds:     refunds,
+		invoices:    invoices,
+	}
+}
+
+// orderEffects run after a transition, in the order the transition lists
+// them. The status has already changed, so they log failures rather than
+// return them.
+var orderEffects = map[string]func(w *OrderWorkflow, ctx context.Context, order *models.Order){
+	"commit_stock":          (*OrderWorkflow).commitOrderStock,
+	"release_stock":         (*OrderWorkflow).releaseOrderStock,
+	"refund":                (*OrderWorkflow).refundOrder,
+	"record_cod_collection": (*OrderWorkflow).recordCODCollection,
+	"record_cod_refusal":    (*OrderWorkflow).recordCODRefusal,
+	"issue_invoice":         (*OrderWorkflow).issueInvoice,
+	"notify":                (*OrderWorkflow).notifyCustomer,
+}
+
+// transition moves order to status on behalf of actor (a user ID or
+// models.OrderActorSystem) acting as role, records why, and runs the
+// transition's effects on the updated order. The write is conditional on the
+// status it was checked against, so when two requests race only the winner
+// records the change and runs the effects.
+func (w *OrderWorkflow) transition(ctx context.Context, order *models.Order, status, actor, role, reason string) (*models.Order, error) {
+	t, err := w.machine.check(order, status, actor, role)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := w.orderRepo.UpdateOrderStatus(ctx, order.ID, order.Status, status)
+	if err != nil {
+		return nil, err
+	}
+	w.record(ctx, order.ID, order.Status, status, actor, reason)
+
+	for _, effect := range t.Effects {
+		orderEffects[effect](w, ctx, updated)
+	}
+	return updated, nil
+}
+
+// record appends a transition to the timeline. The status change has
+// already happened, so a failure here is logged rather than returned.
+func (w *OrderWorkflow) record(ctx context.Context, orderID, from, to, actor, reason string) {
+	err := w.eventRepo.CreateOrderEvent(ctx, &models.OrderEvent{
+		ID:         uuid.New().String(),
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      actor,
+		Reason:     reason,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to record %s -> %s for order %s: %v", from, to, orderID, err)
+	}
+}
+
+// commitOrderStock turns a reservation into a sale. Orders whose stock was
+// committed when they were placed are left as they are.
+func (w *OrderWorkflow) commitOrderStock(ctx context.Context, order *models.Order) {
+	committed, err := w.orderRepo.UpdateStockStatus(ctx, order.ID, models.StockStatusReserved, models.StockStatusCommitted)
+	if err != nil {
+		log.Printf("Failed to commit stock for order %s: %v", order.ID, err)
+	} else if !committed {
+		log.Printf("Order %s was confirmed but had no active stock reservation", order.ID)
+	}
+}
+
+// releaseOrderStock returns an order's stock to inventory exactly once. The
+// stock_status compare-and-set guards against concurrent cancel/expiry.
+func (w *OrderWorkflow) releaseOrderStock(ctx context.Context, order *models.Order) {
+	// Orders loaded without their lines would flip to released and return
+	// nothing, so fetch the lines before claiming the release
+	items := order.Items
+	if len(items) == 0 {
+		var err error
+		if items, err = w.orderRepo.GetOrderItems(ctx, order.ID); err != nil {
+			log.Printf("Failed to load items to release stock for order %s: %v", order.ID, err)
+			return
+		}
+	}
+
+	for _, from := range []string{models.StockStatusReserved, models.StockStatusCommitted} {
+		swapped, err := w.orderRepo.UpdateStockStatus(ctx, order.ID, from, models.StockStatusReleased)
+		if err != nil {
+			log.Printf("Failed to release stock for order %s: %v", order.ID, err)
+			return
+		}
+		if swapped {
+			if err := releaseItems(ctx, w.productRepo, items); err != nil {
+				log.Printf("Failed to return stock for order %s: %v", order.ID, err)
+			}
+			return
+		}
+	}
+}
+
+func (w *OrderWorkflow) refundOrder(ctx context.Context, order *models.Order) {
+	w.refunds.RefundCancelledOrder(ctx, order)
+}
+
+// recordCODCollection notes the cash taken at the door once a COD order is
+// delivered.
+func (w *OrderWorkflow) recordCODCollection(ctx context.Context, order *models.Order) {
+	if paymentMethodOf(order) != models.PaymentMethodCOD {
+		return
+	}
+	pm := order.PaymentMetadata
+	pm["payment_status"] = models.PaymentStatusCODCollected
+	pm["cod_collected_at"] = time.Now().UTC().Format(time.RFC3339)
+	pm["cod_collected_cents"] = order.TotalCents
+
+	if err := w.orderRepo.UpdatePaymentMetadata(ctx, order.ID, pm); err != nil {
+		log.Printf("Failed to record cash collection for order %s: %v", order.ID, err)
+	}
+}
+
+// issueInvoice numbers the invoice of a delivered order. Replacements were
+// paid for by the original order and don't get one.
+func (w *OrderWorkflow) issueInvoice(ctx context.Context, order *models.Order) {
+	if paymentMethodOf(order) == models.PaymentMethodReplacement {
+		return
+	}
+	if _, err := w.invoices.IssueInvoice(ctx, order); err != nil {
+		log.Printf("Failed to issue invoice for order %s: %v", order.ID, err)
+	}
+}
+
+// recordCODRefusal marks a COD order as refused at the door; refusals count
+// against the user's future COD eligibility.
+func (w *OrderWorkflow) recordCODRefusal(ctx context.Context, order *models.Order) {
+	if paymentMethodOf(order) != models.PaymentMethodCOD {
+		return
+	}
+	pm := order.PaymentMetadata
+	pm["payment_status"] = models.PaymentStatusCODRefused
+	pm["cod_refused_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	if err := w.orderRepo.UpdatePaymentMetadata(ctx, order.ID, pm); err != nil {
+		log.Printf("Failed to record cash on delivery refusal for order %s: %v", order.ID, err)
+	}
+}
+
+// notifyCustomer emails the customer the order's new status. It sends in the
+// background so a slow mail server doesn't hold up the request, and does
+// nothing when SMTP isn't configured.
+func (w *OrderWorkflow) notifyCustomer(ctx context.Context, order *models.Order) {
+	if !w.email.Configured() {
+		return
+	}
+	user, err := w.userRepo.GetUserByID(ctx, order.UserID)
+	if err != nil {
+		log.Printf("Failed to look up customer for order %s: %v", order.ID, err)
+		return
+	}
+	go w.email.SendOrderStatus(user.Email, order.ID, order.Status)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+var (
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	// ErrRefundNotSupported means the gateway has no refund API; the refund
+	// has to be paid out by hand
+	ErrRefundNotSupported = errors.New("refunds are not supported by this payment gateway")
+)
+
+// Gateway statuses, normalised across providers
+const (
+	GatewayStatusPending   = "pending"
+	GatewayStatusCompleted = "completed"
+	GatewayStatusFailed    = "failed"
+)
+
+// PaymentGateway is one payment provider. The gateway chosen for an order is
+// recorded in PaymentMetadata["gateway"] so that every later call for that
+// order goes back to the same provider.
+type PaymentGateway interface {
+	Name() string
+	CreateIntent(ctx context.Context, req PaymentIntentRequest) (*PaymentIntent, error)
+	SubmitReference(ctx context.Context, gatewayOrderID, referenceNumber string) error
+	QueryStatus(ctx context.Context, gatewayOrderID string) (*GatewayStatus, error)
+	// VerifyWebhook checks the callback's signature and normalises its payload
+	VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+}
+
+// paymentGateways holds the enabled gateways by name.
+type paymentGateways map[string]PaymentGateway
+
+func newPaymentGateways(gateways []PaymentGateway) paymentGateways {
+	byName := make(paymentGateways, len(gateways))
+	for _, g := range gateways {
+		byName[g.Name()] = g
+	}
+	return byName
+}
+
+// forOrder returns the gateway an order was initiated with. Orders from
+// before gateways were recorded all went through UroPay.
+func (g paymentGateways) forOrder(order *models.Order) (PaymentGateway, error) {
+	gateway, ok := g[gatewayNameFromMetadata(order.PaymentMetadata)]
+	if !ok {
+		return nil, errors.New("unsupported payment gateway")
+	}
+	return gateway, nil
+}
+
+type PaymentIntentRequest struct {
+	OrderID       string
+	AmountPaise   int64
+	CustomerName  string
+	CustomerEmail string
+}
+
+type PaymentIntent struct {
+	GatewayOrderID string
+	UPIString      string
+	QRCode         string
+	AmountInRupees string
+	// Provider-specific fields to keep in the order's payment metadata
+	Metadata map[string]interface{}
+}
+
+type GatewayStatus struct {
+	Status      string // one of the GatewayStatus* constants
+	RawStatus   string // as reported by the provider
+	AmountPaise int64  // 0 when the provider doesn't report it
+}
+
+type WebhookEvent struct {
+	GatewayOrderID  string // empty when the provider doesn't send it
+	ReferenceNumber string
+	Amount          string // in rupees, as sent by the provider
+	Payer           string
+	SentAt          time.Time // zero unless the provider signs a timestamp
+}
+
+type RefundRequest struct {
+	OrderID        string
+	GatewayOrderID string
+	// RefundID is ours and doubles as the idempotency key at the provider
+	RefundID    string
+	AmountPaise int64
+	Reason      string
+}
+
+type RefundResult struct {
+	GatewayRefundID string
+	Status          string // one of the GatewayStatus* constants
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+const reportDateLayout = "2006-01-02"
+
+// PaymentReconciler moves payments forward without waiting for the customer
+// to poll or the gateway to call back: it asks the gateway about every
+// pending order with a payment in flight, and once a day reports where the
+// gateways and our orders disagree.
+type PaymentReconciler struct {
+	cfg        *config.Config
+	payments   *PaymentService
+	orderRepo  repository.OrderStore
+	reportRepo repository.ReconciliationReportStore
+}
+
+func NewPaymentReconciler(cfg *config.Config, payments *PaymentService, orderRepo repository.OrderStore, reportRepo repository.ReconciliationReportStore) *PaymentReconciler {
+	return &PaymentReconciler{
+		cfg:        cfg,
+		payments:   payments,
+		orderRepo:  orderRepo,
+		reportRepo: reportRepo,
+	}
+}
+
+// Reconcile checks pending orders in payment_created/payment_updated with
+// their gateway: completed payments confirm the order, while failed ones and
+// intents older than cfg.PaymentIntentTTL that are still unpaid cancel it
+// and release its stock.
+func (r *PaymentReconciler) Reconcile(ctx context.Context) (*models.ReconcileResult, error) {
+	orders, err := r.orderRepo.GetAllOrders(ctx, models.OrderStatusPending, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ReconcileResult{}
+	now := time.Now()
+	for i := range orders {
+		order := &orders[i]
+		paymentStatus := stringFromMap(order.PaymentMetadata, "payment_status")
+		if paymentStatus != models.PaymentStatusCreated && paymentStatus != models.PaymentStatusUpdated {
+			continue
+		}
+		gatewayOrderID := gatewayOrderIDFromMetadata(order.PaymentMetadata)
+		if gatewayOrderID == "" {
+			continue
+		}
+		result.Checked++
+
+		st, err := r.queryGateway(ctx, order, gatewayOrderID)
+		if err != nil {
+			// Don't expire what might have been paid
+			log.Printf("Reconcile: failed to query payment for order %s: %v", order.ID, err)
+			result.Errors++
+			continue
+		}
+
+		switch {
+		case st.Status == GatewayStatusCompleted:
+			completed, err := r.payments.completeFromGateway(ctx, gatewayNameFromMetadata(order.PaymentMetadata), order, st)
+			if err != nil {
+				log.Printf("Reconcile: failed to apply payment for order %s: %v", order.ID, err)
+			}
+			if !completed {
+				// Quarantined for review, or to be retried next run
+				result.Errors++
+				continue
+			}
+			result.Completed++
+		case st.Status == GatewayStatusFailed:
+			if err := r.expire(ctx, order, models.PaymentStatusFailed, "payment failed"); err != nil {
+				log.Printf("Reconcile: failed to cancel order %s after its payment failed: %v", order.ID, err)
+				result.Errors++
+				continue
+			}
+			result.Failed++
+		case now.Sub(paymentInitiatedAt(order)) > r.cfg.PaymentIntentTTL:
+			if err := r.expire(ctx, order, models.PaymentStatusExpired, "payment expired"); err != nil {
+				log.Printf("Reconcile: failed to expire payment for order %s: %v", order.ID, err)
+				result.Errors++
+				continue
+			}
+			result.Expired++
+		}
+	}
+
+	if result.Completed+result.Failed+result.Expired+result.Errors > 0 {
+		log.Printf("Reconciled %d payments: %d completed, %d failed, %d expired, %d errors",
+			result.Checked, result.Completed, result.Failed, result.Expired, result.Errors)
+	}
+	return result, nil
+}
+
+// Report compares gateway and order totals for payments initiated on date
+// (UTC, YYYY-MM-DD) and stores the result, replacing any earlier report for
+// that day.
+func (r *PaymentReconciler) Report(ctx context.Context, date string) (*models.ReconciliationReport, error) {
+	start, err := time.Parse(reportDateLayout, date)
+	if err != nil {
+		return nil, errors.New("invalid date, want YYYY-MM-DD")
+	}
+	end := start.AddDate(0, 0, 1)
+
+	orders, err := r.orderRepo.GetAllOrders(ctx, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ReconciliationReport{
+		Date:       date,
+		Totals:     []models.ReconciliationTotals{},
+		Mismatches: []models.ReconciliationMismatch{},
+	}
+	totals := map[string]*models.ReconciliationTotals{}
+
+	for i := range orders {
+		order := &orders[i]
+		gatewayOrderID := gatewayOrderIDFromMetadata(order.PaymentMetadata)
+		if gatewayOrderID == "" {
+			continue
+		}
+		initiated := paymentInitiatedAt(order)
+		if initiated.Before(start) || !initiated.Before(end) {
+			continue
+		}
+
+		name := gatewayNameFromMetadata(order.PaymentMetadata)
+		t, ok := totals[name]
+		if !ok {
+			t = &models.ReconciliationTotals{Gateway: name}
+			totals[name] = t
+		}
+		t.Orders++
+
+		paymentStatus := stringFromMap(order.PaymentMetadata, "payment_status")
+		paidLocally := paymentStatus == models.PaymentStatusCompleted
+		if paidLocally {
+			t.OrdersPaidCents += amountCharged(order)
+		}
+
+		mismatch := models.ReconciliationMismatch{
+			OrderID:        order.ID,
+			Gateway:        name,
+			GatewayOrderID: gatewayOrderID,
+			PaymentStatus:  paymentStatus,
+			OrderCents:     amountCharged(order),
+		}
+
+		st, err := r.queryGateway(ctx, order, gatewayOrderID)
+		if err != nil {
+			mismatch.Kind = models.MismatchUnverified
+			mismatch.Detail = err.Error()
+			report.Mismatches = append(report.Mismatches, mismatch)
+			continue
+		}
+		mismatch.GatewayStatus = st.RawStatus
+		mismatch.GatewayCents = st.AmountPaise
+
+		paidAtGateway := st.Status == GatewayStatusCompleted
+		if paidAtGateway {
+			// Gateways that don't report the amount were asked for the intent's
+			amount := st.AmountPaise
+			if amount == 0 {
+				amount, _ = parseRupees(stringFromMap(order.PaymentMetadata, "amount_in_rupees"))
+			}
+			t.GatewayCompletedCents += amount
+		}
+
+		switch {
+		case paidAtGateway && !paidLocally:
+			mismatch.Kind = models.MismatchPaidAtGatewayOnly
+		case paidLocally && !paidAtGateway:
+			mismatch.Kind = models.MismatchPaidLocallyOnly
+		case paidAtGateway && st.AmountPaise != 0 && st.AmountPaise != amountCharged(order):
+			mismatch.Kind = models.MismatchAmount
+		default:
+			continue
+		}
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+
+	for _, t := range totals {
+		report.Totals = append(report.Totals, *t)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool { return report.Totals[i].Gateway < report.Totals[j].Gateway })
+	report.CreatedAt = time.Now().UTC()
+
+	if err := r.reportRepo.SaveReconciliationReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	for _, t := range report.Totals {
+		log.Printf("Reconciliation %s %s: %d orders, gateway completed ₹%s, orders paid ₹%s",
+			date, t.Gateway, t.Orders, formatRupees(t.GatewayCompletedCents), formatRupees(t.OrdersPaidCents))
+	}
+	if len(report.Mismatches) > 0 {
+		log.Printf("Reconciliation %s: %d mismatches need review", date, len(report.Mismatches))
+	}
+	return report, nil
+}
+
+func (r *PaymentReconciler) GetReport(ctx context.Context, date string) (*models.ReconciliationReport, error) {
+	return r.reportRepo.GetReconciliationReport(ctx, date)
+}
+
+func (r *PaymentReconciler) ListReports(ctx context.Context, limit, offset int) ([]models.ReconciliationReport, error) {
+	return r.reportRepo.ListReconciliationReports(ctx, limit, offset)
+}
+
+// Run reconciles every cfg.PaymentReconcileInterval and writes the report for
+// the previous day once that day is over. It blocks until ctx is cancelled, so
+// callers should start it in its own goroutine.
+func (r *PaymentReconciler) Run(ctx context.Context) {
+	interval := r.cfg.PaymentReconcileInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastReport := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Bound each pass so a hung gateway can't stall the next one
+		passCtx, cancel := context.WithTimeout(ctx, interval)
+		if _, err := r.Reconcile(passCtx); err != nil {
+			log.Printf("Payment reconciliation failed: %v", err)
+		}
+
+		yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(reportDateLayout)
+		if yesterday != lastReport {
+			if _, err := r.reportRepo.GetReconciliationReport(passCtx, yesterday); err == nil {
+				lastReport = yesterday
+			} else if _, err := r.Report(passCtx, yesterday); err != nil {
+				log.Printf("Reconciliation report for %s failed: %v", yesterday, err)
+			} else {
+				lastReport = yesterday
+			}
+		}
+		cancel()
+	}
+}
+
+func (r *PaymentReconciler) queryGateway(ctx context.Context, order *models.Order, gatewayOrderID string) (*GatewayStatus, error) {
+	gateway, err := r.payments.gateways.forOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	return gateway.QueryStatus(ctx, gatewayOrderID)
+}
+
+// expire gives up on a payment that failed or was never made: the payment is
+// marked paymentStatus, the order cancelled for reason and its stock
+// returned. A payment that turns up later is quarantined by the webhook
+// handler as order_not_payable.
+func (r *PaymentReconciler) expire(ctx context.Context, order *models.Order, paymentStatus, reason string) error {
+	order.PaymentMetadata["payment_status"] = paymentStatus
+	if err := r.orderRepo.UpdatePaymentMetadata(ctx, order.ID, order.PaymentMetadata); err != nil {
+		return err
+	}
+	if _, err := r.payments.workflow.transition(ctx, order, models.OrderStatusCancelled, models.OrderActorSystem, models.OrderRoleSystem, reason); err != nil {
+		return err
+	}
+	log.Printf("Cancelled order %s (%s) and released its stock", order.ID, reason)
+	return nil
+}
+
+// paymentInitiatedAt is when the order's payment intent was created. Orders
+// initiated before this was recorded fall back to when they were placed.
+func paymentInitiatedAt(order *models.Order) time.Time {
+	if t, err := time.Parse(time.RFC3339, stringFromMap(order.PaymentMetadata, "initiated_at")); err == nil {
+		return t
+	}
+	return order.PlacedAt
+}
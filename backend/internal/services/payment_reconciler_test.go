@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// initiateOrder places an order for two of p1 and opens its payment intent.
+func initiateOrder(t *testing.T, ts *testServices) (*models.Order, string) {
+	t.Helper()
+	order := ts.placeOrder(t, "u1", "p1", 2, "")
+	resp, err := ts.payments.InitiatePayment(context.Background(), &models.InitiatePaymentRequest{OrderID: order.ID}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order, resp.GatewayOrderID
+}
+
+// setPaymentMetadata overwrites keys of an order's payment metadata.
+func (ts *testServices) setPaymentMetadata(t *testing.T, orderID string, values map[string]interface{}) {
+	t.Helper()
+	ctx := context.Background()
+	order, err := ts.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		order.PaymentMetadata[k] = v
+	}
+	if err := ts.orders.UpdatePaymentMetadata(ctx, orderID, order.PaymentMetadata); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name        string
+		prepare     func(t *testing.T, ts *testServices, order *models.Order, gatewayOrderID string)
+		want        models.ReconcileResult
+		wantStatus  string
+		wantPayment string
+		wantStock   int
+	}{
+		{
+			name: "completed payment confirms the order",
+			prepare: func(t *testing.T, ts *testServices, order *models.Order, id string) {
+				ts.fake.SubmitReference(context.Background(), id, "R1")
+			},
+			want:        models.ReconcileResult{Checked: 1, Completed: 1},
+			wantStatus:  models.OrderStatusConfirmed,
+			wantPayment: models.PaymentStatusCompleted,
+			wantStock:   8,
+		},
+		{
+			name: "failed payment cancels the order and releases stock",
+			prepare: func(t *testing.T, ts *testServices, order *models.Order, id string) {
+				ts.fake.SubmitReference(context.Background(), id, "FAIL1")
+			},
+			want:        models.ReconcileResult{Checked: 1, Failed: 1},
+			wantStatus:  models.OrderStatusCancelled,
+			wantPayment: models.PaymentStatusFailed,
+			wantStock:   10,
+		},
+		{
+			name: "stale intent expires the order and releases stock",
+			prepare: func(t *testing.T, ts *testServices, order *models.Order, id string) {
+				ts.setPaymentMetadata(t, order.ID, map[string]interface{}{"initiated_at": time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)})
+			},
+			want:        models.ReconcileResult{Checked: 1, Expired: 1},
+			wantStatus:  models.OrderStatusCancelled,
+			wantPayment: models.PaymentStatusExpired,
+			wantStock:   10,
+		},
+		{
+			name:        "fresh intent is left open",
+			prepare:     func(t *testing.T, ts *testServices, order *models.Order, id string) {},
+			want:        models.ReconcileResult{Checked: 1},
+			wantStatus:  models.OrderStatusPending,
+			wantPayment: models.PaymentStatusCreated,
+			wantStock:   8,
+		},
+		{
+			name: "payment for the wrong amount is quarantined, not completed",
+			prepare: func(t *testing.T, ts *testServices, order *models.Order, id string) {
+				ts.fake.SubmitReference(context.Background(), id, "R1")
+				ts.fake.orders[id].amountPaise++
+			},
+			want:        models.ReconcileResult{Checked: 1, Errors: 1},
+			wantStatus:  models.OrderStatusPending,
+			wantPayment: models.PaymentStatusCreated,
+			wantStock:   8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServices(t)
+			ts.cfg.PaymentIntentTTL = time.Hour
+			ts.addProduct(t, "p1", 3000, 10)
+			order, gatewayOrderID := initiateOrder(t, ts)
+			tt.prepare(t, ts, order, gatewayOrderID)
+
+			reconciler := NewPaymentReconciler(ts.cfg, ts.payments, ts.orders, ts.reports)
+			got, err := reconciler.Reconcile(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Fatalf("result = %+v, want %+v", *got, tt.want)
+			}
+
+			stored, _ := ts.orders.GetOrderByID(context.Background(), order.ID)
+			if stored.Status != tt.wantStatus || stringFromMap(stored.PaymentMetadata, "payment_status") != tt.wantPayment {
+				t.Fatalf("order %s with payment %s, want %s with %s", stored.Status, stringFromMap(stored.PaymentMetadata, "payment_status"), tt.wantStatus, tt.wantPayment)
+			}
+			if got := ts.stockOf(t, "p1"); got != tt.wantStock {
+				t.Fatalf("stock = %d, want %d", got, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestReconciliationReport(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 3000, 100)
+	ctx := context.Background()
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	date := yesterday.Format(reportDateLayout)
+	payByWebhook := func(order *models.Order, id string) {
+		ts.fake.SubmitReference(ctx, id, "R-"+order.ID)
+		ts.sendWebhook(t, fakeWebhookPayload{GatewayOrderID: id, ReferenceNumber: "R-" + order.ID, Amount: formatRupees(order.TotalCents)}, true)
+	}
+
+	matched, matchedID := initiateOrder(t, ts)
+	payByWebhook(matched, matchedID)
+
+	gatewayOnly, gatewayOnlyID := initiateOrder(t, ts)
+	ts.fake.SubmitReference(ctx, gatewayOnlyID, "R2")
+
+	localOnly, _ := initiateOrder(t, ts)
+	ts.setPaymentMetadata(t, localOnly.ID, map[string]interface{}{"payment_status": models.PaymentStatusCompleted})
+
+	wrongAmount, wrongAmountID := initiateOrder(t, ts)
+	payByWebhook(wrongAmount, wrongAmountID)
+	ts.fake.orders[wrongAmountID].amountPaise += 500
+
+	unverified, _ := initiateOrder(t, ts)
+	ts.setPaymentMetadata(t, unverified.ID, map[string]interface{}{"gateway_order_id": "fake_unknown"})
+
+	otherDay, otherDayID := initiateOrder(t, ts)
+	ts.fake.SubmitReference(ctx, otherDayID, "R3")
+
+	for _, order := range []*models.Order{matched, gatewayOnly, localOnly, wrongAmount, unverified} {
+		ts.setPaymentMetadata(t, order.ID, map[string]interface{}{"initiated_at": yesterday.Format(time.RFC3339)})
+	}
+	ts.setPaymentMetadata(t, otherDay.ID, map[string]interface{}{"initiated_at": yesterday.AddDate(0, 0, -1).Format(time.RFC3339)})
+
+	reconciler := NewPaymentReconciler(ts.cfg, ts.payments, ts.orders, ts.reports)
+	report, err := reconciler.Report(ctx, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[string]string{}
+	for _, m := range report.Mismatches {
+		kinds[m.OrderID] = m.Kind
+	}
+	want := map[string]string{
+		gatewayOnly.ID: models.MismatchPaidAtGatewayOnly,
+		localOnly.ID:   models.MismatchPaidLocallyOnly,
+		wrongAmount.ID: models.MismatchAmount,
+		unverified.ID:  models.MismatchUnverified,
+	}
+	if len(kinds) != len(want) {
+		t.Fatalf("mismatches = %v, want %v", kinds, want)
+	}
+	for id, kind := range want {
+		if kinds[id] != kind {
+			t.Fatalf("order %s: mismatch %q, want %q (all: %v)", id, kinds[id], kind, kinds)
+		}
+	}
+
+	total := matched.TotalCents
+	wantTotals := models.ReconciliationTotals{
+		Gateway:               "fake",
+		Orders:                5,
+		GatewayCompletedCents: total + total + total + 500, // matched, gateway only, wrong amount
+		OrdersPaidCents:       total + total + total,       // matched, local only, wrong amount
+	}
+	if len(report.Totals) != 1 || report.Totals[0] != wantTotals {
+		t.Fatalf("totals = %+v, want %+v", report.Totals, wantTotals)
+	}
+
+	if _, err := ts.reports.GetReconciliationReport(ctx, date); err != nil {
+		t.Fatalf("report was not saved: %v", err)
+	}
+	if _, err := reconciler.Report(ctx, "yesterday"); err == nil {
+		t.Fatal("Report accepted a malformed date")
+	}
+}
+
+func TestRunReconcilesAndReportsYesterday(t *testing.T) {
+	ts := newTestServices(t)
+	ts.cfg.PaymentIntentTTL = 72 * time.Hour
+	ts.cfg.PaymentReconcileInterval = 10 * time.Millisecond
+	ts.addProduct(t, "p1", 3000, 10)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	order, gatewayOrderID := initiateOrder(t, ts)
+	ts.setPaymentMetadata(t, order.ID, map[string]interface{}{"initiated_at": yesterday.Format(time.RFC3339)})
+	ts.fake.SubmitReference(context.Background(), gatewayOrderID, "R1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewPaymentReconciler(ts.cfg, ts.payments, ts.orders, ts.reports).Run(ctx)
+		close(done)
+	}()
+
+	var report *models.ReconciliationReport
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if r, err := ts.reports.GetReconciliationReport(context.Background(), yesterday.Format(reportDateLayout)); err == nil {
+			report = r
+			break
+		}
+	}
+	cancel()
+	<-done
+
+	if report == nil {
+		t.Fatal("no report was written for yesterday")
+	}
+	stored, _ := ts.orders.GetOrderByID(context.Background(), order.ID)
+	if stored.Status != models.OrderStatusConfirmed {
+		t.Fatalf("order status = %s, want %s", stored.Status, models.OrderStatusConfirmed)
+	}
+	if len(report.Totals) != 1 || report.Totals[0].Orders != 1 {
+		t.Fatalf("report totals = %+v, want one order", report.Totals)
+	}
+}
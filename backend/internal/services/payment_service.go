@@ -1,51 +1,57 @@
 package services
 
 import (
-	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"crypto/sha512"
-	"encoding/hex"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"sort"
-	"strings"
+	"math"
+	"time"
 
 	"github.com/namanjain.3009/daily_bazaar/internal/config"
 	"github.com/namanjain.3009/daily_bazaar/internal/models"
 	"github.com/namanjain.3009/daily_bazaar/internal/repository"
 )
 
-const uroPayBaseURL = "https://api.uropay.me"
-
 type PaymentService struct {
-	cfg       *config.Config
-	orderRepo *repository.OrderRepository
-	hashedSecret string
+	cfg            *config.Config
+	orderRepo      repository.OrderStore
+	lookupRepo     repository.PaymentLookupStore
+	quarantineRepo repository.WebhookQuarantineStore
+	eventRepo      repository.WebhookEventStore
+	workflow       *OrderWorkflow
+	gateways       paymentGateways
+	defaultGateway string
 }
 
-func NewPaymentService(cfg *config.Config, orderRepo *repository.OrderRepository) *PaymentService {
-	// Pre-compute SHA-512 hash of the secret
-	h := sha512.New()
-	h.Write([]byte(cfg.UroPaySecret))
-	hashed := hex.EncodeToString(h.Sum(nil))
-
+// NewPaymentService registers the given gateways; orders that don't ask for
+// one use cfg.DefaultPaymentGateway.
+func NewPaymentService(
+	cfg *config.Config,
+	orderRepo repository.OrderStore,
+	workflow *OrderWorkflow,
+	lookupRepo repository.PaymentLookupStore,
+	quarantineRepo repository.WebhookQuarantineStore,
+	eventRepo repository.WebhookEventStore,
+	gateways ...PaymentGateway,
+) *PaymentService {
 	return &PaymentService{
-		cfg:          cfg,
-		orderRepo:    orderRepo,
-		hashedSecret: hashed,
+		cfg:            cfg,
+		orderRepo:      orderRepo,
+		lookupRepo:     lookupRepo,
+		quarantineRepo: quarantineRepo,
+		eventRepo:      eventRepo,
+		workflow:       workflow,
+		gateways:       newPaymentGateways(gateways),
+		defaultGateway: cfg.DefaultPaymentGateway,
 	}
 }
 
-// InitiatePayment creates a UroPay order for an existing Daily Bazaar order.
-func (s *PaymentService) InitiatePayment(orderID, customerName, customerEmail string, userID string, amountFromFrontend float64) (*models.InitiatePaymentResponse, error) {
-	order, err := s.orderRepo.GetOrderByID(orderID)
+// InitiatePayment creates a payment intent at the order's gateway.
+func (s *PaymentService) InitiatePayment(ctx context.Context, in *models.InitiatePaymentRequest, userID string) (*models.InitiatePaymentResponse, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, in.OrderID)
 	if err != nil {
-		return nil, errors.New("order not found")
+		return nil, repository.ErrOrderNotFound
 	}
 	if order.UserID != userID {
 		return nil, errors.New("access denied")
@@ -54,143 +60,102 @@ func (s *PaymentService) InitiatePayment(orderID, customerName, customerEmail st
 		return nil, errors.New("order is not in pending status")
 	}
 
+	// The amount always comes from the order; the client's figure is only a
+	// check that it is showing the customer the right total
+	amountPaise := amountDue(order)
+	if amountPaise <= 0 {
+		return nil, errors.New("order has no amount due")
+	}
+	if in.Amount != 0 && int64(math.Round(in.Amount*100)) != amountPaise {
+		return nil, fmt.Errorf("amount does not match order total of ₹%s", formatRupees(amountPaise))
+	}
+
 	// Check if payment already initiated
 	if pm := order.PaymentMetadata; pm != nil {
-		if uroID, ok := pm["uropay_order_id"].(string); ok && uroID != "" {
+		if gatewayOrderID := gatewayOrderIDFromMetadata(pm); gatewayOrderID != "" {
+			// Intents created before amounts were checked may be for the wrong amount
+			if intentPaise, err := parseRupees(stringFromMap(pm, "amount_in_rupees")); err == nil && intentPaise != amountPaise {
+				log.Printf("Order %s has a payment intent for ₹%s but totals ₹%s", order.ID, formatRupees(intentPaise), formatRupees(amountPaise))
+				return nil, errors.New("existing payment intent does not match order total")
+			}
 			// Already initiated — return existing data
-			return &models.InitiatePaymentResponse{
-				UroPayOrderId:  uroID,
-				UPIString:      stringFromMap(pm, "upi_string"),
-				QRCode:         stringFromMap(pm, "qr_code"),
-				AmountInRupees: stringFromMap(pm, "amount_in_rupees"),
-				PaymentStatus:  stringFromMap(pm, "payment_status"),
-			}, nil
+			return paymentResponseFromMetadata(pm), nil
 		}
 	}
 
-	// UroPay expects amount in paise (e.g. 15300 for ₹153)
-	// Frontend sends amount in rupees, convert to paise
-	amountPaise := amountFromFrontend * 100
-	if amountPaise <= 0 {
-		amountPaise = float64(order.TotalCents)
-	}
-
-	// UroPay requires a non-empty customerEmail
-	if customerEmail == "" {
-		customerEmail = "customer@dailybazaar.com"
+	name := in.Gateway
+	if name == "" {
+		name = s.defaultGateway
 	}
-
-	reqBody := models.UroPayGenerateRequest{
-		VPA:             s.cfg.UroPayVPA,
-		VPAName:         s.cfg.UroPayVPAName,
-		Amount:          amountPaise,
-		MerchantOrderId: orderID,
-		CustomerName:    customerName,
-		CustomerEmail:   customerEmail,
-		TransactionNote: fmt.Sprintf("Order %s", orderID[:8]),
-		Notes: map[string]string{
-			"order_id": orderID,
-		},
+	gateway, ok := s.gateways[name]
+	if !ok {
+		return nil, errors.New("unsupported payment gateway")
 	}
 
-	bodyBytes, _ := json.Marshal(reqBody)
-	req, err := http.NewRequest("POST", uroPayBaseURL+"/order/generate", bytes.NewReader(bodyBytes))
+	intent, err := gateway.CreateIntent(ctx, PaymentIntentRequest{
+		OrderID:       in.OrderID,
+		AmountPaise:   amountPaise,
+		CustomerName:  in.CustomerName,
+		CustomerEmail: in.CustomerEmail,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-API-KEY", s.cfg.UroPayAPIKey)
-	req.Header.Set("Authorization", "Bearer "+s.hashedSecret)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("UroPay API error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	log.Printf("UroPay /order/generate response [%d]: %s", resp.StatusCode, string(respBody))
-
-	var uroResp models.UroPayGenerateResponse
-	if err := json.Unmarshal(respBody, &uroResp); err != nil {
-		return nil, fmt.Errorf("UroPay API returned %d: %s", resp.StatusCode, string(respBody))
+		return nil, err
 	}
 
-	if uroResp.Code != 200 {
-		return nil, fmt.Errorf("UroPay error (%d): %s", uroResp.Code, string(uroResp.Message))
+	// Webhooks that carry the gateway's order ID are matched through this
+	if err := s.indexPayment(ctx, gateway.Name(), models.PaymentLookupGatewayOrderID, intent.GatewayOrderID, in.OrderID); err != nil {
+		log.Printf("Failed to index %s order %s for order %s: %v", gateway.Name(), intent.GatewayOrderID, in.OrderID, err)
 	}
 
 	// Store payment metadata in order
-	paymentMeta := map[string]interface{}{
-		"gateway":          "uropay",
-		"uropay_order_id":  uroResp.Data.UroPayOrderId,
-		"upi_string":       uroResp.Data.UPIString,
-		"qr_code":          uroResp.Data.QRCode,
-		"amount_in_rupees": uroResp.Data.AmountInRupees.String(),
-		"payment_status":   models.PaymentStatusCreated,
+	paymentMeta := map[string]interface{}{}
+	for k, v := range order.PaymentMetadata {
+		paymentMeta[k] = v
 	}
+	for k, v := range intent.Metadata {
+		paymentMeta[k] = v
+	}
+	paymentMeta["gateway"] = gateway.Name()
+	paymentMeta["gateway_order_id"] = intent.GatewayOrderID
+	paymentMeta["upi_string"] = intent.UPIString
+	paymentMeta["qr_code"] = intent.QRCode
+	paymentMeta["amount_in_rupees"] = intent.AmountInRupees
+	paymentMeta["payment_status"] = models.PaymentStatusCreated
+	paymentMeta["initiated_at"] = time.Now().UTC().Format(time.RFC3339)
 
-	if err := s.orderRepo.UpdatePaymentMetadata(orderID, paymentMeta); err != nil {
-		log.Printf("Failed to update payment metadata for order %s: %v", orderID, err)
+	if err := s.orderRepo.UpdatePaymentMetadata(ctx, in.OrderID, paymentMeta); err != nil {
+		log.Printf("Failed to update payment metadata for order %s: %v", in.OrderID, err)
 	}
 
-	return &models.InitiatePaymentResponse{
-		UroPayOrderId:  uroResp.Data.UroPayOrderId,
-		UPIString:      uroResp.Data.UPIString,
-		QRCode:         uroResp.Data.QRCode,
-		AmountInRupees: uroResp.Data.AmountInRupees.String(),
-		PaymentStatus:  models.PaymentStatusCreated,
-	}, nil
+	return paymentResponseFromMetadata(paymentMeta), nil
 }
 
-// SubmitUPIReference updates the UroPay order with the customer's UPI reference number.
-func (s *PaymentService) SubmitUPIReference(orderID, referenceNumber, userID string) error {
-	order, err := s.orderRepo.GetOrderByID(orderID)
+// SubmitUPIReference passes the customer's UPI reference number to the gateway.
+func (s *PaymentService) SubmitUPIReference(ctx context.Context, orderID, referenceNumber, userID string) error {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return errors.New("order not found")
+		return repository.ErrOrderNotFound
 	}
 	if order.UserID != userID {
 		return errors.New("access denied")
 	}
 
-	uroPayOrderId := stringFromMap(order.PaymentMetadata, "uropay_order_id")
-	if uroPayOrderId == "" {
+	gatewayOrderID := gatewayOrderIDFromMetadata(order.PaymentMetadata)
+	if gatewayOrderID == "" {
 		return errors.New("payment not initiated for this order")
 	}
-
-	reqBody := models.UroPayUpdateRequest{
-		UroPayOrderId:   uroPayOrderId,
-		ReferenceNumber: referenceNumber,
-	}
-
-	bodyBytes, _ := json.Marshal(reqBody)
-	req, err := http.NewRequest("PATCH", uroPayBaseURL+"/order/update", bytes.NewReader(bodyBytes))
+	gateway, err := s.gateways.forOrder(order)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-API-KEY", s.cfg.UroPayAPIKey)
-	req.Header.Set("Authorization", "Bearer "+s.hashedSecret)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("UroPay API error: %w", err)
+	// Claim the reference first: one UPI transaction can only pay one order
+	if err := s.indexPayment(ctx, gateway.Name(), models.PaymentLookupReference, referenceNumber, orderID); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
 
-	respBody, _ := io.ReadAll(resp.Body)
-
-	var uroResp models.UroPayUpdateResponse
-	if err := json.Unmarshal(respBody, &uroResp); err != nil {
-		return fmt.Errorf("failed to parse UroPay response: %w", err)
-	}
-
-	if uroResp.Code != 200 {
-		return fmt.Errorf("UroPay error: %s", uroResp.Message)
+	if err := gateway.SubmitReference(ctx, gatewayOrderID, referenceNumber); err != nil {
+		return err
 	}
 
 	// Update payment metadata
@@ -201,50 +166,54 @@ func (s *PaymentService) SubmitUPIReference(orderID, referenceNumber, userID str
 	pm["reference_number"] = referenceNumber
 	pm["payment_status"] = models.PaymentStatusUpdated
 
-	return s.orderRepo.UpdatePaymentMetadata(orderID, pm)
+	return s.orderRepo.UpdatePaymentMetadata(ctx, orderID, pm)
 }
 
-// GetPaymentStatus checks the payment status from UroPay and locally.
-func (s *PaymentService) GetPaymentStatus(orderID, userID string, isAdmin bool) (*models.PaymentStatusResponse, error) {
-	order, err := s.orderRepo.GetOrderByID(orderID)
+// GetPaymentStatus checks the payment status at the gateway and locally.
+func (s *PaymentService) GetPaymentStatus(ctx context.Context, orderID, userID string, isAdmin bool) (*models.PaymentStatusResponse, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return nil, errors.New("order not found")
+		return nil, repository.ErrOrderNotFound
 	}
 	if !isAdmin && order.UserID != userID {
 		return nil, errors.New("access denied")
 	}
 
-	uroPayOrderId := stringFromMap(order.PaymentMetadata, "uropay_order_id")
+	gatewayOrderID := gatewayOrderIDFromMetadata(order.PaymentMetadata)
 	paymentStatus := stringFromMap(order.PaymentMetadata, "payment_status")
 
 	result := &models.PaymentStatusResponse{
-		OrderID:       orderID,
-		PaymentStatus: paymentStatus,
-		UroPayOrderId: uroPayOrderId,
-	}
-
-	// If we have a UroPay order ID, poll their status endpoint
-	if uroPayOrderId != "" && paymentStatus != models.PaymentStatusCompleted {
-		req, err := http.NewRequest("GET", uroPayBaseURL+"/order/status/"+uroPayOrderId, nil)
-		if err == nil {
-			req.Header.Set("Accept", "application/json")
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("X-API-KEY", s.cfg.UroPayAPIKey)
-
-			resp, err := http.DefaultClient.Do(req)
-			if err == nil {
-				defer resp.Body.Close()
-				respBody, _ := io.ReadAll(resp.Body)
-				var statusResp models.UroPayStatusResponse
-				if json.Unmarshal(respBody, &statusResp) == nil && statusResp.Code == 200 {
-					result.UroPayStatus = statusResp.Data.OrderStatus
-
-					// If UroPay says COMPLETED, update our order
-					if strings.EqualFold(statusResp.Data.OrderStatus, "COMPLETED") {
-						s.markPaymentCompleted(orderID, order.PaymentMetadata)
-						result.PaymentStatus = models.PaymentStatusCompleted
-					}
-				}
+		OrderID:        orderID,
+		PaymentStatus:  paymentStatus,
+		Gateway:        gatewayNameFromMetadata(order.PaymentMetadata),
+		GatewayOrderID: gatewayOrderID,
+		UroPayOrderId:  stringFromMap(order.PaymentMetadata, "uropay_order_id"),
+	}
+
+	// If the gateway knows this order, poll its status
+	if gatewayOrderID != "" && paymentStatus != models.PaymentStatusCompleted {
+		gateway, err := s.gateways.forOrder(order)
+		if err != nil {
+			return result, nil
+		}
+		st, err := gateway.QueryStatus(ctx, gatewayOrderID)
+		if err != nil {
+			log.Printf("Failed to query %s status for order %s: %v", gateway.Name(), orderID, err)
+			return result, nil
+		}
+		result.GatewayStatus = st.RawStatus
+		if gateway.Name() == "uropay" {
+			result.UroPayStatus = st.RawStatus
+		}
+
+		// If the gateway says the payment went through, update our order
+		if st.Status == GatewayStatusCompleted {
+			completed, err := s.completeFromGateway(ctx, gateway.Name(), order, st)
+			if err != nil {
+				log.Printf("Failed to apply %s payment for order %s: %v", gateway.Name(), orderID, err)
+			}
+			if completed {
+				result.PaymentStatus = models.PaymentStatusCompleted
 			}
 		}
 	}
@@ -252,88 +221,139 @@ func (s *PaymentService) GetPaymentStatus(orderID, userID string, isAdmin bool)
 	return result, nil
 }
 
-// HandleWebhook processes a UroPay webhook callback.
-func (s *PaymentService) HandleWebhook(payload models.UroPayWebhookPayload, signature, environment string) error {
-	// Verify webhook signature
-	if !s.verifyWebhookSignature(payload, signature, environment) {
-		return errors.New("invalid webhook signature")
-	}
-
-	if payload.ReferenceNumber == "" {
-		return errors.New("missing reference number")
-	}
-
-	// Find the order by reference number in payment metadata
-	// We need to search orders that have this reference number
-	orders, err := s.orderRepo.GetAllOrders("", 0, 0)
+// indexPayment records that value identifies orderID's payment at gateway.
+// Re-indexing the same order is a no-op; a value already claimed by another
+// order is an error.
+func (s *PaymentService) indexPayment(ctx context.Context, gateway, kind, value, orderID string) error {
+	err := s.lookupRepo.CreatePaymentLookup(ctx, &models.PaymentLookup{
+		Gateway:   gateway,
+		Kind:      kind,
+		Value:     value,
+		OrderID:   orderID,
+		CreatedAt: time.Now(),
+	})
+	if !errors.Is(err, repository.ErrPaymentLookupExists) {
+		return err
+	}
+
+	existing, err := s.lookupRepo.GetPaymentLookup(ctx, gateway, kind, value)
 	if err != nil {
-		return fmt.Errorf("failed to search orders: %w", err)
+		return err
 	}
-
-	for _, order := range orders {
-		refNum := stringFromMap(order.PaymentMetadata, "reference_number")
-		uroID := stringFromMap(order.PaymentMetadata, "uropay_order_id")
-		if refNum == payload.ReferenceNumber || uroID != "" {
-			// Verify amount matches (payload amount is in rupees as string)
-			s.markPaymentCompleted(order.ID, order.PaymentMetadata)
-			log.Printf("Webhook: payment completed for order %s, ref %s", order.ID, payload.ReferenceNumber)
-			return nil
+	if existing.OrderID != orderID {
+		if kind == models.PaymentLookupReference {
+			return errors.New("reference number already used for another order")
 		}
+		return fmt.Errorf("%s %s is already mapped to another order", kind, value)
 	}
-
-	log.Printf("Webhook: no matching order found for reference %s", payload.ReferenceNumber)
 	return nil
 }
 
-func (s *PaymentService) markPaymentCompleted(orderID string, pm map[string]interface{}) {
-	if pm == nil {
-		pm = map[string]interface{}{}
+// amountDue is what the customer has to pay for an order. TotalCents is the
+// persisted total after any discounts or wallet deductions, so it is the only
+// source of truth; nothing the client sends changes it.
+func amountDue(order *models.Order) int64 {
+	return order.TotalCents
+}
+
+// amountCharged is what the customer was charged at the gateway. It is
+// recorded when the payment completes, since removing items from a paid order
+// lowers TotalCents afterwards.
+func amountCharged(order *models.Order) int64 {
+	if cents, ok := centsFromMap(order.PaymentMetadata, "paid_cents"); ok {
+		return cents
 	}
-	pm["payment_status"] = models.PaymentStatusCompleted
+	return amountDue(order)
+}
 
-	if err := s.orderRepo.UpdatePaymentMetadata(orderID, pm); err != nil {
-		log.Printf("Failed to update payment metadata for order %s: %v", orderID, err)
-		return
+func gatewayNameFromMetadata(pm map[string]interface{}) string {
+	if name := stringFromMap(pm, "gateway"); name != "" {
+		return name
 	}
+	return "uropay"
+}
 
-	// Move order to confirmed status
-	if _, err := s.orderRepo.UpdateOrderStatus(orderID, models.OrderStatusConfirmed); err != nil {
-		log.Printf("Failed to confirm order %s after payment: %v", orderID, err)
+func gatewayOrderIDFromMetadata(pm map[string]interface{}) string {
+	if id := stringFromMap(pm, "gateway_order_id"); id != "" {
+		return id
 	}
+	return stringFromMap(pm, "uropay_order_id")
 }
 
-func (s *PaymentService) verifyWebhookSignature(payload models.UroPayWebhookPayload, signature, environment string) bool {
-	if s.hashedSecret == "" || signature == "" {
-		return false
+func paymentResponseFromMetadata(pm map[string]interface{}) *models.InitiatePaymentResponse {
+	return &models.InitiatePaymentResponse{
+		Gateway:        gatewayNameFromMetadata(pm),
+		GatewayOrderID: gatewayOrderIDFromMetadata(pm),
+		UroPayOrderId:  stringFromMap(pm, "uropay_order_id"),
+		UPIString:      stringFromMap(pm, "upi_string"),
+		QRCode:         stringFromMap(pm, "qr_code"),
+		AmountInRupees: stringFromMap(pm, "amount_in_rupees"),
+		PaymentStatus:  stringFromMap(pm, "payment_status"),
 	}
+}
 
-	// Build sorted data + environment
-	data := map[string]string{
-		"amount":          payload.Amount,
-		"from":            payload.From,
-		"referenceNumber": payload.ReferenceNumber,
-		"vpa":             payload.VPA,
+// completeFromGateway applies a payment the gateway reported as completed
+// when asked about order. It makes the same checks as a webhook: a payment
+// for an order that is no longer pending, or for a different amount than it
+// owes, is quarantined for an admin instead, once per order. It reports
+// whether the order is now paid.
+func (s *PaymentService) completeFromGateway(ctx context.Context, gateway string, order *models.Order, st *GatewayStatus) (bool, error) {
+	pm := order.PaymentMetadata
+	if pm == nil {
+		pm = map[string]interface{}{}
 	}
 
-	keys := make([]string, 0, len(data))
-	for k := range data {
-		keys = append(keys, k)
+	reason, detail := "", ""
+	switch {
+	case order.Status != models.OrderStatusPending:
+		reason, detail = models.QuarantineReasonOrderNotPayable, "order is "+order.Status
+	case st.AmountPaise != 0 && st.AmountPaise != amountDue(order):
+		reason = models.QuarantineReasonAmountMismatch
+		detail = fmt.Sprintf("paid ₹%s, order total is ₹%s", formatRupees(st.AmountPaise), formatRupees(amountDue(order)))
+	}
+	if reason == "" {
+		s.markPaymentCompleted(ctx, order, pm)
+		return true, nil
 	}
-	sort.Strings(keys)
 
-	sorted := make(map[string]string)
-	for _, k := range keys {
-		sorted[k] = data[k]
+	if stringFromMap(pm, "quarantine_id") != "" {
+		return false, nil
 	}
-	sorted["environment"] = environment
+	event := &WebhookEvent{
+		GatewayOrderID:  gatewayOrderIDFromMetadata(pm),
+		ReferenceNumber: stringFromMap(pm, "reference_number"),
+	}
+	if st.AmountPaise != 0 {
+		event.Amount = formatRupees(st.AmountPaise)
+	}
+	q, err := s.quarantineWebhook(ctx, gateway, "", event, nil, order.ID, reason, "status check: "+detail)
+	if err != nil {
+		return false, err
+	}
+	pm["quarantine_id"] = q.ID
+	if err := s.orderRepo.UpdatePaymentMetadata(ctx, order.ID, pm); err != nil {
+		log.Printf("Failed to note quarantined payment on order %s: %v", order.ID, err)
+	}
+	return false, nil
+}
 
-	payloadBytes, _ := json.Marshal(sorted)
+func (s *PaymentService) markPaymentCompleted(ctx context.Context, order *models.Order, pm map[string]interface{}) {
+	orderID := order.ID
+	if pm == nil {
+		pm = map[string]interface{}{}
+	}
+	pm["payment_status"] = models.PaymentStatusCompleted
+	pm["paid_cents"] = amountDue(order)
 
-	mac := hmac.New(sha256.New, []byte(s.hashedSecret))
-	mac.Write(payloadBytes)
-	expected := hex.EncodeToString(mac.Sum(nil))
+	if err := s.orderRepo.UpdatePaymentMetadata(ctx, orderID, pm); err != nil {
+		log.Printf("Failed to update payment metadata for order %s: %v", orderID, err)
+		return
+	}
 
-	return hmac.Equal([]byte(expected), []byte(signature))
+	// Move order to confirmed status; the transition commits the reserved stock
+	if _, err := s.workflow.transition(ctx, order, models.OrderStatusConfirmed, models.OrderActorSystem, models.OrderRoleSystem, "payment completed"); err != nil {
+		log.Printf("Failed to confirm order %s after payment: %v", orderID, err)
+	}
 }
 
 func stringFromMap(m map[string]interface{}, key string) string {
@@ -350,3 +370,15 @@ func stringFromMap(m map[string]interface{}, key string) string {
 	}
 	return s
 }
+
+// centsFromMap reads an amount stored in metadata, which is an int64 when set
+// in this process and a float64 once it has been through JSON.
+func centsFromMap(m map[string]interface{}, key string) (int64, bool) {
+	switch v := m[key].(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+// HandleWebhook records an inbound callback in the webhook event log and
+// processes it. Events are deduplicated per payment, so a replayed webhook is
+// logged but never applied twice.
+func (s *PaymentService) HandleWebhook(ctx context.Context, gatewayName string, header http.Header, body []byte) error {
+	event := &models.WebhookEventRecord{
+		ID:         uuid.New().String(),
+		Gateway:    gatewayName,
+		Headers:    webhookHeaders(header),
+		Body:       string(body),
+		Status:     models.WebhookEventReceived,
+		ReceivedAt: time.Now().UTC(),
+	}
+
+	if err := s.eventRepo.CreateWebhookEvent(ctx, event); err != nil {
+		// Without the record there's no replay protection; have the gateway retry
+		return err
+	}
+
+	return s.processWebhookEvent(ctx, event, true)
+}
+
+// ListWebhookEvents returns logged webhook events, newest first.
+func (s *PaymentService) ListWebhookEvents(ctx context.Context, gateway, status string, limit, offset int) ([]models.WebhookEventRecord, error) {
+	switch status {
+	case "", models.WebhookEventReceived, models.WebhookEventProcessed, models.WebhookEventQuarantined,
+		models.WebhookEventDuplicate, models.WebhookEventRejected, models.WebhookEventFailed:
+	default:
+		return nil, errors.New("invalid webhook event status")
+	}
+	return s.eventRepo.ListWebhookEvents(ctx, gateway, status, limit, offset)
+}
+
+func (s *PaymentService) GetWebhookEvent(ctx context.Context, id string) (*models.WebhookEventRecord, error) {
+	return s.eventRepo.GetWebhookEvent(ctx, id)
+}
+
+// ReprocessWebhookEvent runs a stored event through processing again, e.g.
+// after a failure or once a missing order exists. The signature is checked
+// again but the age limit is not, since the event is old by definition.
+// Applied and quarantined events can't be re-run; quarantined ones are
+// settled through the quarantine instead.
+func (s *PaymentService) ReprocessWebhookEvent(ctx context.Context, id string) (*models.WebhookEventRecord, error) {
+	event, err := s.eventRepo.GetWebhookEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Status {
+	case models.WebhookEventProcessed, models.WebhookEventQuarantined:
+		return nil, errors.New("webhook event was already applied")
+	}
+
+	if err := s.processWebhookEvent(ctx, event, false); err != nil {
+		log.Printf("Reprocessing webhook event %s failed: %v", id, err)
+	}
+
+	// The outcome, good or bad, is on the event
+	return s.eventRepo.GetWebhookEvent(ctx, id)
+}
+
+func (s *PaymentService) processWebhookEvent(ctx context.Context, rec *models.WebhookEventRecord, checkAge bool) error {
+	updates := map[string]interface{}{}
+
+	gateway, ok := s.gateways[rec.Gateway]
+	if !ok {
+		err := errors.New("unsupported payment gateway")
+		s.finishWebhookEvent(ctx, rec, models.WebhookEventRejected, err.Error(), updates)
+		return err
+	}
+
+	event, err := gateway.VerifyWebhook(webhookHeader(rec.Headers), []byte(rec.Body))
+	updates["signature_valid"] = !errors.Is(err, ErrInvalidWebhookSignature)
+	if err != nil {
+		s.finishWebhookEvent(ctx, rec, models.WebhookEventRejected, err.Error(), updates)
+		return err
+	}
+	updates["reference_number"] = event.ReferenceNumber
+
+	if event.ReferenceNumber == "" && event.GatewayOrderID == "" {
+		err := errors.New("missing reference number")
+		s.finishWebhookEvent(ctx, rec, models.WebhookEventRejected, err.Error(), updates)
+		return err
+	}
+
+	// Only gateways that sign a timestamp can be checked for freshness;
+	// for the rest, deduplication below is what stops replays
+	if maxAge := s.cfg.WebhookMaxAge; checkAge && maxAge > 0 && !event.SentAt.IsZero() {
+		if age := time.Since(event.SentAt); age > maxAge || age < -maxAge {
+			err := errors.New("stale webhook event")
+			s.finishWebhookEvent(ctx, rec, models.WebhookEventRejected, fmt.Sprintf("%v (sent %s)", err, event.SentAt.UTC().Format(time.RFC3339)), updates)
+			return err
+		}
+	}
+
+	if err := s.eventRepo.ClaimWebhookEvent(ctx, rec.ID, webhookDedupeKey(gateway.Name(), event)); err != nil {
+		if errors.Is(err, repository.ErrWebhookEventDuplicate) {
+			log.Printf("Webhook: ignoring replay of %s payment ref %s", gateway.Name(), event.ReferenceNumber)
+			s.finishWebhookEvent(ctx, rec, models.WebhookEventDuplicate, "payment was already handled by an earlier event", updates)
+			return nil
+		}
+		s.finishWebhookEvent(ctx, rec, models.WebhookEventFailed, err.Error(), updates)
+		return err
+	}
+
+	status, outcome, err := s.applyWebhookEvent(ctx, gateway.Name(), rec.ID, event, []byte(rec.Body))
+	if err != nil {
+		// Give up the claim so a retry can process the payment
+		updates["dedupe_key"] = nil
+		s.finishWebhookEvent(ctx, rec, models.WebhookEventFailed, err.Error(), updates)
+		return err
+	}
+
+	s.finishWebhookEvent(ctx, rec, status, outcome, updates)
+	return nil
+}
+
+// applyWebhookEvent matches a verified event to its order and marks it paid.
+// A valid event that can't be matched to a payable order for exactly the
+// order total is quarantined for an admin rather than rejected, so the
+// gateway stops retrying it and nothing is lost.
+func (s *PaymentService) applyWebhookEvent(ctx context.Context, gateway, eventID string, event *WebhookEvent, body []byte) (status, outcome string, err error) {
+	quarantine := func(orderID, reason, detail string) (string, string, error) {
+		if _, err := s.quarantineWebhook(ctx, gateway, eventID, event, body, orderID, reason, detail); err != nil {
+			return "", "", err
+		}
+		return models.WebhookEventQuarantined, reason + ": " + detail, nil
+	}
+
+	order, err := s.findWebhookOrder(ctx, gateway, event)
+	if err != nil {
+		if errors.Is(err, repository.ErrPaymentLookupNotFound) {
+			return quarantine("", models.QuarantineReasonUnmatched, "no order matches this payment")
+		}
+		return "", "", err
+	}
+
+	pm := order.PaymentMetadata
+	if pm == nil {
+		pm = map[string]interface{}{}
+	}
+	paidRef := stringFromMap(pm, "reference_number")
+
+	if stringFromMap(pm, "payment_status") == models.PaymentStatusCompleted {
+		if event.ReferenceNumber == "" || event.ReferenceNumber == paidRef {
+			return models.WebhookEventProcessed, "order " + order.ID + " was already paid", nil
+		}
+		// A second payment for the same order needs a refund
+		return quarantine(order.ID, models.QuarantineReasonAlreadyPaid, "order was already paid with reference "+paidRef)
+	}
+	if order.Status != models.OrderStatusPending {
+		return quarantine(order.ID, models.QuarantineReasonOrderNotPayable, "order is "+order.Status)
+	}
+
+	paidPaise, err := parseRupees(event.Amount)
+	if err != nil {
+		return quarantine(order.ID, models.QuarantineReasonInvalidAmount, err.Error())
+	}
+	if paidPaise != amountDue(order) {
+		return quarantine(order.ID, models.QuarantineReasonAmountMismatch,
+			fmt.Sprintf("paid ₹%s, order total is ₹%s", formatRupees(paidPaise), formatRupees(amountDue(order))))
+	}
+
+	if event.ReferenceNumber != "" && paidRef == "" {
+		// Matched on the gateway order ID; remember the reference too
+		pm["reference_number"] = event.ReferenceNumber
+		if err := s.indexPayment(ctx, gateway, models.PaymentLookupReference, event.ReferenceNumber, order.ID); err != nil {
+			log.Printf("Failed to index reference %s for order %s: %v", event.ReferenceNumber, order.ID, err)
+		}
+	}
+
+	s.markPaymentCompleted(ctx, order, pm)
+	log.Printf("Webhook: payment completed for order %s, ref %s", order.ID, event.ReferenceNumber)
+	return models.WebhookEventProcessed, "payment completed for order " + order.ID, nil
+}
+
+func (s *PaymentService) finishWebhookEvent(ctx context.Context, rec *models.WebhookEventRecord, status, outcome string, updates map[string]interface{}) {
+	updates["status"] = status
+	updates["outcome"] = outcome
+	updates["attempts"] = rec.Attempts + 1
+	updates["processed_at"] = time.Now().UTC()
+
+	// Record the outcome even if the gateway has hung up
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.eventRepo.UpdateWebhookEvent(ctx, rec.ID, updates); err != nil {
+		log.Printf("Failed to record outcome of webhook event %s: %v", rec.ID, err)
+	}
+}
+
+// ListQuarantinedWebhooks returns quarantined webhooks, newest first.
+func (s *PaymentService) ListQuarantinedWebhooks(ctx context.Context, status string, limit, offset int) ([]models.QuarantinedWebhook, error) {
+	switch status {
+	case "", models.QuarantineOpen, models.QuarantineResolved, models.QuarantineDismissed:
+	default:
+		return nil, errors.New("invalid quarantine status")
+	}
+	return s.quarantineRepo.ListQuarantinedWebhooks(ctx, status, limit, offset)
+}
+
+// ResolveQuarantinedWebhook settles an open quarantine entry. "apply" marks
+// the order (the request's, or the one the webhook was matched to) as paid by
+// this webhook, skipping the amount check; "dismiss" closes the entry without
+// touching any order.
+func (s *PaymentService) ResolveQuarantinedWebhook(ctx context.Context, id, adminID string, req *models.ResolveQuarantineRequest) (*models.QuarantinedWebhook, error) {
+	q, err := s.quarantineRepo.GetQuarantinedWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.Status != models.QuarantineOpen {
+		return nil, errors.New("webhook is already resolved")
+	}
+
+	updates := map[string]interface{}{
+		"resolution_note": req.Note,
+		"resolved_by":     adminID,
+		"resolved_at":     time.Now().UTC(),
+	}
+
+	switch req.Action {
+	case "dismiss":
+		updates["status"] = models.QuarantineDismissed
+
+	case "apply":
+		orderID := req.OrderID
+		if orderID == "" {
+			orderID = q.OrderID
+		}
+		if orderID == "" {
+			return nil, errors.New("order_id is required")
+		}
+
+		order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+		if err != nil {
+			return nil, repository.ErrOrderNotFound
+		}
+		if order.Status != models.OrderStatusPending {
+			return nil, errors.New("order is not awaiting payment")
+		}
+
+		pm := order.PaymentMetadata
+		if pm == nil {
+			pm = map[string]interface{}{}
+		}
+		if stringFromMap(pm, "gateway") == "" {
+			pm["gateway"] = q.Gateway
+		}
+		if q.ReferenceNumber != "" {
+			if err := s.indexPayment(ctx, q.Gateway, models.PaymentLookupReference, q.ReferenceNumber, order.ID); err != nil {
+				return nil, err
+			}
+			pm["reference_number"] = q.ReferenceNumber
+		}
+
+		s.markPaymentCompleted(ctx, order, pm)
+		updates["status"] = models.QuarantineResolved
+		updates["order_id"] = order.ID
+
+	default:
+		return nil, errors.New("action must be apply or dismiss")
+	}
+
+	return s.quarantineRepo.UpdateQuarantinedWebhook(ctx, id, updates)
+}
+
+// findWebhookOrder matches a webhook through the payment lookup index,
+// preferring the gateway's order ID and falling back to an exact match on
+// the UPI reference number.
+func (s *PaymentService) findWebhookOrder(ctx context.Context, gateway string, event *WebhookEvent) (*models.Order, error) {
+	var lookup *models.PaymentLookup
+	var err error
+
+	if event.GatewayOrderID != "" {
+		lookup, err = s.lookupRepo.GetPaymentLookup(ctx, gateway, models.PaymentLookupGatewayOrderID, event.GatewayOrderID)
+		if err != nil && !errors.Is(err, repository.ErrPaymentLookupNotFound) {
+			return nil, err
+		}
+	}
+	if lookup == nil && event.ReferenceNumber != "" {
+		lookup, err = s.lookupRepo.GetPaymentLookup(ctx, gateway, models.PaymentLookupReference, event.ReferenceNumber)
+	}
+	if lookup == nil {
+		if err == nil {
+			err = repository.ErrPaymentLookupNotFound
+		}
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, lookup.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			// The lookup points at an order that no longer exists
+			return nil, repository.ErrPaymentLookupNotFound
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *PaymentService) quarantineWebhook(ctx context.Context, gateway, eventID string, event *WebhookEvent, body []byte, orderID, reason, detail string) (*models.QuarantinedWebhook, error) {
+	q := &models.QuarantinedWebhook{
+		ID:              uuid.New().String(),
+		EventID:         eventID,
+		Gateway:         gateway,
+		ReferenceNumber: event.ReferenceNumber,
+		GatewayOrderID:  event.GatewayOrderID,
+		Amount:          event.Amount,
+		Payer:           event.Payer,
+		OrderID:         orderID,
+		Reason:          reason,
+		Detail:          detail,
+		Payload:         string(body),
+		Status:          models.QuarantineOpen,
+		ReceivedAt:      time.Now().UTC(),
+	}
+
+	if err := s.quarantineRepo.CreateQuarantinedWebhook(ctx, q); err != nil {
+		// Let the gateway retry rather than drop the payment
+		return nil, err
+	}
+
+	log.Printf("Webhook: quarantined %s payment ref %s (%s: %s)", gateway, event.ReferenceNumber, reason, detail)
+	return q, nil
+}
+
+// webhookDedupeKey identifies the payment an event is about: the UPI
+// reference where there is one, else the gateway's order ID.
+func webhookDedupeKey(gateway string, event *WebhookEvent) string {
+	if event.ReferenceNumber != "" {
+		return gateway + ":ref:" + event.ReferenceNumber
+	}
+	return gateway + ":order:" + event.GatewayOrderID
+}
+
+// webhookHeaders flattens headers for the event log, leaving out credentials.
+func webhookHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for k, v := range header {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Cookie":
+			continue
+		}
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
+
+func webhookHeader(headers map[string]string) http.Header {
+	out := make(http.Header, len(headers))
+	for k, v := range headers {
+		out.Set(k, v)
+	}
+	return out
+}
+
+// parseRupees converts a rupee amount such as "153", "153.5" or "153.00" to
+// paise.
+func parseRupees(amount string) (int64, error) {
+	amount = strings.TrimSpace(amount)
+	whole, frac, hasFrac := strings.Cut(amount, ".")
+	if whole == "" || len(frac) > 2 || (hasFrac && frac == "") {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	rupees, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	var paise uint64
+	if frac != "" {
+		if len(frac) == 1 {
+			frac += "0"
+		}
+		if paise, err = strconv.ParseUint(frac, 10, 8); err != nil {
+			return 0, fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+
+	return int64(rupees)*100 + int64(paise), nil
+}
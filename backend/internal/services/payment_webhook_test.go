@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// initiate places a UPI order and creates its fake gateway intent.
+func initiate(t *testing.T, ts *testServices) (*models.Order, string) {
+	t.Helper()
+	ts.addProduct(t, "p1", 3000, 10)
+	order := ts.placeOrder(t, "u1", "p1", 2, "")
+	resp, err := ts.payments.InitiatePayment(context.Background(), &models.InitiatePaymentRequest{OrderID: order.ID}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order, resp.GatewayOrderID
+}
+
+func (ts *testServices) sendWebhook(t *testing.T, payload fakeWebhookPayload, signed bool) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	header := http.Header{}
+	if signed {
+		header.Set("X-Fake-Signature", ts.fake.Sign(body))
+	}
+	ts.payments.HandleWebhook(context.Background(), "fake", header, body)
+}
+
+func TestWebhookOutcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		prepare    func(t *testing.T, ts *testServices, order *models.Order)
+		payload    func(gatewayOrderID string, total int64) fakeWebhookPayload
+		unsigned   bool
+		wantStatus string
+		wantReason string
+		wantPaid   bool
+	}{
+		{
+			name: "exact payment confirms the order",
+			payload: func(id string, total int64) fakeWebhookPayload {
+				return fakeWebhookPayload{GatewayOrderID: id, ReferenceNumber: "R1", Amount: formatRupees(total)}
+			},
+			wantStatus: models.WebhookEventProcessed,
+			wantPaid:   true,
+		},
+		{
+			name: "bad signature is rejected",
+			payload: func(id string, total int64) fakeWebhookPayload {
+				return fakeWebhookPayload{GatewayOrderID: id, ReferenceNumber: "R1", Amount: formatRupees(total)}
+			},
+			unsigned:   true,
+			wantStatus: models.WebhookEventRejected,
+		},
+		{
+			name: "short payment is quarantined",
+			payload: func(id string, total int64) fakeWebhookPayload {
+				return fakeWebhookPayload{GatewayOrderID: id, ReferenceNumber: "R1", Amount: formatRupees(total - 1)}
+			},
+			wantStatus: models.WebhookEventQuarantined,
+			wantReason: models.QuarantineReasonAmountMismatch,
+		},
+		{
+			name: "unparseable amount is quarantined",
+			payload: func(id string, total int64) fakeWebhookPayload {
+				return fakeWebhookPayload{GatewayOrderID: id, ReferenceNumber: "R1", Amount: "sixty"}
+			},
+			wantStatus: models.WebhookEventQuarantined,
+			wantReason: models.QuarantineReasonInvalidAmount,
+		},
+		{
+			name: "unknown payment is quarantined",
+			payload: func(_ string, total int64) fakeWebhookPayload {
+				return fakeWebhookPayload{GatewayOrderID: "fake_other", ReferenceNumber: "R9", Amount: formatRupees(total)}
+			},
+			wantStatus: models.WebhookEventQuarantined,
+			wantReason: models.QuarantineReasonUnmatched,
+		},
+		{
+			name: "payment for a cancelled order is quarantined",
+			prepare: func(t *testing.T, ts *testServices, order *models.Order) {
+				if _, err := ts.orderService.CancelOrder(context.Background(), order.ID, "u1", false); err != nil {
+					t.Fatal(err)
+				}
+			},
+			payload: func(id string, total int64) fakeWebhookPayload {
+				return fakeWebhookPayload{GatewayOrderID: id, ReferenceNumber: "R1", Amount: formatRupees(total)}
+			},
+			wantStatus: models.WebhookEventQuarantined,
+			wantReason: models.QuarantineReasonOrderNotPayable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServices(t)
+			order, gatewayOrderID := initiate(t, ts)
+			if tt.prepare != nil {
+				tt.prepare(t, ts, order)
+			}
+
+			ts.sendWebhook(t, tt.payload(gatewayOrderID, order.TotalCents), !tt.unsigned)
+
+			ctx := context.Background()
+			events, _ := ts.webhooks.ListWebhookEvents(ctx, "", "", 0, 0)
+			if len(events) != 1 || events[0].Status != tt.wantStatus {
+				t.Fatalf("events = %+v, want one %s", events, tt.wantStatus)
+			}
+
+			quarantined, _ := ts.quarantine.ListQuarantinedWebhooks(ctx, "", 0, 0)
+			if tt.wantReason == "" && len(quarantined) != 0 {
+				t.Fatalf("quarantined %+v, want none", quarantined)
+			}
+			if tt.wantReason != "" && (len(quarantined) != 1 || quarantined[0].Reason != tt.wantReason) {
+				t.Fatalf("quarantined %+v, want one %s", quarantined, tt.wantReason)
+			}
+
+			stored, _ := ts.orders.GetOrderByID(ctx, order.ID)
+			paid := stringFromMap(stored.PaymentMetadata, "payment_status") == models.PaymentStatusCompleted
+			if paid != tt.wantPaid {
+				t.Fatalf("paid = %v, want %v", paid, tt.wantPaid)
+			}
+		})
+	}
+}
+
+func TestWebhookReplayIsAppliedOnce(t *testing.T) {
+	ts := newTestServices(t)
+	order, gatewayOrderID := initiate(t, ts)
+
+	payload := fakeWebhookPayload{GatewayOrderID: gatewayOrderID, ReferenceNumber: "R1", Amount: formatRupees(order.TotalCents)}
+	for i := 0; i < 3; i++ {
+		ts.sendWebhook(t, payload, true)
+	}
+
+	ctx := context.Background()
+	processed, _ := ts.webhooks.ListWebhookEvents(ctx, "", models.WebhookEventProcessed, 0, 0)
+	duplicates, _ := ts.webhooks.ListWebhookEvents(ctx, "", models.WebhookEventDuplicate, 0, 0)
+	if len(processed) != 1 || len(duplicates) != 2 {
+		t.Fatalf("%d processed and %d duplicate events, want 1 and 2", len(processed), len(duplicates))
+	}
+
+	events, _ := ts.events.GetOrderEvents(ctx, order.ID)
+	confirmations := 0
+	for _, e := range events {
+		if e.ToStatus == models.OrderStatusConfirmed {
+			confirmations++
+		}
+	}
+	if confirmations != 1 {
+		t.Fatalf("order confirmed %d times, want 1", confirmations)
+	}
+}
+
+func TestStatusPollChecksOrderBeforeCompleting(t *testing.T) {
+	tests := []struct {
+		name       string
+		prepare    func(t *testing.T, ts *testServices, order *models.Order, gatewayOrderID string)
+		wantReason string
+	}{
+		{
+			name: "matching payment completes",
+		},
+		{
+			name: "cancelled order is quarantined",
+			prepare: func(t *testing.T, ts *testServices, order *models.Order, _ string) {
+				if _, err := ts.orderService.CancelOrder(context.Background(), order.ID, "u1", false); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantReason: models.QuarantineReasonOrderNotPayable,
+		},
+		{
+			name: "wrong amount is quarantined",
+			prepare: func(t *testing.T, ts *testServices, _ *models.Order, gatewayOrderID string) {
+				ts.fake.orders[gatewayOrderID].amountPaise = 100
+			},
+			wantReason: models.QuarantineReasonAmountMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServices(t)
+			order, gatewayOrderID := initiate(t, ts)
+			if tt.prepare != nil {
+				tt.prepare(t, ts, order, gatewayOrderID)
+			}
+			ctx := context.Background()
+			if err := ts.fake.SubmitReference(ctx, gatewayOrderID, "R1"); err != nil {
+				t.Fatal(err)
+			}
+
+			// Customers poll repeatedly; the quarantine is only raised once
+			var resp *models.PaymentStatusResponse
+			for i := 0; i < 3; i++ {
+				var err error
+				if resp, err = ts.payments.GetPaymentStatus(ctx, order.ID, "u1", false); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			quarantined, _ := ts.quarantine.ListQuarantinedWebhooks(ctx, "", 0, 0)
+			if tt.wantReason == "" {
+				if resp.PaymentStatus != models.PaymentStatusCompleted || len(quarantined) != 0 {
+					t.Fatalf("payment_status = %s with %d quarantined, want completed and none", resp.PaymentStatus, len(quarantined))
+				}
+				return
+			}
+			if resp.PaymentStatus == models.PaymentStatusCompleted {
+				t.Fatal("payment was marked completed")
+			}
+			if len(quarantined) != 1 || quarantined[0].Reason != tt.wantReason {
+				t.Fatalf("quarantined %+v, want one %s", quarantined, tt.wantReason)
+			}
+		})
+	}
+}
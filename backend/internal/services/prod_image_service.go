@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 
 	"github.com/google/uuid"
@@ -9,39 +10,39 @@ import (
 )
 
 type ProductImageService struct {
-	imageRepo   *repository.ProductImageRepository
-	productRepo *repository.ProductRepository
+	imageRepo   repository.ProductImageStore
+	productRepo repository.ProductStore
 }
 
-func NewProductImageService(imageRepo *repository.ProductImageRepository, productRepo *repository.ProductRepository) *ProductImageService {
+func NewProductImageService(imageRepo repository.ProductImageStore, productRepo repository.ProductStore) *ProductImageService {
 	return &ProductImageService{
 		imageRepo:   imageRepo,
 		productRepo: productRepo,
 	}
 }
 
-func (s *ProductImageService) GetImagesByProductID(productID string) ([]models.ProductImage, error) {
+func (s *ProductImageService) GetImagesByProductID(ctx context.Context, productID string) ([]models.ProductImage, error) {
 	if productID == "" {
 		return nil, errors.New("product ID is required")
 	}
 
 	// Verify product exists
-	_, err := s.productRepo.GetProductByID(productID)
+	_, err := s.productRepo.GetProductByID(ctx, productID)
 	if err != nil {
 		return nil, errors.New("product not found")
 	}
 
-	return s.imageRepo.GetImagesByProductID(productID)
+	return s.imageRepo.GetImagesByProductID(ctx, productID)
 }
 
-func (s *ProductImageService) GetImageByID(id string) (*models.ProductImage, error) {
+func (s *ProductImageService) GetImageByID(ctx context.Context, id string) (*models.ProductImage, error) {
 	if id == "" {
 		return nil, errors.New("image ID is required")
 	}
-	return s.imageRepo.GetImageByID(id)
+	return s.imageRepo.GetImageByID(ctx, id)
 }
 
-func (s *ProductImageService) AddImage(req *models.AddProductImage) (*models.ProductImage, error) {
+func (s *ProductImageService) AddImage(ctx context.Context, req *models.AddProductImage) (*models.ProductImage, error) {
 	if req.ProductID == "" {
 		return nil, errors.New("product ID is required")
 	}
@@ -50,7 +51,7 @@ func (s *ProductImageService) AddImage(req *models.AddProductImage) (*models.Pro
 	}
 
 	// Verify product exists
-	_, err := s.productRepo.GetProductByID(req.ProductID)
+	_, err := s.productRepo.GetProductByID(ctx, req.ProductID)
 	if err != nil {
 		return nil, errors.New("product not found")
 	}
@@ -58,7 +59,7 @@ func (s *ProductImageService) AddImage(req *models.AddProductImage) (*models.Pro
 	// Get max position if not specified
 	position := req.Position
 	if position == 0 {
-		maxPos, err := s.imageRepo.GetMaxPosition(req.ProductID)
+		maxPos, err := s.imageRepo.GetMaxPosition(ctx, req.ProductID)
 		if err == nil {
 			position = maxPos + 1
 		}
@@ -71,14 +72,14 @@ func (s *ProductImageService) AddImage(req *models.AddProductImage) (*models.Pro
 		Position:  position,
 	}
 
-	if err := s.imageRepo.CreateImage(image); err != nil {
+	if err := s.imageRepo.CreateImage(ctx, image); err != nil {
 		return nil, err
 	}
 
 	return image, nil
 }
 
-func (s *ProductImageService) AddMultipleImages(productID string, urls []string) ([]models.ProductImage, error) {
+func (s *ProductImageService) AddMultipleImages(ctx context.Context, productID string, urls []string) ([]models.ProductImage, error) {
 	if productID == "" {
 		return nil, errors.New("product ID is required")
 	}
@@ -87,13 +88,13 @@ func (s *ProductImageService) AddMultipleImages(productID string, urls []string)
 	}
 
 	// Verify product exists
-	_, err := s.productRepo.GetProductByID(productID)
+	_, err := s.productRepo.GetProductByID(ctx, productID)
 	if err != nil {
 		return nil, errors.New("product not found")
 	}
 
 	// Get current max position
-	maxPos, _ := s.imageRepo.GetMaxPosition(productID)
+	maxPos, _ := s.imageRepo.GetMaxPosition(ctx, productID)
 
 	images := make([]models.ProductImage, 0, len(urls))
 	for i, url := range urls {
@@ -112,20 +113,20 @@ func (s *ProductImageService) AddMultipleImages(productID string, urls []string)
 		return nil, errors.New("no valid image URLs provided")
 	}
 
-	if err := s.imageRepo.CreateImages(images); err != nil {
+	if err := s.imageRepo.CreateImages(ctx, images); err != nil {
 		return nil, err
 	}
 
 	return images, nil
 }
 
-func (s *ProductImageService) UpdateImage(id string, req *models.UpdateProductImage) (*models.ProductImage, error) {
+func (s *ProductImageService) UpdateImage(ctx context.Context, id string, req *models.UpdateProductImage) (*models.ProductImage, error) {
 	if id == "" {
 		return nil, errors.New("image ID is required")
 	}
 
 	// Check if image exists
-	_, err := s.imageRepo.GetImageByID(id)
+	_, err := s.imageRepo.GetImageByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -146,10 +147,10 @@ func (s *ProductImageService) UpdateImage(id string, req *models.UpdateProductIm
 		return nil, errors.New("no fields to update")
 	}
 
-	return s.imageRepo.UpdateImage(id, updates)
+	return s.imageRepo.UpdateImage(ctx, id, updates)
 }
 
-func (s *ProductImageService) ReorderImages(productID string, imageIDs []string) error {
+func (s *ProductImageService) ReorderImages(ctx context.Context, productID string, imageIDs []string) error {
 	if productID == "" {
 		return errors.New("product ID is required")
 	}
@@ -162,7 +163,7 @@ func (s *ProductImageService) ReorderImages(productID string, imageIDs []string)
 		updates := map[string]interface{}{
 			"position": i,
 		}
-		_, err := s.imageRepo.UpdateImage(imageID, updates)
+		_, err := s.imageRepo.UpdateImage(ctx, imageID, updates)
 		if err != nil {
 			return err
 		}
@@ -171,27 +172,27 @@ func (s *ProductImageService) ReorderImages(productID string, imageIDs []string)
 	return nil
 }
 
-func (s *ProductImageService) DeleteImage(id string) error {
+func (s *ProductImageService) DeleteImage(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("image ID is required")
 	}
-	return s.imageRepo.DeleteImage(id)
+	return s.imageRepo.DeleteImage(ctx, id)
 }
 
-func (s *ProductImageService) DeleteAllProductImages(productID string) error {
+func (s *ProductImageService) DeleteAllProductImages(ctx context.Context, productID string) error {
 	if productID == "" {
 		return errors.New("product ID is required")
 	}
-	return s.imageRepo.DeleteImagesByProductID(productID)
+	return s.imageRepo.DeleteImagesByProductID(ctx, productID)
 }
 
-func (s *ProductImageService) SetPrimaryImage(productID, imageID string) error {
+func (s *ProductImageService) SetPrimaryImage(ctx context.Context, productID, imageID string) error {
 	if productID == "" || imageID == "" {
 		return errors.New("product ID and image ID are required")
 	}
 
 	// Get all images for the product
-	images, err := s.imageRepo.GetImagesByProductID(productID)
+	images, err := s.imageRepo.GetImagesByProductID(ctx, productID)
 	if err != nil {
 		return err
 	}
@@ -204,5 +205,5 @@ func (s *ProductImageService) SetPrimaryImage(productID, imageID string) error {
 		}
 	}
 
-	return s.ReorderImages(productID, newOrder)
+	return s.ReorderImages(ctx, productID, newOrder)
 }
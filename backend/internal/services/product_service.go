@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -11,11 +12,11 @@ import (
 )
 
 type ProductService struct {
-	productRepo  *repository.ProductRepository
-	categoryRepo *repository.CategoryRepository
+	productRepo  repository.ProductStore
+	categoryRepo repository.CategoryStore
 }
 
-func NewProductService(productRepo *repository.ProductRepository, categoryRepo *repository.CategoryRepository) *ProductService {
+func NewProductService(productRepo repository.ProductStore, categoryRepo repository.CategoryStore) *ProductService {
 	return &ProductService{
 		productRepo:  productRepo,
 		categoryRepo: categoryRepo,
@@ -23,7 +24,7 @@ func NewProductService(productRepo *repository.ProductRepository, categoryRepo *
 }
 
 // CreateProduct: transactional create with categories
-func (s *ProductService) CreateProduct(req *models.AddProduct) (*models.Product, error) {
+func (s *ProductService) CreateProduct(ctx context.Context, req *models.AddProduct) (*models.Product, error) {
 	// Validation
 	if req.Name == "" {
 		return nil, errors.New("product name is required")
@@ -34,9 +35,12 @@ func (s *ProductService) CreateProduct(req *models.AddProduct) (*models.Product,
 	if len(req.CategoryIDs) == 0 {
 		return nil, errors.New("at least one category is required")
 	}
+	if err := validateTaxData(req.HSNCode, req.TaxClass); err != nil {
+		return nil, err
+	}
 
 	// Validate all categories exist
-	if err := s.validateCategories(req.CategoryIDs); err != nil {
+	if err := s.validateCategories(ctx, req.CategoryIDs); err != nil {
 		return nil, err
 	}
 
@@ -53,46 +57,50 @@ func (s *ProductService) CreateProduct(req *models.AddProduct) (*models.Product,
 		Metadata:    req.Metadata,
 		MRPCents:    req.MRPCents,
 		Weight:      req.Weight,
+
+		HSNCode:          req.HSNCode,
+		TaxClass:         req.TaxClass,
+		PriceIncludesTax: req.PriceIncludesTax,
 	}
 
 	// Step 1: Insert product
-	if err := s.productRepo.CreateProduct(product); err != nil {
+	if err := s.productRepo.CreateProduct(ctx, product); err != nil {
 		return nil, err
 	}
 
 	// Step 2: Link categories (if this fails, manual rollback needed)
-	if err := s.productRepo.LinkProductCategories(product.ID, req.CategoryIDs); err != nil {
+	if err := s.productRepo.LinkProductCategories(ctx, product.ID, req.CategoryIDs); err != nil {
 		// Attempt rollback
-		s.productRepo.DeleteProduct(product.ID)
+		s.productRepo.DeleteProduct(ctx, product.ID)
 		return nil, errors.New("failed to link categories: " + err.Error())
 	}
 
 	// Step 3: Insert Variants
 	if len(req.Variants) > 0 {
-		if err := s.productRepo.ReplaceProductVariants(product.ID, req.Variants); err != nil {
+		if err := s.productRepo.ReplaceProductVariants(ctx, product.ID, req.Variants); err != nil {
 			return nil, errors.New("failed to add variants: " + err.Error())
 		}
 	}
 
 	// Step 4: Insert Images
 	if len(req.Images) > 0 {
-		if err := s.productRepo.ReplaceProductImages(product.ID, req.Images); err != nil {
+		if err := s.productRepo.ReplaceProductImages(ctx, product.ID, req.Images); err != nil {
 			return nil, errors.New("failed to add images: " + err.Error())
 		}
 	}
 
 	// Step 5: Fetch full product with categories, variants, images
-	return s.productRepo.GetProductByID(product.ID)
+	return s.productRepo.GetProductByID(ctx, product.ID)
 }
 
 // UpdateProduct: transactional update with optional category replacement
-func (s *ProductService) UpdateProduct(id string, req *models.UpdateProduct) (*models.Product, error) {
+func (s *ProductService) UpdateProduct(ctx context.Context, id string, req *models.UpdateProduct) (*models.Product, error) {
 	if id == "" {
 		return nil, errors.New("product ID is required")
 	}
 
 	// Check if product exists
-	_, err := s.productRepo.GetProductByID(id)
+	_, err := s.productRepo.GetProductByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -130,10 +138,27 @@ func (s *ProductService) UpdateProduct(id string, req *models.UpdateProduct) (*m
 	if req.Weight != nil {
 		updates["weight"] = *req.Weight
 	}
+	if req.HSNCode != nil || req.TaxClass != nil {
+		var hsnCode, taxClass string
+		if req.HSNCode != nil {
+			hsnCode = *req.HSNCode
+			updates["hsn_code"] = hsnCode
+		}
+		if req.TaxClass != nil {
+			taxClass = *req.TaxClass
+			updates["tax_class"] = taxClass
+		}
+		if err := validateTaxData(hsnCode, taxClass); err != nil {
+			return nil, err
+		}
+	}
+	if req.PriceIncludesTax != nil {
+		updates["price_includes_tax"] = *req.PriceIncludesTax
+	}
 
 	// Update product fields if any
 	if len(updates) > 0 {
-		if _, err := s.productRepo.UpdateProduct(id, updates); err != nil {
+		if _, err := s.productRepo.UpdateProduct(ctx, id, updates); err != nil {
 			return nil, err
 		}
 	}
@@ -141,24 +166,24 @@ func (s *ProductService) UpdateProduct(id string, req *models.UpdateProduct) (*m
 	// Handle category replacement if provided
 	if len(req.CategoryIDs) > 0 {
 		// Validate categories
-		if err := s.validateCategories(req.CategoryIDs); err != nil {
+		if err := s.validateCategories(ctx, req.CategoryIDs); err != nil {
 			return nil, err
 		}
 
 		// Step 1: Delete old mappings
-		if err := s.productRepo.UnlinkAllProductCategories(id); err != nil {
+		if err := s.productRepo.UnlinkAllProductCategories(ctx, id); err != nil {
 			return nil, errors.New("failed to unlink old categories: " + err.Error())
 		}
 
 		// Step 2: Insert new mappings
-		if err := s.productRepo.LinkProductCategories(id, req.CategoryIDs); err != nil {
+		if err := s.productRepo.LinkProductCategories(ctx, id, req.CategoryIDs); err != nil {
 			return nil, errors.New("failed to link new categories: " + err.Error())
 		}
 	}
 
 	// Handle variants replacement if provided (nil means no update, empty slice means clear all)
 	if req.Variants != nil {
-		if err := s.productRepo.ReplaceProductVariants(id, req.Variants); err != nil {
+		if err := s.productRepo.ReplaceProductVariants(ctx, id, req.Variants); err != nil {
 			return nil, err
 		}
 	}
@@ -166,55 +191,55 @@ func (s *ProductService) UpdateProduct(id string, req *models.UpdateProduct) (*m
 	// Handle images replacement if provided
 	if req.Images != nil {
 		// Set ProductID involved in images just in case, though repo handles it
-		if err := s.productRepo.ReplaceProductImages(id, req.Images); err != nil {
+		if err := s.productRepo.ReplaceProductImages(ctx, id, req.Images); err != nil {
 			return nil, err
 		}
 	}
 
 	// Return updated product with categories
-	return s.productRepo.GetProductByID(id)
+	return s.productRepo.GetProductByID(ctx, id)
 }
 
 // GetAllProducts with optional category filter
-func (s *ProductService) GetAllProducts(params *models.ProductSearchParams) ([]models.Product, error) {
-	return s.productRepo.GetAllProducts(params)
+func (s *ProductService) GetAllProducts(ctx context.Context, params *models.ProductSearchParams) ([]models.Product, error) {
+	return s.productRepo.GetAllProducts(ctx, params)
 }
 
 // GetProductsByCategorySQL uses raw SQL via RPC to get products by category
-func (s *ProductService) GetProductsByCategorySQL(categoryID string, limit, offset int) ([]models.Product, error) {
+func (s *ProductService) GetProductsByCategorySQL(ctx context.Context, categoryID string, limit, offset int) ([]models.Product, error) {
 	if categoryID == "" {
 		return nil, errors.New("category ID is required")
 	}
 	if limit <= 0 {
 		limit = 10 // default limit
 	}
-	return s.productRepo.GetProductsByCategorySQL(categoryID, limit, offset)
+	return s.productRepo.GetProductsByCategorySQL(ctx, categoryID, limit, offset)
 }
 
-func (s *ProductService) GetProductByID(id string) (*models.Product, error) {
+func (s *ProductService) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
 	if id == "" {
 		return nil, errors.New("product ID is required")
 	}
-	return s.productRepo.GetProductByID(id)
+	return s.productRepo.GetProductByID(ctx, id)
 }
 
-func (s *ProductService) SearchProducts(query string) ([]models.Product, error) {
-	return s.SearchProductsWithPagination(query, 50, 0)
+func (s *ProductService) SearchProducts(ctx context.Context, query string) ([]models.Product, error) {
+	return s.SearchProductsWithPagination(ctx, query, 50, 0)
 }
 
 // SearchProductsWithPagination searches products with optional limit and offset.
-func (s *ProductService) SearchProductsWithPagination(query string, limit, offset int) ([]models.Product, error) {
+func (s *ProductService) SearchProductsWithPagination(ctx context.Context, query string, limit, offset int) ([]models.Product, error) {
 	if query == "" {
 		return nil, errors.New("search query is required")
 	}
 	if limit <= 0 {
 		limit = 50
 	}
-	return s.productRepo.SearchProductsWithLimit(query, limit, offset)
+	return s.productRepo.SearchProductsWithLimit(ctx, query, limit, offset)
 }
 
 // GetSearchSuggestions returns fuzzy-matched product name suggestions for autocomplete.
-func (s *ProductService) GetSearchSuggestions(query string, limit int) ([]string, error) {
+func (s *ProductService) GetSearchSuggestions(ctx context.Context, query string, limit int) ([]string, error) {
 	if query == "" {
 		return nil, errors.New("search query is required")
 	}
@@ -223,7 +248,7 @@ func (s *ProductService) GetSearchSuggestions(query string, limit int) ([]string
 	}
 
 	// Get all product names for fuzzy matching
-	names, err := s.productRepo.GetAllProductNames()
+	names, err := s.productRepo.GetAllProductNames(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -234,20 +259,20 @@ func (s *ProductService) GetSearchSuggestions(query string, limit int) ([]string
 }
 
 // GetAllProductNames returns all active product names for indexing.
-func (s *ProductService) GetAllProductNames() ([]string, error) {
-	return s.productRepo.GetAllProductNames()
+func (s *ProductService) GetAllProductNames(ctx context.Context) ([]string, error) {
+	return s.productRepo.GetAllProductNames(ctx)
 }
 
-func (s *ProductService) DeleteProduct(id string) error {
+func (s *ProductService) DeleteProduct(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("product ID is required")
 	}
 	// Cascade will auto-delete product_categories
-	return s.productRepo.DeleteProduct(id)
+	return s.productRepo.DeleteProduct(ctx, id)
 }
 
 // validateCategories checks if all category IDs exist
-func (s *ProductService) validateCategories(categoryIDs []string) error {
+func (s *ProductService) validateCategories(ctx context.Context, categoryIDs []string) error {
 	if len(categoryIDs) == 0 {
 		return errors.New("category IDs cannot be empty")
 	}
@@ -258,7 +283,7 @@ func (s *ProductService) validateCategories(categoryIDs []string) error {
 		}
 
 		// Check if category exists
-		if _, err := s.categoryRepo.GetCategoryByID(catID); err != nil {
+		if _, err := s.categoryRepo.GetCategoryByID(ctx, catID); err != nil {
 			return errors.New("category not found: " + catID)
 		}
 	}
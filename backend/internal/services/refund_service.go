@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+// RefundService returns money for paid orders. Wallet-paid orders are
+// refunded to the wallet straight away. Other refunds go through the order's
+// gateway when it has a refund API; otherwise (UroPay, cash on delivery) they
+// wait for an admin to make a UPI payout and record its reference.
+type RefundService struct {
+	refundRepo repository.RefundStore
+	orderRepo  repository.OrderStore
+	walletRepo repository.WalletStore
+	gateways   paymentGateways
+
+	// Serialises refund creation so concurrent partial refunds can't add up
+	// to more than was paid
+	mu sync.Mutex
+}
+
+func NewRefundService(refundRepo repository.RefundStore, orderRepo repository.OrderStore, walletRepo repository.WalletStore, gateways ...PaymentGateway) *RefundService {
+	return &RefundService{
+		refundRepo: refundRepo,
+		orderRepo:  orderRepo,
+		walletRepo: walletRepo,
+		gateways:   newPaymentGateways(gateways),
+	}
+}
+
+// CreateRefund refunds amountCents of an order (0 = everything still
+// refundable) and, for gateway refunds, sends it straight away.
+func (s *RefundService) CreateRefund(ctx context.Context, orderID string, amountCents int64, reason, requestedBy string) (*models.Refund, error) {
+	if amountCents < 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+
+	s.mu.Lock()
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	refundable, err := s.refundable(ctx, order)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if refundable <= 0 {
+		s.mu.Unlock()
+		return nil, errors.New("nothing to refund for this order")
+	}
+	if amountCents == 0 {
+		amountCents = refundable
+	}
+	if amountCents > refundable {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("refund exceeds refundable amount of ₹%s", formatRupees(refundable))
+	}
+
+	now := time.Now().UTC()
+	refund := &models.Refund{
+		ID:          uuid.New().String(),
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		AmountCents: amountCents,
+		Reason:      reason,
+		Status:      models.RefundRequested,
+		Method:      models.RefundMethodManual,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	switch paymentMethodOf(order) {
+	case models.PaymentMethodCOD:
+	case models.PaymentMethodWallet:
+		refund.Method = models.RefundMethodWallet
+	default:
+		refund.Gateway = gatewayNameFromMetadata(order.PaymentMetadata)
+		refund.Method = models.RefundMethodGateway
+	}
+
+	err = s.refundRepo.CreateRefund(ctx, refund)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	switch refund.Method {
+	case models.RefundMethodGateway:
+		return s.sendToGateway(ctx, refund, order)
+	case models.RefundMethodWallet:
+		return s.refundToWallet(ctx, refund)
+	}
+	return refund, nil
+}
+
+// RefundCancelledOrder refunds whatever is left of a paid order that has been
+// cancelled. Orders that were never paid are left alone.
+func (s *RefundService) RefundCancelledOrder(ctx context.Context, order *models.Order) {
+	if paidAmount(order) <= 0 {
+		return
+	}
+	refund, err := s.CreateRefund(ctx, order.ID, 0, "order cancelled", "system")
+	if err != nil {
+		log.Printf("Failed to refund cancelled order %s: %v", order.ID, err)
+		return
+	}
+	log.Printf("Created %s refund %s of ₹%s for cancelled order %s", refund.Method, refund.ID, formatRupees(refund.AmountCents), order.ID)
+}
+
+func (s *RefundService) GetRefundsForOrder(ctx context.Context, orderID, userID string, isAdmin bool) ([]models.Refund, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && order.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+	return s.refundRepo.GetRefundsByOrderID(ctx, orderID)
+}
+
+func (s *RefundService) ListRefunds(ctx context.Context, status string, limit, offset int) ([]models.Refund, error) {
+	switch status {
+	case "", models.RefundRequested, models.RefundProcessing, models.RefundCompleted, models.RefundFailed:
+	default:
+		return nil, errors.New("invalid refund status")
+	}
+	return s.refundRepo.ListRefunds(ctx, status, limit, offset)
+}
+
+// CompleteRefund records a settled refund. Manual refunds need the UPI
+// reference of the payout; gateway refunds stuck in processing can be
+// confirmed once the gateway shows them settled.
+func (s *RefundService) CompleteRefund(ctx context.Context, id, adminID, payoutReference string) (*models.Refund, error) {
+	refund, err := s.refundRepo.GetRefundByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status == models.RefundCompleted {
+		return nil, errors.New("refund is already completed")
+	}
+	if refund.Method == models.RefundMethodManual && payoutReference == "" {
+		return nil, errors.New("payout_reference is required for manual refunds")
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"status":         models.RefundCompleted,
+		"processed_by":   adminID,
+		"failure_reason": "",
+		"updated_at":     now,
+		"completed_at":   now,
+	}
+	if payoutReference != "" {
+		updates["payout_reference"] = payoutReference
+	}
+	return s.refundRepo.UpdateRefund(ctx, id, updates)
+}
+
+// FailRefund marks a refund as failed, e.g. a payout that bounced. The
+// amount becomes refundable again.
+func (s *RefundService) FailRefund(ctx context.Context, id, adminID, reason string) (*models.Refund, error) {
+	refund, err := s.refundRepo.GetRefundByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status == models.RefundCompleted || refund.Status == models.RefundFailed {
+		return nil, errors.New("refund is already " + refund.Status)
+	}
+
+	return s.refundRepo.UpdateRefund(ctx, id, map[string]interface{}{
+		"status":         models.RefundFailed,
+		"failure_reason": reason,
+		"processed_by":   adminID,
+		"updated_at":     time.Now().UTC(),
+	})
+}
+
+// RetryRefund sends a failed gateway or wallet refund again. It is claimed
+// (failed -> processing) before anything is sent, so concurrent retries
+// can't both send it. Gateways get the refund ID as their idempotency key
+// and wallet credits are keyed by it, so a refund that did go through the
+// first time isn't paid twice.
+func (s *RefundService) RetryRefund(ctx context.Context, id string) (*models.Refund, error) {
+	s.mu.Lock()
+	refund, err := s.refundRepo.GetRefundByID(ctx, id)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if (refund.Method != models.RefundMethodGateway && refund.Method != models.RefundMethodWallet) || refund.Status != models.RefundFailed {
+		s.mu.Unlock()
+		return nil, errors.New("only failed gateway or wallet refunds can be retried")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, refund.OrderID)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	// The failed amount was released; make sure it's still owed
+	refundable, err := s.refundable(ctx, order)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if refund.AmountCents > refundable {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("refund exceeds refundable amount of ₹%s", formatRupees(refundable))
+	}
+
+	claimed, err := s.refundRepo.ClaimRefund(ctx, refund.ID, models.RefundFailed, models.RefundProcessing)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New("refund is already being retried")
+	}
+
+	if refund.Method == models.RefundMethodWallet {
+		return s.refundToWallet(ctx, refund)
+	}
+	return s.sendToGateway(ctx, refund, order)
+}
+
+// refundToWallet credits the refund back to the customer's wallet.
+func (s *RefundService) refundToWallet(ctx context.Context, refund *models.Refund) (*models.Refund, error) {
+	updates := map[string]interface{}{"updated_at": time.Now().UTC()}
+
+	if err := creditWalletRefund(ctx, s.walletRepo, refund); err != nil {
+		log.Printf("Wallet refund %s for order %s failed: %v", refund.ID, refund.OrderID, err)
+		updates["status"] = models.RefundFailed
+		updates["failure_reason"] = err.Error()
+	} else {
+		updates["status"] = models.RefundCompleted
+		updates["failure_reason"] = ""
+		updates["completed_at"] = time.Now().UTC()
+	}
+
+	return s.refundRepo.UpdateRefund(ctx, refund.ID, updates)
+}
+
+// sendToGateway asks the order's gateway for the refund. A gateway without a
+// refund API turns it into a manual refund.
+func (s *RefundService) sendToGateway(ctx context.Context, refund *models.Refund, order *models.Order) (*models.Refund, error) {
+	updates := map[string]interface{}{"updated_at": time.Now().UTC()}
+
+	gateway, err := s.gateways.forOrder(order)
+	if err == nil {
+		var result *RefundResult
+		result, err = gateway.Refund(ctx, RefundRequest{
+			OrderID:        order.ID,
+			GatewayOrderID: gatewayOrderIDFromMetadata(order.PaymentMetadata),
+			RefundID:       refund.ID,
+			AmountPaise:    refund.AmountCents,
+			Reason:         refund.Reason,
+		})
+		if err == nil {
+			updates["gateway_refund_id"] = result.GatewayRefundID
+			updates["failure_reason"] = ""
+			switch result.Status {
+			case GatewayStatusCompleted:
+				updates["status"] = models.RefundCompleted
+				updates["completed_at"] = time.Now().UTC()
+			case GatewayStatusFailed:
+				updates["status"] = models.RefundFailed
+				updates["failure_reason"] = "declined by gateway"
+			default:
+				updates["status"] = models.RefundProcessing
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrRefundNotSupported):
+		updates["method"] = models.RefundMethodManual
+		updates["status"] = models.RefundRequested
+	case err != nil:
+		log.Printf("Gateway refund %s for order %s failed: %v", refund.ID, order.ID, err)
+		updates["status"] = models.RefundFailed
+		updates["failure_reason"] = err.Error()
+	}
+
+	return s.refundRepo.UpdateRefund(ctx, refund.ID, updates)
+}
+
+// refundable is what was paid minus every refund that hasn't failed.
+func (s *RefundService) refundable(ctx context.Context, order *models.Order) (int64, error) {
+	paid := paidAmount(order)
+	if paid <= 0 {
+		return 0, nil
+	}
+
+	refunds, err := s.refundRepo.GetRefundsByOrderID(ctx, order.ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range refunds {
+		if r.Status != models.RefundFailed {
+			paid -= r.AmountCents
+		}
+	}
+	return paid, nil
+}
+
+// paidAmount is how much the customer has actually paid for an order.
+func paidAmount(order *models.Order) int64 {
+	switch stringFromMap(order.PaymentMetadata, "payment_status") {
+	case models.PaymentStatusCompleted:
+		return amountCharged(order)
+	case models.PaymentStatusCODCollected:
+		if cents, ok := centsFromMap(order.PaymentMetadata, "cod_collected_cents"); ok {
+			return cents
+		}
+		return order.TotalCents
+	}
+	return 0
+}
+
+func paymentMethodOf(order *models.Order) string {
+	if method := stringFromMap(order.PaymentMetadata, "payment_method"); method != "" {
+		return method
+	}
+	return models.PaymentMethodUPI
+}
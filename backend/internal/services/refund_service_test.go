@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// failedWalletRefund stores a paid wallet order of ₹50 with a failed refund
+// for all of it.
+func failedWalletRefund(t *testing.T, ts *testServices) *models.Refund {
+	t.Helper()
+	ctx := context.Background()
+	order := &models.Order{
+		ID:         "o1",
+		UserID:     "u1",
+		Status:     models.OrderStatusCancelled,
+		TotalCents: 5000,
+		PaymentMetadata: map[string]interface{}{
+			"payment_method": models.PaymentMethodWallet,
+			"payment_status": models.PaymentStatusCompleted,
+			"paid_cents":     5000,
+		},
+	}
+	if err := ts.orders.CreateOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	refund := &models.Refund{
+		ID:          "r1",
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		AmountCents: 5000,
+		Status:      models.RefundFailed,
+		Method:      models.RefundMethodWallet,
+		CreatedAt:   time.Now(),
+	}
+	if err := ts.refunds.CreateRefund(ctx, refund); err != nil {
+		t.Fatal(err)
+	}
+	return refund
+}
+
+func TestRetryRefundCreditsWalletOnce(t *testing.T) {
+	ts := newTestServices(t)
+	refund := failedWalletRefund(t, ts)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts.refundSvc.RetryRefund(ctx, refund.ID)
+		}()
+	}
+	wg.Wait()
+
+	wallet, _ := ts.wallets.GetWallet(ctx, "u1")
+	if wallet.BalanceCents != 5000 {
+		t.Fatalf("balance = %d, want 5000", wallet.BalanceCents)
+	}
+	stored, _ := ts.refunds.GetRefundByID(ctx, refund.ID)
+	if stored.Status != models.RefundCompleted {
+		t.Fatalf("refund status = %s, want completed", stored.Status)
+	}
+}
+
+func TestRetryRefundSkipsCreditAlreadyMade(t *testing.T) {
+	ts := newTestServices(t)
+	refund := failedWalletRefund(t, ts)
+
+	// The credit went in but marking the refund completed didn't
+	ctx := context.Background()
+	if err := creditWalletRefund(ctx, ts.wallets, refund); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ts.refundSvc.RetryRefund(ctx, refund.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.RefundCompleted {
+		t.Fatalf("refund status = %s, want completed", got.Status)
+	}
+	wallet, _ := ts.wallets.GetWallet(ctx, "u1")
+	if wallet.BalanceCents != 5000 {
+		t.Fatalf("balance = %d, want 5000", wallet.BalanceCents)
+	}
+	txns, _ := ts.wallets.GetWalletTransactions(ctx, "u1", 0, 0)
+	if len(txns) != 1 || txns[0].BalanceAfterCents != 5000 {
+		t.Fatalf("ledger = %+v, want one credit leaving 5000", txns)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// maxSubstitutes is how many same-category products are suggested for an
+// item that can't be reordered
+const maxSubstitutes = 3
+
+// Reorder repeats one of the user's past orders at today's prices, into
+// their cart or as a new order. Items that are gone, inactive or out of
+// stock are skipped, with substitutes suggested (and added in their place
+// when asked for); items with less stock than last time are added as far as
+// stock goes. Into the cart, each line is added on its own: one the cart
+// refuses is reported as skipped and the rest are still added.
+func (s *CartService) Reorder(ctx context.Context, orderID, userID string, req *models.ReorderRequest) (*models.ReorderResponse, error) {
+	target := req.Target
+	switch target {
+	case "":
+		target = models.ReorderToCart
+	case models.ReorderToCart, models.ReorderToOrder:
+	default:
+		return nil, errors.New("invalid reorder target")
+	}
+
+	order, err := s.orderService.GetOrderByID(ctx, orderID, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.ReorderResponse{
+		OriginalOrderID: order.ID,
+		Target:          target,
+		Lines:           make([]models.ReorderLine, 0, len(order.Items)),
+	}
+	// Products already in the order make poor substitutes for each other
+	inOrder := map[string]bool{}
+	for _, item := range order.Items {
+		inOrder[item.ProductID] = true
+	}
+
+	var items []models.CreateOrderItem
+	var itemLines []int // index in resp.Lines of each of items
+	for _, item := range order.Items {
+		line := s.reorderLine(ctx, item, inOrder, req.UseSubstitutes)
+		resp.Lines = append(resp.Lines, line)
+		resp.OriginalItemsCents += item.UnitPriceCents * int64(item.Quantity)
+		if line.Quantity == 0 {
+			continue
+		}
+
+		add := models.CreateOrderItem{ProductID: line.ProductID, VariantID: line.VariantID, Quantity: line.Quantity}
+		if line.Substitute != nil {
+			add = models.CreateOrderItem{ProductID: line.Substitute.ProductID, Quantity: line.Quantity}
+		}
+		items = append(items, add)
+		itemLines = append(itemLines, len(resp.Lines)-1)
+		resp.ItemsCents += line.UnitPriceCents * int64(line.Quantity)
+	}
+
+	if len(items) == 0 {
+		return resp, nil
+	}
+
+	if target == models.ReorderToCart {
+		owner := models.CartOwner{UserID: userID}
+		for i, item := range items {
+			cart, err := s.AddItem(ctx, owner, &models.AddCartItemRequest{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+			})
+			if err != nil {
+				line := &resp.Lines[itemLines[i]]
+				log.Printf("Failed to add %s to cart for reorder of %s: %v", item.ProductID, order.ID, err)
+				resp.ItemsCents -= line.UnitPriceCents * int64(line.Quantity)
+				line.Status = models.ReorderLineSkipped
+				line.Reason = "could not be added to cart: " + err.Error()
+				line.Quantity, line.UnitPriceCents, line.PriceChangeCents = 0, 0, 0
+				line.Substitute = nil
+				continue
+			}
+			resp.Cart = cart
+		}
+		return resp, nil
+	}
+
+	createReq := &models.CreateOrderRequest{
+		ShippingAddress: req.ShippingAddress,
+		PaymentMethod:   req.PaymentMethod,
+		PaymentMetadata: req.PaymentMetadata,
+		DeliverySlot:    req.DeliverySlot,
+		Items:           items,
+	}
+	if createReq.ShippingAddress == nil {
+		createReq.ShippingAddress = order.ShippingAddress
+	}
+	if createReq.PaymentMethod == "" {
+		// Replacements weren't paid for and the wallet only pays for
+		// subscriptions; a repeat of those pays the default way
+		switch method := paymentMethodOf(order); method {
+		case models.PaymentMethodReplacement, models.PaymentMethodWallet:
+		default:
+			createReq.PaymentMethod = method
+		}
+	}
+	if createReq.DeliverySlot == "" && s.orderService.shipping.HasSlot(order.DeliverySlot) {
+		createReq.DeliverySlot = order.DeliverySlot
+	}
+
+	resp.Order, err = s.orderService.CreateOrder(ctx, userID, createReq)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Order %s placed as a repeat of %s", resp.Order.ID, order.ID)
+	return resp, nil
+}
+
+// reorderLine works out how much of an original order line can be added
+// again and at what price. Substitutes are never taken from exclude.
+func (s *CartService) reorderLine(ctx context.Context, item models.OrderItem, exclude map[string]bool, useSubstitutes bool) models.ReorderLine {
+	line := models.ReorderLine{
+		ProductID:              item.ProductID,
+		VariantID:              item.VariantID,
+		ProductName:            item.ProductName,
+		VariantName:            item.VariantName,
+		Status:                 models.ReorderLineSkipped,
+		OriginalQuantity:       item.Quantity,
+		OriginalUnitPriceCents: item.UnitPriceCents,
+	}
+
+	product, err := s.productRepo.GetProductByID(ctx, item.ProductID)
+	if err != nil {
+		line.Reason = "product no longer exists"
+		return line
+	}
+
+	price, err := priceFor(product, item.VariantID)
+	stock := stockFor(product, item.VariantID)
+	switch {
+	case !product.Active:
+		line.Reason = "product is no longer available"
+	case err != nil:
+		line.Reason = "selected variant is no longer available"
+	case stock <= 0:
+		line.Reason = "out of stock"
+	default:
+		line.Status = models.ReorderLineAdded
+		line.Quantity = min(item.Quantity, stock)
+		line.UnitPriceCents = price
+		line.PriceChangeCents = price - item.UnitPriceCents
+		if line.Quantity < item.Quantity {
+			line.Status = models.ReorderLineReduced
+			line.Reason = fmt.Sprintf("only %d left in stock", stock)
+		}
+		return line
+	}
+
+	line.Suggestions = s.substitutesFor(ctx, product, item.UnitPriceCents, exclude)
+	if useSubstitutes && len(line.Suggestions) > 0 {
+		sub := line.Suggestions[0]
+		line.Status = models.ReorderLineSubstituted
+		line.Substitute = &sub
+		line.Quantity = min(item.Quantity, sub.Stock)
+		line.UnitPriceCents = sub.UnitPriceCents
+		line.PriceChangeCents = sub.UnitPriceCents - item.UnitPriceCents
+	}
+	return line
+}
+
+// substitutesFor suggests active, in-stock products from product's
+// categories, closest in price to what was paid first.
+func (s *CartService) substitutesFor(ctx context.Context, product *models.Product, priceCents int64, exclude map[string]bool) []models.ReorderSubstitute {
+	seen := map[string]bool{product.ID: true}
+	for id := range exclude {
+		seen[id] = true
+	}
+	var candidates []models.Product
+	for _, category := range product.Categories {
+		products, err := s.productRepo.GetProductsByCategorySQL(ctx, category.ID, 50, 0)
+		if err != nil {
+			log.Printf("Failed to load substitutes for %s from category %s: %v", product.ID, category.ID, err)
+			continue
+		}
+		for _, p := range products {
+			if seen[p.ID] || !p.Active || p.Stock <= 0 {
+				continue
+			}
+			seen[p.ID] = true
+			candidates = append(candidates, p)
+		}
+	}
+
+	distance := func(p models.Product) int64 {
+		d := p.PriceCents - priceCents
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distance(candidates[i]) < distance(candidates[j])
+	})
+
+	out := make([]models.ReorderSubstitute, 0, maxSubstitutes)
+	for _, p := range candidates {
+		if len(out) == maxSubstitutes {
+			break
+		}
+		sub := models.ReorderSubstitute{
+			ProductID:      p.ID,
+			ProductName:    p.Name,
+			UnitPriceCents: p.PriceCents,
+			Stock:          p.Stock,
+		}
+		if len(p.Images) > 0 {
+			sub.ProductImage = p.Images[0].URL
+		}
+		out = append(out, sub)
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository/memory"
+)
+
+// refusingCarts fails to add one product, the way a write can fail halfway
+// through a reorder.
+type refusingCarts struct {
+	*memory.CartRepository
+	productID string
+}
+
+func (r refusingCarts) AddCartItem(ctx context.Context, item *models.CartItem) error {
+	if item.ProductID == r.productID {
+		return errors.New("cart write failed")
+	}
+	return r.CartRepository.AddCartItem(ctx, item)
+}
+
+func TestReorderToCartReportsLinesItCouldNotAdd(t *testing.T) {
+	ts := newTestServices(t)
+	ts.addProduct(t, "p1", 2000, 10)
+	ts.addProduct(t, "p2", 3000, 10)
+	ctx := context.Background()
+
+	order, err := ts.orderService.CreateOrder(ctx, "u1", &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		PaymentMethod:   models.PaymentMethodCOD,
+		Items:           []models.CreateOrderItem{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carts := NewCartService(ts.cfg, refusingCarts{ts.carts, "p2"}, ts.products, ts.orderService)
+	resp, err := carts.Reorder(ctx, order.ID, "u1", &models.ReorderRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := map[string]string{}
+	for _, line := range resp.Lines {
+		statuses[line.ProductID] = line.Status
+	}
+	if statuses["p1"] != models.ReorderLineAdded || statuses["p2"] != models.ReorderLineSkipped {
+		t.Fatalf("line statuses = %v, want p1 added and p2 skipped", statuses)
+	}
+	if resp.ItemsCents != 2000 {
+		t.Fatalf("items = %d cents, want 2000", resp.ItemsCents)
+	}
+	if resp.Cart == nil || len(resp.Cart.Items) != 1 || resp.Cart.Items[0].ProductID != "p1" {
+		t.Fatalf("cart = %+v, want just p1", resp.Cart)
+	}
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+const maxReturnPhotos = 5
+
+// ReturnService handles damaged or wrong items after delivery. Customers
+// open a return for some of an order's items; once an admin approves it the
+// customer gets a refund or a free replacement order, and the items can be
+// put back on sale.
+type ReturnService struct {
+	cfg          *config.Config
+	returnRepo   repository.ReturnStore
+	orderRepo    repository.OrderStore
+	productRepo  repository.ProductStore
+	categoryRepo repository.CategoryStore
+	orders       *OrderService
+	refunds      *RefundService
+
+	// Serialises creating and deciding returns so an item can't be returned
+	// twice over, or a return approved twice
+	mu sync.Mutex
+}
+
+func NewReturnService(cfg *config.Config, returnRepo repository.ReturnStore, orderRepo repository.OrderStore, productRepo repository.ProductStore, categoryRepo repository.CategoryStore, orders *OrderService, refunds *RefundService) *ReturnService {
+	return &ReturnService{
+		cfg:          cfg,
+		returnRepo:   returnRepo,
+		orderRepo:    orderRepo,
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		orders:       orders,
+		refunds:      refunds,
+	}
+}
+
+// CreateReturn opens a return for items of a delivered order. Each item must
+// still be inside its category's return window and can't be returned more
+// times than it was bought.
+func (s *ReturnService) CreateReturn(ctx context.Context, orderID, userID string, req *models.CreateReturnRequest) (*models.ReturnRequest, error) {
+	resolution := req.Resolution
+	switch resolution {
+	case "":
+		resolution = models.ReturnResolutionRefund
+	case models.ReturnResolutionRefund, models.ReturnResolutionReplacement:
+	default:
+		return nil, errors.New("invalid resolution")
+	}
+	if req.Reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("return must contain at least one item")
+	}
+	if len(req.PhotoURLs) > maxReturnPhotos {
+		return nil, fmt.Errorf("at most %d photos can be attached", maxReturnPhotos)
+	}
+	for _, u := range req.PhotoURLs {
+		if u == "" || !isValidURL(u) {
+			return nil, errors.New("invalid photo URL")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+	if order.Status != models.OrderStatusDelivered {
+		return nil, errors.New("only delivered orders can be returned")
+	}
+	deliveredAt := s.deliveredAt(ctx, order)
+
+	returned, err := s.returnedQuantities(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.ReturnItem, 0, len(req.Items))
+	seen := map[string]bool{}
+	for _, ri := range req.Items {
+		item := findOrderItem(order, ri.OrderItemID)
+		if item == nil {
+			return nil, errors.New("order item not found: " + ri.OrderItemID)
+		}
+		if seen[item.ID] {
+			return nil, errors.New("item listed twice: " + item.ProductName)
+		}
+		seen[item.ID] = true
+
+		if ri.Quantity <= 0 {
+			return nil, errors.New("item quantity must be positive")
+		}
+		if left := item.Quantity - returned[item.ID]; ri.Quantity > left {
+			return nil, fmt.Errorf("only %d of %s can still be returned", left, item.ProductName)
+		}
+
+		days := s.returnWindowDays(ctx, item.ProductID)
+		if days == 0 {
+			return nil, errors.New("item cannot be returned: " + item.ProductName)
+		}
+		if time.Since(deliveredAt) > time.Duration(days)*24*time.Hour {
+			return nil, errors.New("return window has closed for: " + item.ProductName)
+		}
+
+		items = append(items, models.ReturnItem{
+			OrderItemID:    item.ID,
+			ProductID:      item.ProductID,
+			ProductName:    item.ProductName,
+			VariantID:      item.VariantID,
+			Quantity:       ri.Quantity,
+			UnitPriceCents: item.UnitPriceCents,
+		})
+	}
+
+	now := time.Now().UTC()
+	ret := &models.ReturnRequest{
+		ID:         uuid.New().String(),
+		OrderID:    order.ID,
+		UserID:     order.UserID,
+		Items:      items,
+		Reason:     req.Reason,
+		PhotoURLs:  req.PhotoURLs,
+		Resolution: resolution,
+		Status:     models.ReturnRequested,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.returnRepo.CreateReturn(ctx, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) GetReturn(ctx context.Context, id, userID string, isAdmin bool) (*models.ReturnRequest, error) {
+	ret, err := s.returnRepo.GetReturnByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && ret.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) GetReturnsForOrder(ctx context.Context, orderID, userID string, isAdmin bool) ([]models.ReturnRequest, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && order.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+	return s.returnRepo.GetReturnsByOrderID(ctx, orderID)
+}
+
+func (s *ReturnService) ListReturns(ctx context.Context, status string, limit, offset int) ([]models.ReturnRequest, error) {
+	switch status {
+	case "", models.ReturnRequested, models.ReturnApproved, models.ReturnRejected:
+	default:
+		return nil, errors.New("invalid return status")
+	}
+	return s.returnRepo.ListReturns(ctx, status, limit, offset)
+}
+
+// ApproveReturn accepts a return: the customer is refunded the items' share
+// of what they paid, or sent a free replacement order. With restock the
+// returned items go back on sale.
+func (s *ReturnService) ApproveReturn(ctx context.Context, id, adminID string, req *models.ApproveReturnRequest) (*models.ReturnRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret, err := s.returnRepo.GetReturnByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != models.ReturnRequested {
+		return nil, errors.New("return has already been decided")
+	}
+	order, err := s.orderRepo.GetOrderByID(ctx, ret.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"status":     models.ReturnApproved,
+		"admin_note": req.Note,
+		"decided_by": adminID,
+		"decided_at": now,
+		"updated_at": now,
+	}
+
+	switch ret.Resolution {
+	case models.ReturnResolutionReplacement:
+		replacement, err := s.orders.createReplacementOrder(ctx, order, ret, adminID)
+		if err != nil {
+			return nil, err
+		}
+		updates["replacement_order_id"] = replacement.ID
+	default:
+		refund, err := s.refunds.CreateRefund(ctx, order.ID, returnRefundCents(order, ret), "return "+ret.ID, adminID)
+		if err != nil {
+			return nil, err
+		}
+		updates["refund_id"] = refund.ID
+	}
+
+	if req.Restock {
+		for _, item := range ret.Items {
+			if err := s.productRepo.ReleaseStock(ctx, item.ProductID, item.VariantID, item.Quantity); err != nil {
+				log.Printf("Failed to restock %d of product %s from return %s: %v", item.Quantity, item.ProductID, ret.ID, err)
+			}
+		}
+		updates["restocked"] = true
+	}
+
+	return s.returnRepo.UpdateReturn(ctx, id, updates)
+}
+
+func (s *ReturnService) RejectReturn(ctx context.Context, id, adminID, note string) (*models.ReturnRequest, error) {
+	if note == "" {
+		return nil, errors.New("note is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret, err := s.returnRepo.GetReturnByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != models.ReturnRequested {
+		return nil, errors.New("return has already been decided")
+	}
+
+	now := time.Now().UTC()
+	return s.returnRepo.UpdateReturn(ctx, id, map[string]interface{}{
+		"status":     models.ReturnRejected,
+		"admin_note": note,
+		"decided_by": adminID,
+		"decided_at": now,
+		"updated_at": now,
+	})
+}
+
+// returnedQuantities is how much of each order item is already in a return
+// that hasn't been rejected.
+func (s *ReturnService) returnedQuantities(ctx context.Context, orderID string) (map[string]int, error) {
+	returns, err := s.returnRepo.GetReturnsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]int{}
+	for _, ret := range returns {
+		if ret.Status == models.ReturnRejected {
+			continue
+		}
+		for _, item := range ret.Items {
+			out[item.OrderItemID] += item.Quantity
+		}
+	}
+	return out, nil
+}
+
+// returnWindowDays is how long a product can be returned after delivery. A
+// product in several categories gets the longest window any of them sets.
+func (s *ReturnService) returnWindowDays(ctx context.Context, productID string) int {
+	product, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		return s.cfg.ReturnWindowDays
+	}
+
+	days := -1
+	for _, pc := range product.Categories {
+		category, err := s.categoryRepo.GetCategoryByID(ctx, pc.ID)
+		if err != nil || category.ReturnWindowDays == nil {
+			continue
+		}
+		days = max(days, *category.ReturnWindowDays)
+	}
+	if days < 0 {
+		return s.cfg.ReturnWindowDays
+	}
+	return days
+}
+
+// deliveredAt is when the order last moved to delivered. Orders delivered
+// before the timeline was kept fall back to when they were placed.
+func (s *ReturnService) deliveredAt(ctx context.Context, order *models.Order) time.Time {
+	events, err := s.orders.eventRepo.GetOrderEvents(ctx, order.ID)
+	if err != nil {
+		log.Printf("Failed to load timeline for order %s: %v", order.ID, err)
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ToStatus == models.OrderStatusDelivered {
+			return events[i].CreatedAt
+		}
+	}
+	return order.PlacedAt
+}
+
+// returnRefundCents is what the returned items cost: their share of each
+// line's taxable value and GST. Shipping isn't refunded.
+func returnRefundCents(order *models.Order, ret *models.ReturnRequest) int64 {
+	var refundCents int64
+	for _, ri := range ret.Items {
+		item := findOrderItem(order, ri.OrderItemID)
+		if item == nil || item.Quantity == 0 {
+			continue
+		}
+		lineCents := item.TaxableCents + item.TaxCents
+		if lineCents == 0 && order.SubtotalCents > 0 {
+			// Placed before GST was kept per line: the price plus its share
+			// of the order's tax
+			gross := item.UnitPriceCents * int64(item.Quantity)
+			lineCents = gross + gross*order.TaxCents/order.SubtotalCents
+		}
+		refundCents += lineCents * int64(ri.Quantity) / int64(item.Quantity)
+	}
+	return refundCents
+}
+
+func findOrderItem(order *models.Order, itemID string) *models.OrderItem {
+	for i := range order.Items {
+		if order.Items[i].ID == itemID {
+			return &order.Items[i]
+		}
+	}
+	return nil
+}
+
+// createReplacementOrder sends the items of an approved return again at no
+// charge. The order starts confirmed with its stock sold, like a cash on
+// delivery order, and has nothing to pay.
+func (s *OrderService) createReplacementOrder(ctx context.Context, original *models.Order, ret *models.ReturnRequest, actorID string) (*models.Order, error) {
+	items := make([]models.OrderItem, 0, len(ret.Items))
+	for _, ri := range ret.Items {
+		item := models.OrderItem{
+			ID:          uuid.New().String(),
+			ProductID:   ri.ProductID,
+			ProductName: ri.ProductName,
+			VariantID:   ri.VariantID,
+			Quantity:    ri.Quantity,
+		}
+		if oi := findOrderItem(original, ri.OrderItemID); oi != nil {
+			item.ProductImage = oi.ProductImage
+			item.VariantName = oi.VariantName
+		}
+		items = append(items, item)
+	}
+
+	if err := s.reserveItems(ctx, items); err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		ID:              uuid.New().String(),
+		UserID:          original.UserID,
+		Status:          models.OrderStatusConfirmed,
+		PlacedAt:        time.Now(),
+		ShippingAddress: original.ShippingAddress,
+		PaymentMetadata: map[string]interface{}{
+			"payment_method":    models.PaymentMethodReplacement,
+			"replaces_order_id": original.ID,
+			"return_id":         ret.ID,
+		},
+		StockStatus: models.StockStatusCommitted,
+	}
+	if err := s.orderRepo.CreateOrder(ctx, order); err != nil {
+		if err := releaseItems(ctx, s.productRepo, items); err != nil {
+			log.Printf("Failed to return stock for unplaced replacement %s: %v", order.ID, err)
+		}
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].OrderID = order.ID
+	}
+	if err := s.orderRepo.CreateOrderItems(ctx, items); err != nil {
+		s.orderRepo.DeleteOrder(ctx, order.ID)
+		if err := releaseItems(ctx, s.productRepo, items); err != nil {
+			log.Printf("Failed to return stock for unplaced replacement %s: %v", order.ID, err)
+		}
+		return nil, errors.New("failed to create order items")
+	}
+
+	s.workflow.record(ctx, order.ID, "", order.Status, actorID, "replacement for return "+ret.ID)
+
+	order.Items = items
+	return order, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository/memory"
+)
+
+// testServices wires the order services to an in-memory store, the way
+// main does for STORAGE=memory.
+type testServices struct {
+	cfg        *config.Config
+	products   *memory.ProductRepository
+	orders     *memory.OrderRepository
+	wallets    *memory.WalletRepository
+	refunds    *memory.RefundRepository
+	events     *memory.OrderEventRepository
+	webhooks   *memory.WebhookEventRepository
+	quarantine *memory.WebhookQuarantineRepository
+	subs       *memory.SubscriptionRepository
+	carts      *memory.CartRepository
+	reports    *memory.ReconciliationReportRepository
+	users      *memory.UserRepository
+	fake       *FakeGateway
+
+	workflow     *OrderWorkflow
+	orderService *OrderService
+	refundSvc    *RefundService
+	payments     *PaymentService
+}
+
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+
+	cfg := &config.Config{GSTDefaultTaxClass: "gst_5", PricesIncludeTax: true, CODEnabled: true, DefaultPaymentGateway: "fake"}
+	db := memory.NewDB()
+	ts := &testServices{
+		cfg:        cfg,
+		products:   memory.NewProductRepository(db),
+		orders:     memory.NewOrderRepository(db),
+		wallets:    memory.NewWalletRepository(db),
+		refunds:    memory.NewRefundRepository(db),
+		events:     memory.NewOrderEventRepository(db),
+		webhooks:   memory.NewWebhookEventRepository(db),
+		quarantine: memory.NewWebhookQuarantineRepository(db),
+		subs:       memory.NewSubscriptionRepository(db),
+		carts:      memory.NewCartRepository(db),
+		reports:    memory.NewReconciliationReportRepository(db),
+		users:      memory.NewUserRepository(db),
+		fake:       NewFakeGateway("test-secret"),
+	}
+
+	machine, err := NewOrderStateMachine(DefaultOrderStateMachine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipping, err := NewShippingCalculator(DefaultShippingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.refundSvc = NewRefundService(ts.refunds, ts.orders, ts.wallets, ts.fake)
+	invoices := NewInvoiceService(cfg, memory.NewInvoiceRepository(db), ts.orders)
+	ts.workflow = NewOrderWorkflow(machine, ts.orders, ts.events, ts.products, ts.users, &EmailService{}, ts.refundSvc, invoices)
+	ts.orderService = NewOrderService(cfg, ts.orders, ts.products, ts.events, ts.wallets, ts.workflow, shipping)
+	ts.payments = NewPaymentService(cfg, ts.orders, ts.workflow, memory.NewPaymentLookupRepository(db), ts.quarantine, ts.webhooks, ts.fake)
+	return ts
+}
+
+func (ts *testServices) addProduct(t *testing.T, id string, priceCents int64, stock int) {
+	t.Helper()
+	err := ts.products.CreateProduct(context.Background(), &models.Product{
+		ID: id, Name: id, PriceCents: priceCents, Stock: stock, Active: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ts *testServices) stockOf(t *testing.T, id string) int {
+	t.Helper()
+	p, err := ts.products.GetProductByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Stock
+}
+
+func (ts *testServices) placeOrder(t *testing.T, userID, productID string, quantity int, method string) *models.Order {
+	t.Helper()
+	order, err := ts.orderService.CreateOrder(context.Background(), userID, &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		PaymentMethod:   method,
+		Items:           []models.CreateOrderItem{{ProductID: productID, Quantity: quantity}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// ShippingZone prices delivery to a set of pincodes, each given as a prefix
+// ("5600") or an inclusive range ("560001-560099"). A zone without pincodes
+// covers everywhere.
+type ShippingZone struct {
+	Name           string   `json:"name"`
+	Pincodes       []string `json:"pincodes,omitempty"`
+	FeeCents       int64    `json:"fee_cents"`
+	FreeAboveCents int64    `json:"free_above_cents,omitempty"` // 0 never waives the fee
+}
+
+// SmallCartFee is charged on orders whose items come to less than BelowCents.
+type SmallCartFee struct {
+	BelowCents int64 `json:"below_cents"`
+	FeeCents   int64 `json:"fee_cents"`
+}
+
+// WeightFee charges PerKgCents for every started kilogram beyond
+// IncludedGrams.
+type WeightFee struct {
+	IncludedGrams int   `json:"included_grams"`
+	PerKgCents    int64 `json:"per_kg_cents"`
+}
+
+// DistanceFee charges PerKmCents for every started kilometre beyond
+// IncludedKm from the store at From, measured in a straight line to the
+// coordinates on the shipping address. Addresses further than MaxKm can't be
+// delivered to; 0 sets no limit. Addresses without coordinates pay no
+// distance fee.
+type DistanceFee struct {
+	From       GeoPoint `json:"from"`
+	IncludedKm float64  `json:"included_km"`
+	PerKmCents int64    `json:"per_km_cents"`
+	MaxKm      float64  `json:"max_km,omitempty"`
+}
+
+// GeoPoint is a latitude and longitude in degrees.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// ShippingDestination is where an order is delivered. Point is nil when the
+// address has no coordinates.
+type ShippingDestination struct {
+	Pincode string
+	Point   *GeoPoint
+}
+
+// DeliverySlotFee is the price of a delivery slot, plus a surge fee when the
+// order is placed during one of its peak windows.
+type DeliverySlotFee struct {
+	FeeCents int64         `json:"fee_cents"`
+	Surge    []SurgeWindow `json:"surge,omitempty"`
+}
+
+// SurgeWindow covers the hours [FromHour, ToHour) in IST, wrapping past
+// midnight when FromHour > ToHour.
+type SurgeWindow struct {
+	FromHour int   `json:"from_hour"`
+	ToHour   int   `json:"to_hour"`
+	FeeCents int64 `json:"fee_cents"`
+}
+
+// ShippingRulesConfig is the JSON form of the shipping rules, as read from
+// SHIPPING_RULES_FILE. Zones are tried in order and the first match wins.
+// The standard slot is always offered and is free unless Slots prices it.
+type ShippingRulesConfig struct {
+	Zones     []ShippingZone             `json:"zones"`
+	SmallCart *SmallCartFee              `json:"small_cart,omitempty"`
+	Weight    *WeightFee                 `json:"weight,omitempty"`
+	Distance  *DistanceFee               `json:"distance,omitempty"`
+	Slots     map[string]DeliverySlotFee `json:"slots,omitempty"`
+}
+
+// ShippingCalculator works out delivery charges. It is built once at startup
+// and read-only afterwards.
+type ShippingCalculator struct {
+	rules ShippingRulesConfig
+}
+
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+// DefaultShippingRules are used when no file is configured: ₹9.99 delivery,
+// free over ₹500, and an express slot that costs more in the evening rush.
+func DefaultShippingRules() ShippingRulesConfig {
+	return ShippingRulesConfig{
+		Zones: []ShippingZone{
+			{Name: "standard", FeeCents: 999, FreeAboveCents: 50000},
+		},
+		Slots: map[string]DeliverySlotFee{
+			"express": {FeeCents: 2900, Surge: []SurgeWindow{{FromHour: 18, ToHour: 21, FeeCents: 1500}}},
+		},
+	}
+}
+
+// LoadShippingRules reads the shipping rules from a JSON file, or uses the
+// default ones when path is empty.
+func LoadShippingRules(path string) (*ShippingCalculator, error) {
+	cfg := DefaultShippingRules()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read shipping rules: %w", err)
+		}
+		cfg = ShippingRulesConfig{}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parse shipping rules: %w", err)
+		}
+	}
+	return NewShippingCalculator(cfg)
+}
+
+// NewShippingCalculator validates cfg: at least one zone, pincodes that are
+// digits or digit ranges, no negative fees and surge hours within a day.
+func NewShippingCalculator(cfg ShippingRulesConfig) (*ShippingCalculator, error) {
+	var problems []string
+	if len(cfg.Zones) == 0 {
+		problems = append(problems, "no zones")
+	}
+	for _, zone := range cfg.Zones {
+		if zone.Name == "" {
+			problems = append(problems, "zone without a name")
+		}
+		if zone.FeeCents < 0 || zone.FreeAboveCents < 0 {
+			problems = append(problems, fmt.Sprintf("zone %q: negative amount", zone.Name))
+		}
+		for _, pincode := range zone.Pincodes {
+			if _, _, ok := parsePincodeBand(pincode); !ok {
+				problems = append(problems, fmt.Sprintf("zone %q: invalid pincode %q", zone.Name, pincode))
+			}
+		}
+	}
+	if cfg.SmallCart != nil && (cfg.SmallCart.BelowCents < 0 || cfg.SmallCart.FeeCents < 0) {
+		problems = append(problems, "small_cart: negative amount")
+	}
+	if cfg.Weight != nil && (cfg.Weight.IncludedGrams < 0 || cfg.Weight.PerKgCents < 0) {
+		problems = append(problems, "weight: negative amount")
+	}
+	if d := cfg.Distance; d != nil {
+		if d.IncludedKm < 0 || d.PerKmCents < 0 || d.MaxKm < 0 {
+			problems = append(problems, "distance: negative amount")
+		}
+		if !validPoint(d.From) {
+			problems = append(problems, "distance: invalid store coordinates")
+		}
+	}
+	for name, slot := range cfg.Slots {
+		if name == "" {
+			problems = append(problems, "slot without a name")
+		}
+		if slot.FeeCents < 0 {
+			problems = append(problems, fmt.Sprintf("slot %q: negative fee", name))
+		}
+		for _, w := range slot.Surge {
+			if w.FromHour < 0 || w.FromHour > 23 || w.ToHour < 0 || w.ToHour > 24 || w.FromHour == w.ToHour || w.FeeCents < 0 {
+				problems = append(problems, fmt.Sprintf("slot %q: invalid surge window %d-%d", name, w.FromHour, w.ToHour))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("shipping rules: %s", strings.Join(problems, "; "))
+	}
+
+	return &ShippingCalculator{rules: cfg}, nil
+}
+
+// HasSlot reports whether slot can be booked.
+func (c *ShippingCalculator) HasSlot(slot string) bool {
+	_, ok := c.rules.Slots[slot]
+	return ok || slot == models.DeliverySlotStandard
+}
+
+// Delivers returns why dest can't be delivered to, or nil if it can.
+func (c *ShippingCalculator) Delivers(dest ShippingDestination) error {
+	if c.zoneFor(dest.Pincode) == nil {
+		return errors.New("delivery is not available to this pincode")
+	}
+	if d := c.rules.Distance; d != nil && d.MaxKm > 0 && dest.Point != nil && distanceKm(d.From, *dest.Point) > d.MaxKm {
+		return errors.New("delivery is not available this far from the store")
+	}
+	return nil
+}
+
+// Quote prices delivery of taxed order lines to dest in slot, for an order
+// placed at the given time. An empty destination, as in a cart preview, uses
+// the zone that covers everywhere, or the first zone if none does, and pays
+// no distance fee.
+func (c *ShippingCalculator) Quote(items []models.OrderItem, dest ShippingDestination, slot string, at time.Time) (zone *ShippingZone, fees []models.ShippingFee, err error) {
+	if !c.HasSlot(slot) {
+		return nil, nil, errors.New("invalid delivery slot")
+	}
+	if err := c.Delivers(dest); err != nil {
+		return nil, nil, err
+	}
+	zone = c.zoneFor(dest.Pincode)
+
+	var goodsCents int64
+	grams := 0
+	for _, item := range items {
+		goodsCents += item.TaxableCents + item.TaxCents
+		grams += item.WeightGrams * item.Quantity
+	}
+
+	delivery := zone.FeeCents
+	label := "Delivery fee"
+	if zone.FreeAboveCents > 0 && goodsCents >= zone.FreeAboveCents {
+		delivery = 0
+		label = "Free delivery"
+	}
+	fees = append(fees, models.ShippingFee{Code: models.ShippingFeeDelivery, Label: label, AmountCents: delivery})
+
+	if sc := c.rules.SmallCart; sc != nil && sc.FeeCents > 0 && goodsCents < sc.BelowCents {
+		fees = append(fees, models.ShippingFee{
+			Code:        models.ShippingFeeSmallCart,
+			Label:       "Small cart fee (orders under ₹" + formatRupees(sc.BelowCents) + ")",
+			AmountCents: sc.FeeCents,
+		})
+	}
+
+	if w := c.rules.Weight; w != nil && w.PerKgCents > 0 && grams > w.IncludedGrams {
+		kg := (grams - w.IncludedGrams + 999) / 1000
+		fees = append(fees, models.ShippingFee{
+			Code:        models.ShippingFeeWeight,
+			Label:       fmt.Sprintf("Heavy order fee (%d kg over)", kg),
+			AmountCents: int64(kg) * w.PerKgCents,
+		})
+	}
+
+	if d := c.rules.Distance; d != nil && d.PerKmCents > 0 && dest.Point != nil {
+		if over := distanceKm(d.From, *dest.Point) - d.IncludedKm; over > 0 {
+			km := int64(math.Ceil(over))
+			fees = append(fees, models.ShippingFee{
+				Code:        models.ShippingFeeDistance,
+				Label:       fmt.Sprintf("Distance fee (%d km over)", km),
+				AmountCents: km * d.PerKmCents,
+			})
+		}
+	}
+
+	if s, ok := c.rules.Slots[slot]; ok {
+		if s.FeeCents > 0 {
+			fees = append(fees, models.ShippingFee{Code: models.ShippingFeeSlot, Label: strings.ToUpper(slot[:1]) + slot[1:] + " delivery", AmountCents: s.FeeCents})
+		}
+		hour := at.In(ist).Hour()
+		for _, w := range s.Surge {
+			if inSurgeWindow(w, hour) && w.FeeCents > 0 {
+				fees = append(fees, models.ShippingFee{Code: models.ShippingFeeSurge, Label: "Peak hour surge", AmountCents: w.FeeCents})
+				break
+			}
+		}
+	}
+
+	return zone, fees, nil
+}
+
+func (c *ShippingCalculator) zoneFor(pincode string) *ShippingZone {
+	for i := range c.rules.Zones {
+		zone := &c.rules.Zones[i]
+		if len(zone.Pincodes) == 0 {
+			return zone
+		}
+		if pincode == "" {
+			continue
+		}
+		for _, band := range zone.Pincodes {
+			if pincodeInBand(pincode, band) {
+				return zone
+			}
+		}
+	}
+	if pincode == "" {
+		return &c.rules.Zones[0]
+	}
+	return nil
+}
+
+// parsePincodeBand splits "560001-560099" into its ends; a single value is
+// a prefix and comes back as both.
+func parsePincodeBand(band string) (from, to string, ok bool) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(band), "-")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !isRange {
+		to = from
+	}
+	digits := func(s string) bool { return s != "" && strings.Trim(s, "0123456789") == "" }
+	if !digits(from) || !digits(to) || (isRange && (len(from) != 6 || len(to) != 6 || from > to)) {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+func pincodeInBand(pincode, band string) bool {
+	from, to, ok := parsePincodeBand(band)
+	if !ok {
+		return false
+	}
+	if from == to {
+		return strings.HasPrefix(pincode, from)
+	}
+	return len(pincode) == 6 && pincode >= from && pincode <= to
+}
+
+// destinationOf reads the pincode and, when both are given, the "latitude"
+// and "longitude" of a free-form shipping address.
+func destinationOf(address map[string]interface{}) ShippingDestination {
+	dest := ShippingDestination{Pincode: pincodeFromAddress(address)}
+	lat, latOK := coordinate(address["latitude"])
+	lng, lngOK := coordinate(address["longitude"])
+	if p := (GeoPoint{Lat: lat, Lng: lng}); latOK && lngOK && validPoint(p) {
+		dest.Point = &p
+	}
+	return dest
+}
+
+func coordinate(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func validPoint(p GeoPoint) bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// distanceKm is the great-circle distance between two points.
+func distanceKm(a, b GeoPoint) float64 {
+	const earthRadiusKm = 6371.0
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLng := rad(b.Lat-a.Lat), rad(b.Lng-a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+func inSurgeWindow(w SurgeWindow, hour int) bool {
+	if w.FromHour < w.ToHour {
+		return hour >= w.FromHour && hour < w.ToHour
+	}
+	return hour >= w.FromHour || hour < w.ToHour
+}
+
+var weightPattern = regexp.MustCompile(`(?i)^\s*([0-9]+(?:\.[0-9]+)?)\s*(kg|kgs|g|gm|gms|grams?|l|ltr|litres?|liters?|ml)\s*$`)
+
+// weightGrams reads a product weight such as "500 g", "1.5kg" or "1 L"
+// (liquids are taken at 1 g/ml). Anything else counts as weightless.
+func weightGrams(weight string) int {
+	m := weightPattern.FindStringSubmatch(weight)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToLower(m[2])[0] {
+	case 'k', 'l':
+		n *= 1000
+	}
+	return int(n + 0.5)
+}
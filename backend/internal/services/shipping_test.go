@@ -0,0 +1,111 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+func TestShippingQuote(t *testing.T) {
+	store := GeoPoint{Lat: 12.9716, Lng: 77.5946}
+	calc, err := NewShippingCalculator(ShippingRulesConfig{
+		Zones: []ShippingZone{
+			{Name: "city", Pincodes: []string{"5600", "560100-560110"}, FeeCents: 1500, FreeAboveCents: 20000},
+			{Name: "suburbs", Pincodes: []string{"562100-562199"}, FeeCents: 3000},
+		},
+		SmallCart: &SmallCartFee{BelowCents: 10000, FeeCents: 700},
+		Weight:    &WeightFee{IncludedGrams: 3000, PerKgCents: 500},
+		Distance:  &DistanceFee{From: store, IncludedKm: 3, PerKmCents: 1000, MaxKm: 20},
+		Slots: map[string]DeliverySlotFee{
+			"express": {FeeCents: 2500, Surge: []SurgeWindow{{FromHour: 18, ToHour: 21, FeeCents: 1000}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	morning := time.Date(2026, 10, 17, 10, 0, 0, 0, ist)
+	evening := time.Date(2026, 10, 17, 19, 0, 0, 0, ist)
+	line := func(goodsCents int64, grams, quantity int) []models.OrderItem {
+		return []models.OrderItem{{TaxableCents: goodsCents, WeightGrams: grams, Quantity: quantity}}
+	}
+	// About 9.5 km north of the store
+	farPoint := &GeoPoint{Lat: store.Lat + 0.0855, Lng: store.Lng}
+
+	tests := []struct {
+		name      string
+		items     []models.OrderItem
+		dest      ShippingDestination
+		slot      string
+		at        time.Time
+		wantZone  string
+		wantCodes []string
+		wantCents int64
+		wantErr   bool
+	}{
+		{"pincode prefix", line(15000, 0, 1), ShippingDestination{Pincode: "560001"}, "standard", morning, "city", []string{"delivery"}, 1500, false},
+		{"pincode range, free above threshold", line(25000, 0, 1), ShippingDestination{Pincode: "560105"}, "standard", morning, "city", []string{"delivery"}, 0, false},
+		{"second zone", line(25000, 0, 1), ShippingDestination{Pincode: "562150"}, "standard", morning, "suburbs", []string{"delivery"}, 3000, false},
+		{"small cart", line(5000, 0, 1), ShippingDestination{Pincode: "560001"}, "standard", morning, "city", []string{"delivery", "small_cart"}, 2200, false},
+		{"heavy order", line(15000, 2000, 2), ShippingDestination{Pincode: "560001"}, "standard", morning, "city", []string{"delivery", "weight"}, 2000, false},
+		{"within included distance", line(15000, 0, 1), ShippingDestination{Pincode: "560001", Point: &store}, "standard", morning, "city", []string{"delivery"}, 1500, false},
+		{"beyond included distance", line(15000, 0, 1), ShippingDestination{Pincode: "560001", Point: farPoint}, "standard", morning, "city", []string{"delivery", "distance"}, 8500, false},
+		{"beyond max distance", line(15000, 0, 1), ShippingDestination{Pincode: "560001", Point: &GeoPoint{Lat: store.Lat + 0.3, Lng: store.Lng}}, "standard", morning, "", nil, 0, true},
+		{"express", line(15000, 0, 1), ShippingDestination{Pincode: "560001"}, "express", morning, "city", []string{"delivery", "slot"}, 4000, false},
+		{"express at peak", line(15000, 0, 1), ShippingDestination{Pincode: "560001"}, "express", evening, "city", []string{"delivery", "slot", "surge"}, 5000, false},
+		{"cart preview uses first zone", line(15000, 0, 1), ShippingDestination{}, "standard", morning, "city", []string{"delivery"}, 1500, false},
+		{"pincode no zone covers", line(15000, 0, 1), ShippingDestination{Pincode: "110001"}, "standard", morning, "", nil, 0, true},
+		{"range needs a full pincode", line(15000, 0, 1), ShippingDestination{Pincode: "56210"}, "standard", morning, "", nil, 0, true},
+		{"unknown slot", line(15000, 0, 1), ShippingDestination{Pincode: "560001"}, "midnight", morning, "", nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, fees, err := calc.Quote(tt.items, tt.dest, tt.slot, tt.at)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got zone %s, want an error", zone.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var codes []string
+			var cents int64
+			for _, fee := range fees {
+				codes = append(codes, fee.Code)
+				cents += fee.AmountCents
+			}
+			if zone.Name != tt.wantZone || !slices.Equal(codes, tt.wantCodes) || cents != tt.wantCents {
+				t.Fatalf("got zone %s, fees %v totalling %d; want %s, %v totalling %d", zone.Name, codes, cents, tt.wantZone, tt.wantCodes, tt.wantCents)
+			}
+		})
+	}
+}
+
+func TestDestinationOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		address map[string]interface{}
+		want    ShippingDestination
+	}{
+		{"pincode only", map[string]interface{}{"pincode": "560001"}, ShippingDestination{Pincode: "560001"}},
+		{"numeric pincode and coordinates", map[string]interface{}{"pincode": 560001.0, "latitude": 12.97, "longitude": 77.59}, ShippingDestination{Pincode: "560001", Point: &GeoPoint{Lat: 12.97, Lng: 77.59}}},
+		{"coordinates as strings", map[string]interface{}{"latitude": "12.97", "longitude": " 77.59"}, ShippingDestination{Point: &GeoPoint{Lat: 12.97, Lng: 77.59}}},
+		{"half a coordinate", map[string]interface{}{"latitude": 12.97}, ShippingDestination{}},
+		{"out of range", map[string]interface{}{"latitude": 120.0, "longitude": 77.59}, ShippingDestination{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := destinationOf(tt.address)
+			if got.Pincode != tt.want.Pincode || (got.Point == nil) != (tt.want.Point == nil) || (got.Point != nil && *got.Point != *tt.want.Point) {
+				t.Fatalf("got %+v (point %v), want %+v (point %v)", got, got.Point, tt.want, tt.want.Point)
+			}
+		})
+	}
+}
@@ -0,0 +1,818 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+const subscriptionDateLayout = "2006-01-02"
+
+// subscriptionHorizonDays bounds the search for a subscription's next
+// delivery
+const subscriptionHorizonDays = 400
+
+// minSubscriptionClaimTimeout is the least time a pending claim is left to
+// its pass before another may take it over
+const minSubscriptionClaimTimeout = 10 * time.Minute
+
+// SubscriptionService manages standing orders and places each delivery as an
+// ordinary order ahead of its day. Dates are IST calendar days.
+type SubscriptionService struct {
+	cfg         *config.Config
+	subRepo     repository.SubscriptionStore
+	productRepo repository.ProductStore
+	userRepo    repository.UserStore
+	orders      *OrderService
+	email       *EmailService
+
+	// One generator pass at a time
+	runMu sync.Mutex
+}
+
+func NewSubscriptionService(cfg *config.Config, subRepo repository.SubscriptionStore, productRepo repository.ProductStore, userRepo repository.UserStore, orders *OrderService, email *EmailService) *SubscriptionService {
+	return &SubscriptionService{
+		cfg:         cfg,
+		subRepo:     subRepo,
+		productRepo: productRepo,
+		userRepo:    userRepo,
+		orders:      orders,
+		email:       email,
+	}
+}
+
+func (s *SubscriptionService) CreateSubscription(ctx context.Context, userID string, req *models.CreateSubscriptionRequest) (*models.Subscription, error) {
+	product, err := s.productRepo.GetProductByID(ctx, req.ProductID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	if !product.Active {
+		return nil, errors.New("product is not available")
+	}
+	if _, err := priceFor(product, req.VariantID); err != nil {
+		return nil, err
+	}
+	if req.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	weekdays, err := checkSchedule(req.Frequency, req.Weekdays)
+	if err != nil {
+		return nil, err
+	}
+
+	tomorrow := istDay(time.Now()).AddDate(0, 0, 1)
+	startDate := req.StartDate
+	if startDate == "" {
+		startDate = tomorrow.Format(subscriptionDateLayout)
+	}
+	start, err := parseSubscriptionDate(startDate, "start_date")
+	if err != nil {
+		return nil, err
+	}
+	if start.Before(tomorrow) {
+		return nil, errors.New("start_date must be tomorrow or later")
+	}
+	if req.EndDate != "" {
+		end, err := parseSubscriptionDate(req.EndDate, "end_date")
+		if err != nil {
+			return nil, err
+		}
+		if end.Before(start) {
+			return nil, errors.New("end_date must not be before start_date")
+		}
+	}
+
+	if err := s.checkDelivery(req.ShippingAddress, req.DeliverySlot); err != nil {
+		return nil, err
+	}
+	paymentMethod, err := checkSubscriptionPayment(req.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	sub := &models.Subscription{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		ProductID:       product.ID,
+		VariantID:       req.VariantID,
+		Quantity:        req.Quantity,
+		Frequency:       req.Frequency,
+		Weekdays:        weekdays,
+		StartDate:       startDate,
+		EndDate:         req.EndDate,
+		Status:          models.SubscriptionActive,
+		ShippingAddress: req.ShippingAddress,
+		DeliverySlot:    req.DeliverySlot,
+		PaymentMethod:   paymentMethod,
+		CODFallback:     req.CODFallback,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.subRepo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	vacation := s.vacationOf(ctx, userID)
+	s.describe(ctx, sub, vacation)
+	return sub, nil
+}
+
+func (s *SubscriptionService) GetSubscription(ctx context.Context, id, userID string, isAdmin bool) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	s.describe(ctx, sub, s.vacationOf(ctx, sub.UserID))
+	return sub, nil
+}
+
+func (s *SubscriptionService) GetUserSubscriptions(ctx context.Context, userID string) ([]models.Subscription, error) {
+	subs, err := s.subRepo.GetSubscriptionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	vacation := s.vacationOf(ctx, userID)
+	for i := range subs {
+		s.describe(ctx, &subs[i], vacation)
+	}
+	return subs, nil
+}
+
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context, status string, limit, offset int) ([]models.Subscription, error) {
+	switch status {
+	case "", models.SubscriptionActive, models.SubscriptionPaused, models.SubscriptionCancelled:
+	default:
+		return nil, errors.New("invalid subscription status")
+	}
+	return s.subRepo.ListSubscriptions(ctx, status, limit, offset)
+}
+
+// UpdateSubscription changes the quantity, schedule, address or payment of a
+// subscription. Orders already placed are not changed.
+func (s *SubscriptionService) UpdateSubscription(ctx context.Context, id, userID string, req *models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		return nil, errors.New("subscription is cancelled")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Quantity != nil {
+		if *req.Quantity <= 0 {
+			return nil, errors.New("quantity must be positive")
+		}
+		updates["quantity"] = *req.Quantity
+	}
+	if req.Frequency != nil || req.Weekdays != nil {
+		frequency, weekdays := sub.Frequency, sub.Weekdays
+		if req.Frequency != nil {
+			frequency = *req.Frequency
+		}
+		if req.Weekdays != nil {
+			weekdays = req.Weekdays
+		}
+		if weekdays, err = checkSchedule(frequency, weekdays); err != nil {
+			return nil, err
+		}
+		updates["frequency"] = frequency
+		updates["weekdays"] = weekdays
+	}
+	if req.EndDate != nil {
+		if *req.EndDate == "" {
+			updates["end_date"] = nil
+		} else {
+			end, err := parseSubscriptionDate(*req.EndDate, "end_date")
+			if err != nil {
+				return nil, err
+			}
+			if start, _ := parseSubscriptionDate(sub.StartDate, "start_date"); end.Before(start) {
+				return nil, errors.New("end_date must not be before start_date")
+			}
+			updates["end_date"] = *req.EndDate
+		}
+	}
+
+	address, slot := sub.ShippingAddress, sub.DeliverySlot
+	if req.ShippingAddress != nil {
+		address = req.ShippingAddress
+		updates["shipping_address"] = address
+	}
+	if req.DeliverySlot != nil {
+		slot = *req.DeliverySlot
+		updates["delivery_slot"] = slot
+	}
+	if err := s.checkDelivery(address, slot); err != nil {
+		return nil, err
+	}
+	if req.PaymentMethod != nil {
+		method, err := checkSubscriptionPayment(*req.PaymentMethod)
+		if err != nil {
+			return nil, err
+		}
+		updates["payment_method"] = method
+	}
+	if req.CODFallback != nil {
+		updates["cod_fallback"] = *req.CODFallback
+	}
+
+	return s.update(ctx, sub.ID, updates)
+}
+
+// PauseSubscription stops deliveries until the day after until, or until the
+// subscription is resumed when until is empty.
+func (s *SubscriptionService) PauseSubscription(ctx context.Context, id, userID, until string) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		return nil, errors.New("subscription is cancelled")
+	}
+
+	updates := map[string]interface{}{"status": models.SubscriptionPaused, "paused_until": nil}
+	if until != "" {
+		day, err := parseSubscriptionDate(until, "until")
+		if err != nil {
+			return nil, err
+		}
+		if !day.After(istDay(time.Now())) {
+			return nil, errors.New("until must be a future date")
+		}
+		updates["paused_until"] = until
+	}
+	return s.update(ctx, sub.ID, updates)
+}
+
+func (s *SubscriptionService) ResumeSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != models.SubscriptionPaused {
+		return nil, errors.New("subscription is not paused")
+	}
+	return s.update(ctx, sub.ID, map[string]interface{}{"status": models.SubscriptionActive, "paused_until": nil})
+}
+
+// CancelSubscription stops all future deliveries. Orders already placed are
+// kept and can be cancelled like any other order.
+func (s *SubscriptionService) CancelSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		return nil, errors.New("subscription is already cancelled")
+	}
+	return s.update(ctx, sub.ID, map[string]interface{}{"status": models.SubscriptionCancelled, "paused_until": nil})
+}
+
+// SkipDate leaves out one scheduled delivery. Dates whose order has already
+// been placed can't be skipped; cancel that order instead.
+func (s *SubscriptionService) SkipDate(ctx context.Context, id, userID, date string) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		return nil, errors.New("subscription is cancelled")
+	}
+
+	day, err := parseSubscriptionDate(date, "date")
+	if err != nil {
+		return nil, err
+	}
+	today := istDay(time.Now())
+	if !day.After(today) {
+		return nil, errors.New("only future dates can be skipped")
+	}
+	if !onSchedule(sub, day) {
+		return nil, errors.New("no delivery is scheduled on this date")
+	}
+	if _, err := s.subRepo.GetSubscriptionDelivery(ctx, sub.ID, date); err == nil {
+		return nil, errors.New("the order for this date has already been placed")
+	}
+
+	// Dates that have gone by no longer matter
+	skips := []string{date}
+	for _, d := range sub.SkipDates {
+		if d > today.Format(subscriptionDateLayout) && d != date {
+			skips = append(skips, d)
+		}
+	}
+	slices.Sort(skips)
+	return s.update(ctx, sub.ID, map[string]interface{}{"skip_dates": skips})
+}
+
+// UnskipDate puts a skipped delivery back.
+func (s *SubscriptionService) UnskipDate(ctx context.Context, id, userID, date string) (*models.Subscription, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(sub.SkipDates, date) {
+		return nil, errors.New("date is not skipped")
+	}
+	skips := slices.DeleteFunc(slices.Clone(sub.SkipDates), func(d string) bool { return d == date })
+	return s.update(ctx, sub.ID, map[string]interface{}{"skip_dates": skips})
+}
+
+func (s *SubscriptionService) GetDeliveries(ctx context.Context, id, userID string, isAdmin bool, limit, offset int) ([]models.SubscriptionDelivery, error) {
+	sub, err := s.ownedSubscription(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.subRepo.GetSubscriptionDeliveries(ctx, sub.ID, limit, offset)
+}
+
+// SetVacation holds every one of the user's subscriptions between two dates,
+// replacing any earlier vacation.
+func (s *SubscriptionService) SetVacation(ctx context.Context, userID string, req *models.SubscriptionVacationRequest) (*models.SubscriptionVacation, error) {
+	start, err := parseSubscriptionDate(req.StartDate, "start_date")
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseSubscriptionDate(req.EndDate, "end_date")
+	if err != nil {
+		return nil, err
+	}
+	if !start.After(istDay(time.Now())) {
+		return nil, errors.New("start_date must be tomorrow or later")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date must not be before start_date")
+	}
+
+	vacation := &models.SubscriptionVacation{
+		UserID:    userID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := s.subRepo.SaveSubscriptionVacation(ctx, vacation); err != nil {
+		return nil, err
+	}
+	return vacation, nil
+}
+
+func (s *SubscriptionService) GetVacation(ctx context.Context, userID string) (*models.SubscriptionVacation, error) {
+	return s.subRepo.GetSubscriptionVacation(ctx, userID)
+}
+
+func (s *SubscriptionService) EndVacation(ctx context.Context, userID string) error {
+	if _, err := s.subRepo.GetSubscriptionVacation(ctx, userID); err != nil {
+		return err
+	}
+	return s.subRepo.DeleteSubscriptionVacation(ctx, userID)
+}
+
+// RunOnce places the orders of every delivery day that starts within
+// cfg.SubscriptionLeadTime of now. Each day is claimed with a delivery record
+// first, so overlapping passes (or servers) never order it twice. A day that
+// can't be ordered, e.g. because the wallet is short, is retried on every
+// pass until it begins and is then recorded as missed.
+func (s *SubscriptionService) RunOnce(ctx context.Context, now time.Time) (*models.SubscriptionRunSummary, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	var subs []models.Subscription
+	for _, status := range []string{models.SubscriptionActive, models.SubscriptionPaused} {
+		batch, err := s.subRepo.ListSubscriptions(ctx, status, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, batch...)
+	}
+
+	summary := &models.SubscriptionRunSummary{}
+	today := istDay(now)
+	vacations := map[string]*models.SubscriptionVacation{}
+	for i := range subs {
+		sub := &subs[i]
+		vacation, ok := vacations[sub.UserID]
+		if !ok {
+			vacation = s.vacationOf(ctx, sub.UserID)
+			vacations[sub.UserID] = vacation
+		}
+
+		// A pause with an end date lifts itself once it is over
+		if sub.Status == models.SubscriptionPaused && sub.PausedUntil != "" &&
+			sub.PausedUntil <= today.Format(subscriptionDateLayout) {
+			if _, err := s.update(ctx, sub.ID, map[string]interface{}{"status": models.SubscriptionActive, "paused_until": nil}); err != nil {
+				log.Printf("Failed to resume subscription %s: %v", sub.ID, err)
+			} else {
+				sub.Status, sub.PausedUntil = models.SubscriptionActive, ""
+			}
+		}
+
+		if isDue(sub, vacation, today) {
+			s.recordMissed(ctx, sub, today, summary)
+		}
+		for day := today.AddDate(0, 0, 1); !day.Add(-s.cfg.SubscriptionLeadTime).After(now); day = day.AddDate(0, 0, 1) {
+			if isDue(sub, vacation, day) {
+				s.placeDelivery(ctx, sub, day, summary)
+			}
+		}
+	}
+	return summary, nil
+}
+
+// Run generates subscription orders every cfg.SubscriptionRunInterval. It
+// blocks until ctx is cancelled, so callers should start it in its own
+// goroutine.
+func (s *SubscriptionService) Run(ctx context.Context) {
+	interval := s.cfg.SubscriptionRunInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		passCtx, cancel := context.WithTimeout(ctx, interval)
+		summary, err := s.RunOnce(passCtx, time.Now())
+		cancel()
+		if err != nil {
+			log.Printf("Subscription order generation failed: %v", err)
+			continue
+		}
+		if summary.Ordered+summary.Failed+summary.Missed > 0 {
+			log.Printf("Subscription orders: %d placed, %d failed, %d missed", summary.Ordered, summary.Failed, summary.Missed)
+		}
+	}
+}
+
+// placeDelivery claims day for sub and places its order, giving the claim
+// back if the order fails so a later pass can try again. The claim names the
+// order before it is placed, so a claim left pending by a pass that died can
+// be taken over later and finished without ordering twice.
+func (s *SubscriptionService) placeDelivery(ctx context.Context, sub *models.Subscription, day time.Time, summary *models.SubscriptionRunSummary) {
+	date := day.Format(subscriptionDateLayout)
+	now := time.Now().UTC()
+	claim := &models.SubscriptionDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		DeliveryDate:   date,
+		Status:         models.SubscriptionDeliveryPending,
+		OrderID:        uuid.New().String(),
+		ClaimedAt:      now,
+		CreatedAt:      now,
+	}
+	if err := s.subRepo.CreateSubscriptionDelivery(ctx, claim); err != nil {
+		if !errors.Is(err, repository.ErrSubscriptionDeliveryExists) {
+			log.Printf("Failed to claim %s for subscription %s: %v", date, sub.ID, err)
+			return
+		}
+		if claim = s.takeOverClaim(ctx, sub, date, now); claim == nil {
+			return
+		}
+		// The pass that died may have got as far as placing the order
+		if order, err := s.orders.orderRepo.GetOrderByID(ctx, claim.OrderID); err == nil {
+			s.recordOrdered(ctx, sub, claim, order, summary)
+			return
+		}
+	}
+
+	order, err := s.placeOrder(ctx, sub, claim)
+	if err != nil {
+		// A pass that took the claim over may have placed it meanwhile
+		if order, getErr := s.orders.orderRepo.GetOrderByID(ctx, claim.OrderID); getErr == nil {
+			s.recordOrdered(ctx, sub, claim, order, summary)
+			return
+		}
+		if err := s.subRepo.DeleteSubscriptionDelivery(ctx, claim.ID); err != nil {
+			log.Printf("Failed to release %s for subscription %s: %v", date, sub.ID, err)
+		}
+		s.recordFailure(ctx, sub, date, err)
+		summary.Failed++
+		return
+	}
+	s.recordOrdered(ctx, sub, claim, order, summary)
+}
+
+// takeOverClaim returns the existing claim on date if it has been pending
+// for longer than claimTimeout and this pass won it, else nil.
+func (s *SubscriptionService) takeOverClaim(ctx context.Context, sub *models.Subscription, date string, now time.Time) *models.SubscriptionDelivery {
+	claim, err := s.subRepo.GetSubscriptionDelivery(ctx, sub.ID, date)
+	if err != nil {
+		log.Printf("Failed to look up %s for subscription %s: %v", date, sub.ID, err)
+		return nil
+	}
+	if claim.Status != models.SubscriptionDeliveryPending || now.Sub(claim.ClaimedAt) < s.claimTimeout() {
+		return nil
+	}
+
+	won, err := s.subRepo.ReclaimSubscriptionDelivery(ctx, claim.ID, now.Add(-s.claimTimeout()), now)
+	if err != nil {
+		log.Printf("Failed to take over %s for subscription %s: %v", date, sub.ID, err)
+		return nil
+	}
+	if !won {
+		return nil
+	}
+	log.Printf("Taking over stale claim on %s for subscription %s", date, sub.ID)
+	if claim.OrderID == "" {
+		// Claimed before orders were named up front
+		claim.OrderID = uuid.New().String()
+		if err := s.subRepo.UpdateSubscriptionDelivery(ctx, claim.ID, map[string]interface{}{"order_id": claim.OrderID}); err != nil {
+			log.Printf("Failed to name order for %s of subscription %s: %v", date, sub.ID, err)
+			return nil
+		}
+	}
+	return claim
+}
+
+// claimTimeout is how long a pending claim may stay unfinished before its
+// pass is taken to have died. Passes are bounded by the run interval, so
+// it is twice that.
+func (s *SubscriptionService) claimTimeout() time.Duration {
+	if d := 2 * s.cfg.SubscriptionRunInterval; d > minSubscriptionClaimTimeout {
+		return d
+	}
+	return minSubscriptionClaimTimeout
+}
+
+// recordOrdered marks claim as ordered with order.
+func (s *SubscriptionService) recordOrdered(ctx context.Context, sub *models.Subscription, claim *models.SubscriptionDelivery, order *models.Order, summary *models.SubscriptionRunSummary) {
+	method := paymentMethodOf(order)
+	if err := s.subRepo.UpdateSubscriptionDelivery(ctx, claim.ID, map[string]interface{}{
+		"status":         models.SubscriptionDeliveryOrdered,
+		"order_id":       order.ID,
+		"payment_method": method,
+	}); err != nil {
+		log.Printf("Failed to record order %s for subscription %s on %s: %v", order.ID, sub.ID, claim.DeliveryDate, err)
+	}
+	if sub.LastError != "" {
+		if _, err := s.update(ctx, sub.ID, map[string]interface{}{"last_error": nil}); err != nil {
+			log.Printf("Failed to clear error of subscription %s: %v", sub.ID, err)
+		}
+		sub.LastError = ""
+	}
+	log.Printf("Order %s placed for subscription %s on %s (%s)", order.ID, sub.ID, claim.DeliveryDate, method)
+	summary.Ordered++
+}
+
+// placeOrder orders one delivery the way the subscription pays, falling back
+// to cash on delivery when the wallet is short and the customer allowed it.
+// The order gets the ID and date named in claim.
+func (s *SubscriptionService) placeOrder(ctx context.Context, sub *models.Subscription, claim *models.SubscriptionDelivery) (*models.Order, error) {
+	req := &models.CreateOrderRequest{
+		ShippingAddress: sub.ShippingAddress,
+		PaymentMethod:   sub.PaymentMethod,
+		DeliverySlot:    sub.DeliverySlot,
+		Items: []models.CreateOrderItem{
+			{ProductID: sub.ProductID, VariantID: sub.VariantID, Quantity: sub.Quantity},
+		},
+	}
+
+	order, err := s.orders.placeSubscriptionOrder(ctx, sub.UserID, claim.OrderID, claim.DeliveryDate, req)
+	if errors.Is(err, repository.ErrInsufficientBalance) && sub.CODFallback {
+		log.Printf("Wallet short for subscription %s, ordering cash on delivery", sub.ID)
+		req.PaymentMethod = models.PaymentMethodCOD
+		return s.orders.placeSubscriptionOrder(ctx, sub.UserID, claim.OrderID, claim.DeliveryDate, req)
+	}
+	return order, err
+}
+
+// recordFailure keeps why the latest order couldn't be placed and tells the
+// customer, once per distinct reason, so they can top up or change it.
+func (s *SubscriptionService) recordFailure(ctx context.Context, sub *models.Subscription, date string, cause error) {
+	reason := cause.Error()
+	log.Printf("Failed to place order for subscription %s on %s: %s", sub.ID, date, reason)
+	if reason == sub.LastError {
+		return
+	}
+
+	if _, err := s.update(ctx, sub.ID, map[string]interface{}{"last_error": reason}); err != nil {
+		log.Printf("Failed to record error of subscription %s: %v", sub.ID, err)
+	}
+	sub.LastError = reason
+
+	if !s.email.Configured() {
+		return
+	}
+	user, err := s.userRepo.GetUserByID(ctx, sub.UserID)
+	if err != nil {
+		log.Printf("Failed to look up customer for subscription %s: %v", sub.ID, err)
+		return
+	}
+	name := "subscription"
+	if product, err := s.productRepo.GetProductByID(ctx, sub.ProductID); err == nil {
+		name = product.Name
+	}
+	go s.email.SendSubscriptionFailure(user.Email, name, date, reason)
+}
+
+// recordMissed notes a delivery day that began without an order. A claim
+// still pending from a pass that died is settled here too: as ordered if its
+// order was placed, else as missed.
+func (s *SubscriptionService) recordMissed(ctx context.Context, sub *models.Subscription, day time.Time, summary *models.SubscriptionRunSummary) {
+	reason := sub.LastError
+	if reason == "" {
+		reason = "not ordered in time"
+	}
+	date := day.Format(subscriptionDateLayout)
+	now := time.Now().UTC()
+	err := s.subRepo.CreateSubscriptionDelivery(ctx, &models.SubscriptionDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		DeliveryDate:   date,
+		Status:         models.SubscriptionDeliveryMissed,
+		Reason:         reason,
+		ClaimedAt:      now,
+		CreatedAt:      now,
+	})
+	switch {
+	case err == nil:
+		summary.Missed++
+		return
+	case !errors.Is(err, repository.ErrSubscriptionDeliveryExists):
+		log.Printf("Failed to record missed delivery for subscription %s: %v", sub.ID, err)
+		return
+	}
+
+	claim := s.takeOverClaim(ctx, sub, date, now)
+	if claim == nil {
+		return
+	}
+	if order, err := s.orders.orderRepo.GetOrderByID(ctx, claim.OrderID); err == nil {
+		s.recordOrdered(ctx, sub, claim, order, summary)
+		return
+	}
+	if err := s.subRepo.UpdateSubscriptionDelivery(ctx, claim.ID, map[string]interface{}{
+		"status":   models.SubscriptionDeliveryMissed,
+		"order_id": nil,
+		"reason":   reason,
+	}); err != nil {
+		log.Printf("Failed to record missed delivery for subscription %s: %v", sub.ID, err)
+		return
+	}
+	summary.Missed++
+}
+
+func (s *SubscriptionService) ownedSubscription(ctx context.Context, id, userID string, isAdmin bool) (*models.Subscription, error) {
+	sub, err := s.subRepo.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && sub.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+	return sub, nil
+}
+
+func (s *SubscriptionService) update(ctx context.Context, id string, updates map[string]interface{}) (*models.Subscription, error) {
+	updates["updated_at"] = time.Now().UTC()
+	sub, err := s.subRepo.UpdateSubscription(ctx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+	s.describe(ctx, sub, s.vacationOf(ctx, sub.UserID))
+	return sub, nil
+}
+
+// describe fills in the product name and next delivery date for display.
+func (s *SubscriptionService) describe(ctx context.Context, sub *models.Subscription, vacation *models.SubscriptionVacation) {
+	if product, err := s.productRepo.GetProductByID(ctx, sub.ProductID); err == nil {
+		sub.ProductName = product.Name
+	}
+	sub.NextDeliveryDate = ""
+	day := istDay(time.Now())
+	for i := 0; i < subscriptionHorizonDays; i++ {
+		day = day.AddDate(0, 0, 1)
+		if isDue(sub, vacation, day) {
+			sub.NextDeliveryDate = day.Format(subscriptionDateLayout)
+			return
+		}
+	}
+}
+
+// vacationOf returns the user's vacation, or nil if they have none.
+func (s *SubscriptionService) vacationOf(ctx context.Context, userID string) *models.SubscriptionVacation {
+	vacation, err := s.subRepo.GetSubscriptionVacation(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	return vacation
+}
+
+// checkDelivery makes sure the address can be delivered to in the slot.
+func (s *SubscriptionService) checkDelivery(address map[string]interface{}, slot string) error {
+	if address == nil {
+		return errors.New("shipping address is required")
+	}
+	if slot != "" && !s.orders.shipping.HasSlot(slot) {
+		return errors.New("invalid delivery slot")
+	}
+	return s.orders.shipping.Delivers(destinationOf(address))
+}
+
+// checkSchedule validates a frequency and returns its weekdays sorted and
+// without repeats; only the weekdays frequency keeps them.
+func checkSchedule(frequency string, weekdays []int) ([]int, error) {
+	switch frequency {
+	case models.SubscriptionDaily, models.SubscriptionAlternateDays:
+		return nil, nil
+	case models.SubscriptionWeekdays:
+	default:
+		return nil, errors.New("invalid frequency")
+	}
+
+	if len(weekdays) == 0 {
+		return nil, errors.New("weekdays are required for a weekdays subscription")
+	}
+	out := []int{}
+	for _, d := range weekdays {
+		if d < 0 || d > 6 {
+			return nil, errors.New("weekdays must be 0 (Sunday) to 6 (Saturday)")
+		}
+		if !slices.Contains(out, d) {
+			out = append(out, d)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func checkSubscriptionPayment(method string) (string, error) {
+	switch method {
+	case "":
+		return models.PaymentMethodWallet, nil
+	case models.PaymentMethodWallet, models.PaymentMethodCOD:
+		return method, nil
+	}
+	return "", errors.New("invalid payment method, want wallet or cod")
+}
+
+// onSchedule reports whether the subscription's schedule falls on day,
+// ignoring pauses, skips and vacations.
+func onSchedule(sub *models.Subscription, day time.Time) bool {
+	date := day.Format(subscriptionDateLayout)
+	if date < sub.StartDate || (sub.EndDate != "" && date > sub.EndDate) {
+		return false
+	}
+
+	switch sub.Frequency {
+	case models.SubscriptionDaily:
+		return true
+	case models.SubscriptionAlternateDays:
+		start, err := parseSubscriptionDate(sub.StartDate, "start_date")
+		if err != nil {
+			return false
+		}
+		return int(day.Sub(start).Hours()/24)%2 == 0
+	case models.SubscriptionWeekdays:
+		return slices.Contains(sub.Weekdays, int(day.Weekday()))
+	}
+	return false
+}
+
+// isDue reports whether sub should deliver on day.
+func isDue(sub *models.Subscription, vacation *models.SubscriptionVacation, day time.Time) bool {
+	if sub.Status == models.SubscriptionCancelled || !onSchedule(sub, day) {
+		return false
+	}
+
+	date := day.Format(subscriptionDateLayout)
+	if sub.Status == models.SubscriptionPaused && (sub.PausedUntil == "" || date <= sub.PausedUntil) {
+		return false
+	}
+	if slices.Contains(sub.SkipDates, date) {
+		return false
+	}
+	if vacation != nil && date >= vacation.StartDate && date <= vacation.EndDate {
+		return false
+	}
+	return true
+}
+
+// istDay is the start of t's calendar day in India.
+func istDay(t time.Time) time.Time {
+	y, m, d := t.In(ist).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, ist)
+}
+
+func parseSubscriptionDate(date, field string) (time.Time, error) {
+	day, err := time.ParseInLocation(subscriptionDateLayout, date, ist)
+	if err != nil {
+		return time.Time{}, errors.New("invalid " + field + ", want YYYY-MM-DD")
+	}
+	return day, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+func TestRunOnceSettlesStaleClaims(t *testing.T) {
+	now := time.Now()
+	today := istDay(now).Format(subscriptionDateLayout)
+	tomorrow := istDay(now).AddDate(0, 0, 1).Format(subscriptionDateLayout)
+	stale := now.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		date       string
+		claimedAt  time.Time
+		orderSaved bool // the dead pass got as far as saving the order
+		wantStatus string
+		wantOrders int
+	}{
+		{"stale claim is ordered again", tomorrow, stale, false, models.SubscriptionDeliveryOrdered, 1},
+		{"stale claim with its order is finished", tomorrow, stale, true, models.SubscriptionDeliveryOrdered, 1},
+		{"live claim is left alone", tomorrow, now, false, models.SubscriptionDeliveryPending, 0},
+		{"stale claim for today is missed", today, stale, false, models.SubscriptionDeliveryMissed, 0},
+		{"stale claim for today with its order is finished", today, stale, true, models.SubscriptionDeliveryOrdered, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServices(t)
+			ts.cfg.SubscriptionLeadTime = 36 * time.Hour
+			ts.addProduct(t, "p1", 3000, 10)
+			ctx := context.Background()
+			if _, err := ts.wallets.AdjustWalletBalance(ctx, "u1", 100000); err != nil {
+				t.Fatal(err)
+			}
+
+			subRepo := ts.subs
+			subs := NewSubscriptionService(ts.cfg, subRepo, ts.products, ts.users, ts.orderService, &EmailService{})
+			sub := &models.Subscription{
+				ID:              "s1",
+				UserID:          "u1",
+				ProductID:       "p1",
+				Quantity:        1,
+				Frequency:       models.SubscriptionDaily,
+				StartDate:       istDay(now).AddDate(0, 0, -1).Format(subscriptionDateLayout),
+				EndDate:         tomorrow,
+				Status:          models.SubscriptionActive,
+				ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+				PaymentMethod:   models.PaymentMethodWallet,
+			}
+			if err := subRepo.CreateSubscription(ctx, sub); err != nil {
+				t.Fatal(err)
+			}
+			// The other day is already ordered, so only tt.date is in play
+			for _, date := range []string{today, tomorrow} {
+				status := models.SubscriptionDeliveryOrdered
+				if date == tt.date {
+					status = models.SubscriptionDeliveryPending
+				}
+				err := subRepo.CreateSubscriptionDelivery(ctx, &models.SubscriptionDelivery{
+					ID: "d-" + date, SubscriptionID: sub.ID, UserID: sub.UserID, DeliveryDate: date,
+					Status: status, OrderID: "o-" + date, ClaimedAt: tt.claimedAt,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.orderSaved {
+				if _, err := ts.orderService.placeSubscriptionOrder(ctx, "u1", "o-"+tt.date, tt.date, &models.CreateOrderRequest{
+					ShippingAddress: sub.ShippingAddress,
+					PaymentMethod:   models.PaymentMethodWallet,
+					Items:           []models.CreateOrderItem{{ProductID: "p1", Quantity: 1}},
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := subs.RunOnce(ctx, now); err != nil {
+				t.Fatal(err)
+			}
+
+			delivery, err := subRepo.GetSubscriptionDelivery(ctx, sub.ID, tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if delivery.Status != tt.wantStatus {
+				t.Fatalf("delivery status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+			orders, _ := ts.orders.GetOrdersByUserID(ctx, "u1")
+			if len(orders) != tt.wantOrders {
+				t.Fatalf("%d orders, want %d", len(orders), tt.wantOrders)
+			}
+			if tt.wantOrders == 1 && (orders[0].ID != "o-"+tt.date || orders[0].DeliveryDate != tt.date) {
+				t.Fatalf("order %s for %q, want o-%s for %s", orders[0].ID, orders[0].DeliveryDate, tt.date, tt.date)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+)
+
+// UroPayGateway collects UPI payments through UroPay. UroPay has no refund
+// API, so refunds for these orders are paid out manually.
+type UroPayGateway struct {
+	cfg          *config.Config
+	hashedSecret string
+	httpClient   *http.Client
+}
+
+func NewUroPayGateway(cfg *config.Config, httpClient *http.Client) *UroPayGateway {
+	// Pre-compute SHA-512 hash of the secret
+	h := sha512.New()
+	h.Write([]byte(cfg.UroPaySecret))
+	hashed := hex.EncodeToString(h.Sum(nil))
+
+	return &UroPayGateway{
+		cfg:          cfg,
+		hashedSecret: hashed,
+		httpClient:   httpClient,
+	}
+}
+
+func (g *UroPayGateway) Name() string {
+	return "uropay"
+}
+
+func (g *UroPayGateway) CreateIntent(ctx context.Context, in PaymentIntentRequest) (*PaymentIntent, error) {
+	// UroPay requires a non-empty customerEmail
+	customerEmail := in.CustomerEmail
+	if customerEmail == "" {
+		customerEmail = "customer@dailybazaar.com"
+	}
+
+	note := in.OrderID
+	if len(note) > 8 {
+		note = note[:8]
+	}
+
+	reqBody := models.UroPayGenerateRequest{
+		VPA:     g.cfg.UroPayVPA,
+		VPAName: g.cfg.UroPayVPAName,
+		// UroPay expects amount in paise (e.g. 15300 for ₹153)
+		Amount:          float64(in.AmountPaise),
+		MerchantOrderId: in.OrderID,
+		CustomerName:    in.CustomerName,
+		CustomerEmail:   customerEmail,
+		TransactionNote: fmt.Sprintf("Order %s", note),
+		Notes: map[string]string{
+			"order_id": in.OrderID,
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req, err := http.NewRequestWithContext(ctx, "POST", g.cfg.UroPayBaseURL+"/order/generate", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	g.setHeaders(req)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("UroPay API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	log.Printf("UroPay /order/generate response [%d]: %s", resp.StatusCode, string(respBody))
+
+	var uroResp models.UroPayGenerateResponse
+	if err := json.Unmarshal(respBody, &uroResp); err != nil {
+		return nil, fmt.Errorf("UroPay API returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if uroResp.Code != 200 {
+		return nil, fmt.Errorf("UroPay error (%d): %s", uroResp.Code, string(uroResp.Message))
+	}
+
+	return &PaymentIntent{
+		GatewayOrderID: uroResp.Data.UroPayOrderId,
+		UPIString:      uroResp.Data.UPIString,
+		QRCode:         uroResp.Data.QRCode,
+		AmountInRupees: uroResp.Data.AmountInRupees.String(),
+		Metadata: map[string]interface{}{
+			// Kept for orders and clients that predate the gateway field
+			"uropay_order_id": uroResp.Data.UroPayOrderId,
+		},
+	}, nil
+}
+
+func (g *UroPayGateway) SubmitReference(ctx context.Context, uroPayOrderId, referenceNumber string) error {
+	reqBody := models.UroPayUpdateRequest{
+		UroPayOrderId:   uroPayOrderId,
+		ReferenceNumber: referenceNumber,
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req, err := http.NewRequestWithContext(ctx, "PATCH", g.cfg.UroPayBaseURL+"/order/update", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	g.setHeaders(req)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("UroPay API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	var uroResp models.UroPayUpdateResponse
+	if err := json.Unmarshal(respBody, &uroResp); err != nil {
+		return fmt.Errorf("failed to parse UroPay response: %w", err)
+	}
+
+	if uroResp.Code != 200 {
+		return fmt.Errorf("UroPay error: %s", uroResp.Message)
+	}
+	return nil
+}
+
+func (g *UroPayGateway) QueryStatus(ctx context.Context, uroPayOrderId string) (*GatewayStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.cfg.UroPayBaseURL+"/order/status/"+uroPayOrderId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-KEY", g.cfg.UroPayAPIKey)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("UroPay API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var statusResp models.UroPayStatusResponse
+	if err := json.Unmarshal(respBody, &statusResp); err != nil {
+		return nil, fmt.Errorf("failed to parse UroPay response: %w", err)
+	}
+	if statusResp.Code != 200 {
+		return nil, fmt.Errorf("UroPay error: %s", statusResp.Message)
+	}
+
+	raw := statusResp.Data.OrderStatus
+	status := GatewayStatusPending
+	switch strings.ToUpper(raw) {
+	case "COMPLETED":
+		status = GatewayStatusCompleted
+	case "FAILED", "CANCELLED", "EXPIRED":
+		status = GatewayStatusFailed
+	}
+	return &GatewayStatus{Status: status, RawStatus: raw}, nil
+}
+
+func (g *UroPayGateway) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	var payload models.UroPayWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("invalid webhook payload")
+	}
+
+	if !g.verifySignature(payload, header.Get("X-Uropay-Signature"), header.Get("X-Uropay-Environment")) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	return &WebhookEvent{
+		ReferenceNumber: payload.ReferenceNumber,
+		Amount:          payload.Amount,
+		Payer:           payload.From,
+	}, nil
+}
+
+func (g *UroPayGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	return nil, ErrRefundNotSupported
+}
+
+func (g *UroPayGateway) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-API-KEY", g.cfg.UroPayAPIKey)
+	req.Header.Set("Authorization", "Bearer "+g.hashedSecret)
+}
+
+func (g *UroPayGateway) verifySignature(payload models.UroPayWebhookPayload, signature, environment string) bool {
+	if g.hashedSecret == "" || signature == "" {
+		return false
+	}
+
+	// Build sorted data + environment
+	data := map[string]string{
+		"amount":          payload.Amount,
+		"from":            payload.From,
+		"referenceNumber": payload.ReferenceNumber,
+		"vpa":             payload.VPA,
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sorted := make(map[string]string)
+	for _, k := range keys {
+		sorted[k] = data[k]
+	}
+	sorted["environment"] = environment
+
+	payloadBytes, _ := json.Marshal(sorted)
+
+	mac := hmac.New(sha256.New, []byte(g.hashedSecret))
+	mac.Write(payloadBytes)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository"
+)
+
+// walletHistory is how many recent transactions come back with a wallet
+const walletHistory = 50
+
+// WalletService shows customers their prepaid balance and lets admins top it
+// up. Spending and refunds happen through orders.
+type WalletService struct {
+	walletRepo repository.WalletStore
+	userRepo   repository.UserStore
+}
+
+func NewWalletService(walletRepo repository.WalletStore, userRepo repository.UserStore) *WalletService {
+	return &WalletService{
+		walletRepo: walletRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// GetWallet returns the balance with the latest transactions.
+func (s *WalletService) GetWallet(ctx context.Context, userID string) (*models.Wallet, error) {
+	wallet, err := s.walletRepo.GetWallet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet.Transactions, err = s.walletRepo.GetWalletTransactions(ctx, userID, walletHistory, 0)
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// Credit adds money to a user's wallet, e.g. after they paid an admin for a
+// top-up.
+func (s *WalletService) Credit(ctx context.Context, userID string, req *models.CreditWalletRequest, adminID string) (*models.Wallet, error) {
+	if req.AmountCents <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if _, err := s.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if _, err := moveWalletBalance(ctx, s.walletRepo, userID, req.AmountCents, models.WalletCredit, "", req.Note, adminID); err != nil {
+		return nil, err
+	}
+	log.Printf("Wallet of user %s credited ₹%s by %s", userID, formatRupees(req.AmountCents), adminID)
+	return s.GetWallet(ctx, userID)
+}
+
+// moveWalletBalance changes a wallet's balance and writes the matching
+// ledger line. The balance is the source of truth, so a ledger line that
+// can't be written is only logged.
+func moveWalletBalance(ctx context.Context, walletRepo repository.WalletStore, userID string, amountCents int64, kind, orderID, note, actor string) (*models.WalletTransaction, error) {
+	balance, err := walletRepo.AdjustWalletBalance(ctx, userID, amountCents)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := &models.WalletTransaction{
+		ID:                uuid.New().String(),
+		UserID:            userID,
+		AmountCents:       amountCents,
+		BalanceAfterCents: balance,
+		Kind:              kind,
+		OrderID:           orderID,
+		Note:              note,
+		CreatedBy:         actor,
+		CreatedAt:         time.Now().UTC(),
+	}
+	if err := walletRepo.CreateWalletTransaction(ctx, txn); err != nil {
+		log.Printf("Failed to record wallet transaction for user %s (%s ₹%s): %v", userID, kind, formatRupees(amountCents), err)
+	}
+	return txn, nil
+}
+
+// creditWalletRefund pays refund back into the customer's wallet, once. The
+// ledger line, unique per refund, is written before the balance moves: a
+// retry that finds it knows the money went in. If the balance can't be
+// changed the line is removed again so the refund can be retried.
+func creditWalletRefund(ctx context.Context, walletRepo repository.WalletStore, refund *models.Refund) error {
+	txn := &models.WalletTransaction{
+		ID:          uuid.New().String(),
+		UserID:      refund.UserID,
+		AmountCents: refund.AmountCents,
+		Kind:        models.WalletRefund,
+		OrderID:     refund.OrderID,
+		RefundID:    refund.ID,
+		Note:        refund.Reason,
+		CreatedBy:   "system",
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := walletRepo.CreateWalletTransaction(ctx, txn); err != nil {
+		if errors.Is(err, repository.ErrWalletRefundExists) {
+			return nil
+		}
+		return err
+	}
+
+	balance, err := walletRepo.AdjustWalletBalance(ctx, refund.UserID, refund.AmountCents)
+	if err != nil {
+		if delErr := walletRepo.DeleteWalletTransaction(ctx, txn.ID); delErr != nil {
+			log.Printf("Failed to remove wallet transaction %s for refund %s: %v", txn.ID, refund.ID, delErr)
+		}
+		return err
+	}
+
+	if err := walletRepo.UpdateWalletTransaction(ctx, txn.ID, map[string]interface{}{"balance_after_cents": balance}); err != nil {
+		log.Printf("Failed to record balance on wallet transaction %s: %v", txn.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the upstream while its
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Breaker is a consecutive-failure circuit breaker. After threshold failures
+// in a row it opens and rejects calls for cooldown, then lets a single probe
+// through (half-open); the probe's outcome closes or re-opens it.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	lastError   string
+	lastFailure time.Time
+}
+
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown, state: StateClosed}
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Allow reports whether a call may proceed. Callers that are allowed must
+// report the outcome with Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		// Only one probe at a time
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastFailure = time.Now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	b.probing = false
+
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Release ends an allowed call without recording an outcome, e.g. when the
+// caller cancelled it.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if b.state == StateHalfOpen {
+		// Let the next call probe instead of waiting out another cooldown
+		b.state = StateOpen
+		b.openedAt = time.Now().Add(-b.cooldown)
+	}
+}
+
+// BreakerStatus is a point-in-time view of a breaker for health reporting.
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := BreakerStatus{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	// An open breaker whose cooldown has passed will admit the next call
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cooldown {
+		st.State = StateHalfOpen
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		st.OpenedAt = &openedAt
+	}
+	if !b.lastFailure.IsZero() {
+		lastFailure := b.lastFailure
+		st.LastFailureAt = &lastFailure
+	}
+	return st
+}
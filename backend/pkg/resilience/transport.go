@@ -0,0 +1,206 @@
+// Package resilience wraps outbound HTTP calls with retries (jittered
+// exponential backoff) and a per-upstream circuit breaker.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Options struct {
+	// MaxRetries is the number of attempts after the first one
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// AttemptTimeout bounds each attempt (0 = only the request context)
+	AttemptTimeout time.Duration
+}
+
+// Transport is an http.RoundTripper that retries safe requests and trips a
+// breaker when the upstream keeps failing.
+//
+// Idempotent methods (GET, HEAD, OPTIONS, PUT, DELETE) are always retried.
+// POST and PATCH are retried only when the request carries an
+// Idempotency-Key header the upstream honours, or when the connection could
+// not be opened so the request never left; repeating them could otherwise
+// apply twice.
+type Transport struct {
+	base    http.RoundTripper
+	breaker *Breaker
+	opts    Options
+}
+
+func NewTransport(base http.RoundTripper, breaker *Breaker, opts Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 100 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 2 * time.Second
+	}
+	return &Transport{base: base, breaker: breaker, opts: opts}
+}
+
+// NewHTTPClient returns a client whose transport is guarded by breaker.
+// Overall time is bounded by the request context rather than Client.Timeout
+// so that retries share the caller's deadline.
+func NewHTTPClient(breaker *Breaker, opts Options) *http.Client {
+	return &http.Client{Transport: NewTransport(nil, breaker, opts)}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isRetryable(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.breaker.Allow(); err != nil {
+			closeBody(req)
+			return nil, fmt.Errorf("%s: %w", t.breaker.Name(), err)
+		}
+
+		resp, err := t.attempt(req, attempt)
+
+		// The caller gave up; that says nothing about upstream health
+		if req.Context().Err() != nil {
+			t.breaker.Release()
+			if err == nil {
+				return resp, nil
+			}
+			return nil, err
+		}
+
+		if err != nil || resp.StatusCode >= 500 {
+			failure := err
+			if failure == nil {
+				failure = fmt.Errorf("status %d", resp.StatusCode)
+			}
+			t.breaker.Failure(failure)
+		} else {
+			t.breaker.Success()
+		}
+
+		if attempt >= t.opts.MaxRetries || !shouldRetry(resp, err) || !(retryable || notSent(err)) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (t *Transport) attempt(req *http.Request, n int) (*http.Response, error) {
+	r := req
+	if n > 0 {
+		r = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+	}
+
+	if t.opts.AttemptTimeout <= 0 {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), t.opts.AttemptTimeout)
+	resp, err := t.base.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// Keep the attempt context alive until the caller has read the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff is exponential with equal jitter, honouring Retry-After when the
+// upstream sends one that fits within MaxDelay.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			if d := time.Duration(secs) * time.Second; d <= t.opts.MaxDelay {
+				return d
+			}
+		}
+	}
+
+	d := t.opts.BaseDelay << attempt
+	if d <= 0 || d > t.opts.MaxDelay {
+		d = t.opts.MaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func isRetryable(req *http.Request) bool {
+	// A body we can't replay can't be retried
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return req.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+// notSent reports whether err means the request never reached the upstream:
+// the name didn't resolve or the connection was refused.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
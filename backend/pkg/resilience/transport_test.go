@@ -0,0 +1,87 @@
+package resilience
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testClient(maxRetries int) *http.Client {
+	breaker := NewBreaker("test", 100, time.Second)
+	return NewHTTPClient(breaker, Options{MaxRetries: maxRetries, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+}
+
+func TestRetriesOnlySafeRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		key       string
+		wantCalls int32
+	}{
+		{"get is retried", http.MethodGet, "", 3},
+		{"post is not retried", http.MethodPost, "", 1},
+		{"patch is not retried", http.MethodPatch, "", 1},
+		{"keyed post is retried", http.MethodPost, "abc", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			req, _ := http.NewRequest(tt.method, srv.URL, bytes.NewReader([]byte(`{}`)))
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			resp, err := testClient(2).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("upstream saw %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetriesPostWhenConnectionRefused(t *testing.T) {
+	// Reserve a port and close it so nothing is listening
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	var attempts int32
+	breaker := NewBreaker("test", 100, time.Second)
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	client := &http.Client{Transport: NewTransport(base, breaker, Options{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr, bytes.NewReader([]byte(`{}`)))
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("%d attempts, want 3", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -1 +1,318 @@
+// Package supabase is a small typed client for the Supabase PostgREST API.
+// It owns request construction, auth headers, value escaping, Prefer
+// handling and error decoding so repositories only describe their queries.
 package supabase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the project at baseURL (e.g.
+// https://xyz.supabase.co) authenticating with apiKey.
+func NewClient(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: httpClient,
+	}
+}
+
+// From starts a query against a table or view.
+func (c *Client) From(table string) *Query {
+	return &Query{
+		client: c,
+		path:   "/rest/v1/" + table,
+		params: url.Values{},
+		header: http.Header{},
+	}
+}
+
+// RPC calls a Postgres function exposed at /rest/v1/rpc/<fn> and decodes the
+// result into out (which may be nil).
+func (c *Client) RPC(ctx context.Context, fn string, params interface{}, out interface{}) error {
+	q := &Query{
+		client: c,
+		path:   "/rest/v1/rpc/" + fn,
+		params: url.Values{},
+		header: http.Header{},
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return q.execute(ctx, http.MethodPost, params, out)
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("apikey", c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return c.httpClient.Do(req)
+}
+
+// Query is a PostgREST request under construction. Filter methods return the
+// query so calls can be chained; a query is executed by Get, Insert, Upsert,
+// Update or Delete and should not be reused afterwards. The context passed to
+// those bounds the HTTP round trip, so request deadlines and client
+// disconnects cancel the upstream call.
+type Query struct {
+	client *Client
+	path   string
+	params url.Values
+	header http.Header
+	prefer []string
+}
+
+// Select sets the column list, including embedded resources such as
+// "*,images:product_images(id,url)".
+func (q *Query) Select(columns string) *Query {
+	q.params.Set("select", columns)
+	return q
+}
+
+func (q *Query) Eq(column string, value interface{}) *Query {
+	return q.filter(column, "eq", value)
+}
+
+func (q *Query) Neq(column string, value interface{}) *Query {
+	return q.filter(column, "neq", value)
+}
+
+func (q *Query) Gt(column string, value interface{}) *Query {
+	return q.filter(column, "gt", value)
+}
+
+func (q *Query) Gte(column string, value interface{}) *Query {
+	return q.filter(column, "gte", value)
+}
+
+func (q *Query) Lt(column string, value interface{}) *Query {
+	return q.filter(column, "lt", value)
+}
+
+func (q *Query) Lte(column string, value interface{}) *Query {
+	return q.filter(column, "lte", value)
+}
+
+// ILike matches a case-insensitive pattern; use * (or %) as the wildcard.
+func (q *Query) ILike(column, pattern string) *Query {
+	return q.filter(column, "ilike", pattern)
+}
+
+// In matches any of values.
+func (q *Query) In(column string, values []string) *Query {
+	q.params.Add(column, "in."+List(values))
+	return q
+}
+
+// IsNull / NotNull filter on NULL-ness.
+func (q *Query) IsNull(column string) *Query {
+	q.params.Add(column, "is.null")
+	return q
+}
+
+func (q *Query) NotNull(column string) *Query {
+	q.params.Add(column, "not.is.null")
+	return q
+}
+
+// Or combines conditions built with Cond, e.g.
+// Or(Cond("name", "ilike", p), Cond("description", "ilike", p)).
+func (q *Query) Or(conditions ...string) *Query {
+	q.params.Add("or", "("+strings.Join(conditions, ",")+")")
+	return q
+}
+
+// Order sorts by column; call repeatedly for secondary sort keys.
+func (q *Query) Order(column string, ascending bool) *Query {
+	dir := "desc"
+	if ascending {
+		dir = "asc"
+	}
+	if existing := q.params.Get("order"); existing != "" {
+		q.params.Set("order", existing+","+column+"."+dir)
+	} else {
+		q.params.Set("order", column+"."+dir)
+	}
+	return q
+}
+
+// Limit caps the number of rows; zero or less leaves it unset.
+func (q *Query) Limit(n int) *Query {
+	if n > 0 {
+		q.params.Set("limit", strconv.Itoa(n))
+	}
+	return q
+}
+
+// Offset skips rows; zero or less leaves it unset.
+func (q *Query) Offset(n int) *Query {
+	if n > 0 {
+		q.params.Set("offset", strconv.Itoa(n))
+	}
+	return q
+}
+
+// Range selects rows from..to inclusive (zero-based) via the Range header.
+func (q *Query) Range(from, to int) *Query {
+	q.header.Set("Range-Unit", "items")
+	q.header.Set("Range", fmt.Sprintf("%d-%d", from, to))
+	return q
+}
+
+// Prefer adds a Prefer directive such as "count=exact" or
+// "resolution=ignore-duplicates".
+func (q *Query) Prefer(directive string) *Query {
+	q.prefer = append(q.prefer, directive)
+	return q
+}
+
+// Get runs a SELECT and decodes the rows into out (normally a slice).
+func (q *Query) Get(ctx context.Context, out interface{}) error {
+	return q.execute(ctx, http.MethodGet, nil, out)
+}
+
+// Insert creates one row or a slice of rows. When out is non-nil the
+// inserted rows are returned and decoded into it.
+func (q *Query) Insert(ctx context.Context, rows interface{}, out interface{}) error {
+	q.preferReturn(out)
+	return q.execute(ctx, http.MethodPost, rows, out)
+}
+
+// Upsert inserts rows, updating existing ones that clash on the primary key
+// (or on the columns passed to OnConflict).
+func (q *Query) Upsert(ctx context.Context, rows interface{}, out interface{}) error {
+	q.Prefer("resolution=merge-duplicates")
+	return q.Insert(ctx, rows, out)
+}
+
+// OnConflict names the unique columns Upsert should resolve on.
+func (q *Query) OnConflict(columns string) *Query {
+	q.params.Set("on_conflict", columns)
+	return q
+}
+
+// Update patches every row matching the filters. When out is non-nil the
+// updated rows are returned and decoded into it; an empty result means no
+// row matched.
+func (q *Query) Update(ctx context.Context, values interface{}, out interface{}) error {
+	q.preferReturn(out)
+	return q.execute(ctx, http.MethodPatch, values, out)
+}
+
+// Delete removes every row matching the filters.
+func (q *Query) Delete(ctx context.Context) error {
+	return q.execute(ctx, http.MethodDelete, nil, nil)
+}
+
+// URL returns the request URL the query would use.
+func (q *Query) URL() string {
+	u := q.client.baseURL + q.path
+	if len(q.params) > 0 {
+		u += "?" + q.params.Encode()
+	}
+	return u
+}
+
+// filter adds a plain "column=op.value" filter. PostgREST takes everything
+// after the operator literally here, so the value is not quoted; URL encoding
+// keeps it from spilling into other parameters.
+func (q *Query) filter(column, op string, value interface{}) *Query {
+	q.params.Add(column, op+"."+fmt.Sprint(value))
+	return q
+}
+
+func (q *Query) preferReturn(out interface{}) {
+	if out != nil {
+		q.Prefer("return=representation")
+	} else {
+		q.Prefer("return=minimal")
+	}
+}
+
+func (q *Query) execute(ctx context.Context, method string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("supabase: failed to encode body: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, q.URL(), reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range q.header {
+		req.Header[k] = v
+	}
+	if len(q.prefer) > 0 {
+		req.Header.Set("Prefer", strings.Join(q.prefer, ","))
+	}
+
+	resp, err := q.client.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newError(method, q.path, resp.StatusCode, respBody)
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("supabase: failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// Cond builds a "column.op.value" condition for use inside Or.
+func Cond(column, op string, value interface{}) string {
+	return column + "." + op + "." + Quote(value)
+}
+
+// List renders values as a PostgREST list, e.g. ("a","b,c").
+func List(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = Quote(v)
+	}
+	return "(" + strings.Join(quoted, ",") + ")"
+}
+
+// Quote renders a value for an in.(...) list or an or=(...) condition,
+// wrapping it in double quotes when it contains characters PostgREST treats
+// as syntax there (, . : ( ) " \ or spaces). Plain operator filters must not
+// be quoted: PostgREST would keep the quotes as part of the value.
+func Quote(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, ",.:()\"\\ ") {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		return `"` + s + `"`
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package supabase
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFilterValues(t *testing.T) {
+	c := NewClient("https://example.supabase.co", "key", nil)
+
+	tests := []struct {
+		name  string
+		query *Query
+		param string
+		want  string
+	}{
+		{"eq email is not quoted", c.From("users").Eq("email", "a.b@example.com"), "email", "eq.a.b@example.com"},
+		{"lt timestamp is not quoted", c.From("t").Lt("expires_at", "2026-10-17T07:50:23Z"), "expires_at", "lt.2026-10-17T07:50:23Z"},
+		{"value with comma stays one filter", c.From("t").Eq("name", "a,b&c=d"), "name", "eq.a,b&c=d"},
+		{"in list quotes syntax", c.From("t").In("id", []string{"a", "b,c", `d"e`}), "id", `in.(a,"b,c","d\"e")`},
+		{"or condition quotes syntax", c.From("t").Or(Cond("name", "ilike", "*milk, 1L*")), "or", `(name.ilike."*milk, 1L*")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.query.URL())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := u.Query()[tt.param]
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("%s = %q, want %q", tt.param, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Common PostgREST / Postgres error codes.
+const (
+	CodeNoRows              = "PGRST116" // .single() matched zero or many rows
+	CodeUniqueViolation     = "23505"
+	CodeForeignKeyViolation = "23503"
+	CodeCheckViolation      = "23514"
+)
+
+// Error is a non-2xx PostgREST response. Code carries the PostgREST or
+// Postgres SQLSTATE code when the body included one.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Method     string `json:"-"`
+	Path       string `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details"`
+	Hint       string `json:"hint"`
+	Body       string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("supabase %s %s: status %d, code %s: %s", e.Method, e.Path, e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("supabase %s %s: status %d, body: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+func newError(method, path string, status int, body []byte) *Error {
+	e := &Error{}
+	// Details/hint may be null or non-string; ignore decode failures
+	_ = json.Unmarshal(body, e)
+	e.StatusCode = status
+	e.Method = method
+	e.Path = path
+	e.Body = string(body)
+	return e
+}
+
+// IsCode reports whether err is a PostgREST error with the given code.
+func IsCode(err error, code string) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
+
+// StatusCode returns the HTTP status of a PostgREST error, or 0.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,323 @@
+// Package uropaysim is a local stand-in for the UroPay API. It serves
+// /order/generate, /order/update and /order/status/{id} with the same request
+// and response shapes as api.uropay.me, and pays orders by posting signed
+// webhooks, so the pay-then-confirm flow can run without the real service.
+package uropaysim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Order statuses as reported by UroPay
+const (
+	StatusCreated   = "CREATED"
+	StatusUpdated   = "UPDATED"
+	StatusCompleted = "COMPLETED"
+	StatusFailed    = "FAILED"
+)
+
+type Options struct {
+	// Credentials the client must present; an empty APIKey accepts any key
+	APIKey string
+	Secret string
+	VPA    string
+
+	// Environment is sent in X-Uropay-Environment (default "TEST")
+	Environment string
+
+	// WebhookURL receives a signed webhook when an order is paid; empty
+	// disables webhooks
+	WebhookURL string
+	// PayOnUpdate completes an order once its reference is submitted, as if
+	// the customer's UPI payment went through. References starting with
+	// "FAIL" fail instead.
+	PayOnUpdate bool
+	// WebhookDelay is how long after payment the webhook is sent
+	WebhookDelay time.Duration
+
+	HTTPClient *http.Client
+}
+
+// Server is an http.Handler; mount it at the root of a listener or behind
+// http.StripPrefix and point the UroPay base URL at it.
+type Server struct {
+	opts    Options
+	emitter *Emitter
+	mux     *http.ServeMux
+
+	mu       sync.Mutex
+	seq      int
+	orders   map[string]*Order // by UroPay order ID
+	merchant map[string]string // merchantOrderId -> UroPay order ID
+}
+
+// Order is the simulator's view of one UroPay order.
+type Order struct {
+	ID              string
+	MerchantOrderID string
+	AmountPaise     int64
+	CustomerName    string
+	CustomerEmail   string
+	ReferenceNumber string
+	Status          string
+	CreatedAt       time.Time
+}
+
+func New(opts Options) *Server {
+	if opts.Environment == "" {
+		opts.Environment = "TEST"
+	}
+	if opts.VPA == "" {
+		opts.VPA = "dailybazaar@upi"
+	}
+
+	s := &Server{
+		opts:     opts,
+		mux:      http.NewServeMux(),
+		orders:   map[string]*Order{},
+		merchant: map[string]string{},
+	}
+	if opts.WebhookURL != "" {
+		s.emitter = &Emitter{
+			URL:         opts.WebhookURL,
+			Secret:      opts.Secret,
+			Environment: opts.Environment,
+			Client:      opts.HTTPClient,
+		}
+	}
+
+	// Status lookups only need the API key; changes also need the secret
+	s.mux.HandleFunc("POST /order/generate", s.requireSecret(s.generate))
+	s.mux.HandleFunc("PATCH /order/update", s.requireSecret(s.update))
+	s.mux.HandleFunc("GET /order/status/{id}", s.status)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.APIKey != "" && r.Header.Get("X-API-KEY") != s.opts.APIKey {
+		writeJSON(w, http.StatusUnauthorized, "error", "invalid api key", nil)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Order returns a copy of a simulated order.
+func (s *Server) Order(id string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// Complete marks an order paid and, if a webhook URL is set, notifies the
+// merchant. The reference defaults to the one the customer submitted.
+func (s *Server) Complete(ctx context.Context, id, referenceNumber string) error {
+	s.mu.Lock()
+	o, ok := s.orders[id]
+	if !ok {
+		s.mu.Unlock()
+		return errors.New("order not found")
+	}
+	if referenceNumber != "" {
+		o.ReferenceNumber = referenceNumber
+	}
+	if o.ReferenceNumber == "" {
+		s.mu.Unlock()
+		return errors.New("order has no reference number")
+	}
+	o.Status = StatusCompleted
+	payload := WebhookPayload{
+		Amount:          formatRupees(o.AmountPaise),
+		ReferenceNumber: o.ReferenceNumber,
+		From:            o.CustomerName,
+		VPA:             s.opts.VPA,
+	}
+	s.mu.Unlock()
+
+	if s.emitter == nil {
+		return nil
+	}
+	return s.emitter.Emit(ctx, payload)
+}
+
+// Fail marks an order failed.
+func (s *Server) Fail(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return errors.New("order not found")
+	}
+	o.Status = StatusFailed
+	return nil
+}
+
+type generateRequest struct {
+	VPA             string            `json:"vpa"`
+	VPAName         string            `json:"vpaName"`
+	Amount          float64           `json:"amount"` // in paise
+	MerchantOrderID string            `json:"merchantOrderId"`
+	CustomerName    string            `json:"customerName"`
+	CustomerEmail   string            `json:"customerEmail"`
+	TransactionNote string            `json:"transactionNote,omitempty"`
+	Notes           map[string]string `json:"notes,omitempty"`
+}
+
+type updateRequest struct {
+	UroPayOrderID   string `json:"uroPayOrderId"`
+	ReferenceNumber string `json:"referenceNumber"`
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, "error", "invalid request body", nil)
+		return
+	}
+	if req.MerchantOrderID == "" || req.CustomerName == "" || req.CustomerEmail == "" || req.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, "error", "merchantOrderId, customerName, customerEmail and amount are required", nil)
+		return
+	}
+
+	s.mu.Lock()
+	// Same merchant order, same UroPay order
+	o, ok := s.orders[s.merchant[req.MerchantOrderID]]
+	if !ok {
+		s.seq++
+		o = &Order{
+			ID:              fmt.Sprintf("sim_%06d", s.seq),
+			MerchantOrderID: req.MerchantOrderID,
+			AmountPaise:     int64(req.Amount),
+			CustomerName:    req.CustomerName,
+			CustomerEmail:   req.CustomerEmail,
+			Status:          StatusCreated,
+			CreatedAt:       time.Now(),
+		}
+		s.orders[o.ID] = o
+		s.merchant[o.MerchantOrderID] = o.ID
+	}
+	out := *o
+	s.mu.Unlock()
+
+	vpa := req.VPA
+	if vpa == "" {
+		vpa = s.opts.VPA
+	}
+	amount := formatRupees(out.AmountPaise)
+	writeJSON(w, http.StatusOK, "success", "order generated", map[string]interface{}{
+		"uroPayOrderId":  out.ID,
+		"orderStatus":    out.Status,
+		"upiString":      fmt.Sprintf("upi://pay?pa=%s&pn=%s&am=%s&tr=%s", vpa, req.VPAName, amount, out.ID),
+		"qrCode":         "",
+		"amountInRupees": json.Number(amount),
+	})
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request) {
+	var req updateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, "error", "invalid request body", nil)
+		return
+	}
+	if req.UroPayOrderID == "" || req.ReferenceNumber == "" {
+		writeJSON(w, http.StatusBadRequest, "error", "uroPayOrderId and referenceNumber are required", nil)
+		return
+	}
+
+	s.mu.Lock()
+	o, ok := s.orders[req.UroPayOrderID]
+	if !ok {
+		s.mu.Unlock()
+		writeJSON(w, http.StatusNotFound, "error", "order not found", nil)
+		return
+	}
+	if o.Status == StatusCreated {
+		o.Status = StatusUpdated
+	}
+	o.ReferenceNumber = req.ReferenceNumber
+	out := *o
+	s.mu.Unlock()
+
+	if s.opts.PayOnUpdate && out.Status == StatusUpdated {
+		go s.settle(out.ID, out.ReferenceNumber)
+	}
+
+	writeJSON(w, http.StatusOK, "success", "order updated", map[string]interface{}{
+		"uroPayOrderId": out.ID,
+		"orderStatus":   out.Status,
+	})
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	o, ok := s.Order(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, "error", "order not found", nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, "success", "order status", map[string]interface{}{
+		"uroPayOrderId": o.ID,
+		"orderStatus":   o.Status,
+	})
+}
+
+// settle plays the customer's bank: after WebhookDelay the payment either
+// goes through (and the merchant is told) or fails.
+func (s *Server) settle(id, referenceNumber string) {
+	time.Sleep(s.opts.WebhookDelay)
+
+	if strings.HasPrefix(referenceNumber, "FAIL") {
+		if err := s.Fail(id); err != nil {
+			log.Printf("uropaysim: failed to fail order %s: %v", id, err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Complete(ctx, id, referenceNumber); err != nil {
+		log.Printf("uropaysim: webhook for order %s failed: %v", id, err)
+	}
+}
+
+// requireSecret checks the bearer token, which is the hex SHA-512 of the
+// merchant secret.
+func (s *Server) requireSecret(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+hashSecret(s.opts.Secret) {
+			writeJSON(w, http.StatusUnauthorized, "error", "invalid credentials", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON writes UroPay's response envelope; the HTTP status doubles as
+// the "code" field.
+func writeJSON(w http.ResponseWriter, code int, status, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    code,
+		"status":  status,
+		"message": message,
+		"data":    data,
+	})
+}
+
+func formatRupees(paise int64) string {
+	return fmt.Sprintf("%d.%02d", paise/100, paise%100)
+}
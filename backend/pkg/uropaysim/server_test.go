@@ -0,0 +1,179 @@
+package uropaysim_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/namanjain.3009/daily_bazaar/internal/config"
+	"github.com/namanjain.3009/daily_bazaar/internal/models"
+	"github.com/namanjain.3009/daily_bazaar/internal/repository/memory"
+	"github.com/namanjain.3009/daily_bazaar/internal/services"
+	"github.com/namanjain.3009/daily_bazaar/pkg/uropaysim"
+)
+
+const (
+	apiKey = "sim-key"
+	secret = "sim-secret"
+)
+
+// merchant is the backend's payment side, in memory, talking to the
+// simulator through the real UroPay gateway.
+type merchant struct {
+	sim      *uropaysim.Server
+	orders   *memory.OrderRepository
+	payments *services.PaymentService
+	webhooks *httptest.Server
+	order    *models.Order
+}
+
+func newMerchant(t *testing.T) *merchant {
+	t.Helper()
+	m := &merchant{}
+
+	// The simulator needs the webhook URL before the services exist
+	m.webhooks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := m.payments.HandleWebhook(r.Context(), "uropay", r.Header, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(m.webhooks.Close)
+
+	m.sim = uropaysim.New(uropaysim.Options{APIKey: apiKey, Secret: secret, WebhookURL: m.webhooks.URL})
+	api := httptest.NewServer(m.sim)
+	t.Cleanup(api.Close)
+
+	cfg := &config.Config{
+		UroPayAPIKey:          apiKey,
+		UroPaySecret:          secret,
+		UroPayBaseURL:         api.URL,
+		GSTDefaultTaxClass:    models.TaxClassGST5,
+		PricesIncludeTax:      true,
+		DefaultPaymentGateway: "uropay",
+	}
+	db := memory.NewDB()
+	products := memory.NewProductRepository(db)
+	m.orders = memory.NewOrderRepository(db)
+	events := memory.NewOrderEventRepository(db)
+	wallets := memory.NewWalletRepository(db)
+	gateway := services.NewUroPayGateway(cfg, api.Client())
+
+	machine, err := services.NewOrderStateMachine(services.DefaultOrderStateMachine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipping, err := services.NewShippingCalculator(services.DefaultShippingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunds := services.NewRefundService(memory.NewRefundRepository(db), m.orders, wallets, gateway)
+	invoices := services.NewInvoiceService(cfg, memory.NewInvoiceRepository(db), m.orders)
+	workflow := services.NewOrderWorkflow(machine, m.orders, events, products, memory.NewUserRepository(db), &services.EmailService{}, refunds, invoices)
+	orderService := services.NewOrderService(cfg, m.orders, products, events, wallets, workflow, shipping)
+	m.payments = services.NewPaymentService(cfg, m.orders, workflow, memory.NewPaymentLookupRepository(db),
+		memory.NewWebhookQuarantineRepository(db), memory.NewWebhookEventRepository(db), gateway)
+
+	ctx := context.Background()
+	if err := products.CreateProduct(ctx, &models.Product{ID: "p1", Name: "Rice", PriceCents: 15300, Stock: 10, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	m.order, err = orderService.CreateOrder(ctx, "u1", &models.CreateOrderRequest{
+		ShippingAddress: map[string]interface{}{"pincode": "560001", "state": "Karnataka"},
+		Items:           []models.CreateOrderItem{{ProductID: "p1", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func (m *merchant) stored(t *testing.T) *models.Order {
+	t.Helper()
+	order, err := m.orders.GetOrderByID(context.Background(), m.order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestPaymentThroughSimulator(t *testing.T) {
+	m := newMerchant(t)
+	ctx := context.Background()
+
+	// generate
+	intent, err := m.payments.InitiatePayment(ctx, &models.InitiatePaymentRequest{
+		OrderID: m.order.ID, CustomerName: "Asha", CustomerEmail: "asha@example.com",
+	}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	simOrder, ok := m.sim.Order(intent.GatewayOrderID)
+	if !ok {
+		t.Fatalf("simulator has no order %q", intent.GatewayOrderID)
+	}
+	if simOrder.MerchantOrderID != m.order.ID || simOrder.AmountPaise != m.order.TotalCents {
+		t.Fatalf("simulator order for %s of %d paise, want %s for %d", simOrder.MerchantOrderID, simOrder.AmountPaise, m.order.ID, m.order.TotalCents)
+	}
+
+	// update
+	if err := m.payments.SubmitUPIReference(ctx, m.order.ID, "412345678901", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if simOrder, _ = m.sim.Order(intent.GatewayOrderID); simOrder.Status != uropaysim.StatusUpdated || simOrder.ReferenceNumber != "412345678901" {
+		t.Fatalf("simulator order is %s with reference %q, want UPDATED with the submitted one", simOrder.Status, simOrder.ReferenceNumber)
+	}
+
+	// status, before the money arrives
+	st, err := m.payments.GetPaymentStatus(ctx, m.order.ID, "u1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.GatewayStatus != uropaysim.StatusUpdated || st.PaymentStatus != models.PaymentStatusUpdated {
+		t.Fatalf("status = %s at the gateway, %s locally; want UPDATED and %s", st.GatewayStatus, st.PaymentStatus, models.PaymentStatusUpdated)
+	}
+	if got := m.stored(t).Status; got != models.OrderStatusPending {
+		t.Fatalf("order is %s before payment, want pending", got)
+	}
+
+	// signed webhook
+	if err := m.sim.Complete(ctx, intent.GatewayOrderID, ""); err != nil {
+		t.Fatal(err)
+	}
+	order := m.stored(t)
+	if order.Status != models.OrderStatusConfirmed || order.PaymentMetadata["payment_status"] != models.PaymentStatusCompleted {
+		t.Fatalf("order is %s with payment %v, want confirmed and completed", order.Status, order.PaymentMetadata["payment_status"])
+	}
+}
+
+func TestSimulatorWebhookWithWrongSecretIsRejected(t *testing.T) {
+	m := newMerchant(t)
+	ctx := context.Background()
+
+	intent, err := m.payments.InitiatePayment(ctx, &models.InitiatePaymentRequest{
+		OrderID: m.order.ID, CustomerName: "Asha", CustomerEmail: "asha@example.com",
+	}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.payments.SubmitUPIReference(ctx, m.order.ID, "412345678901", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	simOrder, _ := m.sim.Order(intent.GatewayOrderID)
+
+	forger := &uropaysim.Emitter{URL: m.webhooks.URL, Secret: "not-the-secret", Environment: "TEST"}
+	err = forger.Emit(ctx, uropaysim.WebhookPayload{
+		Amount:          intent.AmountInRupees,
+		ReferenceNumber: simOrder.ReferenceNumber,
+		From:            "Asha",
+		VPA:             "dailybazaar@upi",
+	})
+	if err == nil {
+		t.Fatal("a webhook signed with the wrong secret was accepted")
+	}
+	if got := m.stored(t).Status; got != models.OrderStatusPending {
+		t.Fatalf("order is %s, want pending", got)
+	}
+}
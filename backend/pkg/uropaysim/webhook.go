@@ -0,0 +1,87 @@
+package uropaysim
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookPayload is the body UroPay posts when a payment is received.
+type WebhookPayload struct {
+	Amount          string `json:"amount"` // in rupees, e.g. "153.00"
+	ReferenceNumber string `json:"referenceNumber"`
+	From            string `json:"from"`
+	VPA             string `json:"vpa"`
+}
+
+// Sign computes X-Uropay-Signature for a payload: HMAC-SHA256, keyed with the
+// hex SHA-512 of the merchant secret, over the JSON of the payload fields plus
+// the environment with keys in sorted order.
+func Sign(secret, environment string, p WebhookPayload) string {
+	data := map[string]string{
+		"amount":          p.Amount,
+		"from":            p.From,
+		"referenceNumber": p.ReferenceNumber,
+		"vpa":             p.VPA,
+		"environment":     environment,
+	}
+	// encoding/json sorts map keys
+	body, _ := json.Marshal(data)
+
+	mac := hmac.New(sha256.New, []byte(hashSecret(secret)))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Emitter delivers signed UroPay webhooks to a merchant endpoint.
+type Emitter struct {
+	URL         string
+	Secret      string
+	Environment string
+	Client      *http.Client
+}
+
+func (e *Emitter) Emit(ctx context.Context, p WebhookPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Uropay-Environment", e.Environment)
+	req.Header.Set("X-Uropay-Signature", Sign(e.Secret, e.Environment, p))
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// hashSecret is what UroPay clients send as their bearer token and use as
+// the webhook HMAC key.
+func hashSecret(secret string) string {
+	h := sha512.Sum512([]byte(secret))
+	return hex.EncodeToString(h[:])
+}